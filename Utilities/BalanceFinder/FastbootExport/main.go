@@ -15,7 +15,7 @@ import (
 
 func main() {
 	var (
-		filename = flag.String("f", "FastBoot_MAIN_v8.db", "FastbootFile location")
+		filename = flag.String("f", "FastBoot_MAIN_v12.db", "FastbootFile location")
 	)
 
 	flag.Parse()
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	"github.com/FactomProject/factomd/database/hybridDB"
+	"github.com/FactomProject/factomd/state"
+)
+
+const usage = `Usage: CoinbaseReport [flags] <identity chain id>
+
+Reports the coinbase payouts of an authority identity from a factomd database: the output of every
+payout period, the efficiency applied, the cancels and the share left for grants.
+
+`
+
+func main() {
+	dbType := flag.String("db", "level", "Database type, level or bolt")
+	path := flag.String("path", "", "Database to read, e.g. ~/.factom/m2/main-database/ldb/MAIN/factoid_level.db")
+	network := flag.String("network", "MAIN", "Network of the database, LOCAL and CUSTOM networks pay every 5 blocks")
+	declaration := flag.Int("declaration", 0, "Blocks from a descriptor to its payout, if not the default of the network")
+	from := flag.Uint("from", 0, "First descriptor height")
+	to := flag.Uint("to", math.MaxUint32, "Last descriptor height")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *path == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	chainID, err := primitives.HexToHash(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Bad identity chain id:", err)
+		os.Exit(1)
+	}
+
+	switch *network {
+	case "MAIN", "TEST":
+	case "LOCAL":
+		constants.SetLocalCoinBaseConstants()
+	default:
+		constants.SetCustomCoinBaseConstants()
+	}
+	if *declaration > 0 {
+		constants.COINBASE_DECLARATION = uint32(*declaration)
+	}
+
+	var dbase *hybridDB.HybridDB
+	switch *dbType {
+	case "bolt":
+		dbase = hybridDB.NewBoltMapHybridDB(nil, *path)
+	case "level":
+		dbase, err = hybridDB.NewLevelMapHybridDB(*path, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "-db should be level or bolt")
+		os.Exit(1)
+	}
+	dbo := databaseOverlay.NewOverlay(dbase)
+	defer dbo.Close()
+
+	r, err := state.NewCoinbaseReport(dbo, chainID, uint32(*from), uint32(*to))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	printReport(r)
+}
+
+func printReport(r *state.CoinbaseReport) {
+	fmt.Printf("Identity %s, descriptors %d to %d, saved to %d\n\n", r.IdentityChainID, r.From, r.To, r.Height)
+	fmt.Printf("%10s %10s %5s %-52s %10s %14s %14s  %s\n",
+		"Descriptor", "Payout", "Index", "Address", "Efficiency", "Amount", "Grant share", "Status")
+	for _, p := range r.Periods {
+		status := "Missing"
+		switch {
+		case p.CancelHeight != 0:
+			status = fmt.Sprintf("Cancelled at %d", p.CancelHeight)
+		case p.Paid:
+			status = "Paid"
+		case p.PayoutHeight > r.Height:
+			status = "Pending"
+		}
+		fmt.Printf("%10d %10d %5d %-52s %9s%% %14s %14s  %s\n",
+			p.DescriptorHeight, p.PayoutHeight, p.Index, p.Address, primitives.EfficiencyToString(p.Efficiency),
+			primitives.ConvertDecimalToString(p.Amount), primitives.ConvertDecimalToString(p.GrantShare), status)
+	}
+	fmt.Println()
+	fmt.Printf("Paid        %s FCT\n", primitives.ConvertDecimalToString(r.Paid))
+	fmt.Printf("Pending     %s FCT\n", primitives.ConvertDecimalToString(r.Pending))
+	fmt.Printf("Cancelled   %s FCT\n", primitives.ConvertDecimalToString(r.Cancelled))
+	fmt.Printf("Grant share %s FCT\n", primitives.ConvertDecimalToString(r.GrantShare))
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/FactomProject/factomd/engine"
+	"github.com/FactomProject/factomd/state"
+)
+
+const usage = `Usage: JournalReplay <command> [flags]
+
+  capture   Save the journal of a running node (-journaling), or convert an old journal
+  replay    Replay a journal into a fresh node, and compare its blocks to a golden file
+  minimize  Shrink a journal to the messages that still reproduce an issue
+
+Run JournalReplay <command> -h for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "capture":
+		err = capture(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	case "minimize":
+		err = minimize(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func capture(args []string) error {
+	flags := flag.NewFlagSet("capture", flag.ExitOnError)
+	url := flags.String("url", "http://localhost:8088/debug", "Debug API of the node")
+	file := flags.String("f", "", "Convert this journal file instead of asking a node")
+	out := flags.String("o", "incident.journal", "Journal to write")
+	flags.Parse(args)
+
+	var data []byte
+	var err error
+	if *file != "" {
+		data, err = ioutil.ReadFile(*file)
+	} else {
+		data, err = fetchJournal(*url)
+	}
+	if err != nil {
+		return err
+	}
+	header, entries, err := state.ReadJournal(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := writeJournal(*out, header, entries); err != nil {
+		return err
+	}
+	fmt.Printf("Captured %d messages of %s to %s\n", len(entries), header.Node, *out)
+	return nil
+}
+
+// fetchJournal gets the lines of the journal of a node with the "messages" call of the debug API
+func fetchJournal(url string) ([]byte, error) {
+	req := `{"jsonrpc": "2.0", "id": 0, "method": "messages"}`
+	resp, err := http.Post(url, "application/json", strings.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Result *struct {
+			Messages []json.RawMessage
+		}
+		Error *struct {
+			Message string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	if r.Error != nil {
+		return nil, fmt.Errorf("%s: %s", url, r.Error.Message)
+	}
+	if r.Result == nil || len(r.Result.Messages) == 0 {
+		return nil, fmt.Errorf("%s: empty journal, is the node running with -journaling?", url)
+	}
+	var data []byte
+	for _, line := range r.Result.Messages {
+		data = append(append(data, line...), '\n')
+	}
+	return data, nil
+}
+
+func replay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	config := flags.String("config", "", "Config file of the node that wrote the journal")
+	golden := flags.String("golden", "", "Golden file of the blocks the replay must save")
+	update := flags.Bool("update", false, "Write the blocks of the replay to the golden file")
+	trace := flags.String("trace", "", "Write a consensus trace of the replay to this directory")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("replay needs one journal")
+	}
+
+	header, entries, err := readJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *trace != "" {
+		if err := os.MkdirAll(*trace, 0755); err != nil {
+			return err
+		}
+	}
+	result, err := engine.ReplayJournal(header, entries, newState(*config, header.Network, *trace))
+	if err != nil {
+		return err
+	}
+	for h, keymr := range result.Blocks {
+		fmt.Printf("%6d %s\n", h, keymr)
+	}
+	for _, v := range result.Violations {
+		fmt.Println(v)
+	}
+
+	if *golden == "" {
+		return nil
+	}
+	if *update {
+		f, err := os.Create(*golden)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return engine.WriteGolden(f, result.Blocks)
+	}
+	want, err := readGolden(*golden)
+	if err != nil {
+		return err
+	}
+	if err := engine.CompareGolden(want, result.Blocks); err != nil {
+		return err
+	}
+	if len(result.Violations) > 0 {
+		return fmt.Errorf("%d invariant violations", len(result.Violations))
+	}
+	fmt.Printf("The %d blocks match %s\n", len(want), *golden)
+	return nil
+}
+
+func minimize(args []string) error {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	config := flags.String("config", "", "Config file of the node that wrote the journal")
+	golden := flags.String("golden", "", "Golden file of the right blocks, the issue reproduces while the replay saves other blocks")
+	violation := flags.String("violation", "", "The issue reproduces while an invariant violation contains this text")
+	out := flags.String("o", "minimized.journal", "Journal to write")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("minimize needs one journal")
+	}
+	if (*golden == "") == (*violation == "") {
+		return fmt.Errorf("minimize needs either -golden or -violation")
+	}
+
+	header, entries, err := readJournal(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var want []string
+	if *golden != "" {
+		if want, err = readGolden(*golden); err != nil {
+			return err
+		}
+	}
+
+	runs := 0
+	reproduces := func(entries []state.JournalEntry) bool {
+		runs++
+		result, err := engine.ReplayJournal(header, entries, newState(*config, header.Network, ""))
+		if err != nil {
+			return false
+		}
+		if *violation != "" {
+			for _, v := range result.Violations {
+				if strings.Contains(v, *violation) {
+					return true
+				}
+			}
+			return false
+		}
+		return engine.CompareGolden(want, result.Blocks) != nil
+	}
+
+	if !reproduces(entries) {
+		return fmt.Errorf("The journal doesn't reproduce the issue")
+	}
+	minimized := engine.MinimizeJournal(entries, reproduces)
+	if err := writeJournal(*out, header, minimized); err != nil {
+		return err
+	}
+	fmt.Printf("Minimized %d messages to %d in %d replays, wrote %s\n", len(entries), len(minimized), runs, *out)
+	return nil
+}
+
+func newState(config, network, trace string) func() *state.State {
+	return func() *state.State {
+		s := engine.NewReplayState(config, network)
+		s.ConsensusTraceDir = trace
+		return s
+	}
+}
+
+func readJournal(name string) (*state.JournalHeader, []state.JournalEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return state.ReadJournal(f)
+}
+
+func writeJournal(name string, header *state.JournalHeader, entries []state.JournalEntry) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return state.WriteJournal(f, header, entries)
+}
+
+func readGolden(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return engine.ReadGolden(f)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/lightclient"
+	"github.com/FactomProject/factomd/state"
+)
+
+const usage = `Usage: LightClient <command> [flags]
+
+  sync         Sync the directory block headers and admin blocks, and check their signatures
+  entry HASH   Check an entry is in a signed directory block
+  tx TXID      Check a factoid transaction is in a signed directory block
+
+Run LightClient <command> -h for the flags of a command.
+`
+
+type options struct {
+	url      string
+	file     string
+	network  string
+	identity string
+	key      string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "sync":
+		err = syncHeaders(os.Args[2:])
+	case "entry":
+		err = verify("entry", os.Args[2:])
+	case "tx":
+		err = verify("tx", os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(name string, args []string) (*options, *flag.FlagSet) {
+	o := new(options)
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&o.url, "url", "http://localhost:8088/v2", "API of the full node")
+	flags.StringVar(&o.file, "f", "lightclient.dat", "File of the synced headers and authority set")
+	flags.StringVar(&o.network, "network", "MAIN", "Network: MAIN, TEST, LOCAL or CUSTOM")
+	flags.StringVar(&o.identity, "bootstrapidentity", "", "Bootstrap identity of a CUSTOM network")
+	flags.StringVar(&o.key, "bootstrapkey", "", "Bootstrap key of a CUSTOM network")
+	flags.Parse(args)
+	return o, flags
+}
+
+// open returns a client on the headers of the file, synced to the highest block of the node
+func open(o *options) (*lightclient.Client, error) {
+	s := new(state.State)
+	switch strings.ToUpper(o.network) {
+	case "MAIN":
+		s.NetworkNumber = constants.NETWORK_MAIN
+	case "TEST":
+		s.NetworkNumber = constants.NETWORK_TEST
+	case "LOCAL":
+		s.NetworkNumber = constants.NETWORK_LOCAL
+	case "CUSTOM":
+		if o.identity == "" || o.key == "" {
+			return nil, fmt.Errorf("A CUSTOM network needs -bootstrapidentity and -bootstrapkey")
+		}
+		s.NetworkNumber = constants.NETWORK_CUSTOM
+		s.CustomBootstrapIdentity = o.identity
+		s.CustomBootstrapKey = o.key
+	default:
+		return nil, fmt.Errorf("Unknown network %s", o.network)
+	}
+	c := lightclient.New(&lightclient.APISource{URL: o.url}, s.GetNetworkBootStrapIdentity(), s.GetNetworkBootStrapKey())
+
+	data, err := ioutil.ReadFile(o.file)
+	if err == nil {
+		err = c.UnmarshalBinary(data)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %v", o.file, err)
+	}
+
+	added, syncErr := c.Sync()
+	if added > 0 {
+		// Keep the blocks that were checked, even if a later one failed
+		data, err := c.MarshalBinary()
+		if err == nil {
+			err = ioutil.WriteFile(o.file, data, 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if syncErr != nil {
+		return nil, syncErr
+	}
+	return c, nil
+}
+
+func syncHeaders(args []string) error {
+	o, _ := parseFlags("sync", args)
+	c, err := open(o)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Synced %d headers, signed to the height %d, %d federated servers\n",
+		len(c.Headers), c.VerifiedHeight(), c.Identities.FedServerCount())
+	return nil
+}
+
+func verify(what string, args []string) error {
+	o, flags := parseFlags(what, args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%s needs one hash", what)
+	}
+	hash, err := primitives.HexToHash(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	c, err := open(o)
+	if err != nil {
+		return err
+	}
+
+	var height uint32
+	var tx interfaces.ITransaction
+	if what == "entry" {
+		height, err = c.VerifyEntry(hash)
+	} else {
+		tx, height, err = c.VerifyTransaction(hash)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is in the directory block %d, %s\n", hash, height, c.KeyMRs[height])
+	if tx != nil {
+		fmt.Println(tx)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FactomProject/factomd/state"
+)
+
+func main() {
+	var (
+		out      = flag.String("o", "trace.html", "HTML file to write the sequence diagrams to")
+		timeline = flag.Bool("timeline", false, "Print the merged timeline instead of writing the HTML")
+		from     = flag.Int("from", 0, "First block to show")
+		to       = flag.Int("to", -1, "Last block to show, -1 for all")
+		events   = flag.String("events", "", "Comma separated events to show, all of them if empty")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <trace files or directories>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	merged, err := readTraces(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	merged = filterEvents(merged, *from, *to, *events)
+
+	if *timeline {
+		for i := range merged {
+			fmt.Println(merged[i].String())
+		}
+		return
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if err := Render(f, merged); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d events of %d blocks to %s\n", len(merged), len(groupBlocks(merged)), *out)
+}
+
+// readTraces reads the trace files, and the *_trace.jsonl files of the directories, into one timeline
+func readTraces(args []string) ([]state.TraceEvent, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(arg, "*_trace.jsonl"))
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("No traces in %s", arg)
+		}
+		files = append(files, matches...)
+	}
+
+	var traces [][]state.TraceEvent
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		trace, err := state.ReadTrace(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		traces = append(traces, trace)
+	}
+	return state.MergeTraces(traces...), nil
+}
+
+// filterEvents keeps the events of the blocks from..to (to < 0 for all) of the given kinds
+func filterEvents(all []state.TraceEvent, from, to int, kinds string) []state.TraceEvent {
+	keep := map[string]bool{}
+	for _, k := range strings.Split(kinds, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keep[k] = true
+		}
+	}
+	var events []state.TraceEvent
+	for _, e := range all {
+		if int(e.DBHeight) < from || (to >= 0 && int(e.DBHeight) > to) {
+			continue
+		}
+		if len(keep) > 0 && !keep[e.Event] {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/FactomProject/factomd/state"
+)
+
+// Layout of the sequence diagrams, in pixels
+const (
+	laneWidth = 220
+	rowHeight = 18
+	topMargin = 40
+)
+
+var eventColors = map[string]string{
+	state.TraceReceived: "#999999",
+	state.TraceAck:      "#1f77b4",
+	state.TraceVMHeight: "#2ca02c",
+	state.TraceEOM:      "#ff7f0e",
+	state.TraceDBSig:    "#9467bd",
+	state.TraceRound:    "#d62728",
+	state.TraceVote:     "#e377c2",
+	state.TraceSaved:    "#000000",
+}
+
+// Lane is the lifeline of a node
+type Lane struct {
+	Name string
+	X    int
+}
+
+// Row is one event on the lifeline of its node
+type Row struct {
+	X, Y   int
+	Color  string
+	Label  string
+	Detail string // Shown when hovering over the event
+}
+
+// Arrow is a message from one node to another
+type Arrow struct {
+	X1, Y1, X2, Y2 int
+}
+
+// Block is the sequence diagram of the events of one directory block
+type Block struct {
+	DBHeight uint32
+	Width    int
+	Height   int
+	Lanes    []Lane
+	Rows     []Row
+	Arrows   []Arrow
+}
+
+// groupBlocks splits the timeline by directory block height, in order of height
+func groupBlocks(events []state.TraceEvent) map[uint32][]state.TraceEvent {
+	blocks := make(map[uint32][]state.TraceEvent)
+	for _, e := range events {
+		blocks[e.DBHeight] = append(blocks[e.DBHeight], e)
+	}
+	return blocks
+}
+
+// layout places the events of a block on the lifelines of the nodes
+func layout(dbheight uint32, events []state.TraceEvent, nodes []string) Block {
+	b := Block{DBHeight: dbheight, Width: len(nodes) * laneWidth, Height: topMargin + (len(events)+1)*rowHeight}
+	column := make(map[string]int)
+	for i, n := range nodes {
+		column[n] = i*laneWidth + laneWidth/2
+		b.Lanes = append(b.Lanes, Lane{n, column[n]})
+	}
+
+	// Where each node last did something with a message, so received messages get an arrow from there
+	origin := make(map[string]int)
+	for i, e := range events {
+		y := topMargin + (i+1)*rowHeight
+		label := e.Event
+		if e.Msg != "" {
+			label += " " + e.Msg
+		}
+		if e.Event == state.TraceVMHeight || e.Event == state.TraceAck {
+			label += fmt.Sprintf(" %d/%d", e.VM, e.Height)
+		}
+		b.Rows = append(b.Rows, Row{X: column[e.Node], Y: y, Color: eventColors[e.Event], Label: label, Detail: e.String()})
+
+		if e.Event == state.TraceReceived && e.From != "" {
+			if x, ok := column[e.From]; ok {
+				y1 := y
+				if oy, ok := origin[e.From+e.Hash]; ok && e.Hash != "" {
+					y1 = oy
+				}
+				b.Arrows = append(b.Arrows, Arrow{x, y1, column[e.Node], y})
+			}
+		}
+		if e.Hash != "" {
+			if _, ok := origin[e.Node+e.Hash]; !ok {
+				origin[e.Node+e.Hash] = y
+			}
+		}
+	}
+	return b
+}
+
+// Render writes an HTML page with a sequence diagram for every block of the timeline
+func Render(w io.Writer, events []state.TraceEvent) error {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, e := range events {
+		if !seen[e.Node] {
+			seen[e.Node] = true
+			nodes = append(nodes, e.Node)
+		}
+	}
+	sort.Strings(nodes)
+
+	blocks := groupBlocks(events)
+	var heights []int
+	for h := range blocks {
+		heights = append(heights, int(h))
+	}
+	sort.Ints(heights)
+
+	var page []Block
+	for _, h := range heights {
+		page = append(page, layout(uint32(h), blocks[uint32(h)], nodes))
+	}
+	return pageTemplate.Execute(w, struct {
+		Nodes  []string
+		Blocks []Block
+		Colors map[string]string
+	}{nodes, page, eventColors})
+}
+
+var pageTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Consensus trace</title>
+<style>
+body { font-family: sans-serif; }
+svg text { font-size: 11px; font-family: monospace; }
+.lane { stroke: #cccccc; stroke-dasharray: 4 4; }
+.arrow { stroke: #999999; marker-end: url(#head); }
+.legend span { display: inline-block; margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>Consensus trace of {{len .Nodes}} nodes</h1>
+<p class="legend">{{range $event, $color := .Colors}}<span style="color: {{$color}}">&#9679; {{$event}}</span>{{end}}</p>
+{{range .Blocks}}
+<h2 id="block-{{.DBHeight}}">Block {{.DBHeight}}</h2>
+<svg width="{{.Width}}" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg">
+<defs><marker id="head" markerWidth="8" markerHeight="8" refX="8" refY="4" orient="auto"><path d="M0,0 L8,4 L0,8 z" fill="#999999"/></marker></defs>
+{{$height := .Height}}{{range .Lanes}}<text x="{{.X}}" y="20" text-anchor="middle" font-weight="bold">{{.Name}}</text>
+<line class="lane" x1="{{.X}}" y1="28" x2="{{.X}}" y2="{{$height}}"/>
+{{end}}{{range .Arrows}}<line class="arrow" x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+{{end}}{{range .Rows}}<g><title>{{.Detail}}</title><circle cx="{{.X}}" cy="{{.Y}}" r="4" fill="{{.Color}}"/><text x="{{.X}}" y="{{.Y}}" dx="7" dy="4" fill="{{.Color}}">{{.Label}}</text></g>
+{{end}}</svg>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/FactomProject/factomd/state"
+)
+
+func TestRender(t *testing.T) {
+	events := []state.TraceEvent{
+		{Node: "FNode0", Time: 1, Event: state.TraceAck, DBHeight: 3, Msg: "EOM", Hash: "aa"},
+		{Node: "FNode1", Time: 2, Event: state.TraceReceived, DBHeight: 3, Msg: "EOM", Hash: "aa", From: "FNode0"},
+		{Node: "FNode1", Time: 3, Event: state.TraceReceived, DBHeight: 3, Msg: "EOM", Hash: "bb", From: "FNode2"},
+		{Node: "FNode2", Time: 4, Event: state.TraceSaved, DBHeight: 4, Info: "<script>"},
+	}
+
+	b := layout(3, events[:3], []string{"FNode0", "FNode1", "FNode2"})
+	if len(b.Lanes) != 3 || len(b.Rows) != 3 {
+		t.Fatalf("Wrong layout %+v", b)
+	}
+	if len(b.Arrows) != 2 {
+		t.Fatalf("Expected 2 arrows, got %+v", b.Arrows)
+	}
+	if a := b.Arrows[0]; a.X1 != b.Lanes[0].X || a.Y1 != b.Rows[0].Y || a.X2 != b.Lanes[1].X || a.Y2 != b.Rows[1].Y {
+		t.Errorf("Arrow not from the ack to the receive %+v", a)
+	}
+	if a := b.Arrows[1]; a.X1 != b.Lanes[2].X || a.Y1 != a.Y2 {
+		t.Errorf("Arrow without an origin not drawn from the sender %+v", a)
+	}
+
+	var out bytes.Buffer
+	if err := Render(&out, events); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, s := range []string{`id="block-3"`, `id="block-4"`, "ack EOM", "&lt;script&gt;", "#1f77b4"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("Page doesn't contain %q", s)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("Page not escaped")
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	events := []state.TraceEvent{{DBHeight: 1, Event: "eom"}, {DBHeight: 2, Event: "eom"}, {DBHeight: 2, Event: "ack"}, {DBHeight: 3, Event: "eom"}}
+	if got := filterEvents(events, 2, -1, ""); len(got) != 3 {
+		t.Errorf("Kept %d events from block 2", len(got))
+	}
+	if got := filterEvents(events, 0, 2, "eom, saved"); len(got) != 2 {
+		t.Errorf("Kept %d eom events up to block 2", len(got))
+	}
+}
@@ -16,6 +16,12 @@ const (
 	_                       ActivationType = iota // 0 Don't use ZERO
 	ELECTION_NO_SORT                       = iota // 1 -- this is a passing activation and this ID may be reused once that height is passes and the references are removed
 	TESTNET_COINBASE_PERIOD                = iota // 2 -- this is a passing activation and this ID may be reused once that height is passes and the references are removed
+	FAST_FAILOVER                          = iota // 3
+	MULTISIG_RCD                           = iota // 4
+	TIMELOCK_RCD                           = iota // 5
+	GRANT_PROPOSALS                        = iota // 6
+	EC_TIPS                                = iota // 7
+	EC_DELEGATION                          = iota // 8
 	//
 	ACTIVATION_TYPE_COUNT = iota - 1 // Always Last
 )
@@ -40,18 +46,63 @@ func init() {
 			0, // active at the beginning of time unless overridden below
 			map[string]int{
 				"MAIN":                      146060 + 8*24*10 + 1, // On 6/20/18 11:45 mainnet was 146060, we want activation at 6/28/18 at ~12pm
-				"LOCAL":                     10,                   // Must be > 6 for TestActivationHeightElection to pass
-				"CUSTOM:fct_community_test": 33037 + 2*24*10 + 1,  // On 6/22/18 11:45 testnet was 33037, we want activation at 6/24/18 at 12:00pm
+				"TEST":                      0,
+				"LOCAL":                     10,                  // Must be > 6 for TestActivationHeightElection to pass
+				"CUSTOM:fct_community_test": 33037 + 2*24*10 + 1, // On 6/22/18 11:45 testnet was 33037, we want activation at 6/24/18 at 12:00pm
 			},
 		},
 		Activation{"TestNetCoinBasePeriod", TESTNET_COINBASE_PERIOD,
 			"Change testnet coin base payout delay to 140 blocks",
-			math.MaxInt32, // inactive unless overridden below
+			0, // Networks that were not listed got the 140 blocks from their first coinbase, keep them on it
 			map[string]int{
+				"MAIN":                      0,
+				"TEST":                      0,
 				"LOCAL":                     math.MaxInt32,
 				"CUSTOM:fct_community_test": 45335, //  Monday morning September 17
 			},
 		},
+		Activation{"FastFailover", FAST_FAILOVER,
+			"Hand a stalled VM to a backup audit server, ranked for the block ahead of time, within seconds",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
+		Activation{"MultisigRCD", MULTISIG_RCD,
+			"Accept factoid transactions from m of n multisig (RCD type 2) addresses",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
+		Activation{"TimelockRCD", TIMELOCK_RCD,
+			"Accept factoid transactions from height and time locked (RCD type 3) addresses",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
+		Activation{"GrantProposals", GRANT_PROPOSALS,
+			"Pay the grants proposed by signed entries in the grant chain, besides the hard coded grants",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
+		Activation{"ECTips", EC_TIPS,
+			"Accept commits paying entry credits over the max of their entry as a tip, and order the commits of a leader by tip",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
+		Activation{"ECDelegation", EC_DELEGATION,
+			"Read EC delegation entries, and let commits signed by a delegate spend the entry credits of its owner",
+			math.MaxInt32, // inactive unless overridden below
+			map[string]int{
+				"LOCAL": math.MaxInt32,
+			},
+		},
 	}
 
 	if ACTIVATION_TYPE_COUNT != len(activations) {
@@ -92,6 +143,8 @@ func networkname() string {
 	return netName
 }
 
+// IsActive returns true if the feature is active at the height, on the network of the node. A network
+// the activation does not list activates at its DefaultHeight.
 func IsActive(id ActivationType, height int) bool {
 	netName := networkname()
 	a, ok := ActivationMap[id]
@@ -109,8 +162,24 @@ func IsActive(id ActivationType, height int) bool {
 		} else {
 			fmt.Fprintf(os.Stderr, "Activation %s does not know network name \"%s\". Never activating.\n", id.String(), netName)
 		}
-		return true
+		h = a.DefaultHeight
 	}
 
 	return height >= h
 }
+
+// SetTestActivationHeight sets the height a feature activates at on the network of the node, and returns
+// a function that puts back the height it had. For tests.
+func SetTestActivationHeight(id ActivationType, height int) (restore func()) {
+	heights := ActivationMap[id].ActivationHeight
+	network := networkname()
+	old, ok := heights[network]
+	heights[network] = height
+	return func() {
+		if ok {
+			heights[network] = old
+		} else {
+			delete(heights, network)
+		}
+	}
+}
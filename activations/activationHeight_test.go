@@ -0,0 +1,75 @@
+package activations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsActiveUnlistedNetwork(t *testing.T) {
+	network := networkname()
+	heights := ActivationMap[FAST_FAILOVER].ActivationHeight
+	old, ok := heights[network]
+	delete(heights, network)
+	defer func() {
+		if ok {
+			heights[network] = old
+		} else {
+			delete(heights, network)
+		}
+	}()
+
+	// The default height applies from the first call on
+	for i := 0; i < 2; i++ {
+		if IsActive(FAST_FAILOVER, math.MaxInt32-1) {
+			t.Errorf("Call %d: activated on a network the activation does not list", i)
+		}
+	}
+	if h := heights[network]; h != math.MaxInt32 {
+		t.Errorf("Expected the default height to be recorded, got %d", h)
+	}
+}
+
+func TestActivationHeightsOfNetworks(t *testing.T) {
+	tests := []struct {
+		id      ActivationType
+		network string
+		height  int
+	}{
+		{ELECTION_NO_SORT, "MAIN", 146060 + 8*24*10 + 1},
+		{ELECTION_NO_SORT, "TEST", 0},
+		{ELECTION_NO_SORT, "CUSTOM:unlisted", 0},
+		// The payout delay of the networks that were not listed stays 140 blocks
+		{TESTNET_COINBASE_PERIOD, "MAIN", 0},
+		{TESTNET_COINBASE_PERIOD, "TEST", 0},
+		{TESTNET_COINBASE_PERIOD, "CUSTOM:unlisted", 0},
+		{TESTNET_COINBASE_PERIOD, "LOCAL", math.MaxInt32},
+		{TESTNET_COINBASE_PERIOD, "CUSTOM:fct_community_test", 45335},
+		{FAST_FAILOVER, "MAIN", math.MaxInt32},
+		{FAST_FAILOVER, "CUSTOM:unlisted", math.MaxInt32},
+		{EC_DELEGATION, "TEST", math.MaxInt32},
+	}
+	for _, test := range tests {
+		a := ActivationMap[test.id]
+		h, ok := a.ActivationHeight[test.network]
+		if !ok {
+			h = a.DefaultHeight
+		}
+		if h != test.height {
+			t.Errorf("%s on %s: expected the height %d, got %d", test.id, test.network, test.height, h)
+		}
+	}
+}
+
+func TestSetTestActivationHeight(t *testing.T) {
+	network := networkname()
+	before, ok := ActivationMap[FAST_FAILOVER].ActivationHeight[network]
+
+	restore := SetTestActivationHeight(FAST_FAILOVER, 7)
+	if !IsActive(FAST_FAILOVER, 7) || IsActive(FAST_FAILOVER, 6) {
+		t.Errorf("Expected the activation at 7")
+	}
+	restore()
+	if h, found := ActivationMap[FAST_FAILOVER].ActivationHeight[network]; found != ok || h != before {
+		t.Errorf("Expected the height %d back, got %d", before, h)
+	}
+}
@@ -0,0 +1,148 @@
+// Activation heights of CUSTOM networks can be set at boot, from factomd.conf or from a file signed by
+// the bootstrap key of the network, instead of in the list of activations
+
+package activations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/FactomProject/ed25519"
+)
+
+// ScheduleFile is a signed file of activation heights for a network
+type ScheduleFile struct {
+	Network     string         `json:"network"`     // e.g. "CUSTOM:fct_community_test"
+	Activations map[string]int `json:"activations"` // activation name to height
+	Signature   string         `json:"signature"`   // hex ed25519 signature of SigData() by the bootstrap key of the network
+}
+
+// SigData is what the bootstrap key signs, the file without its signature
+func (f *ScheduleFile) SigData() []byte {
+	data, _ := json.Marshal(ScheduleFile{Network: f.Network, Activations: f.Activations}) // map keys marshal sorted
+	return data
+}
+
+func (f *ScheduleFile) Sign(priv *[ed25519.PrivateKeySize]byte) {
+	f.Signature = hex.EncodeToString(ed25519.Sign(priv, f.SigData())[:])
+}
+
+func (f *ScheduleFile) Verify(pub *[ed25519.PublicKeySize]byte) error {
+	sig, err := hex.DecodeString(f.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("Bad signature %q", f.Signature)
+	}
+	var s [ed25519.SignatureSize]byte
+	copy(s[:], sig)
+	if !ed25519.VerifyCanonical(pub, f.SigData(), &s) {
+		return fmt.Errorf("Not signed by the bootstrap key %x", pub[:])
+	}
+	return nil
+}
+
+// LoadScheduleFile reads a schedule file and checks its signature and network
+func LoadScheduleFile(filename string, network string, pub *[ed25519.PublicKeySize]byte) (map[string]int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f := new(ScheduleFile)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	if f.Network != network {
+		return nil, fmt.Errorf("Schedule of the network %q, not %q", f.Network, network)
+	}
+	if err := f.Verify(pub); err != nil {
+		return nil, err
+	}
+	return f.Activations, nil
+}
+
+// ParseSchedule parses activation heights from the config, <name>:<height>,...
+func ParseSchedule(text string) (map[string]int, error) {
+	heights := make(map[string]int)
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Expected <name>:<height>, got %q", item)
+		}
+		h, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || h < 0 {
+			return nil, fmt.Errorf("Bad height in %q", item)
+		}
+		heights[strings.TrimSpace(parts[0])] = h
+	}
+	return heights, nil
+}
+
+// SetActivationHeights overrides the activation heights of a CUSTOM network, by activation name. Call it
+// at boot, before the first IsActive().
+func SetActivationHeights(network string, heights map[string]int) error {
+	if !strings.HasPrefix(network, "CUSTOM:") {
+		return fmt.Errorf("Only CUSTOM networks set their activation heights, not %q", network)
+	}
+	ids := make(map[string]ActivationType, len(ActivationMap))
+	for id, a := range ActivationMap {
+		ids[a.Name] = id
+	}
+	for name, h := range heights {
+		if _, ok := ids[name]; !ok {
+			return fmt.Errorf("Unknown activation %q", name)
+		}
+		if h < 0 {
+			return fmt.Errorf("Bad height %d for %s", h, name)
+		}
+	}
+	for name, h := range heights {
+		ActivationMap[ids[name]].ActivationHeight[network] = h
+	}
+	return nil
+}
+
+// GetActivationHeight returns the height a feature activates at on the network, math.MaxInt32 for never
+func GetActivationHeight(id ActivationType, network string) int {
+	a, ok := ActivationMap[id]
+	if !ok {
+		return math.MaxInt32
+	}
+	if h, ok := a.ActivationHeight[network]; ok {
+		return h
+	}
+	return a.DefaultHeight
+}
+
+// Activations returns the activations ordered by ID
+func Activations() []Activation {
+	list := make([]Activation, 0, len(ActivationMap))
+	for _, a := range ActivationMap {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list
+}
+
+// ScheduleHash is a hash of every activation height of the network, for nodes to compare their schedules
+func ScheduleHash(network string) string {
+	h := sha256.New()
+	for _, a := range Activations() {
+		fmt.Fprintf(h, "%s:%d,", a.Name, GetActivationHeight(a.Id, network))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NetworkName is the network name the activation heights are looked up by
+func NetworkName() string {
+	return networkname()
+}
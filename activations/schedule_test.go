@@ -0,0 +1,104 @@
+package activations_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FactomProject/ed25519"
+	. "github.com/FactomProject/factomd/activations"
+)
+
+func TestParseSchedule(t *testing.T) {
+	heights, err := ParseSchedule(" FastFailover:100, MultisigRCD:0 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 2 || heights["FastFailover"] != 100 || heights["MultisigRCD"] != 0 {
+		t.Errorf("Wrong heights %v", heights)
+	}
+	for _, bad := range []string{"FastFailover", "FastFailover:x", "FastFailover:-1", "FastFailover:1:2"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("Parsed %q", bad)
+		}
+	}
+}
+
+func TestSetActivationHeights(t *testing.T) {
+	network := "CUSTOM:schedule_test"
+	before := ScheduleHash(network)
+
+	if err := SetActivationHeights("LOCAL", map[string]int{"FastFailover": 5}); err == nil {
+		t.Errorf("Set the activation heights of a network that is not CUSTOM")
+	}
+	if err := SetActivationHeights(network, map[string]int{"FastFailover": 5, "NoSuchThing": 5}); err == nil {
+		t.Errorf("Set the height of an unknown activation")
+	}
+	if GetActivationHeight(FAST_FAILOVER, network) != math.MaxInt32 || ScheduleHash(network) != before {
+		t.Errorf("A failed SetActivationHeights changed the schedule")
+	}
+
+	if err := SetActivationHeights(network, map[string]int{"FastFailover": 5}); err != nil {
+		t.Fatal(err)
+	}
+	if h := GetActivationHeight(FAST_FAILOVER, network); h != 5 {
+		t.Errorf("Expected the height 5, got %d", h)
+	}
+	if h := GetActivationHeight(FAST_FAILOVER, "CUSTOM:other"); h != math.MaxInt32 {
+		t.Errorf("Changed another network, height %d", h)
+	}
+	if ScheduleHash(network) == before {
+		t.Errorf("The schedule hash did not change")
+	}
+
+	list := Activations()
+	if len(list) != ACTIVATION_TYPE_COUNT {
+		t.Fatalf("Expected %d activations, got %d", ACTIVATION_TYPE_COUNT, len(list))
+	}
+	for i, a := range list {
+		if a.Id != ActivationType(i+1) {
+			t.Errorf("Activation %d is %v", i, a.Id)
+		}
+	}
+}
+
+func TestLoadScheduleFile(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &ScheduleFile{Network: "CUSTOM:file_test", Activations: map[string]int{"TimelockRCD": 7}}
+	f.Sign(priv)
+	data, _ := json.Marshal(f)
+	filename := filepath.Join(dir, "activations.json")
+	ioutil.WriteFile(filename, data, 0600)
+
+	heights, err := LoadScheduleFile(filename, "CUSTOM:file_test", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 1 || heights["TimelockRCD"] != 7 {
+		t.Errorf("Wrong heights %v", heights)
+	}
+	if _, err := LoadScheduleFile(filename, "CUSTOM:file_test", other); err == nil {
+		t.Errorf("Loaded a file signed by another key")
+	}
+	if _, err := LoadScheduleFile(filename, "CUSTOM:another_test", pub); err == nil {
+		t.Errorf("Loaded the file of another network")
+	}
+
+	f.Activations["TimelockRCD"] = 8
+	data, _ = json.Marshal(f)
+	ioutil.WriteFile(filename, data, 0600)
+	if _, err := LoadScheduleFile(filename, "CUSTOM:file_test", pub); err == nil {
+		t.Errorf("Loaded a changed file")
+	}
+}
@@ -53,6 +53,8 @@ const (
 	INTERNALSTARTELECTION                     // 39
 	FEDVOTE_MSG_BASE                          // 40
 	SYNC_MSG                                  // 41
+	DBSTATE_COMPACT_MSG                       // 42
+	DBSTATE_COMPACT_MISSING_MSG               // 43
 
 	NUM_MESSAGES // Not used, just a counter for the number of messages.
 )
@@ -70,12 +72,37 @@ func NormallyFullBroadcast(t byte) bool {
 func NormallyPeer2Peer(t byte) bool {
 	switch t {
 	case MISSING_MSG, MISSING_DATA, DATA_RESPONSE, MISSING_MSG_RESPONSE, BOUNCE_MSG, BOUNCEREPLY_MSG,
-		MISSING_ENTRY_BLOCKS, ENTRY_BLOCK_RESPONSE, DBSTATE_MSG, DBSTATE_MISSING_MSG:
+		MISSING_ENTRY_BLOCKS, ENTRY_BLOCK_RESPONSE, DBSTATE_MSG, DBSTATE_MISSING_MSG,
+		DBSTATE_COMPACT_MSG, DBSTATE_COMPACT_MISSING_MSG:
 		return true
 	}
 	return false
 }
 
+// Gossip policies, how broadcast messages are spread over the p2p network
+const (
+	GOSSIP_FLOOD     byte = iota // Send the message to the broadcast peers
+	GOSSIP_INVENTORY             // Announce the message hash, and only send the message to the peers asking for it
+)
+
+// GossipPolicies holds the gossip policy for each message type.  Types not listed are flooded.
+// Latency critical consensus messages (acks, EOMs, DBSigs, elections) must stay GOSSIP_FLOOD;
+// messages most peers already have are cheaper to spread by inventory.
+var GossipPolicies = map[byte]byte{
+	COMMIT_CHAIN_MSG:        GOSSIP_INVENTORY,
+	COMMIT_ENTRY_MSG:        GOSSIP_INVENTORY,
+	REVEAL_ENTRY_MSG:        GOSSIP_INVENTORY,
+	FACTOID_TRANSACTION_MSG: GOSSIP_INVENTORY,
+}
+
+// GossipPolicy returns the gossip policy for broadcasts of the given message type
+func GossipPolicy(t byte) byte {
+	if policy, ok := GossipPolicies[t]; ok {
+		return policy
+	}
+	return GOSSIP_FLOOD
+}
+
 // Entry Credit Block entries
 const (
 	ECIDServerIndexNumber byte = iota // 0 Must be these values, per the specification
@@ -137,6 +164,10 @@ func MessageName(Type byte) string {
 		return "REMOVESERVER"
 	case DBSTATE_MSG:
 		return "DBState"
+	case DBSTATE_COMPACT_MSG:
+		return "DBState Compact"
+	case DBSTATE_COMPACT_MISSING_MSG:
+		return "DBState Compact Missing"
 	case BOUNCE_MSG:
 		return "Bounce Message"
 	case BOUNCEREPLY_MSG:
@@ -223,6 +254,10 @@ const (
 	DBSTATE_REQUEST_LIM_HIGH = 200
 	DBSTATE_REQUEST_LIM_MED  = 50
 
+	// Followers at most this many blocks behind ask for compact DBStates, as they have most likely
+	// seen the transactions and entries of those blocks as messages already.
+	DBSTATE_COMPACT_WINDOW = 10
+
 	// Replay -- Dynamic Replay filter based on messages as they are processed.
 	INTERNAL_REPLAY = 1
 	NETWORK_REPLAY  = 2
@@ -274,8 +309,9 @@ const (
 	//Block mem bool size
 	//MY Process List size
 
-	//Max number of entry credits per entry
-	//Max number of entry credits per chain
+	MAX_ENTRY_CREDITS = uint8(10)  //Max number of entry credits per entry
+	MAX_CHAIN_CREDITS = uint8(20)  //Max number of entry credits per chain
+	MAX_EC_TIP        = uint8(100) //Max entry credits a commit pays over the max of its entry, for priority
 
 	COMMIT_TIME_WINDOW = time.Duration(12) //Time windows for commit chain and commit entry +/- 12 hours
 
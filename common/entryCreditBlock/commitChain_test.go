@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	ed "github.com/FactomProject/ed25519"
+	"github.com/FactomProject/factomd/common/constants"
 	. "github.com/FactomProject/factomd/common/entryCreditBlock"
 	"github.com/FactomProject/factomd/common/primitives"
 )
@@ -205,3 +206,26 @@ func TestCommitChainIsValid(t *testing.T) {
 		t.Error("Credits are 21, should be invalid")
 	}
 }
+
+func TestCommitChainTip(t *testing.T) {
+	c := NewCommitChain()
+	c.Init()
+	p, _ := primitives.NewPrivateKeyFromHex("0000000000000000000000000000000000000000000000000000000000000000")
+
+	for _, test := range []struct {
+		credits uint8
+		tip     uint8
+		valid   bool
+	}{{20, 0, true}, {21, 1, true}, {20 + constants.MAX_EC_TIP, constants.MAX_EC_TIP, true}, {21 + constants.MAX_EC_TIP, 0, false}} {
+		c.Credits = test.credits
+		if err := c.Sign(p.Key[:]); err != nil {
+			t.Error(err)
+		}
+		if c.IsValidWithTip(constants.MAX_EC_TIP) != test.valid {
+			t.Errorf("Credits are %d, valid should be %v", test.credits, test.valid)
+		}
+		if test.valid && c.GetTip() != test.tip {
+			t.Errorf("Credits are %d, tip should be %d, not %d", test.credits, test.tip, c.GetTip())
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/FactomProject/factomd/common/constants"
 	. "github.com/FactomProject/factomd/common/entryCreditBlock"
 	"github.com/FactomProject/factomd/common/primitives"
 )
@@ -157,3 +158,26 @@ func TestCommitEntryIsValid(t *testing.T) {
 		t.Error("Credits are 11, should be invalid")
 	}
 }
+
+func TestCommitEntryTip(t *testing.T) {
+	c := NewCommitEntry()
+	c.Init()
+	p, _ := primitives.NewPrivateKeyFromHex("0000000000000000000000000000000000000000000000000000000000000000")
+
+	for _, test := range []struct {
+		credits uint8
+		tip     uint8
+		valid   bool
+	}{{10, 0, true}, {11, 1, true}, {10 + constants.MAX_EC_TIP, constants.MAX_EC_TIP, true}, {11 + constants.MAX_EC_TIP, 0, false}} {
+		c.Credits = test.credits
+		if err := c.Sign(p.Key[:]); err != nil {
+			t.Error(err)
+		}
+		if c.IsValidWithTip(constants.MAX_EC_TIP) != test.valid {
+			t.Errorf("Credits are %d, valid should be %v", test.credits, test.valid)
+		}
+		if test.valid && c.GetTip() != test.tip {
+			t.Errorf("Credits are %d, tip should be %d, not %d", test.credits, test.tip, c.GetTip())
+		}
+	}
+}
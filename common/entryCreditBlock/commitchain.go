@@ -173,10 +173,17 @@ func (c *CommitChain) GetTimestamp() interfaces.Timestamp {
 	return primitives.NewTimestampFromMilliseconds(milli)
 }
 
+// IsValid checks a commit without a tip
 func (c *CommitChain) IsValid() bool {
+	return c.IsValidWithTip(0)
+}
+
+// IsValidWithTip checks a commit that may pay up to maxTip entry credits over
+// the max of a chain
+func (c *CommitChain) IsValidWithTip(maxTip uint8) bool {
 	c.Init()
 	//double check the credits in the commit
-	if c.Credits < 11 || c.Version != 0 || c.Credits > 20 {
+	if c.Credits < 11 || c.Version != 0 || int(c.Credits) > int(constants.MAX_CHAIN_CREDITS)+int(maxTip) {
 		return false
 	}
 
@@ -188,6 +195,15 @@ func (c *CommitChain) IsValid() bool {
 	}
 }
 
+// GetTip returns the entry credits paid over the max of a chain, which buy
+// priority with the leaders
+func (c *CommitChain) GetTip() uint8 {
+	if c.Credits <= constants.MAX_CHAIN_CREDITS {
+		return 0
+	}
+	return c.Credits - constants.MAX_CHAIN_CREDITS
+}
+
 func (c *CommitChain) GetHash() interfaces.IHash {
 	data, _ := c.MarshalBinary()
 	return primitives.Sha(data)
@@ -159,9 +159,16 @@ func (c *CommitEntry) GetTimestamp() interfaces.Timestamp {
 	return primitives.NewTimestampFromMilliseconds(milli)
 }
 
+// IsValid checks a commit without a tip
 func (c *CommitEntry) IsValid() bool {
+	return c.IsValidWithTip(0)
+}
+
+// IsValidWithTip checks a commit that may pay up to maxTip entry credits over
+// the max of an entry
+func (c *CommitEntry) IsValidWithTip(maxTip uint8) bool {
 	//double check the credits in the commit
-	if c.Credits < 1 || c.Version != 0 || c.Credits > 10 {
+	if c.Credits < 1 || c.Version != 0 || int(c.Credits) > int(constants.MAX_ENTRY_CREDITS)+int(maxTip) {
 		return false
 	}
 
@@ -173,6 +180,15 @@ func (c *CommitEntry) IsValid() bool {
 	}
 }
 
+// GetTip returns the entry credits paid over the max of an entry, which buy
+// priority with the leaders
+func (c *CommitEntry) GetTip() uint8 {
+	if c.Credits <= constants.MAX_ENTRY_CREDITS {
+		return 0
+	}
+	return c.Credits - constants.MAX_ENTRY_CREDITS
+}
+
 func (c *CommitEntry) GetHash() interfaces.IHash {
 	h, _ := c.MarshalBinary()
 	return primitives.Sha(h)
@@ -6,6 +6,7 @@ package factoid
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
@@ -21,23 +22,42 @@ import (
  * Helper Functions
  ***********************/
 
+// MaxRCDDepth is how deep RCD_2s and RCD_3s can nest other RCDs
+const MaxRCDDepth = 8
+
+// minRCDSize is the size of the smallest RCD, an RCD_1
+const minRCDSize = 1 + constants.ADDRESS_LENGTH
+
 func UnmarshalBinaryAuth(data []byte) (a interfaces.IRCD, newData []byte, err error) {
+	return unmarshalBinaryAuth(data, 0)
+}
+
+// unmarshalBinaryAuth unmarshals an RCD nested depth levels deep in RCD_2s and RCD_3s
+func unmarshalBinaryAuth(data []byte, depth int) (a interfaces.IRCD, newData []byte, err error) {
 	if data == nil || len(data) < 1 {
 		return nil, nil, fmt.Errorf("Not enough data to unmarshal")
 	}
+	if depth > MaxRCDDepth {
+		return nil, nil, fmt.Errorf("RCDs nested more than %d deep", MaxRCDDepth)
+	}
 	t := data[0]
 
-	var auth interfaces.IRCD
 	switch int(t) {
 	case 1:
-		auth = new(RCD_1)
+		auth := new(RCD_1)
+		data, err = auth.UnmarshalBinaryData(data)
+		return auth, data, err
 	case 2:
-		auth = new(RCD_2)
+		auth := new(RCD_2)
+		data, err = auth.unmarshalBinaryData(data, depth)
+		return auth, data, err
+	case 3:
+		auth := new(RCD_3)
+		data, err = auth.unmarshalBinaryData(data, depth)
+		return auth, data, err
 	default:
 		return nil, nil, fmt.Errorf("Invalid type byte for authorizations: %x ", int(t))
 	}
-	data, err = auth.UnmarshalBinaryData(data)
-	return auth, data, err
 }
 
 func NewRCD_1(publicKey []byte) interfaces.IRCD {
@@ -49,16 +69,17 @@ func NewRCD_1(publicKey []byte) interfaces.IRCD {
 	return a
 }
 
-func NewRCD_2(n int, m int, addresses []interfaces.IAddress) (interfaces.IRCD, error) {
-	if len(addresses) != m {
-		return nil, fmt.Errorf("Improper number of addresses.  m = %d n = %d #addresses = %d", m, n, len(addresses))
+// NewRCD_2 returns an m of n multisig RCD, n being the number of nested RCDs
+func NewRCD_2(m int, rcds []interfaces.IRCD) (interfaces.IRCD, error) {
+	if m < 1 || m > len(rcds) || len(rcds) > math.MaxUint16 {
+		return nil, fmt.Errorf("Improper multisig.  m = %d n = %d", m, len(rcds))
 	}
 
 	au := new(RCD_2)
-	au.N = n
+	au.N = len(rcds)
 	au.M = m
-	au.N_Addresses = make([]interfaces.IAddress, len(addresses), len(addresses))
-	copy(au.N_Addresses, addresses)
+	au.RCDs = make([]interfaces.IRCD, len(rcds), len(rcds))
+	copy(au.RCDs, rcds)
 
 	return au, nil
 }
@@ -69,7 +90,41 @@ func CreateRCD(data []byte) interfaces.IRCD {
 		return new(RCD_1)
 	case 2:
 		return new(RCD_2)
+	case 3:
+		return new(RCD_3)
 	default:
 		panic("Bad Data encountered by CreateRCD.  Should never happen")
 	}
 }
+
+// WalkRCD calls f on an RCD and every RCD nested in it, until f returns an error
+func WalkRCD(rcd interfaces.IRCD, f func(interfaces.IRCD) error) error {
+	if err := f(rcd); err != nil {
+		return err
+	}
+	switch r := rcd.(type) {
+	case *RCD_2:
+		for _, nested := range r.RCDs {
+			if err := WalkRCD(nested, f); err != nil {
+				return err
+			}
+		}
+	case *RCD_3:
+		if r.RCD != nil {
+			return WalkRCD(r.RCD, f)
+		}
+	}
+	return nil
+}
+
+// CheckLocks returns an error if an RCD, or an RCD nested in it, is time locked
+// in a block of the given height and time (milliseconds).  A lock nested in a
+// multisig holds the whole multisig, even if the other RCDs could sign.
+func CheckLocks(rcd interfaces.IRCD, height uint32, timestamp uint64) error {
+	return WalkRCD(rcd, func(r interfaces.IRCD) error {
+		if lock, ok := r.(*RCD_3); ok && lock.IsLocked(height, timestamp) {
+			return fmt.Errorf("The RCD is locked until the height %d and the time %d", lock.LockHeight, lock.LockTime)
+		}
+		return nil
+	})
+}
@@ -7,7 +7,10 @@ package factoid
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
@@ -19,45 +22,80 @@ import (
 
 // Type 2 RCD implement multisig
 // m of n
-// Must have m addresses from which to choose, no fewer, no more
-// Must have n RCD, no fewer no more.
+// Must have n nested RCDs, no fewer, no more.
+// Must have valid signatures for at least m of them.
 // NOTE: This does mean you can have a multisig nested in a
 // multisig.  It just works.
+//
+// The signature block of an RCD_2 has a slot for every signature of
+// every nested RCD, in order.  Slots of the RCDs that don't sign are
+// left empty.
 
 type RCD_2 struct {
-	M           int                   // Number signatures required
-	N           int                   // Total sigatures possible
-	N_Addresses []interfaces.IAddress // n addresses
+	M    int               // Number signatures required
+	N    int               // Total sigatures possible
+	RCDs []interfaces.IRCD // n nested RCDs
 }
 
 var _ interfaces.IRCD = (*RCD_2)(nil)
 
-/*************************************
- *       Stubs
- *************************************/
+/***************************************
+ *       Methods
+ ***************************************/
 
+// The address is the hash of the RCD, like for an RCD_1
 func (b RCD_2) GetAddress() (interfaces.IAddress, error) {
-	return nil, nil
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return CreateAddress(primitives.Shad(data)), nil
 }
 
+// NumberOfSignatures is the number of slots of the signature block, the
+// signatures of all the nested RCDs
 func (b RCD_2) NumberOfSignatures() int {
-	return 1
+	n := 0
+	for _, rcd := range b.RCDs {
+		n += rcd.NumberOfSignatures()
+	}
+	return n
 }
 
-/***************************************
- *       Methods
- ***************************************/
-
 func (b RCD_2) IsSameAs(rcd interfaces.IRCD) bool {
 	return b.String() == rcd.String()
 }
 
-func (b RCD_2) UnmarshalBinary(data []byte) error {
+func (b *RCD_2) UnmarshalBinary(data []byte) error {
 	_, err := b.UnmarshalBinaryData(data)
 	return err
 }
 
+// CheckSig checks the signatures of the nested RCDs, each against its slots of
+// the signature block, and needs m of them to be valid
 func (b RCD_2) CheckSig(trans interfaces.ITransaction, sigblk interfaces.ISignatureBlock) bool {
+	if sigblk == nil || b.M < 1 || b.M > len(b.RCDs) {
+		return false
+	}
+	valid := 0
+	slot := 0
+	for _, rcd := range b.RCDs {
+		nested := new(SignatureBlock)
+		for i := 0; i < rcd.NumberOfSignatures(); i++ {
+			sig := sigblk.GetSignature(slot)
+			if sig == nil {
+				sig = new(FactoidSignature)
+			}
+			nested.Signatures = append(nested.Signatures, sig)
+			slot++
+		}
+		if rcd.CheckSig(trans, nested) {
+			valid++
+			if valid >= b.M {
+				return true
+			}
+		}
+	}
 	return false
 }
 
@@ -65,12 +103,43 @@ func (e *RCD_2) JSONByte() ([]byte, error) {
 	return primitives.EncodeJSON(e)
 }
 
-// TODO: Fix Json marshaling of RCD_2. Right now the RCD type
-// is not included in the json marshal.
 func (e *RCD_2) JSONString() (string, error) {
 	return primitives.EncodeJSONString(e)
 }
 
+// MarshalJSON is the RCD in hex, with its type, like for an RCD_1
+func (e *RCD_2) MarshalJSON() (rval []byte, err error) {
+	defer func(pe *error) {
+		if *pe != nil {
+			fmt.Fprintf(os.Stderr, "RCD_2.MarshalJSON err:%v", *pe)
+		}
+	}(&err)
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fmt.Sprintf("%x", data))
+}
+
+func (e *RCD_2) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	raw, err := hex.DecodeString(text)
+	if err != nil {
+		return err
+	}
+	rest, err := e.UnmarshalBinaryData(raw)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%d bytes left over after the RCD", len(rest))
+	}
+	return nil
+}
+
 func (b RCD_2) String() string {
 	txt, err := b.CustomMarshalText()
 	if err != nil {
@@ -83,14 +152,19 @@ func (w RCD_2) Clone() interfaces.IRCD {
 	c := new(RCD_2)
 	c.M = w.M
 	c.N = w.N
-	c.N_Addresses = make([]interfaces.IAddress, len(w.N_Addresses))
-	for i, address := range w.N_Addresses {
-		c.N_Addresses[i] = CreateAddress(address)
+	c.RCDs = make([]interfaces.IRCD, len(w.RCDs))
+	for i, rcd := range w.RCDs {
+		c.RCDs[i] = rcd.Clone()
 	}
 	return c
 }
 
 func (t *RCD_2) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	return t.unmarshalBinaryData(data, 0)
+}
+
+// unmarshalBinaryData unmarshals an RCD_2 nested depth levels deep
+func (t *RCD_2) unmarshalBinaryData(data []byte, depth int) (newData []byte, err error) {
 	if data == nil || len(data) < 5 {
 		return nil, fmt.Errorf("Not enough data to unmarshal")
 	}
@@ -102,28 +176,37 @@ func (t *RCD_2) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
 
 	t.N, data = int(binary.BigEndian.Uint16(data[0:2])), data[2:]
 	t.M, data = int(binary.BigEndian.Uint16(data[0:2])), data[2:]
+	if t.M < 1 || t.M > t.N {
+		return nil, fmt.Errorf("Bad multisig, %d of %d", t.M, t.N)
+	}
+	if t.N > len(data)/minRCDSize {
+		return nil, fmt.Errorf("Not enough data for %d RCDs", t.N)
+	}
 
-	t.N_Addresses = make([]interfaces.IAddress, t.M, t.M)
-
-	for i, _ := range t.N_Addresses {
-		t.N_Addresses[i] = new(Address)
-		data, err = t.N_Addresses[i].UnmarshalBinaryData(data)
+	t.RCDs = make([]interfaces.IRCD, 0, t.N)
+	for i := 0; i < t.N; i++ {
+		var rcd interfaces.IRCD
+		rcd, data, err = unmarshalBinaryAuth(data, depth+1)
 		if err != nil {
 			return nil, err
 		}
+		t.RCDs = append(t.RCDs, rcd)
 	}
 
 	return data, nil
 }
 
 func (a RCD_2) MarshalBinary() ([]byte, error) {
+	if a.N != len(a.RCDs) {
+		return nil, fmt.Errorf("RCD_2 has %d RCDs, expected %d", len(a.RCDs), a.N)
+	}
 	var out primitives.Buffer
 
 	binary.Write(&out, binary.BigEndian, uint8(2))
 	binary.Write(&out, binary.BigEndian, uint16(a.N))
 	binary.Write(&out, binary.BigEndian, uint16(a.M))
-	for i := 0; i < a.M; i++ {
-		data, err := a.N_Addresses[i].MarshalBinary()
+	for _, rcd := range a.RCDs {
+		data, err := rcd.MarshalBinary()
 		if err != nil {
 			return nil, err
 		}
@@ -136,16 +219,23 @@ func (a RCD_2) MarshalBinary() ([]byte, error) {
 func (a RCD_2) CustomMarshalText() ([]byte, error) {
 	var out primitives.Buffer
 
+	out.WriteString("RCD 2: ")
 	primitives.WriteNumber8(&out, uint8(2)) // Type 2 Authorization
 	out.WriteString("\n n: ")
 	primitives.WriteNumber16(&out, uint16(a.N))
 	out.WriteString(" m: ")
 	primitives.WriteNumber16(&out, uint16(a.M))
 	out.WriteString("\n")
-	for i := 0; i < a.M; i++ {
-		out.WriteString("  m: ")
-		out.WriteString(hex.EncodeToString(a.N_Addresses[i].Bytes()))
-		out.WriteString("\n")
+	for _, rcd := range a.RCDs {
+		txt, err := rcd.CustomMarshalText()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(txt), "\n"), "\n") {
+			out.WriteString("  ")
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
 	}
 
 	return out.DeepCopyBytes(), nil
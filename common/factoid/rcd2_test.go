@@ -5,11 +5,16 @@
 package factoid_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"math/rand"
+	"runtime"
 	"testing"
 
 	. "github.com/FactomProject/factomd/common/factoid"
 	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/testHelper"
 )
 
 func TestUnmarshalNilRCD_2(t *testing.T) {
@@ -94,17 +99,206 @@ func TestRCD2Clone(t *testing.T) {
 	}
 }
 
+func TestNewRCD_2(t *testing.T) {
+	rcds := []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), testHelper.NewFactoidRCDAddress(2)}
+	for _, m := range []int{0, 3, -1} {
+		if _, err := NewRCD_2(m, rcds); err == nil {
+			t.Errorf("Made a %d of 2 multisig", m)
+		}
+	}
+	if _, err := NewRCD_2(1, nil); err == nil {
+		t.Errorf("Made a multisig without RCDs")
+	}
+
+	// A marshalled RCD must have m of n with m <= n
+	rcd, _ := NewRCD_2(2, rcds)
+	data, _ := rcd.MarshalBinary()
+	data[4] = 3
+	if _, err := new(RCD_2).UnmarshalBinaryData(data); err == nil {
+		t.Errorf("Unmarshalled a 3 of 2 multisig")
+	}
+}
+
+func TestRCD2Address(t *testing.T) {
+	rcds := []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), testHelper.NewFactoidRCDAddress(2)}
+	one, _ := NewRCD_2(1, rcds)
+	two, _ := NewRCD_2(2, rcds)
+
+	a1, err := one.GetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, _ := two.GetAddress()
+	if a1 == nil || a1.IsSameAs(a2) {
+		t.Errorf("1 of 2 and 2 of 2 have the same address")
+	}
+	data, _ := one.MarshalBinary()
+	if !a1.IsSameAs(primitives.Shad(data)) {
+		t.Errorf("The address is not the hash of the RCD")
+	}
+	for _, rcd := range rcds {
+		a, _ := rcd.GetAddress()
+		if a.IsSameAs(a1) {
+			t.Errorf("The multisig has the address of a nested RCD")
+		}
+	}
+}
+
+func TestRCD2JSON(t *testing.T) {
+	rcd := nextAuth2_rcd2()
+	data, err := json.Marshal(rcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcd2 := new(RCD_2)
+	if err := json.Unmarshal(data, rcd2); err != nil {
+		t.Fatal(err)
+	}
+	if !rcd.IsSameAs(rcd2) {
+		t.Errorf("RCDs are not equal")
+	}
+}
+
+// multisigTransaction spends from the address of rcd, signed by the keys of testHelper in the slots
+// of the signature block
+func multisigTransaction(rcd interfaces.IRCD, slots map[int]uint64) *Transaction {
+	tx := new(Transaction)
+	address, _ := rcd.GetAddress()
+	tx.AddInput(address, 1000)
+	tx.AddAuthorization(rcd)
+
+	data, err := tx.MarshalBinarySig()
+	if err != nil {
+		panic(err)
+	}
+	sigBlock := new(SignatureBlock)
+	for slot, key := range slots {
+		sigBlock.SetSignatureAt(slot, NewED25519Signature(testHelper.NewPrivKey(key), data))
+	}
+	tx.SetSignatureBlock(0, sigBlock)
+	return tx
+}
+
+func TestRCD2CheckSig(t *testing.T) {
+	rcds := []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), testHelper.NewFactoidRCDAddress(2), testHelper.NewFactoidRCDAddress(3)}
+	rcd, _ := NewRCD_2(2, rcds)
+
+	tests := []struct {
+		slots map[int]uint64
+		valid bool
+	}{
+		{map[int]uint64{0: 1, 1: 2}, true},
+		{map[int]uint64{0: 1, 2: 3}, true},
+		{map[int]uint64{0: 1, 1: 2, 2: 3}, true},
+		{map[int]uint64{1: 2}, false},
+		{map[int]uint64{0: 1, 1: 3}, false}, // Signed by the wrong key
+		{map[int]uint64{0: 1, 1: 4}, false}, // Not a key of the multisig
+		{nil, false},
+	}
+	for i, test := range tests {
+		tx := multisigTransaction(rcd, test.slots)
+		if err := tx.Validate(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.ValidateSignatures(); (err == nil) != test.valid {
+			t.Errorf("Test %d: expected valid %v, got %v", i, test.valid, err)
+		}
+
+		// The signatures survive the round trip
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx2 := new(Transaction)
+		if err := tx2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx2.ValidateSignatures(); (err == nil) != test.valid {
+			t.Errorf("Test %d: expected valid %v after unmarshalling, got %v", i, test.valid, err)
+		}
+		if !tx2.GetHash().IsSameAs(tx.GetHash()) {
+			t.Errorf("Test %d: the transaction changed in the round trip", i)
+		}
+	}
+
+	// No more signatures than the slots of the nested RCDs
+	tx := multisigTransaction(rcd, map[int]uint64{0: 1, 1: 2, 3: 3})
+	if err := tx.ValidateSignatures(); err == nil {
+		t.Errorf("Validated a signature block with a signature too many")
+	}
+}
+
+func TestRCD2Nested(t *testing.T) {
+	inner, _ := NewRCD_2(2, []interfaces.IRCD{testHelper.NewFactoidRCDAddress(2), testHelper.NewFactoidRCDAddress(3)})
+	rcd, _ := NewRCD_2(1, []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), inner})
+	if rcd.NumberOfSignatures() != 3 {
+		t.Errorf("Expected 3 signatures, got %d", rcd.NumberOfSignatures())
+	}
+
+	// Slot 0 is the first RCD, slots 1 and 2 the nested multisig
+	for slots, valid := range map[*map[int]uint64]bool{
+		&map[int]uint64{0: 1}:       true,
+		&map[int]uint64{1: 2, 2: 3}: true,
+		&map[int]uint64{1: 2}:       false,
+		&map[int]uint64{2: 3}:       false,
+	} {
+		tx := multisigTransaction(rcd, *slots)
+		if err := tx.ValidateSignatures(); (err == nil) != valid {
+			t.Errorf("Slots %v: expected valid %v, got %v", *slots, valid, err)
+		}
+	}
+
+	// Every signature the RCD can take is paid for
+	tx := multisigTransaction(rcd, map[int]uint64{0: 1})
+	fee, err := tx.CalculateFee(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee != 1+3 {
+		t.Errorf("Expected a fee of 4 EC, got %d", fee)
+	}
+}
+
+func TestRCD2DeepNesting(t *testing.T) {
+	// 400 nested headers claiming 65535 RCDs each, in 2,000 bytes
+	data := bytes.Repeat([]byte{2, 0xff, 0xff, 0, 1}, 400)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, _, err := UnmarshalBinaryAuth(data); err == nil {
+		t.Errorf("Unmarshaled the nested headers")
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Allocated %d bytes to unmarshal %d", n, len(data))
+	}
+
+	// As deep as it can go, then one more
+	var rcd interfaces.IRCD = NewRCD_1(nextSig())
+	for i := 0; i < MaxRCDDepth; i++ {
+		rcd, _ = NewRCD_2(1, []interfaces.IRCD{rcd})
+	}
+	data, _ = rcd.MarshalBinary()
+	if _, _, err := UnmarshalBinaryAuth(data); err != nil {
+		t.Error(err)
+	}
+	rcd, _ = NewRCD_3(0, 0, rcd)
+	data, _ = rcd.MarshalBinary()
+	if _, _, err := UnmarshalBinaryAuth(data); err == nil {
+		t.Errorf("Unmarshaled RCDs nested %d deep", MaxRCDDepth+1)
+	}
+}
+
 func nextAuth2_rcd2() *RCD_2 {
 	if r == nil {
 		r = rand.New(rand.NewSource(1))
 	}
 	n := r.Int()%4 + 1
 	m := r.Int()%4 + n
-	addresses := make([]interfaces.IAddress, m, m)
+	rcds := make([]interfaces.IRCD, m, m)
 	for j := 0; j < m; j++ {
-		addresses[j] = nextAddress()
+		rcds[j] = NewRCD_1(nextSig())
 	}
 
-	rcd, _ := NewRCD_2(n, m, addresses)
+	rcd, _ := NewRCD_2(n, rcds)
 	return rcd.(*RCD_2)
 }
@@ -0,0 +1,216 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package factoid
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+/************************
+ * RCD 3
+ ************************/
+
+// Type 3 RCD is a time lock
+// It wraps another RCD, which signs as usual, but the factoids at its
+// address can't be spent before the directory block LockHeight, nor
+// before LockTime.  A zero height or time is no lock.
+// The lock is checked against the block the transaction goes in, see
+// FactoidState.Validate.
+
+type RCD_3 struct {
+	LockHeight uint32          // Lowest directory block height the RCD can spend in
+	LockTime   uint64          // Lowest block time (milliseconds) the RCD can spend at
+	RCD        interfaces.IRCD // The RCD that signs
+}
+
+var _ interfaces.IRCD = (*RCD_3)(nil)
+
+func NewRCD_3(lockHeight uint32, lockTime uint64, rcd interfaces.IRCD) (interfaces.IRCD, error) {
+	if rcd == nil {
+		return nil, fmt.Errorf("A time lock needs an RCD to sign")
+	}
+	au := new(RCD_3)
+	au.LockHeight = lockHeight
+	au.LockTime = lockTime
+	au.RCD = rcd
+	return au, nil
+}
+
+/***************************************
+ *       Methods
+ ***************************************/
+
+// IsLocked returns true if the RCD can't spend in a block of the given height
+// and time (milliseconds)
+func (b RCD_3) IsLocked(height uint32, timestamp uint64) bool {
+	return height < b.LockHeight || timestamp < b.LockTime
+}
+
+// The address is the hash of the RCD, so the lock is part of the address
+func (b RCD_3) GetAddress() (interfaces.IAddress, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return CreateAddress(primitives.Shad(data)), nil
+}
+
+func (b RCD_3) NumberOfSignatures() int {
+	if b.RCD == nil {
+		return 0
+	}
+	return b.RCD.NumberOfSignatures()
+}
+
+func (b RCD_3) IsSameAs(rcd interfaces.IRCD) bool {
+	return b.String() == rcd.String()
+}
+
+func (b *RCD_3) UnmarshalBinary(data []byte) error {
+	_, err := b.UnmarshalBinaryData(data)
+	return err
+}
+
+// CheckSig only checks the signatures, by the wrapped RCD.  The lock needs
+// the height of the block, and is checked by the factoid state.
+func (b RCD_3) CheckSig(trans interfaces.ITransaction, sigblk interfaces.ISignatureBlock) bool {
+	if b.RCD == nil {
+		return false
+	}
+	return b.RCD.CheckSig(trans, sigblk)
+}
+
+func (e *RCD_3) JSONByte() ([]byte, error) {
+	return primitives.EncodeJSON(e)
+}
+
+func (e *RCD_3) JSONString() (string, error) {
+	return primitives.EncodeJSONString(e)
+}
+
+// MarshalJSON is the RCD in hex, with its type, like for an RCD_1
+func (e *RCD_3) MarshalJSON() (rval []byte, err error) {
+	defer func(pe *error) {
+		if *pe != nil {
+			fmt.Fprintf(os.Stderr, "RCD_3.MarshalJSON err:%v", *pe)
+		}
+	}(&err)
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fmt.Sprintf("%x", data))
+}
+
+func (e *RCD_3) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	raw, err := hex.DecodeString(text)
+	if err != nil {
+		return err
+	}
+	rest, err := e.UnmarshalBinaryData(raw)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%d bytes left over after the RCD", len(rest))
+	}
+	return nil
+}
+
+func (b RCD_3) String() string {
+	txt, err := b.CustomMarshalText()
+	if err != nil {
+		return "<error>"
+	}
+	return string(txt)
+}
+
+func (w RCD_3) Clone() interfaces.IRCD {
+	c := new(RCD_3)
+	c.LockHeight = w.LockHeight
+	c.LockTime = w.LockTime
+	if w.RCD != nil {
+		c.RCD = w.RCD.Clone()
+	}
+	return c
+}
+
+func (t *RCD_3) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	return t.unmarshalBinaryData(data, 0)
+}
+
+// unmarshalBinaryData unmarshals an RCD_3 nested depth levels deep
+func (t *RCD_3) unmarshalBinaryData(data []byte, depth int) (newData []byte, err error) {
+	if data == nil || len(data) < 13 {
+		return nil, fmt.Errorf("Not enough data to unmarshal")
+	}
+	if data[0] != 3 {
+		return nil, fmt.Errorf("Bad data fed to RCD_3 UnmarshalBinaryData()")
+	}
+	buf := primitives.NewBuffer(data[1:])
+	if t.LockHeight, err = buf.PopUInt32(); err != nil {
+		return nil, err
+	}
+	if t.LockTime, err = buf.PopUInt64(); err != nil {
+		return nil, err
+	}
+	t.RCD, newData, err = unmarshalBinaryAuth(buf.DeepCopyBytes(), depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return newData, nil
+}
+
+func (a RCD_3) MarshalBinary() ([]byte, error) {
+	if a.RCD == nil {
+		return nil, fmt.Errorf("RCD_3 has no RCD")
+	}
+	buf := primitives.NewBuffer(nil)
+	if err := buf.PushByte(3); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(a.LockHeight); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt64(a.LockTime); err != nil {
+		return nil, err
+	}
+	if err := buf.PushBinaryMarshallable(a.RCD); err != nil {
+		return nil, err
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (a RCD_3) CustomMarshalText() ([]byte, error) {
+	var out primitives.Buffer
+
+	out.WriteString("RCD 3: ")
+	primitives.WriteNumber8(&out, uint8(3)) // Type 3 Authorization
+	out.WriteString(fmt.Sprintf("\n lock height: %d lock time: %d\n", a.LockHeight, a.LockTime))
+	if a.RCD != nil {
+		txt, err := a.RCD.CustomMarshalText()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(txt), "\n"), "\n") {
+			out.WriteString("  ")
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+	}
+
+	return out.DeepCopyBytes(), nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package factoid_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestUnmarshalNilRCD_3(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Panic caught during the test - %v", r)
+		}
+	}()
+
+	a := new(RCD_3)
+	err := a.UnmarshalBinary(nil)
+	if err == nil {
+		t.Errorf("Error is nil when it shouldn't be")
+	}
+
+	err = a.UnmarshalBinary([]byte{})
+	if err == nil {
+		t.Errorf("Error is nil when it shouldn't be")
+	}
+}
+
+func TestRCD3MarshalUnmarshal(t *testing.T) {
+	multisig := nextAuth2_rcd2()
+	for _, nested := range []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), multisig} {
+		rcd, err := NewRCD_3(100, 1500000000000, nested)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hex, err := rcd.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcd2, rest, err := UnmarshalBinaryAuth(append(hex, 0xff))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rest) != 1 {
+			t.Errorf("Returned %d bytes of spare data, expected 1", len(rest))
+		}
+		if !rcd.IsSameAs(rcd2) || !rcd.IsSameAs(rcd.Clone()) {
+			t.Errorf("RCDs are not equal")
+		}
+		if rcd.NumberOfSignatures() != nested.NumberOfSignatures() {
+			t.Errorf("Expected %d signatures, got %d", nested.NumberOfSignatures(), rcd.NumberOfSignatures())
+		}
+
+		data, err := json.Marshal(rcd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcd3 := new(RCD_3)
+		if err := json.Unmarshal(data, rcd3); err != nil {
+			t.Fatal(err)
+		}
+		if !rcd.IsSameAs(rcd3) {
+			t.Errorf("RCDs are not equal after JSON")
+		}
+	}
+}
+
+func TestRCD3Address(t *testing.T) {
+	nested := testHelper.NewFactoidRCDAddress(1)
+	a, _ := nested.GetAddress()
+	seen := map[[32]byte]bool{a.Fixed(): true}
+	for _, lock := range [][2]uint64{{0, 0}, {10, 0}, {0, 10}} {
+		rcd, _ := NewRCD_3(uint32(lock[0]), lock[1], nested)
+		a, err := rcd.GetAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[a.Fixed()] {
+			t.Errorf("Lock %v has the address of another lock", lock)
+		}
+		seen[a.Fixed()] = true
+	}
+}
+
+func TestRCD3Locks(t *testing.T) {
+	lock, _ := NewRCD_3(100, 5000, testHelper.NewFactoidRCDAddress(1))
+	tests := []struct {
+		height    uint32
+		timestamp uint64
+		locked    bool
+	}{
+		{99, 5000, true},
+		{100, 4999, true},
+		{100, 5000, false},
+		{200, 6000, false},
+	}
+	for _, test := range tests {
+		if lock.(*RCD_3).IsLocked(test.height, test.timestamp) != test.locked {
+			t.Errorf("At %d and %d expected locked %v", test.height, test.timestamp, test.locked)
+		}
+		if (CheckLocks(lock, test.height, test.timestamp) != nil) != test.locked {
+			t.Errorf("CheckLocks at %d and %d expected locked %v", test.height, test.timestamp, test.locked)
+		}
+	}
+
+	// A lock in a multisig holds the multisig
+	multisig, _ := NewRCD_2(1, []interfaces.IRCD{testHelper.NewFactoidRCDAddress(2), lock})
+	if CheckLocks(multisig, 99, 5000) == nil {
+		t.Errorf("The lock in the multisig doesn't hold it")
+	}
+	if CheckLocks(multisig, 100, 5000) != nil {
+		t.Errorf("The lock in the multisig holds it after its height")
+	}
+}
+
+func TestRCD3CheckSig(t *testing.T) {
+	rcd, _ := NewRCD_3(100, 0, testHelper.NewFactoidRCDAddress(1))
+	for key, valid := range map[uint64]bool{1: true, 2: false} {
+		tx := multisigTransaction(rcd, map[int]uint64{0: key})
+		if err := tx.Validate(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.ValidateSignatures(); (err == nil) != valid {
+			t.Errorf("Signed by %d: expected valid %v, got %v", key, valid, err)
+		}
+
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx2 := new(Transaction)
+		if err := tx2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !tx2.GetHash().IsSameAs(tx.GetHash()) || (tx2.ValidateSignatures() == nil) != valid {
+			t.Errorf("Signed by %d: the transaction changed in the round trip", key)
+		}
+	}
+}
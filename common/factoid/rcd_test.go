@@ -57,11 +57,11 @@ func nextAuth2_rcd() interfaces.IRCD {
 	}
 	n := r.Int()%4 + 1
 	m := r.Int()%4 + n
-	addresses := make([]interfaces.IAddress, m, m)
+	rcds := make([]interfaces.IRCD, m, m)
 	for j := 0; j < m; j++ {
-		addresses[j] = nextAddress()
+		rcds[j] = NewRCD_1(nextSig())
 	}
 
-	rcd, _ := NewRCD_2(n, m, addresses)
+	rcd, _ := NewRCD_2(n, rcds)
 	return rcd
 }
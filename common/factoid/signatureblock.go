@@ -72,6 +72,15 @@ func (s *SignatureBlock) AddSignature(sig interfaces.ISignature) {
 	}
 }
 
+// SetSignatureAt puts a signature in a slot of the block, for an RCD that takes
+// more than one.  The slots before it are padded with empty signatures.
+func (s *SignatureBlock) SetSignatureAt(index int, sig interfaces.ISignature) {
+	for len(s.Signatures) <= index {
+		s.Signatures = append(s.Signatures, new(FactoidSignature))
+	}
+	s.Signatures[index] = sig
+}
+
 func (s SignatureBlock) GetSignature(index int) interfaces.ISignature {
 	if len(s.Signatures) <= index {
 		return nil
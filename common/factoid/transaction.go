@@ -198,6 +198,7 @@ func (t *Transaction) AddRCD(rcd interfaces.IRCD) {
 //Number of signatures checked -- These cause expensive computation on
 //    all full nodes. A fee of 10 EC equivalent must be paid for each
 //    signature included.
+//    A multisig RCD pays for every signature it can take, signed or not.
 func (t Transaction) CalculateFee(factoshisPerEC uint64) (uint64, error) {
 	// First look at the size of the transaction, and make sure
 	// everything is inbounds.
@@ -366,6 +367,12 @@ func (t Transaction) ValidateSignatures() error {
 		missingCnt := 0
 		sigBlks := t.GetSignatureBlocks()
 		for i, rcd := range t.RCDs {
+			// No more signatures than the RCD takes; a multisig RCD takes
+			// one for every signature of its nested RCDs
+			if sigBlks[i] != nil && len(sigBlks[i].GetSignatures()) > rcd.NumberOfSignatures() {
+				return fmt.Errorf("Signature block %d has %d signatures, its RCD takes %d",
+					i, len(sigBlks[i].GetSignatures()), rcd.NumberOfSignatures())
+			}
 			if !rcd.CheckSig(&t, sigBlks[i]) {
 				missingCnt++
 			}
@@ -503,11 +510,17 @@ func (t *Transaction) UnmarshalBinaryData(data []byte) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		t.SigBlocks[i] = new(SignatureBlock)
-		err = buf.PopBinaryMarshallable(t.SigBlocks[i])
-		if err != nil {
-			return nil, err
+		// The RCD says how many signatures follow it
+		sigBlock := new(SignatureBlock)
+		for j := 0; j < t.RCDs[i].NumberOfSignatures(); j++ {
+			sig := new(FactoidSignature)
+			err = buf.PopBinaryMarshallable(sig)
+			if err != nil {
+				return nil, err
+			}
+			sigBlock.Signatures = append(sigBlock.Signatures, sig)
 		}
+		t.SigBlocks[i] = sigBlock
 	}
 
 	t.Txid = t.GetSigHash()
@@ -597,17 +610,23 @@ func (t Transaction) MarshalBinary() ([]byte, error) {
 			return nil, err
 		}
 
-		// Then write its signature blocks.  This needs to be
-		// reworked so we use the information from the RCD block
-		// to control the writing of the signatures.  After all,
-		// we don't want to restrict what might be required to
-		// sign an input.
+		// Then write its signature block, as many signatures as the
+		// RCD takes.  Missing ones are written empty.
 		if len(t.SigBlocks) <= i {
 			t.SigBlocks = append(t.SigBlocks, new(SignatureBlock))
 		}
-		err = buf.PushBinaryMarshallable(t.SigBlocks[i])
-		if err != nil {
-			return nil, err
+		for j := 0; j < rcd.NumberOfSignatures(); j++ {
+			var sig interfaces.ISignature
+			if t.SigBlocks[i] != nil {
+				sig = t.SigBlocks[i].GetSignature(j)
+			}
+			if sig == nil {
+				sig = new(FactoidSignature)
+			}
+			err = buf.PushBinaryMarshallable(sig)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
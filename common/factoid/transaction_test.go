@@ -80,12 +80,12 @@ func nextAuth2() interfaces.IRCD {
 	}
 	n := r.Int()%4 + 1
 	m := r.Int()%4 + n
-	addresses := make([]interfaces.IAddress, m, m)
+	rcds := make([]interfaces.IRCD, m, m)
 	for j := 0; j < m; j++ {
-		addresses[j] = nextAddress()
+		rcds[j] = NewRCD_1(nextSig())
 	}
 
-	rcd, _ := NewRCD_2(n, m, addresses)
+	rcd, _ := NewRCD_2(n, rcds)
 	return rcd
 }
 
@@ -148,7 +148,12 @@ func getSignedTrans() interfaces.ITransaction {
 	}
 
 	for i := 0; i < 2; i++ {
-		t.AddAuthorization(nextAuth2())
+		rcd := nextAuth2()
+		t.AddAuthorization(rcd)
+		// A slot for every signature of the multisig, as it unmarshals
+		sigBlock := new(SignatureBlock)
+		sigBlock.SetSignatureAt(rcd.NumberOfSignatures()-1, new(FactoidSignature))
+		t.SetSignatureBlock(3+i, sigBlock)
 	}
 
 	return nb
@@ -29,15 +29,24 @@ type FactomParams struct {
 	ControlPanelPortOverride int
 	LogPort                  string
 	BlkTime                  int
+	BlockSchedule            string
+	CustomActivations        string
 	FaultTimeout             int
 	RoundTimeout             int
+	FastFaultTimeout         int
+	FastRoundTimeout         int
 	RuntimeLog               bool
 	Exclusive                bool
 	ExclusiveIn              bool
+	NAT                      string
+	SimSeed                  int64
+	Scenario                 string
 	Prefix                   string
 	Rotate                   bool
 	TimeOffset               int
 	KeepMismatch             bool
+	Invariants               string
+	ConsensusTrace           string
 	StartDelay               int64
 	Deadline                 int
 	CustomNet                []byte
@@ -100,7 +100,7 @@ func (im *IdentityManager) ApplyAddFederatedServer(entry interfaces.IABEntry, st
 
 	// New server. Check if the identity exists, and create it if it does not
 	id := im.GetIdentity(e.IdentityChainID)
-	if id == nil {
+	if id == nil && st != nil {
 		st.AddIdentityFromChainID(e.IdentityChainID)
 		id = im.GetIdentity(e.IdentityChainID)
 	}
@@ -126,7 +126,7 @@ func (im *IdentityManager) ApplyAddAuditServer(entry interfaces.IABEntry, st int
 	e := entry.(*adminBlock.AddAuditServer)
 	// New server. Check if the identity exists, and create it if it does not
 	id := im.GetIdentity(e.IdentityChainID)
-	if id == nil {
+	if id == nil && st != nil {
 		st.AddIdentityFromChainID(e.IdentityChainID)
 		id = im.GetIdentity(e.IdentityChainID)
 	}
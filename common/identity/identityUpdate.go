@@ -0,0 +1,334 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package identity
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	. "github.com/FactomProject/factomd/common/identityEntries"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/util"
+)
+
+// The updates an operator makes to the identity of a server, signed by its identity key 1
+const (
+	UpdateBlockSigningKey = "block-signing-key"
+	UpdateBitcoinKey      = "bitcoin-key"
+	UpdateMatryoshkaHash  = "matryoshka-hash"
+	UpdateCoinbaseAddress = "coinbase-address"
+	UpdateEfficiency      = "efficiency"
+	UpdateCoinbaseCancel  = "coinbase-cancel"
+)
+
+// KeyFile holds what an operator needs to update the identity of a server
+type KeyFile struct {
+	IdentityChainID   string `json:"identitychainid"`
+	ManagementChainID string `json:"managementchainid"`
+	IdentityKey       string `json:"identitykey"`  // Hex private key of the identity key 1
+	ECPrivateKey      string `json:"ecprivatekey"` // Es... address paying for the entries
+
+	chainID    interfaces.IHash
+	management interfaces.IHash
+	key        *primitives.PrivateKey
+	ecKey      []byte
+}
+
+// LoadKeyFile reads a key file and checks its keys
+func LoadKeyFile(filename string) (*KeyFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	kf := new(KeyFile)
+	if err := json.Unmarshal(data, kf); err != nil {
+		return nil, err
+	}
+	if err := kf.Init(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return kf, nil
+}
+
+// Init parses the keys of the file
+func (kf *KeyFile) Init() (err error) {
+	if kf.chainID, err = primitives.HexToHash(kf.IdentityChainID); err != nil {
+		return fmt.Errorf("Bad identity chain ID: %v", err)
+	}
+	if kf.management, err = primitives.HexToHash(kf.ManagementChainID); err != nil {
+		return fmt.Errorf("Bad management chain ID: %v", err)
+	}
+	key, err := hex.DecodeString(kf.IdentityKey)
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("The identity key should be 32 bytes of hex")
+	}
+	kf.key = primitives.NewPrivateKeyFromHexBytes(key)
+	if kf.ecKey, err = primitives.HumanReadableECPrivateKeyToPrivateKey(kf.ECPrivateKey); err != nil {
+		return fmt.Errorf("Bad entry credit key: %v", err)
+	}
+	return nil
+}
+
+// IdentityUpdate is a change to the identity of the key file. Value is the new block signing public key,
+// Bitcoin key hash, Matryoshka hash, coinbase address (FA...), efficiency in percent ("12.34"), or the
+// <descriptor height>:<output index> of a coinbase cancel.
+type IdentityUpdate struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Level   byte   `json:"level"`   // Bitcoin key level, 0 to 3
+	KeyType byte   `json:"keytype"` // Bitcoin key type, 0 P2PKH, 1 P2SH
+}
+
+// BuildIdentityUpdate makes the entry of an update, signed by the identity key of the key file
+func (kf *KeyFile) BuildIdentityUpdate(u IdentityUpdate, now interfaces.Timestamp) (*entryBlock.Entry, error) {
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(now.GetTimeSeconds()))
+	preimage := append([]byte{0x01}, kf.key.Pub[:]...)
+	sign := func(data []byte) []byte {
+		return kf.key.Sign(data).GetSignature()[:]
+	}
+
+	var extIDs [][]byte
+	chainID := kf.management
+	switch u.Type {
+	case UpdateBlockSigningKey:
+		pub, err := hex.DecodeString(u.Value)
+		if err != nil || len(pub) != 32 {
+			return nil, fmt.Errorf("The block signing key should be a 32 byte hex public key")
+		}
+		s := &NewBlockSigningKeyStruct{FunctionName: []byte("New Block Signing Key"), RootIdentityChainID: kf.chainID,
+			NewPublicKey: pub, Timestamp: ts, PreimageIdentityKey: preimage}
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+	case UpdateBitcoinKey:
+		key, err := hex.DecodeString(u.Value)
+		if err != nil || len(key) != 20 {
+			return nil, fmt.Errorf("The Bitcoin key should be a 20 byte hex key hash")
+		}
+		if u.Level > 3 || u.KeyType > 1 {
+			return nil, fmt.Errorf("Bitcoin key level should be 0 to 3 and type 0 or 1, got %d and %d", u.Level, u.KeyType)
+		}
+		s := &NewBitcoinKeyStructure{FunctionName: []byte("New Bitcoin Key"), RootIdentityChainID: kf.chainID,
+			BitcoinKeyLevel: u.Level, KeyType: u.KeyType, Timestamp: ts, PreimageIdentityKey: preimage}
+		copy(s.NewKey[:], key)
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+	case UpdateMatryoshkaHash:
+		mhash, err := primitives.HexToHash(u.Value)
+		if err != nil {
+			return nil, fmt.Errorf("The Matryoshka hash should be 32 bytes of hex")
+		}
+		s := &NewMatryoshkaHashStructure{FunctionName: []byte("New Matryoshka Hash"), RootIdentityChainID: kf.chainID,
+			OutermostMHash: mhash, Timestamp: ts, PreimageIdentityKey: preimage}
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+	case UpdateCoinbaseAddress:
+		if !primitives.ValidateFUserStr(u.Value) {
+			return nil, fmt.Errorf("Bad factoid address %q", u.Value)
+		}
+		s := &NewCoinbaseAddressStruct{RootIdentityChainID: kf.chainID,
+			CoinbaseAddress: primitives.NewHash(primitives.ConvertUserStrToAddress(u.Value)), Timestamp: ts, PreimageIdentityKey: preimage}
+		s.SetFunctionName()
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+		chainID = kf.chainID // Coinbase addresses go in the root chain
+	case UpdateEfficiency:
+		eff, err := ParseEfficiency(u.Value)
+		if err != nil {
+			return nil, err
+		}
+		s := &NewServerEfficiencyStruct{RootIdentityChainID: kf.chainID, Efficiency: eff, Timestamp: ts, PreimageIdentityKey: preimage}
+		s.SetFunctionName()
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+	case UpdateCoinbaseCancel:
+		parts := strings.Split(u.Value, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("A coinbase cancel should be <descriptor height>:<output index>, got %q", u.Value)
+		}
+		height, err1 := strconv.ParseUint(parts[0], 10, 32)
+		index, err2 := strconv.ParseUint(parts[1], 10, 32)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("A coinbase cancel should be <descriptor height>:<output index>, got %q", u.Value)
+		}
+		s := &NewCoinbaseCancelStruct{RootIdentityChainID: kf.chainID, CoinbaseDescriptorHeight: uint32(height),
+			CoinbaseDescriptorIndex: uint32(index), PreimageIdentityKey: preimage}
+		s.SetFunctionName()
+		s.Signature = sign(s.MarshalForSig())
+		extIDs = s.ToExternalIDs()
+	default:
+		return nil, fmt.Errorf("Unknown identity update %q", u.Type)
+	}
+
+	e := entryBlock.NewEntry()
+	e.ChainID = chainID
+	for _, x := range extIDs {
+		e.ExtIDs = append(e.ExtIDs, primitives.ByteSlice{Bytes: x})
+	}
+	return e, nil
+}
+
+// ParseEfficiency parses an efficiency in percent, "12.34", to 1234
+func ParseEfficiency(text string) (uint16, error) {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || f < 0 || f > 100 {
+		return 0, fmt.Errorf("The efficiency should be a percent from 0 to 100, got %q", text)
+	}
+	return uint16(f*100 + 0.5), nil
+}
+
+// ComposeCommit makes the commit of an entry, paid by the entry credit key of the key file
+func (kf *KeyFile) ComposeCommit(e interfaces.IEBEntry, now interfaces.Timestamp) (*entryCreditBlock.CommitEntry, error) {
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	c := entryCreditBlock.NewCommitEntry()
+	ms := make([]byte, 8)
+	binary.BigEndian.PutUint64(ms, now.GetTimeMilliUInt64())
+	copy(c.MilliTime[:], ms[2:])
+	c.EntryHash = e.GetHash()
+	if c.Credits, err = util.EntryCost(data); err != nil {
+		return nil, err
+	}
+	if err := c.Sign(kf.ecKey); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewBlockSigningKey makes a key to rotate the block signing key to
+func NewBlockSigningKey() *primitives.PrivateKey {
+	return primitives.RandomPrivateKey()
+}
+
+// IdentityUpdatePreview is what an identity entry changes, and from when. An entry is synced into the
+// identity a block after its own, and only the changes of authorities go in that admin block, see
+// IDENTITY.md.
+type IdentityUpdatePreview struct {
+	Type             string `json:"type"`
+	IdentityChainID  string `json:"identitychainid"`
+	ChainID          string `json:"chainid"` // Chain the entry goes in
+	EntryHash        string `json:"entryhash"`
+	Authority        bool   `json:"authority"`
+	AdminEntry       string `json:"adminentry,omitempty"`
+	EntryHeight      uint32 `json:"entryheight"`      // Block the entry goes in
+	AdminBlockHeight uint32 `json:"adminblockheight"` // Admin block of the change
+	EffectiveHeight  uint32 `json:"effectiveheight"`  // Block the change is in effect from
+	Note             string `json:"note,omitempty"`
+}
+
+// PreviewIdentityUpdate checks an identity entry against the identity it updates, and returns the admin
+// block entry it makes if it goes in the block at height
+func (im *IdentityManager) PreviewIdentityUpdate(e interfaces.IEBEntry, height uint32) (*IdentityUpdatePreview, error) {
+	extIDs := e.ExternalIDs()
+	if len(extIDs) < 3 {
+		return nil, fmt.Errorf("Not an identity entry")
+	}
+
+	p := new(IdentityUpdatePreview)
+	p.ChainID = e.GetChainID().String()
+	p.EntryHash = e.GetHash().String()
+	p.EntryHeight = height
+	p.AdminBlockHeight = height + 1
+	p.EffectiveHeight = height + 2
+
+	var root interfaces.IHash
+	var verify func(key1 interfaces.IHash) error
+	var abe interfaces.IABEntry
+	inRoot := false
+	switch string(extIDs[1]) {
+	case "New Block Signing Key":
+		s, err := DecodeNewBlockSigningKeyStructFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateBlockSigningKey, s.RootIdentityChainID, s.VerifySignature
+		pub := new(primitives.PublicKey)
+		pub.UnmarshalBinary(s.NewPublicKey)
+		abe = adminBlock.NewAddFederatedServerSigningKey(root, 0, *pub, p.EffectiveHeight)
+	case "New Bitcoin Key":
+		s, err := DecodeNewBitcoinKeyStructureFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateBitcoinKey, s.RootIdentityChainID, s.VerifySignature
+		abe = adminBlock.NewAddFederatedServerBitcoinAnchorKey(root, s.BitcoinKeyLevel, s.KeyType, primitives.ByteSlice20(s.NewKey))
+	case "New Matryoshka Hash":
+		s, err := DecodeNewMatryoshkaHashStructureFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateMatryoshkaHash, s.RootIdentityChainID, s.VerifySignature
+		abe = adminBlock.NewAddReplaceMatryoshkaHash(root, s.OutermostMHash)
+	case "Coinbase Address":
+		s, err := DecodeNewNewCoinbaseAddressStructFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateCoinbaseAddress, s.RootIdentityChainID, s.VerifySignature
+		abe = adminBlock.NewAddFactoidAddress(root, s.CoinbaseAddress)
+		inRoot = true
+	case "Server Efficiency":
+		s, err := DecodeNewServerEfficiencyStructFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateEfficiency, s.RootIdentityChainID, s.VerifySignature
+		eff := s.Efficiency
+		if eff > 10000 {
+			eff = 10000
+		}
+		abe = adminBlock.NewAddEfficiency(root, eff)
+		p.Note = "The next coinbase descriptor from the effective height pays with the new efficiency"
+	case "Coinbase Cancel":
+		s, err := DecodeNewCoinbaseCancelStructFromExtIDs(extIDs)
+		if err != nil {
+			return nil, err
+		}
+		p.Type, root, verify = UpdateCoinbaseCancel, s.RootIdentityChainID, s.VerifySignature
+		if height < s.CoinbaseDescriptorHeight || height > s.CoinbaseDescriptorHeight+constants.COINBASE_DECLARATION {
+			return nil, fmt.Errorf("The coinbase of height %d can be cancelled from %d to %d, not at %d", s.CoinbaseDescriptorHeight,
+				s.CoinbaseDescriptorHeight, s.CoinbaseDescriptorHeight+constants.COINBASE_DECLARATION, height)
+		}
+		abe = adminBlock.NewCancelCoinbaseDescriptor(s.CoinbaseDescriptorHeight, s.CoinbaseDescriptorIndex)
+		p.Note = "The cancel goes in the admin block once a majority of the authorities cancel the output"
+	default:
+		return nil, fmt.Errorf("Unknown identity entry %q", extIDs[1])
+	}
+	p.IdentityChainID = root.String()
+
+	id := im.GetIdentity(root)
+	if id == nil {
+		return nil, fmt.Errorf("Identity %s not found", root.String())
+	}
+	if err := verify(id.Keys[0]); err != nil {
+		return nil, err
+	}
+	if inRoot && !e.GetChainID().IsSameAs(root) {
+		return nil, fmt.Errorf("The entry should go in the identity chain %s", root.String())
+	}
+	if !inRoot && !e.GetChainID().IsSameAs(id.ManagementChainID) {
+		return nil, fmt.Errorf("The entry should go in the management chain %s", id.ManagementChainID.String())
+	}
+
+	if im.GetAuthority(root) == nil {
+		p.Note = "Not an authority, the identity changes without an admin block entry"
+		return p, nil
+	}
+	p.Authority = true
+	p.AdminEntry = abe.String()
+	return p, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/constants"
+	. "github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+func newTestKeyFile(t *testing.T) (*KeyFile, *IdentityManager) {
+	key := primitives.RandomPrivateKey()
+	ec, err := primitives.PrivateKeyStringToHumanReadableECPrivateKey(primitives.RandomPrivateKey().PrivateKeyString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kf := &KeyFile{
+		IdentityChainID:   "888888aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		ManagementChainID: "888888bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		IdentityKey:       key.PrivateKeyString(),
+		ECPrivateKey:      ec,
+	}
+	if err := kf.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	id := NewIdentity()
+	id.IdentityChainID, _ = primitives.HexToHash(kf.IdentityChainID)
+	id.ManagementChainID, _ = primitives.HexToHash(kf.ManagementChainID)
+	id.Keys[0] = primitives.Shad(append([]byte{0x01}, key.Pub[:]...))
+	im := NewIdentityManager()
+	im.SetIdentity(id.IdentityChainID, id)
+	return kf, im
+}
+
+func TestPreviewIdentityUpdate(t *testing.T) {
+	kf, im := newTestKeyFile(t)
+	now := primitives.NewTimestampNow()
+	address := primitives.ConvertFctAddressToUserStr(primitives.RandomHash())
+
+	updates := []IdentityUpdate{
+		{Type: UpdateBlockSigningKey, Value: NewBlockSigningKey().PublicKeyString()},
+		{Type: UpdateBitcoinKey, Value: "0123456789abcdef0123456789abcdef01234567", Level: 1, KeyType: 1},
+		{Type: UpdateMatryoshkaHash, Value: primitives.RandomHash().String()},
+		{Type: UpdateCoinbaseAddress, Value: address},
+		{Type: UpdateEfficiency, Value: "12.34"},
+		{Type: UpdateCoinbaseCancel, Value: "8:1"},
+	}
+
+	// Not an authority yet, nothing goes in the admin block
+	e, err := kf.BuildIdentityUpdate(updates[0], now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := im.PreviewIdentityUpdate(e, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Authority || p.AdminEntry != "" || p.Note == "" {
+		t.Errorf("Expected no admin block entry, got %+v", p)
+	}
+
+	auth := NewAuthority()
+	auth.AuthorityChainID, _ = primitives.HexToHash(kf.IdentityChainID)
+	im.SetAuthority(auth.AuthorityChainID, auth)
+
+	for _, u := range updates {
+		e, err := kf.BuildIdentityUpdate(u, now)
+		if err != nil {
+			t.Fatalf("%s: %v", u.Type, err)
+		}
+		p, err := im.PreviewIdentityUpdate(e, 10)
+		if err != nil {
+			t.Fatalf("%s: %v", u.Type, err)
+		}
+		if p.Type != u.Type || !p.Authority || p.AdminEntry == "" || p.IdentityChainID != kf.IdentityChainID {
+			t.Errorf("%s: wrong preview %+v", u.Type, p)
+		}
+		if p.EntryHeight != 10 || p.AdminBlockHeight != 11 || p.EffectiveHeight != 12 {
+			t.Errorf("%s: expected heights 10, 11, 12, got %d, %d, %d", u.Type, p.EntryHeight, p.AdminBlockHeight, p.EffectiveHeight)
+		}
+		chainID := kf.ManagementChainID
+		if u.Type == UpdateCoinbaseAddress {
+			chainID = kf.IdentityChainID
+		}
+		if p.ChainID != chainID {
+			t.Errorf("%s: expected the entry in %s, got %s", u.Type, chainID, p.ChainID)
+		}
+
+		c, err := kf.ComposeCommit(e, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.IsValid() || !c.EntryHash.IsSameAs(e.GetHash()) {
+			t.Errorf("%s: bad commit", u.Type)
+		}
+	}
+
+	// A cancel past the declaration of its descriptor
+	e, _ = kf.BuildIdentityUpdate(updates[5], now)
+	if _, err := im.PreviewIdentityUpdate(e, 9+constants.COINBASE_DECLARATION); err == nil {
+		t.Error("Expected an error for a late coinbase cancel")
+	}
+
+	// Signed by a key that is not the identity key 1
+	other, _ := newTestKeyFile(t)
+	e, _ = other.BuildIdentityUpdate(updates[4], now)
+	if _, err := im.PreviewIdentityUpdate(e, 10); err == nil {
+		t.Error("Expected an error for the wrong identity key")
+	}
+
+	if _, err := kf.BuildIdentityUpdate(IdentityUpdate{Type: UpdateEfficiency, Value: "101"}, now); err == nil {
+		t.Error("Expected an error for an efficiency over 100%")
+	}
+	if _, err := kf.BuildIdentityUpdate(IdentityUpdate{Type: UpdateCoinbaseCancel, Value: "8"}, now); err == nil {
+		t.Error("Expected an error for a cancel without an index")
+	}
+}
+
+func TestParseEfficiency(t *testing.T) {
+	for text, eff := range map[string]uint16{"0": 0, "12.34": 1234, "50": 5000, "100": 10000} {
+		if e, err := ParseEfficiency(text); err != nil || e != eff {
+			t.Errorf("%s: expected %d, got %d %v", text, eff, e, err)
+		}
+	}
+}
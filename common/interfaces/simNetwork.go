@@ -0,0 +1,24 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package interfaces
+
+// LinkFault is the fault model of one direction of a link of the simulated network. The zero value is
+// a perfect link. Rates are out of every thousand messages.
+type LinkFault struct {
+	Cut          bool   `json:"cut" yaml:"cut"`                   // Drop everything, one way cuts make asymmetric partitions
+	DropRate     int    `json:"droprate" yaml:"droprate"`         // Messages dropped
+	Latency      int64  `json:"latency" yaml:"latency"`           // Fixed delay in milliseconds
+	Jitter       int64  `json:"jitter" yaml:"jitter"`             // Random delay added to the latency, in milliseconds
+	Distribution string `json:"distribution" yaml:"distribution"` // Of the jitter: uniform (default), normal or exponential
+	Bandwidth    int    `json:"bandwidth" yaml:"bandwidth"`       // Bytes per second, 0 is unlimited
+	Reorder      int    `json:"reorder" yaml:"reorder"`           // Messages held back so the following messages overtake them
+	Duplicate    int    `json:"duplicate" yaml:"duplicate"`       // Messages delivered twice
+}
+
+// ISimNetwork gives access to the links between the nodes of a simulation
+type ISimNetwork interface {
+	LinkFaults() interface{}                             // The fault model of every link
+	SetLinkFault(from, to string, fault LinkFault) error // Empty names match every node
+}
@@ -9,6 +9,7 @@ import "github.com/FactomProject/factomd/activations"
 type DBStateSent struct {
 	DBHeight uint32
 	Sent     Timestamp
+	Compact  bool // A compact DBState was sent
 }
 
 // IQueue is the interface returned by returning queue functions
@@ -47,6 +48,7 @@ type IState interface {
 
 	GetDirectoryBlockInSeconds() int
 	SetDirectoryBlockInSeconds(int)
+	GetMinutesPerBlock(dbheight uint32) int
 	GetFactomdVersion() string
 	GetDBHeightComplete() uint32
 	DatabaseContains(hash IHash) bool
@@ -204,17 +206,18 @@ type IState interface {
 	GetSystemMsg(dbheight, height uint32) IMsg // Return the system message at the given height.
 	SendDBSig(dbheight uint32, vmIndex int)    // If a Leader, we have to send a DBSig out for the previous block
 
-	FollowerExecuteMsg(IMsg)          // Messages that go into the process list
-	FollowerExecuteEOM(IMsg)          // Messages that go into the process list
-	FollowerExecuteAck(IMsg)          // Ack Msg calls this function.
-	FollowerExecuteDBState(IMsg)      // Add the given DBState to this server
-	FollowerExecuteSFault(IMsg)       // Handling of Server Fault Messages
-	FollowerExecuteFullFault(IMsg)    // Handle Server Full-Fault Messages
-	FollowerExecuteMMR(IMsg)          // Handle Missing Message Responses
-	FollowerExecuteDataResponse(IMsg) // Handle Data Response
-	FollowerExecuteMissingMsg(IMsg)   // Handle requests for missing messages
-	FollowerExecuteCommitChain(IMsg)  // CommitChain needs to look for a Reveal Entry
-	FollowerExecuteCommitEntry(IMsg)  // CommitEntry needs to look for a Reveal Entry
+	FollowerExecuteMsg(IMsg)            // Messages that go into the process list
+	FollowerExecuteEOM(IMsg)            // Messages that go into the process list
+	FollowerExecuteAck(IMsg)            // Ack Msg calls this function.
+	FollowerExecuteDBState(IMsg)        // Add the given DBState to this server
+	FollowerExecuteDBStateCompact(IMsg) // Rebuild a compact DBState from known messages, and add it
+	FollowerExecuteSFault(IMsg)         // Handling of Server Fault Messages
+	FollowerExecuteFullFault(IMsg)      // Handle Server Full-Fault Messages
+	FollowerExecuteMMR(IMsg)            // Handle Missing Message Responses
+	FollowerExecuteDataResponse(IMsg)   // Handle Data Response
+	FollowerExecuteMissingMsg(IMsg)     // Handle requests for missing messages
+	FollowerExecuteCommitChain(IMsg)    // CommitChain needs to look for a Reveal Entry
+	FollowerExecuteCommitEntry(IMsg)    // CommitEntry needs to look for a Reveal Entry
 	FollowerExecuteRevealEntry(IMsg)
 
 	ProcessAddServer(dbheight uint32, addServerMsg IMsg) bool
@@ -285,6 +288,9 @@ type IState interface {
 	ExchangeRateAuthorityIsValid(IEBEntry) bool
 	FerEntryIsValid(passedFEREntry IFEREntry) bool
 	GetPredictiveFER() uint64
+	GetFERHistory(offset int, limit int) interface{}
+	GetECCommitBalance(ecPubKey [32]byte, credits uint8) int64 // Of the EC address paying, the owner of a delegation
+	GetCoinbaseReport(identityChainID IHash, from uint32, to uint32) (interface{}, error)
 
 	// Identity Section
 	VerifyIsAuthority(cid IHash) bool // True if is authority
@@ -293,6 +299,9 @@ type IState interface {
 	FastVerifyAuthoritySignature(Message []byte, signature IFullSignature, dbheight uint32) (int, error)
 	UpdateAuthSigningKeys(height uint32)
 	AddIdentityFromChainID(cid IHash) error
+	PreviewIdentityUpdate(entry IEBEntry) (interface{}, error)
+	GetAuthoritySet(height uint32) (interface{}, error)
+	GetAuthorityChanges(identityChainID IHash, from uint32, to uint32) (interface{}, error)
 
 	AddAuthorityDelta(changeString string)
 
@@ -315,6 +324,22 @@ type IState interface {
 	SetDropRate(int)
 	GetBootTime() int64
 
+	// Access to the peer database of the p2p network
+	GetKnownPeers() (interface{}, error)
+	SetPeerQuality(address string, quality int32) error
+	BanPeer(address string, reason string) error
+	UnbanPeer(address string) error
+	RemovePeer(address string) error
+
+	// Access to the links of the simulated network
+	GetLinkFaults() (interface{}, error)
+	SetLinkFault(from, to string, fault LinkFault) error
+
+	// Cadence of the authority set, and the fast failover of the elections
+	NoteHeartbeat(id IHash)
+	GetFailoverStatus() interface{}
+	GetProcessListStatus(dbheight int) interface{}
+
 	// Access to Holding Queue
 	LoadHoldingMap() map[[32]byte]IMsg
 	LoadAcksMap() map[[32]byte]IMsg
@@ -95,7 +95,7 @@ func (m *Ack) Validate(s interfaces.IState) int {
 		s.SetHighestAck(m.DBHeight) // assume the ack isn't lying. this will make us start requesting DBState blocks...
 	}
 
-	delta := (int(m.DBHeight)-int(s.GetLeaderPL().GetDBHeight()))*10 + (int(m.Minute) - int(s.GetCurrentMinute()))
+	delta := (int(m.DBHeight)-int(s.GetLeaderPL().GetDBHeight()))*s.GetMinutesPerBlock(m.DBHeight) + (int(m.Minute) - int(s.GetCurrentMinute()))
 
 	if delta > 30 {
 		s.LogMessage("ackQueue", "Drop ack from future", m)
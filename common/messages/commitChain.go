@@ -106,12 +106,12 @@ func (m *CommitChainMsg) Type() byte {
 //  0   -- Cannot tell if message is Valid
 //  1   -- Message is valid
 func (m *CommitChainMsg) Validate(state interfaces.IState) int {
-	if !m.validsig && !m.CommitChain.IsValid() {
+	if !m.validsig && !m.CommitChain.IsValidWithTip(MaxECTip(state)) {
 		return -1
 	}
 	m.validsig = true
 
-	ebal := state.GetFactoidState().GetECBalance(*m.CommitChain.ECPubKey)
+	ebal := state.GetECCommitBalance(*m.CommitChain.ECPubKey, m.CommitChain.Credits)
 	v := int(ebal) - int(m.CommitChain.Credits)
 	if v < 0 {
 		return 0
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"fmt"
 
+	"github.com/FactomProject/factomd/activations"
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/entryCreditBlock"
 	"github.com/FactomProject/factomd/common/interfaces"
@@ -224,18 +225,27 @@ func (m *CommitEntryMsg) LogFields() log.Fields {
 //  0   -- Cannot tell if message is Valid
 //  1   -- Message is valid
 func (m *CommitEntryMsg) Validate(state interfaces.IState) int {
-	if !m.validsig && !m.CommitEntry.IsValid() {
+	if !m.validsig && !m.CommitEntry.IsValidWithTip(MaxECTip(state)) {
 		return -1
 	}
 	m.validsig = true
 
-	ebal := state.GetFactoidState().GetECBalance(*m.CommitEntry.ECPubKey)
+	ebal := state.GetECCommitBalance(*m.CommitEntry.ECPubKey, m.CommitEntry.Credits)
 	if int(m.CommitEntry.Credits) > int(ebal) {
 		return 0
 	}
 	return 1
 }
 
+// MaxECTip returns the most entry credits a commit can tip in the block being built, none before the
+// activation of the tips
+func MaxECTip(state interfaces.IState) uint8 {
+	if !activations.IsActive(activations.EC_TIPS, int(state.GetLLeaderHeight())) {
+		return 0
+	}
+	return constants.MAX_EC_TIP
+}
+
 func (m *CommitEntryMsg) ComputeVMIndex(state interfaces.IState) {
 	m.VMIndex = state.ComputeVMIndex(constants.EC_CHAINID)
 }
@@ -0,0 +1,430 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+
+	"github.com/FactomProject/factomd/common/messages/msgbase"
+	log "github.com/sirupsen/logrus"
+)
+
+// Communicate a Directory Block State in compact form.
+//
+// A follower that is almost in sync has already seen most of the factoid transactions and
+// entries of a block as messages.  The compact DBState carries the directory, admin, entry
+// credit and entry blocks in full, but the factoid transactions only as short IDs, and no
+// entries at all.  The receiver rebuilds the full DBState from the messages it already has.
+// Entries it does not have are fetched by the entry syncing; if a transaction is missing, the
+// full DBState is requested instead.
+
+type DBStateCompactMsg struct {
+	msgbase.MessageBase
+	Timestamp interfaces.Timestamp
+
+	DirectoryBlock   interfaces.IDirectoryBlock
+	AdminBlock       interfaces.IAdminBlock
+	EntryCreditBlock interfaces.IEntryCreditBlock
+
+	// The Factoid Block header, the transactions are listed in Transactions
+	FBodyMR          interfaces.IHash
+	FPrevKeyMR       interfaces.IHash
+	FPrevLedgerKeyMR interfaces.IHash
+	FExchRate        uint64
+	FEndOfPeriod     [10]uint32
+	Transactions     []CompactTransaction
+
+	EBlocks []interfaces.IEntryBlock
+
+	SignatureList SigList
+}
+
+// CompactTransaction is a factoid transaction in a compact DBState.  Transactions the receiver
+// is expected to have are sent as a short ID only, the rest (like the coinbase) in full.
+type CompactTransaction struct {
+	ShortID     uint64
+	Transaction interfaces.ITransaction // nil if only the short ID was sent
+}
+
+var _ interfaces.IMsg = (*DBStateCompactMsg)(nil)
+
+// ShortID returns the 8 byte id of a hash in this DBState.  The ids are salted with the
+// directory block KeyMR, so collisions can not be precomputed for all blocks.
+func (m *DBStateCompactMsg) ShortID(hash interfaces.IHash) uint64 {
+	data := append(m.DirectoryBlock.GetKeyMR().Bytes(), hash.Bytes()...)
+	return binary.BigEndian.Uint64(primitives.Sha(data).Bytes()[:8])
+}
+
+func (m *DBStateCompactMsg) GetRepeatHash() interfaces.IHash {
+	return m.DirectoryBlock.GetHash()
+}
+
+func (m *DBStateCompactMsg) GetHash() interfaces.IHash {
+	return m.GetMsgHash()
+}
+
+func (m *DBStateCompactMsg) GetMsgHash() interfaces.IHash {
+	if m.MsgHash == nil {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return nil
+		}
+		m.MsgHash = primitives.Sha(data)
+	}
+	return m.MsgHash
+}
+
+func (m *DBStateCompactMsg) Type() byte {
+	return constants.DBSTATE_COMPACT_MSG
+}
+
+func (m *DBStateCompactMsg) GetTimestamp() interfaces.Timestamp {
+	return m.Timestamp
+}
+
+// Validate the message, given the state.  Two possible results:
+//  < 0 -- Message is invalid.  Discard
+//  1   -- Message is valid
+// The rebuilt DBStateMsg is validated in full when it is executed.
+func (m *DBStateCompactMsg) Validate(state interfaces.IState) int {
+	if m.DirectoryBlock == nil || m.AdminBlock == nil || m.EntryCreditBlock == nil || m.FBodyMR == nil {
+		state.AddStatus(fmt.Sprintf("DBStateCompactMsg.Validate() Fail  Doesn't have all the blocks"))
+		return -1
+	}
+
+	if state.GetNetworkID() != m.DirectoryBlock.GetHeader().GetNetworkID() {
+		state.AddStatus(fmt.Sprintf("DBStateCompactMsg.Validate() Fail  ht: %d Expecting NetworkID %x and found %x",
+			m.DirectoryBlock.GetHeader().GetDBHeight(), state.GetNetworkID(), m.DirectoryBlock.GetHeader().GetNetworkID()))
+		return -1
+	}
+	return 1
+}
+
+// Expand rebuilds the full DBStateMsg from the given transactions and entries.  An error is
+// returned if a transaction is missing, or the rebuilt Factoid Block does not match the
+// directory block.  Entries that are not found are left out of the DBStateMsg.
+func (m *DBStateCompactMsg) Expand(transactions []interfaces.ITransaction, entries map[[32]byte]interfaces.IEBEntry) (*DBStateMsg, error) {
+	known := make(map[uint64]interfaces.ITransaction, len(transactions))
+	for _, tx := range transactions {
+		known[m.ShortID(tx.GetHash())] = tx
+	}
+
+	fblock := new(factoid.FBlock)
+	fblock.Init()
+	fblock.BodyMR = m.FBodyMR
+	fblock.PrevKeyMR = m.FPrevKeyMR
+	fblock.PrevLedgerKeyMR = m.FPrevLedgerKeyMR
+	fblock.ExchRate = m.FExchRate
+	fblock.DBHeight = m.DirectoryBlock.GetDatabaseHeight()
+
+	missing := 0
+	period := 0
+	for i, ct := range m.Transactions {
+		for period < len(m.FEndOfPeriod) && m.FEndOfPeriod[period] > 0 && i == int(m.FEndOfPeriod[period]) {
+			period++
+			fblock.EndOfPeriod(period)
+		}
+		tx := ct.Transaction
+		if tx == nil {
+			tx = known[ct.ShortID]
+		}
+		if tx == nil {
+			missing++
+			continue
+		}
+		fblock.Transactions = append(fblock.Transactions, tx)
+	}
+	if missing > 0 {
+		return nil, fmt.Errorf("Missing %d of %d transactions", missing, len(m.Transactions))
+	}
+	for period < len(m.FEndOfPeriod) && m.FEndOfPeriod[period] > 0 {
+		period++
+		fblock.EndOfPeriod(period)
+	}
+
+	dbentries := m.DirectoryBlock.GetDBEntries()
+	if len(dbentries) < 3 || !dbentries[2].GetKeyMR().IsSameAs(fblock.GetKeyMR()) {
+		return nil, fmt.Errorf("Rebuilt Factoid Block does not match the Directory Block")
+	}
+
+	var ents []interfaces.IEBEntry
+	for _, eb := range m.EBlocks {
+		for _, h := range eb.GetEntryHashes() {
+			if h.IsMinuteMarker() {
+				continue
+			}
+			if e, ok := entries[h.Fixed()]; ok {
+				ents = append(ents, e)
+			}
+		}
+	}
+
+	msg := NewDBStateMsg(m.Timestamp, m.DirectoryBlock, m.AdminBlock, fblock, m.EntryCreditBlock,
+		m.EBlocks, ents, m.SignatureList.List).(*DBStateMsg)
+	msg.NoResend = m.NoResend
+	msg.SetOrigin(m.GetOrigin())
+	msg.SetNetworkOrigin(m.GetNetworkOrigin())
+	return msg, nil
+}
+
+func (m *DBStateCompactMsg) ComputeVMIndex(state interfaces.IState) {}
+
+// Execute the leader functions of the given message
+func (m *DBStateCompactMsg) LeaderExecute(state interfaces.IState) {
+	m.FollowerExecute(state)
+}
+
+func (m *DBStateCompactMsg) FollowerExecute(state interfaces.IState) {
+	state.FollowerExecuteDBStateCompact(m)
+}
+
+// DBState messages do not go into the process list.
+func (e *DBStateCompactMsg) Process(dbheight uint32, state interfaces.IState) bool {
+	panic("DBStateCompactMsg should never have its Process() method called")
+}
+
+func (e *DBStateCompactMsg) JSONByte() ([]byte, error) {
+	return primitives.EncodeJSON(e)
+}
+
+func (e *DBStateCompactMsg) JSONString() (string, error) {
+	return primitives.EncodeJSONString(e)
+}
+
+func (m *DBStateCompactMsg) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling Directory Block State Compact Message: %v", r)
+		}
+	}()
+
+	newData = data
+	if newData[0] != m.Type() {
+		return nil, fmt.Errorf("Invalid Message type")
+	}
+	newData = newData[1:]
+
+	m.Peer2Peer = true
+
+	m.Timestamp = new(primitives.Timestamp)
+	newData, err = m.Timestamp.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	m.DirectoryBlock = new(directoryBlock.DirectoryBlock)
+	newData, err = m.DirectoryBlock.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	m.AdminBlock = new(adminBlock.AdminBlock)
+	newData, err = m.AdminBlock.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	m.EntryCreditBlock = entryCreditBlock.NewECBlock()
+	newData, err = m.EntryCreditBlock.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	m.FBodyMR = new(primitives.Hash)
+	newData, err = m.FBodyMR.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+	m.FPrevKeyMR = new(primitives.Hash)
+	newData, err = m.FPrevKeyMR.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+	m.FPrevLedgerKeyMR = new(primitives.Hash)
+	newData, err = m.FPrevLedgerKeyMR.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+	m.FExchRate, newData = binary.BigEndian.Uint64(newData[0:8]), newData[8:]
+	for i := range m.FEndOfPeriod {
+		m.FEndOfPeriod[i], newData = binary.BigEndian.Uint32(newData[0:4]), newData[4:]
+	}
+
+	txCount, newData := binary.BigEndian.Uint32(newData[0:4]), newData[4:]
+	m.Transactions = nil
+	for i := uint32(0); i < txCount; i++ {
+		var ct CompactTransaction
+		full := newData[0]
+		newData = newData[1:]
+		if full == 0 {
+			ct.ShortID, newData = binary.BigEndian.Uint64(newData[0:8]), newData[8:]
+		} else {
+			tx := new(factoid.Transaction)
+			newData, err = tx.UnmarshalBinaryData(newData)
+			if err != nil {
+				return nil, err
+			}
+			ct.Transaction = tx
+		}
+		m.Transactions = append(m.Transactions, ct)
+	}
+
+	eBlockCount, newData := binary.BigEndian.Uint32(newData[0:4]), newData[4:]
+	m.EBlocks = nil
+	for i := uint32(0); i < eBlockCount; i++ {
+		eBlock := entryBlock.NewEBlock()
+		newData, err = eBlock.UnmarshalBinaryData(newData)
+		if err != nil {
+			return nil, err
+		}
+		m.EBlocks = append(m.EBlocks, eBlock)
+	}
+
+	newData, err = m.SignatureList.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (m *DBStateCompactMsg) UnmarshalBinary(data []byte) error {
+	_, err := m.UnmarshalBinaryData(data)
+	return err
+}
+
+func (m *DBStateCompactMsg) MarshalBinary() (rval []byte, err error) {
+	defer func(pe *error) {
+		if *pe != nil {
+			fmt.Fprintf(os.Stderr, "DBStateCompactMsg.MarshalBinary err:%v", *pe)
+		}
+	}(&err)
+	var buf primitives.Buffer
+
+	binary.Write(&buf, binary.BigEndian, m.Type())
+
+	t := m.GetTimestamp()
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(data)
+
+	for _, b := range []interfaces.BinaryMarshallable{m.DirectoryBlock, m.AdminBlock, m.EntryCreditBlock,
+		m.FBodyMR, m.FPrevKeyMR, m.FPrevLedgerKeyMR} {
+		data, err = b.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+
+	binary.Write(&buf, binary.BigEndian, m.FExchRate)
+	for _, v := range m.FEndOfPeriod {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(m.Transactions)))
+	for _, ct := range m.Transactions {
+		if ct.Transaction == nil {
+			buf.WriteByte(0)
+			binary.Write(&buf, binary.BigEndian, ct.ShortID)
+			continue
+		}
+		buf.WriteByte(1)
+		data, err = ct.Transaction.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(m.EBlocks)))
+	for _, eb := range m.EBlocks {
+		data, err = eb.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+
+	if d, err := m.SignatureList.MarshalBinary(); err != nil {
+		return nil, err
+	} else {
+		buf.Write(d)
+	}
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (m *DBStateCompactMsg) String() string {
+	data, _ := m.MarshalBinary()
+	return fmt.Sprintf("DBStateCompact: dbht:%3d [size: %11s] dblock %6x admin %6x ec %6x txs %d eblocks %d hash %6x",
+		m.DirectoryBlock.GetHeader().GetDBHeight(),
+		primitives.AddCommas(int64(len(data))),
+		m.DirectoryBlock.GetKeyMR().Bytes()[:3],
+		m.AdminBlock.GetHash().Bytes()[:3],
+		m.EntryCreditBlock.GetHash().Bytes()[:3],
+		len(m.Transactions),
+		len(m.EBlocks),
+		m.GetHash().Bytes()[:3])
+}
+
+func (m *DBStateCompactMsg) LogFields() log.Fields {
+	return log.Fields{"category": "message", "messagetype": "dbstatecompact",
+		"dbheight":     m.DirectoryBlock.GetHeader().GetDBHeight(),
+		"dblockhash":   m.DirectoryBlock.GetKeyMR().String(),
+		"ablockhash":   m.AdminBlock.GetHash().String(),
+		"ecblockhash":  m.EntryCreditBlock.GetHash().String(),
+		"transactions": len(m.Transactions),
+		"hash":         m.GetHash().String()}
+}
+
+// NewDBStateCompactMsg builds the compact form of a DBStateMsg.  Transactions without inputs
+// (the coinbase) were never sent as messages, so they are always included in full.
+func NewDBStateCompactMsg(full *DBStateMsg) *DBStateCompactMsg {
+	msg := new(DBStateCompactMsg)
+	msg.NoResend = true
+	msg.Peer2Peer = true
+
+	msg.Timestamp = full.Timestamp
+	msg.DirectoryBlock = full.DirectoryBlock
+	msg.AdminBlock = full.AdminBlock
+	msg.EntryCreditBlock = full.EntryCreditBlock
+
+	fb := full.FactoidBlock
+	msg.FBodyMR = fb.GetBodyMR()
+	msg.FPrevKeyMR = fb.GetPrevKeyMR()
+	msg.FPrevLedgerKeyMR = fb.GetPrevLedgerKeyMR()
+	msg.FExchRate = fb.GetExchRate()
+	for i, v := range fb.GetEndOfPeriod() {
+		msg.FEndOfPeriod[i] = uint32(v)
+	}
+	for _, tx := range fb.GetTransactions() {
+		var ct CompactTransaction
+		if len(tx.GetInputs()) == 0 {
+			ct.Transaction = tx
+		} else {
+			ct.ShortID = msg.ShortID(tx.GetHash())
+		}
+		msg.Transactions = append(msg.Transactions, ct)
+	}
+
+	msg.EBlocks = full.EBlocks
+	msg.SignatureList = full.SignatureList
+
+	return msg
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+
+	"github.com/FactomProject/factomd/common/messages/msgbase"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ask for a range of Directory Block States in compact form.  Sent by followers that are
+// almost in sync; see DBStateCompactMsg.
+
+type DBStateCompactMissing struct {
+	msgbase.MessageBase
+	Timestamp interfaces.Timestamp
+
+	DBHeightStart uint32 // First block missing
+	DBHeightEnd   uint32 // Last block missing.
+
+	//Not signed!
+}
+
+var _ interfaces.IMsg = (*DBStateCompactMissing)(nil)
+
+func (a *DBStateCompactMissing) IsSameAs(b *DBStateCompactMissing) bool {
+	if b == nil {
+		return false
+	}
+	if a.Timestamp.GetTimeMilli() != b.Timestamp.GetTimeMilli() {
+		return false
+	}
+	if a.DBHeightStart != b.DBHeightStart {
+		return false
+	}
+	if a.DBHeightEnd != b.DBHeightEnd {
+		return false
+	}
+
+	return true
+}
+
+func (m *DBStateCompactMissing) GetRepeatHash() interfaces.IHash {
+	return m.GetMsgHash()
+}
+
+func (m *DBStateCompactMissing) GetHash() interfaces.IHash {
+	return m.GetMsgHash()
+}
+
+func (m *DBStateCompactMissing) GetMsgHash() interfaces.IHash {
+	if m.MsgHash == nil {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return nil
+		}
+		m.MsgHash = primitives.Sha(data)
+	}
+	return m.MsgHash
+}
+
+func (m *DBStateCompactMissing) Type() byte {
+	return constants.DBSTATE_COMPACT_MISSING_MSG
+}
+
+func (m *DBStateCompactMissing) GetTimestamp() interfaces.Timestamp {
+	return m.Timestamp
+}
+
+// Validate the message, given the state.  Three possible results:
+//  < 0 -- Message is invalid.  Discard
+//  0   -- Cannot tell if message is Valid
+//  1   -- Message is valid
+func (m *DBStateCompactMissing) Validate(state interfaces.IState) int {
+	if m.DBHeightStart > m.DBHeightEnd {
+		return -1
+	}
+	return 1
+}
+
+func (m *DBStateCompactMissing) ComputeVMIndex(state interfaces.IState) {
+}
+
+// Execute the leader functions of the given message
+func (m *DBStateCompactMissing) LeaderExecute(state interfaces.IState) {
+	m.FollowerExecute(state)
+}
+
+func (m *DBStateCompactMissing) FollowerExecute(state interfaces.IState) {
+	if state.NetworkOutMsgQueue().Length() > state.NetworkOutMsgQueue().Cap()*99/100 {
+		return
+	}
+
+	start, end := NewEnd(state.InMsgQueue().Length(), m.DBHeightStart, m.DBHeightEnd)
+	if end == 0 {
+		return
+	}
+
+	sent := 0
+	for dbs := start; dbs <= end && sent < 1024*1024; dbs++ {
+		sent += sendDBState(m, dbs, state, true)
+	}
+}
+
+// Requests do not go into the process list.
+func (e *DBStateCompactMissing) Process(dbheight uint32, state interfaces.IState) bool {
+	panic("DBStateCompactMissing object should never have its Process() method called")
+}
+
+func (e *DBStateCompactMissing) JSONByte() ([]byte, error) {
+	return primitives.EncodeJSON(e)
+}
+
+func (e *DBStateCompactMissing) JSONString() (string, error) {
+	return primitives.EncodeJSONString(e)
+}
+
+func (m *DBStateCompactMissing) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Error unmarshalling Directory Block State Compact Missing Message: %v", r)
+		}
+	}()
+	newData = data
+	if newData[0] != m.Type() {
+		return nil, fmt.Errorf("Invalid Message type")
+	}
+	newData = newData[1:]
+
+	m.Peer2Peer = true // This is always a Peer2peer message
+
+	m.Timestamp = new(primitives.Timestamp)
+	newData, err = m.Timestamp.UnmarshalBinaryData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	m.DBHeightStart, newData = binary.BigEndian.Uint32(newData[0:4]), newData[4:]
+	m.DBHeightEnd, newData = binary.BigEndian.Uint32(newData[0:4]), newData[4:]
+
+	return
+}
+
+func (m *DBStateCompactMissing) UnmarshalBinary(data []byte) error {
+	_, err := m.UnmarshalBinaryData(data)
+	return err
+}
+
+func (m *DBStateCompactMissing) MarshalForSignature() (rval []byte, err error) {
+	defer func(pe *error) {
+		if *pe != nil {
+			fmt.Fprintf(os.Stderr, "DBStateCompactMissing.MarshalForSignature err:%v", *pe)
+		}
+	}(&err)
+	var buf primitives.Buffer
+
+	binary.Write(&buf, binary.BigEndian, m.Type())
+
+	t := m.GetTimestamp()
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(data)
+
+	binary.Write(&buf, binary.BigEndian, m.DBHeightStart)
+	binary.Write(&buf, binary.BigEndian, m.DBHeightEnd)
+
+	return buf.DeepCopyBytes(), nil
+}
+
+func (m *DBStateCompactMissing) MarshalBinary() (rval []byte, err error) {
+	defer func(pe *error) {
+		if *pe != nil {
+			fmt.Fprintf(os.Stderr, "DBStateCompactMissing.MarshalBinary err:%v", *pe)
+		}
+	}(&err)
+	return m.MarshalForSignature()
+}
+
+func (m *DBStateCompactMissing) String() string {
+	return fmt.Sprintf("DBStateCompactMissing: %d-%d", m.DBHeightStart, m.DBHeightEnd)
+}
+
+func (m *DBStateCompactMissing) LogFields() log.Fields {
+	return log.Fields{"category": "message", "messagetype": "dbstatecompactmissing",
+		"dbheightstart": m.DBHeightStart,
+		"dbheightend":   m.DBHeightEnd}
+}
+
+func NewDBStateCompactMissing(state interfaces.IState, dbheightStart uint32, dbheightEnd uint32) interfaces.IMsg {
+	msg := new(DBStateCompactMissing)
+
+	msg.Peer2Peer = true // Always a peer2peer request.
+	msg.Timestamp = state.GetTimestamp()
+	msg.DBHeightStart = dbheightStart
+	msg.DBHeightEnd = dbheightEnd
+
+	return msg
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package messages_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	. "github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/messages/msgsupport"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+func TestUnmarshalNilDBStateCompactMsg(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Panic caught during the test - %v", r)
+		}
+	}()
+
+	a := new(DBStateCompactMsg)
+	err := a.UnmarshalBinary(nil)
+	if err == nil {
+		t.Errorf("Error is nil when it shouldn't be")
+	}
+
+	err = a.UnmarshalBinary([]byte{})
+	if err == nil {
+		t.Errorf("Error is nil when it shouldn't be")
+	}
+}
+
+func TestMarshalUnmarshalDBStateCompactMsg(t *testing.T) {
+	full := newDBStateMsg()
+	msg := NewDBStateCompactMsg(full)
+
+	hex, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullHex, err := full.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hex) >= len(fullHex) {
+		t.Errorf("Compact DBState (%d bytes) is not smaller than the full DBState (%d bytes)", len(hex), len(fullHex))
+	}
+
+	msg2, err := msgsupport.UnmarshalMessage(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg2.Type() != constants.DBSTATE_COMPACT_MSG {
+		t.Error("Invalid message type unmarshalled")
+	}
+
+	hex2, err := msg2.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primitives.AreBytesEqual(hex, hex2) == false {
+		t.Error("Hexes do not match")
+	}
+}
+
+func TestDBStateCompactMsgExpand(t *testing.T) {
+	full := newDBStateMsg()
+	hex, err := NewDBStateCompactMsg(full).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := msgsupport.UnmarshalMessage(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact := m.(*DBStateCompactMsg)
+
+	txs := full.FactoidBlock.GetTransactions()
+	if len(txs) < 2 {
+		t.Fatalf("Test block needs a transaction besides the coinbase, has %d", len(txs))
+	}
+	if compact.Transactions[0].Transaction == nil {
+		t.Error("Coinbase should be sent in full")
+	}
+	for _, ct := range compact.Transactions[1:] {
+		if ct.Transaction != nil {
+			t.Error("Transactions should be sent as short IDs")
+		}
+	}
+
+	// Without the transactions, the block can not be rebuilt
+	if _, err := compact.Expand(nil, nil); err == nil {
+		t.Error("Expand should fail when transactions are missing")
+	}
+
+	entries := make(map[[32]byte]interfaces.IEBEntry)
+	for _, e := range full.Entries {
+		entries[e.GetHash().Fixed()] = e
+	}
+	rebuilt, err := compact.Expand(txs[1:], entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rebuilt.FactoidBlock.GetKeyMR().IsSameAs(full.FactoidBlock.GetKeyMR()) {
+		t.Error("Rebuilt Factoid Block does not match")
+	}
+	if len(rebuilt.Entries) != len(full.Entries) {
+		t.Errorf("Rebuilt DBState has %d entries, expected %d", len(rebuilt.Entries), len(full.Entries))
+	}
+	if rebuilt.IsSameAs(full) == false {
+		t.Error("Rebuilt DBState does not match the full DBState")
+	}
+
+	// Entries we don't have are left to the entry syncing
+	rebuilt, err = compact.Expand(txs[1:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuilt.Entries) != 0 {
+		t.Errorf("Rebuilt DBState has %d entries, expected none", len(rebuilt.Entries))
+	}
+}
+
+func TestMarshalUnmarshalDBStateCompactMissing(t *testing.T) {
+	msg := new(DBStateCompactMissing)
+	msg.Timestamp = primitives.NewTimestampNow()
+	msg.DBHeightStart = 0x01234567
+	msg.DBHeightEnd = 0x89abcdef
+
+	hex, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg2, err := msgsupport.UnmarshalMessage(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg2.Type() != constants.DBSTATE_COMPACT_MISSING_MSG {
+		t.Error("Invalid message type unmarshalled")
+	}
+	if msg.IsSameAs(msg2.(*DBStateCompactMissing)) == false {
+		t.Error("DBStateCompactMissing messages are not identical")
+	}
+	if msg2.IsPeer2Peer() == false {
+		t.Error("DBStateCompactMissing should be a peer to peer message")
+	}
+}
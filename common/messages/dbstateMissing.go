@@ -96,6 +96,11 @@ func (m *DBStateMissing) LeaderExecute(state interfaces.IState) {
 
 // Only send the same block again after 15 seconds.
 func (m *DBStateMissing) send(dbheight uint32, state interfaces.IState) (msglen int) {
+	return sendDBState(m, dbheight, state, false)
+}
+
+// sendDBState answers a request for the DBState at the given height, in compact form if asked.
+func sendDBState(m interfaces.IMsg, dbheight uint32, state interfaces.IState, compact bool) (msglen int) {
 	send := true
 
 	now := state.GetTimestamp()
@@ -104,7 +109,7 @@ func (m *DBStateMissing) send(dbheight uint32, state interfaces.IState) (msglen
 
 	for _, v := range sents {
 		if now.GetTimeSeconds()-v.Sent.GetTimeSeconds() < 10 {
-			if v.DBHeight == dbheight {
+			if v.DBHeight == dbheight && v.Compact == compact {
 				send = false
 			}
 			keeps = append(keeps, v)
@@ -113,6 +118,9 @@ func (m *DBStateMissing) send(dbheight uint32, state interfaces.IState) (msglen
 	if send {
 		msg, err := state.LoadDBState(dbheight)
 		if msg != nil && err == nil {
+			if full, ok := msg.(*DBStateMsg); ok && compact {
+				msg = NewDBStateCompactMsg(full)
+			}
 			b, err := msg.MarshalBinary()
 			if err != nil {
 				return
@@ -126,6 +134,7 @@ func (m *DBStateMissing) send(dbheight uint32, state interfaces.IState) (msglen
 			v := new(interfaces.DBStateSent)
 			v.DBHeight = dbheight
 			v.Sent = now
+			v.Compact = compact
 			keeps = append(keeps, v)
 		}
 		state.SetDBStatesSent(keeps)
@@ -2,7 +2,6 @@ package electionMsgs
 
 import (
 	"github.com/FactomProject/factomd/common/interfaces"
-	primitives2 "github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/electionsCore/election"
 	"github.com/FactomProject/factomd/electionsCore/imessage"
 	"github.com/FactomProject/factomd/electionsCore/primitives"
@@ -131,8 +130,7 @@ func NewElectionAdapter(e *elections.Elections, dbHash interfaces.IHash) *Electi
 		authset.AddHash(f.GetChainID(), 1)
 	}
 
-	for _, a := range buildPriorityOrder(ea.Election.Audit, dbHash, e.Minute, e.VMIndex) {
-		idhash := primitives2.NewHash(a[:])
+	for _, idhash := range auditOrder(e, dbHash) {
 		authset.AddHash(idhash, 0)
 		ea.AuditServerList = append(ea.AuditServerList, idhash)
 	}
@@ -79,12 +79,19 @@ func (m *EomSigInternal) GetMsgHash() interfaces.IHash {
 	}
 	return m.MsgHash
 }
+
+// startFault starts the timeout for the current election round on the clock
+func startFault(e *elections.Elections, sigtype bool, timeoutDuration time.Duration) {
+	dbheight, minute, timeOutId := e.DBHeight, e.Minute, e.FaultId.Load()
+	primitives.Go(func() { Fault(e, dbheight, minute, timeOutId, &e.FaultId, sigtype, timeoutDuration) })
+}
+
 func Fault(e *elections.Elections, dbheight int, minute int, timeOutId int, currentTimeoutId *atomic.AtomicInt, sigtype bool, timeoutDuration time.Duration) {
 	//	e.LogPrintf("election", "Start Timeout %d", timeOutId)
 	for !e.State.(*state.State).DBFinished || e.State.(*state.State).IgnoreMissing {
-		time.Sleep(timeoutDuration)
+		primitives.Sleep(timeoutDuration)
 	}
-	time.Sleep(timeoutDuration)
+	primitives.Sleep(timeoutDuration)
 
 	if currentTimeoutId.Load() == timeOutId {
 		//		e.LogPrintf("election", "Timeout %d", timeOutId)
@@ -136,6 +143,8 @@ func (m *EomSigInternal) ElectionProcess(is interfaces.IState, elect interfaces.
 	mv := int(m.DBHeight) > e.DBHeight || m.ComparisonMinute() > e.ComparisonMinute()
 
 	if mv {
+		newBlock := int(m.DBHeight) > e.DBHeight
+
 		// Set our Identity Chain (Just in case it has changed.)
 		e.FedID = s.IdentityChainID
 
@@ -164,12 +173,16 @@ func (m *EomSigInternal) ElectionProcess(is interfaces.IState, elect interfaces.
 		// Set the title in the state
 		s.Election0 = Title()
 
+		if newBlock {
+			publishBackups(e, s)
+		}
+
 		e.FaultId.Store(e.FaultId.Load() + 1) // increment the timeout counter
-		go Fault(e, e.DBHeight, e.Minute, e.FaultId.Load(), &e.FaultId, m.SigType, e.Timeout)
+		startFault(e, m.SigType, e.CurrentFaultTimeout())
 
 		// Drain all waiting messages as we have advanced, they can now be processed again
 		// as moving forward in mins/blocks may invalidate/validate some messages
-		go e.ProcessWaiting()
+		primitives.Go(e.ProcessWaiting)
 
 		t := "EOM"
 		if !m.SigType {
@@ -0,0 +1,53 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package electionMsgs
+
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+	primitives2 "github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/elections"
+	"github.com/FactomProject/factomd/electionsCore/primitives"
+	"github.com/FactomProject/factomd/state"
+)
+
+// buildBackupOrder ranks the audit servers that back up a federated server for a whole block. Unlike
+// the priority order of an election it doesn't depend on the minute or the VM of the fault, only on
+// the previous block, the federated server and the audit servers, so every node ranks them the same
+// however long it has been running.
+func buildBackupOrder(audits []interfaces.IServer, prevKeyMR interfaces.IHash, fedIdx int) []primitives.Identity {
+	return buildPriorityOrder(audits, prevKeyMR, 0xff, fedIdx)
+}
+
+func identityHashes(ids []primitives.Identity) []interfaces.IHash {
+	var hashes []interfaces.IHash
+	for _, id := range ids {
+		hashes = append(hashes, primitives2.NewHash(id[:]))
+	}
+	return hashes
+}
+
+// auditOrder returns the order the audit servers volunteer in, in the election for the federated
+// server e.Electing, dbHash being the KeyMR of the previous block. With the fast failover that's the
+// backup order of the block.
+func auditOrder(e *elections.Elections, dbHash interfaces.IHash) []interfaces.IHash {
+	if e.FastFailover() && e.Electing >= 0 && e.Electing < len(e.Federated) {
+		return identityHashes(buildBackupOrder(e.Audit, dbHash, e.Electing))
+	}
+	return identityHashes(buildPriorityOrder(e.Audit, dbHash, e.Minute, e.VMIndex))
+}
+
+// publishBackups puts the backup order of every federated server for the block in the failover status,
+// for the debug API. The elections don't read it back, they rank the backups when they need them.
+func publishBackups(e *elections.Elections, s *state.State) {
+	var backups [][]interfaces.IHash
+	if e.DBHeight > 0 {
+		if prev := s.GetDirectoryBlockByHeight(uint32(e.DBHeight - 1)); prev != nil {
+			for i := range e.Federated {
+				backups = append(backups, identityHashes(buildBackupOrder(e.Audit, prev.GetKeyMR(), i)))
+			}
+		}
+	}
+	e.PublishFailoverStatus(backups)
+}
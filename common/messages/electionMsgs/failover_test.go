@@ -0,0 +1,77 @@
+package electionMsgs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/interfaces"
+	. "github.com/FactomProject/factomd/common/messages/electionMsgs"
+	"github.com/FactomProject/factomd/common/messages/electionMsgs/electionMsgTesting"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+func TestFastFailoverTimeouts(t *testing.T) {
+	c := electionMsgTesting.NewController(3, 3)
+	e := c.Elections[0]
+	e.Timeout = 60 * time.Second
+	e.RoundTimeout = 20 * time.Second
+	e.DBHeight = 10
+
+	defer activations.SetTestActivationHeight(activations.FAST_FAILOVER, 11)()
+	if e.FastFailover() {
+		t.Error("Fast failover active below its height")
+	}
+	if e.CurrentFaultTimeout() != e.Timeout || e.CurrentRoundTimeout() != e.RoundTimeout {
+		t.Errorf("Expected the normal timeouts, got %s and %s", e.CurrentFaultTimeout(), e.CurrentRoundTimeout())
+	}
+
+	e.DBHeight = 11
+	if !e.FastFailover() {
+		t.Error("Fast failover not active at its height")
+	}
+	if e.CurrentFaultTimeout() != 10*time.Second || e.CurrentRoundTimeout() != 5*time.Second {
+		t.Errorf("Expected the fast timeouts, got %s and %s", e.CurrentFaultTimeout(), e.CurrentRoundTimeout())
+	}
+}
+
+func TestFastFailoverBackupOrder(t *testing.T) {
+	c := electionMsgTesting.NewController(3, 3)
+	e := c.Elections[0]
+	e.DBHeight = 10
+	e.Electing = 1
+	prev := primitives.Sha([]byte("previous block"))
+
+	normal := NewElectionAdapter(e, prev).GetAudits()
+
+	// Not active, the adapter keeps the priority order of the election
+	defer activations.SetTestActivationHeight(activations.FAST_FAILOVER, 11)()
+	if got := NewElectionAdapter(e, prev).GetAudits(); !sameIds(got, normal) {
+		t.Errorf("Inactive fast failover changed the audit order")
+	}
+
+	// Active, the backup order is the same in every minute, on every node
+	defer activations.SetTestActivationHeight(activations.FAST_FAILOVER, 10)()
+	backups := NewElectionAdapter(e, prev).GetAudits()
+	if len(backups) != len(e.Audit) {
+		t.Fatalf("Expected %d backups, got %d", len(e.Audit), len(backups))
+	}
+	for _, other := range c.Elections[1:] {
+		other.DBHeight, other.Electing, other.Minute = 10, 1, e.Minute+3
+		if got := NewElectionAdapter(other, prev).GetAudits(); !sameIds(got, backups) {
+			t.Errorf("Expected the backup order %v, got %v", backups, got)
+		}
+	}
+}
+
+func sameIds(a, b []interfaces.IHash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].IsSameAs(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -97,6 +97,7 @@ func (m *FedVoteLevelMsg) ElectionProcess(is interfaces.IState, elect interfaces
 	}
 
 	resp.SendOut(is, resp)
+	traceVote(is, resp)
 
 	// We also need to check if we should change our state if the election resolved
 	if vote, ok := resp.(*FedVoteLevelMsg); ok {
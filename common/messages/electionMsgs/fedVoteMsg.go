@@ -310,3 +310,16 @@ func (m *FedVoteMsg) String() string {
 	}
 	return fmt.Sprintf("%s DBHeight %d Minute %d", "FedVoteMsg ", m.DBHeight, m.Minute)
 }
+
+// traceVote adds a vote this node sent out to the consensus trace
+func traceVote(is interfaces.IState, vote interfaces.IMsg) {
+	s := is.(*state.State)
+	if !s.Tracing() {
+		return
+	}
+	info := ""
+	if v, ok := vote.(*FedVoteLevelMsg); ok {
+		info = fmt.Sprintf("level %d rank %d committed %v for %x", v.Level, v.Rank, v.Committed, v.Volunteer.ServerID.Bytes()[3:6])
+	}
+	s.TraceMsg(state.TraceVote, vote, "", info)
+}
@@ -88,6 +88,7 @@ func (m *FedVoteVolunteerMsg) ElectionProcess(is interfaces.IState, elect interf
 	}
 
 	resp.SendOut(is, resp)
+	traceVote(is, resp)
 	/*_____ End Election Adapter Control  _____*/
 
 }
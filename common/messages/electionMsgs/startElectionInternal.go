@@ -36,7 +36,7 @@ func (m *StartElectionInternal) ElectionProcess(s interfaces.IState, elect inter
 	// If the electing is set to -1, that election has ended before we got to start it.
 	// Still trigger the Fault loop, it will self terminate if we've moved forward
 	if e.Electing == -1 {
-		go Fault(e, e.DBHeight, e.Minute, e.FaultId.Load(), &e.FaultId, m.SigType, e.RoundTimeout)
+		startFault(e, m.SigType, e.CurrentRoundTimeout())
 		return
 	}
 	e.Adapter = NewElectionAdapter(e, m.PreviousDBHash)
@@ -50,7 +50,7 @@ func (m *StartElectionInternal) ElectionProcess(s interfaces.IState, elect inter
 		e.Adapter.SetObserver(true)
 	}
 
-	go Fault(e, e.DBHeight, e.Minute, e.FaultId.Load(), &e.FaultId, m.SigType, e.RoundTimeout)
+	startFault(e, m.SigType, e.CurrentRoundTimeout())
 }
 
 // Execute the leader functions of the given message
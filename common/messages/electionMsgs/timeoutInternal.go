@@ -76,7 +76,7 @@ func (m *TimeoutInternal) InitiateElectionAdapter(st interfaces.IState) bool {
 	e.State.InMsgQueue().Enqueue(msg)
 
 	// When we start a new election, we can process all messages that were being held
-	go e.ProcessWaiting()
+	primitives.Go(e.ProcessWaiting)
 	return true
 }
 
@@ -150,6 +150,7 @@ func (m *TimeoutInternal) ElectionProcess(is interfaces.IState, elect interfaces
 			e.Round = append(e.Round, 0)
 		}
 		e.Round[e.Electing] = 0
+		traceRound(s, e)
 
 		sync := "dbsig"
 		if m.SigType {
@@ -175,12 +176,13 @@ func (m *TimeoutInternal) ElectionProcess(is interfaces.IState, elect interfaces
 
 	// New timeout, new round of elections.
 	e.Round[e.Electing]++
+	traceRound(s, e)
 
 	// If we don't have all our sync messages, we will have to come back around and see if all is well.
 	// Start our timer to timeout this sync
 
 	e.FaultId.Store(e.FaultId.Load() + 1) // increment the timeout counter
-	go Fault(e, e.DBHeight, e.Minute, e.FaultId.Load(), &e.FaultId, m.SigType, e.RoundTimeout)
+	startFault(e, m.SigType, e.CurrentRoundTimeout())
 
 	auditIdx := 0
 	if len(e.Audit) > 0 {
@@ -314,3 +316,12 @@ func (m *TimeoutInternal) String() string {
 func (a *TimeoutInternal) IsSameAs(b *TimeoutInternal) bool {
 	return true
 }
+
+// traceRound adds the start of an election round to the consensus trace
+func traceRound(s *state.State, e *elections.Elections) {
+	if !s.Tracing() {
+		return
+	}
+	s.Trace(state.TraceEvent{Event: state.TraceRound, DBHeight: uint32(e.DBHeight), Minute: e.Minute, VM: e.VMIndex,
+		Info: fmt.Sprintf("round %d replacing fed %d[%x]", e.Round[e.Electing], e.Electing, e.FedID.Bytes()[3:6])})
+}
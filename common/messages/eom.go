@@ -134,6 +134,11 @@ func (m *EOM) Validate(state interfaces.IState) int {
 		return -1
 	}
 
+	// The block may have fewer minutes than the ten an EOM can carry
+	if int(m.Minute) >= state.GetMinutesPerBlock(m.DBHeight) {
+		return -1
+	}
+
 	found, _ := state.GetVirtualServers(m.DBHeight, int(m.Minute), m.ChainID)
 	if !found { // Only EOM from federated servers are valid.
 		return -1
@@ -309,6 +309,7 @@ func (m *Heartbeat) FollowerExecute(is interfaces.IState) {
 				}
 			}
 			auditServer.SetOnline(true)
+			is.NoteHeartbeat(m.IdentityChainID)
 		}
 	}
 }
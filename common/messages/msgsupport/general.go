@@ -55,6 +55,10 @@ func CreateMsg(messageType byte) interfaces.IMsg {
 		return new(messages.DBStateMissing)
 	case constants.DBSTATE_MSG:
 		return new(messages.DBStateMsg)
+	case constants.DBSTATE_COMPACT_MSG:
+		return new(messages.DBStateCompactMsg)
+	case constants.DBSTATE_COMPACT_MISSING_MSG:
+		return new(messages.DBStateCompactMissing)
 	case constants.ADDSERVER_MSG:
 		return new(messages.AddServerMsg)
 	case constants.CHANGESERVER_KEY_MSG:
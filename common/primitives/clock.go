@@ -0,0 +1,104 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package primitives
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Clock is the source of time for the node. Everything in the consensus path should read the time,
+// sleep and start goroutines through the package functions below, so the deterministic simulator
+// can replace the wall clock with a VirtualClock.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep pauses the calling goroutine for the duration
+	Sleep(d time.Duration)
+	// Go starts f in a new goroutine that is allowed to Sleep on this clock
+	Go(f func())
+	// IsVirtual returns true if time only moves when the clock's tasks sleep
+	IsVirtual() bool
+	// Rand returns the random number generator for simulated randomness (message delays, drops, ...).
+	// Only to be used by goroutines started with Go on a virtual clock.
+	Rand() *rand.Rand
+}
+
+// The wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+func (realClock) Go(f func())           { go f() }
+func (realClock) IsVirtual() bool       { return false }
+func (realClock) Rand() *rand.Rand      { return nil }
+
+var clock Clock = realClock{}
+
+// SetClock replaces the clock. Must be called before the nodes are started.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
+
+// GetClock returns the clock in use
+func GetClock() Clock {
+	return clock
+}
+
+// Now returns the current time of the clock
+func Now() time.Time {
+	return clock.Now()
+}
+
+// Sleep pauses the calling goroutine on the clock
+func Sleep(d time.Duration) {
+	clock.Sleep(d)
+}
+
+// Go starts f in a goroutine on the clock
+func Go(f func()) {
+	clock.Go(f)
+}
+
+// IsClockVirtual returns true when running in the deterministic simulator
+func IsClockVirtual() bool {
+	return clock.IsVirtual()
+}
+
+// RandIntn returns a random number in [0,n). Seeded by the simulator when the clock is virtual,
+// so runs can be replayed.
+func RandIntn(n int) int {
+	if r := clock.Rand(); r != nil {
+		return r.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// RandInt63n returns a random number in [0,n), see RandIntn()
+func RandInt63n(n int64) int64 {
+	if r := clock.Rand(); r != nil {
+		return r.Int63n(n)
+	}
+	return rand.Int63n(n)
+}
+
+// RandNormFloat64 returns a normally distributed number with mean 0 and standard deviation 1, see RandIntn()
+func RandNormFloat64() float64 {
+	if r := clock.Rand(); r != nil {
+		return r.NormFloat64()
+	}
+	return rand.NormFloat64()
+}
+
+// RandExpFloat64 returns an exponentially distributed number with mean 1, see RandIntn()
+func RandExpFloat64() float64 {
+	if r := clock.Rand(); r != nil {
+		return r.ExpFloat64()
+	}
+	return rand.ExpFloat64()
+}
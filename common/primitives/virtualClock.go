@@ -0,0 +1,215 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package primitives
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VirtualClockResolution is the smallest step of virtual time. A task sleeping for less (or zero)
+// still lets virtual time move forward, so polling loops can't stall the simulation.
+const VirtualClockResolution = time.Microsecond
+
+// VirtualClock runs a simulation in virtual time. Goroutines started with Go are tasks, and only one
+// task runs at a time: it runs until it calls Sleep (or returns), then the scheduler moves the clock to
+// the earliest wakeup and resumes that task. Tasks waking at the same time are picked with a random
+// generator seeded with the simulation seed, so the interleaving of the tasks, and with it the
+// delivery of messages between simulated nodes, is the same for every run with that seed.
+//
+// Time only moves while the simulation is run with RunFor or RunUntil, as fast as the tasks can
+// execute. Between runs every task is parked, so the caller can inspect the nodes safely.
+//
+// Tasks must not block outside of Sleep (on a channel or a mutex held by another task), or the
+// scheduler stalls; it panics after StallTimeout of real time. Goroutines that aren't tasks must not
+// Sleep on the clock while the simulation is running.
+type VirtualClock struct {
+	// StallTimeout is the real time a task may run without yielding before the scheduler gives up
+	StallTimeout time.Duration
+
+	mutex   sync.Mutex
+	seed    int64
+	now     time.Time
+	rand    *rand.Rand
+	tasks   []*virtualTask // parked tasks
+	nextID  uint64
+	running *virtualTask
+	yield   chan struct{} // the running task hands control back to the scheduler
+	steps   uint64
+	trace   uint64 // fingerprint of the schedule so far
+}
+
+type virtualTask struct {
+	id     uint64
+	wake   time.Time
+	resume chan struct{}
+}
+
+var _ Clock = (*VirtualClock)(nil)
+
+// NewVirtualClock returns a clock starting at the given time, scheduling tasks with the seed
+func NewVirtualClock(seed int64, start time.Time) *VirtualClock {
+	c := new(VirtualClock)
+	c.StallTimeout = time.Minute
+	c.seed = seed
+	c.now = start
+	c.rand = rand.New(rand.NewSource(seed))
+	c.yield = make(chan struct{})
+	c.trace = fnv.New64a().Sum64()
+	return c
+}
+
+// Seed returns the seed of the simulation
+func (c *VirtualClock) Seed() int64 {
+	return c.seed
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) IsVirtual() bool {
+	return true
+}
+
+func (c *VirtualClock) Rand() *rand.Rand {
+	return c.rand
+}
+
+// Go adds a task, it first runs at the current virtual time
+func (c *VirtualClock) Go(f func()) {
+	c.mutex.Lock()
+	t := &virtualTask{id: c.nextID, wake: c.now, resume: make(chan struct{})}
+	c.nextID++
+	c.tasks = append(c.tasks, t)
+	c.mutex.Unlock()
+
+	go func() {
+		<-t.resume
+		defer c.exit()
+		f()
+	}()
+}
+
+// Sleep parks the running task until the virtual clock reaches now + d
+func (c *VirtualClock) Sleep(d time.Duration) {
+	if d < VirtualClockResolution {
+		d = VirtualClockResolution
+	}
+	c.mutex.Lock()
+	t := c.running
+	if t == nil {
+		c.mutex.Unlock()
+		panic("VirtualClock.Sleep called outside of a task, start the goroutine with Go")
+	}
+	t.wake = c.now.Add(d)
+	c.tasks = append(c.tasks, t)
+	c.running = nil
+	c.mutex.Unlock()
+
+	c.yield <- struct{}{}
+	<-t.resume
+}
+
+// exit removes the running task once it returns
+func (c *VirtualClock) exit() {
+	c.mutex.Lock()
+	c.running = nil
+	c.mutex.Unlock()
+	c.yield <- struct{}{}
+}
+
+// Steps returns the number of times a task was resumed
+func (c *VirtualClock) Steps() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.steps
+}
+
+// Trace returns a fingerprint of the schedule so far: which task ran at which virtual time, in order.
+// Two runs of the same simulation with the same seed have the same trace.
+func (c *VirtualClock) Trace() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.trace
+}
+
+// Tasks returns the number of live tasks
+func (c *VirtualClock) Tasks() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.tasks)
+}
+
+// RunFor runs the simulation for d of virtual time
+func (c *VirtualClock) RunFor(d time.Duration) {
+	c.RunUntil(func() bool { return false }, d)
+}
+
+// RunUntil runs the simulation until done returns true, or d of virtual time has passed. done is
+// called between tasks, while all tasks are parked. Returns true if done returned true.
+func (c *VirtualClock) RunUntil(done func() bool, d time.Duration) bool {
+	deadline := c.Now().Add(d)
+	for !done() {
+		t := c.next(deadline)
+		if t == nil {
+			return false
+		}
+		t.resume <- struct{}{}
+		select {
+		case <-c.yield:
+		case <-time.After(c.StallTimeout):
+			panic(fmt.Sprintf("VirtualClock: task %d did not yield for %s at %s, is it blocked outside of Sleep?",
+				t.id, c.StallTimeout, c.Now()))
+		}
+	}
+	return true
+}
+
+// next picks the task to run, and moves the clock to its wakeup. Returns nil, with the clock moved to
+// the deadline, if no task wakes up before the deadline.
+func (c *VirtualClock) next(deadline time.Time) *virtualTask {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var ready []int // indexes of the tasks with the earliest wakeup
+	for i, t := range c.tasks {
+		switch {
+		case len(ready) == 0 || t.wake.Before(c.tasks[ready[0]].wake):
+			ready = append(ready[:0], i)
+		case t.wake.Equal(c.tasks[ready[0]].wake):
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) == 0 || deadline.Before(c.tasks[ready[0]].wake) {
+		if c.now.Before(deadline) {
+			c.now = deadline
+		}
+		return nil
+	}
+
+	// the order of the parked tasks depends on the order they went to sleep, sort by id so the
+	// seeded pick does not depend on anything but the schedule itself
+	sort.Slice(ready, func(i, j int) bool { return c.tasks[ready[i]].id < c.tasks[ready[j]].id })
+	index := ready[c.rand.Intn(len(ready))]
+	t := c.tasks[index]
+	c.tasks = append(c.tasks[:index], c.tasks[index+1:]...)
+	if c.now.Before(t.wake) {
+		c.now = t.wake
+	}
+	c.running = t
+	c.steps++
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d %d %d", c.trace, t.id, c.now.UnixNano())
+	c.trace = h.Sum64()
+	return t
+}
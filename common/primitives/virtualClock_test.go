@@ -0,0 +1,159 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package primitives_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/FactomProject/factomd/common/primitives"
+)
+
+var virtualStart = time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// runWorkers runs tasks that all wake up at the same times, and returns the order they ran in
+func runWorkers(seed int64) ([]string, uint64) {
+	c := NewVirtualClock(seed, virtualStart)
+	var order []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("w%d", i)
+		c.Go(func() {
+			for j := 0; j < 10; j++ {
+				order = append(order, name)
+				c.Sleep(time.Second)
+			}
+		})
+	}
+	c.RunFor(time.Minute)
+	return order, c.Trace()
+}
+
+func TestVirtualClockReplay(t *testing.T) {
+	order1, trace1 := runWorkers(42)
+	order2, trace2 := runWorkers(42)
+	if trace1 != trace2 {
+		t.Errorf("Same seed gave different traces %x %x", trace1, trace2)
+	}
+	if fmt.Sprint(order1) != fmt.Sprint(order2) {
+		t.Errorf("Same seed gave different orders\n%v\n%v", order1, order2)
+	}
+	if len(order1) != 50 {
+		t.Errorf("Expected 50 steps, got %d", len(order1))
+	}
+
+	// another seed interleaves the tasks that wake at the same time differently
+	order3, trace3 := runWorkers(7)
+	if trace1 == trace3 || fmt.Sprint(order1) == fmt.Sprint(order3) {
+		t.Errorf("Different seeds gave the same schedule")
+	}
+}
+
+func TestVirtualClockTime(t *testing.T) {
+	c := NewVirtualClock(1, virtualStart)
+	var woke []time.Time
+	c.Go(func() {
+		for {
+			c.Sleep(time.Hour)
+			woke = append(woke, c.Now())
+		}
+	})
+
+	start := time.Now()
+	c.RunFor(24*time.Hour + time.Minute)
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("A virtual day took %s", time.Since(start))
+	}
+	if len(woke) != 24 {
+		t.Fatalf("Expected 24 wakeups, got %d", len(woke))
+	}
+	for i, w := range woke {
+		if !w.Equal(virtualStart.Add(time.Duration(i+1) * time.Hour)) {
+			t.Errorf("Wakeup %d at %s", i, w)
+		}
+	}
+	if !c.Now().Equal(virtualStart.Add(24*time.Hour + time.Minute)) {
+		t.Errorf("Clock at %s after the run", c.Now())
+	}
+	if c.Tasks() != 1 {
+		t.Errorf("Expected the task to be parked, got %d tasks", c.Tasks())
+	}
+}
+
+func TestVirtualClockRunUntil(t *testing.T) {
+	c := NewVirtualClock(1, virtualStart)
+	count := 0
+	c.Go(func() {
+		for i := 0; i < 100; i++ {
+			count++
+			c.Sleep(time.Second)
+		}
+	})
+
+	if !c.RunUntil(func() bool { return count == 10 }, time.Hour) {
+		t.Errorf("RunUntil did not reach the condition")
+	}
+	if count != 10 {
+		t.Errorf("Expected the run to stop at 10, got %d", count)
+	}
+	if c.RunUntil(func() bool { return count == 1000 }, time.Hour) {
+		t.Errorf("RunUntil reached an impossible condition")
+	}
+	if count != 100 || c.Tasks() != 0 {
+		t.Errorf("Expected the task to finish, got %d counts and %d tasks", count, c.Tasks())
+	}
+}
+
+func TestVirtualClockSleepOutsideTask(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Sleep outside of a task did not panic")
+		}
+	}()
+	NewVirtualClock(1, virtualStart).Sleep(time.Second)
+}
+
+func TestVirtualClockPackageFunctions(t *testing.T) {
+	c := NewVirtualClock(3, virtualStart)
+	SetClock(c)
+	defer SetClock(nil)
+
+	if !IsClockVirtual() || GetClock() != c {
+		t.Fatalf("Clock not set")
+	}
+	var draws []int
+	Go(func() {
+		for i := 0; i < 3; i++ {
+			draws = append(draws, RandIntn(1000))
+			Sleep(time.Millisecond)
+		}
+	})
+	c.RunFor(time.Second)
+	if !Now().Equal(virtualStart.Add(time.Second)) {
+		t.Errorf("Now() is %s", Now())
+	}
+	if GetTimeMilli() != uint64(virtualStart.Add(time.Second).UnixNano()/1e6) {
+		t.Errorf("Timestamps don't use the clock")
+	}
+
+	c2 := NewVirtualClock(3, virtualStart)
+	SetClock(c2)
+	var draws2 []int
+	Go(func() {
+		for i := 0; i < 3; i++ {
+			draws2 = append(draws2, RandIntn(1000))
+			Sleep(time.Millisecond)
+		}
+	})
+	c2.RunFor(time.Second)
+	if fmt.Sprint(draws) != fmt.Sprint(draws2) {
+		t.Errorf("Same seed drew %v and %v", draws, draws2)
+	}
+
+	SetClock(nil)
+	if IsClockVirtual() {
+		t.Errorf("Expected the wall clock")
+	}
+}
@@ -337,7 +337,8 @@ func (e *Elections) LogPrintf(logName string, format string, more ...interface{}
 	}
 }
 
-// Check that the process list and Election Authority Sets match
+// Check that the process list and Election Authority Sets match, a mismatch violates the "authority set"
+// invariant
 func CheckAuthSetsMatch(caller string, e *Elections, s *state.State) {
 
 	pl := s.ProcessLists.Get(uint32(e.DBHeight))
@@ -380,7 +381,7 @@ func CheckAuthSetsMatch(caller string, e *Elections, s *state.State) {
 
 	var mismatch1 bool
 	for i, f := range s_fservers {
-		if e_fservers[i].GetChainID() != f.GetChainID() {
+		if !e_fservers[i].GetChainID().IsSameAs(f.GetChainID()) {
 			printAll("Process List FedSet is not the same as Election FedSet at %d", i)
 			mismatch1 = true
 		}
@@ -396,7 +397,7 @@ func CheckAuthSetsMatch(caller string, e *Elections, s *state.State) {
 
 	var mismatch2 bool
 	for i, f := range s_aservers {
-		if e_aservers[i].GetChainID() != f.GetChainID() {
+		if !e_aservers[i].GetChainID().IsSameAs(f.GetChainID()) {
 			printAll("Process List AudSet is not the same as Election AudSet at %d", i)
 			mismatch2 = true
 		}
@@ -410,6 +411,12 @@ func CheckAuthSetsMatch(caller string, e *Elections, s *state.State) {
 		printAll("")
 	}
 
+	if mismatch1 || mismatch2 {
+		s.CheckInvariant("authority set", func() error {
+			return fmt.Errorf("%s: the authority sets of the process list and the elections differ at %d", caller, e.DBHeight)
+		})
+	}
+
 	//if !mismatch1 && !mismatch2 {
 	//	printAll("AuthSet Matched!")
 	//}
@@ -0,0 +1,59 @@
+package elections
+
+import (
+	"time"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/state"
+)
+
+// Without the fast failover an election starts FaultTimeout after the first EOM or DBSig of a sync
+// point, and a round lasts RoundTimeout, so a stalled VM costs minutes. Once FastFailover is active:
+//   - the election starts FastFaultTimeout after the sync point
+//   - a round lasts FastRoundTimeout
+//   - the backup audit servers of a federated server are ranked from the previous block, the same for
+//     the whole block, so every node knows who volunteers in which round before anything goes wrong
+
+var FastFaultTimeout int = 10 // Seconds, set from the command line
+var FastRoundTimeout int = 5  // Seconds, set from the command line
+
+// FastFailover returns true if the fast failover is active at the height of the election
+func (e *Elections) FastFailover() bool {
+	return activations.IsActive(activations.FAST_FAILOVER, e.DBHeight)
+}
+
+// CurrentFaultTimeout returns how long to wait for the EOMs or DBSigs of a sync point before electing
+func (e *Elections) CurrentFaultTimeout() time.Duration {
+	if e.FastFailover() {
+		return time.Duration(FastFaultTimeout) * time.Second
+	}
+	return e.Timeout
+}
+
+// CurrentRoundTimeout returns how long a round of an election lasts
+func (e *Elections) CurrentRoundTimeout() time.Duration {
+	if e.FastFailover() {
+		return time.Duration(FastRoundTimeout) * time.Second
+	}
+	return e.RoundTimeout
+}
+
+// PublishFailoverStatus publishes the timeouts of the block, and the ranked backups of its federated
+// servers, for the debug API
+func (e *Elections) PublishFailoverStatus(backups [][]interfaces.IHash) {
+	status := state.FailoverStatus{
+		Active:       e.FastFailover(),
+		DBHeight:     e.DBHeight,
+		FaultTimeout: int(e.CurrentFaultTimeout() / time.Second),
+		RoundTimeout: int(e.CurrentRoundTimeout() / time.Second),
+	}
+	for _, ranked := range backups {
+		var ids []string
+		for _, id := range ranked {
+			ids = append(ids, id.String())
+		}
+		status.Backups = append(status.Backups, ids)
+	}
+	e.State.(*state.State).SetFailoverStatus(status)
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/FactomProject/factomd/activations"
 	"github.com/FactomProject/factomd/common/constants"
 	. "github.com/FactomProject/factomd/common/globals"
 	"github.com/FactomProject/factomd/common/interfaces"
@@ -134,6 +135,13 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 		p.BlkTime = s.DirectoryBlockInSeconds
 	}
 
+	if p.BlockSchedule != "" {
+		if err := s.SetBlockSchedule(p.BlockSchedule); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("Invalid blockschedule: %v\n", err))
+			os.Exit(1)
+		}
+	}
+
 	s.FaultTimeout = 9999999 //todo: Old Fault Mechanism -- remove
 
 	if p.Follower {
@@ -215,6 +223,13 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 	}
 
 	s.KeepMismatch = p.KeepMismatch
+	s.InvariantMode = p.Invariants
+	if p.ConsensusTrace != "" {
+		if err := os.MkdirAll(p.ConsensusTrace, 0755); err != nil {
+			panic(fmt.Sprintf("Can't create the consensus trace directory: %v", err))
+		}
+		s.ConsensusTraceDir = p.ConsensusTrace
+	}
 
 	if len(p.Db) > 0 {
 		s.DBType = p.Db
@@ -241,6 +256,10 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 
 	s.AddPrefix(p.Prefix)
 	s.SetOut(false)
+	if p.SimSeed != 0 {
+		setupDeterministicSim(p.SimSeed)
+		s.Salt = simSalt()
+	}
 	s.Init()
 	s.SetDropRate(p.DropRate)
 
@@ -275,12 +294,17 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 	os.Stderr.WriteString(fmt.Sprintf("%20s \"%s\"\n", "peers", p.Peers))
 	os.Stderr.WriteString(fmt.Sprintf("%20s \"%t\"\n", "exclusive", p.Exclusive))
 	os.Stderr.WriteString(fmt.Sprintf("%20s \"%t\"\n", "exclusive_in", p.ExclusiveIn))
+	os.Stderr.WriteString(fmt.Sprintf("%20s \"%s\"\n", "nat", p.NAT))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "simseed", p.SimSeed))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "block time", p.BlkTime))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "block schedule", s.BlockSchedule))
 	//os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "faultTimeout", p.FaultTimeout)) // TODO old fault timeout mechanism to be removed
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "runtimeLog", p.RuntimeLog))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "rotate", p.Rotate))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "timeOffset", p.TimeOffset))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "keepMismatch", p.KeepMismatch))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %s\n", "invariants", p.Invariants))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %s\n", "consensusTrace", p.ConsensusTrace))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "startDelay", p.StartDelay))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %v\n", "Network", s.Network))
 	os.Stderr.WriteString(fmt.Sprintf("%20s %x (%s)\n", "customnet", p.CustomNet, p.CustomNetName))
@@ -291,6 +315,8 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "Start 2nd Sync at ht", s.EntryDBHeightComplete))
 
 	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "faultTimeout", elections.FaultTimeout))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "fastFaultTimeout", elections.FastFaultTimeout))
+	os.Stderr.WriteString(fmt.Sprintf("%20s %d\n", "fastRoundTimeout", elections.FastRoundTimeout))
 
 	if "" == s.RpcPass {
 		os.Stderr.WriteString(fmt.Sprintf("%20s %s\n", "rpcpass", "is blank"))
@@ -330,6 +356,7 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 		networkPort = s.MainNetworkPort
 		configPeers = s.MainSpecialPeers
 		s.DirectoryBlockInSeconds = 600
+		s.BlockSchedule = nil
 	case "TEST", "test":
 		networkID = p2p.TestNet
 		seedURL = s.TestSeedURL
@@ -360,6 +387,11 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 		// Also update the coinbase constants for custom networks
 		fmt.Println("Running on the custom network, use custom coinbase constants")
 		constants.SetCustomCoinBaseConstants()
+
+		if err := setCustomActivations(s, p); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("Invalid activations: %v\n", err))
+			os.Exit(1)
+		}
 	default:
 		panic("Invalid Network choice in Config File or command line. Choose MAIN, TEST, LOCAL, or CUSTOM")
 	}
@@ -384,6 +416,8 @@ func NetStart(s *state.State, p *FactomParams, listenToStdin bool) {
 			ConfigPeers:              configPeers,
 			CmdLinePeers:             p.Peers,
 			ConnectionMetricsChannel: connectionMetricsChannel,
+			NAT:                      p.NAT,
+			Activations:              activations.ScheduleHash(activations.NetworkName()),
 		}
 		p2pNetwork = new(p2p.Controller).Init(ci)
 		fnodes[0].State.NetworkController = p2pNetwork
@@ -566,12 +600,16 @@ func makeServer(s *state.State) *FactomNode {
 		newState = s.Clone(len(fnodes)).(*state.State)
 		newState.EFactory = new(electionMsgs.ElectionsFactory) // not an elegant place but before we let the messages hit the state
 		time.Sleep(10 * time.Millisecond)
+		if simulation != nil {
+			newState.Salt = simSalt()
+		}
 		newState.Init()
 		newState.EFactory = new(electionMsgs.ElectionsFactory)
 	}
 
 	fnode := new(FactomNode)
 	fnode.State = newState
+	fnode.State.SimNetwork = simNetwork{}
 	fnodes = append(fnodes, fnode)
 	fnode.MLog = mLog
 
@@ -583,14 +621,15 @@ func startServers(load bool) {
 		if i > 0 {
 			fnode.State.Init()
 		}
-		go NetworkProcessorNet(fnode)
+		fnode := fnode
+		primitives.Go(func() { NetworkProcessorNet(fnode) })
 		if load {
-			go state.LoadDatabase(fnode.State)
+			primitives.Go(func() { state.LoadDatabase(fnode.State) })
 		}
-		go fnode.State.GoSyncEntries()
-		go Timer(fnode.State)
-		go fnode.State.ValidatorLoop()
-		go elections.Run(fnode.State)
+		primitives.Go(fnode.State.GoSyncEntries)
+		primitives.Go(func() { Timer(fnode.State) })
+		primitives.Go(fnode.State.ValidatorLoop)
+		primitives.Go(func() { elections.Run(fnode.State) })
 	}
 }
 
@@ -604,6 +643,39 @@ func setupFirstAuthority(s *state.State) {
 	s.IdentityControl.SetBootstrapIdentity(s.GetNetworkBootStrapIdentity(), s.GetNetworkBootStrapKey())
 }
 
+// setCustomActivations sets the activation heights of a CUSTOM network from the signed file of the config,
+// then from CustomActivations, then from the command line
+func setCustomActivations(s *state.State, p *FactomParams) error {
+	network := fmt.Sprintf("CUSTOM:%s", p.CustomNetName)
+	if s.CustomActivationsFile != "" {
+		key, err := primitives.HexToHash(s.CustomBootstrapKey)
+		if err != nil {
+			return fmt.Errorf("CustomBootstrapKey: %v", err)
+		}
+		pub := key.Fixed()
+		heights, err := activations.LoadScheduleFile(s.CustomActivationsFile, network, &pub)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s.CustomActivationsFile, err)
+		}
+		if err := activations.SetActivationHeights(network, heights); err != nil {
+			return fmt.Errorf("%s: %v", s.CustomActivationsFile, err)
+		}
+	}
+	for _, schedule := range []string{s.CustomActivations, p.CustomActivations} {
+		heights, err := activations.ParseSchedule(schedule)
+		if err != nil {
+			return err
+		}
+		if err := activations.SetActivationHeights(network, heights); err != nil {
+			return err
+		}
+	}
+	for _, a := range activations.Activations() {
+		os.Stderr.WriteString(fmt.Sprintf("%20s %s at %d\n", "activation", a.Name, activations.GetActivationHeight(a.Id, network)))
+	}
+	return nil
+}
+
 func networkHousekeeping() {
 	for {
 		time.Sleep(1 * time.Second)
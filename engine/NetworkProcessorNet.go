@@ -6,7 +6,6 @@ package engine
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/FactomProject/factomd/common/constants"
@@ -14,15 +13,16 @@ import (
 	"github.com/FactomProject/factomd/common/messages"
 	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/log"
+	"github.com/FactomProject/factomd/state"
 )
 
 var _ = log.Printf
 var _ = fmt.Print
 
 func NetworkProcessorNet(fnode *FactomNode) {
-	go Peers(fnode)
-	go NetworkOutputs(fnode)
-	go InvalidOutputs(fnode)
+	primitives.Go(func() { Peers(fnode) })
+	primitives.Go(func() { NetworkOutputs(fnode) })
+	primitives.Go(func() { InvalidOutputs(fnode) })
 }
 
 func Peers(fnode *FactomNode) {
@@ -289,11 +289,13 @@ func Peers(fnode *FactomNode) {
 
 				// don't resend peer to peer messages or responses
 				switch msg.Type() {
-				case constants.MISSING_DATA, constants.MISSING_MSG, constants.MISSING_MSG_RESPONSE, constants.DBSTATE_MISSING_MSG, constants.DATA_RESPONSE:
+				case constants.MISSING_DATA, constants.MISSING_MSG, constants.MISSING_MSG_RESPONSE, constants.DBSTATE_MISSING_MSG,
+					constants.DBSTATE_COMPACT_MISSING_MSG, constants.DATA_RESPONSE:
 					msg.SetNoResend(true)
 				}
 				if !crossBootIgnore(msg) {
 					fnode.State.LogMessage("NetworkInputs", fromPeer+", enqueue", msg)
+					fnode.State.TraceMsg(state.TraceReceived, msg, peer.GetNameTo(), "")
 					if t := msg.Type(); t == constants.REVEAL_ENTRY_MSG || t == constants.COMMIT_CHAIN_MSG || t == constants.COMMIT_ENTRY_MSG {
 						fnode.State.LogMessage("NetworkInputs", fromPeer+", enqueue2", msg)
 						fnode.State.LogMessage("InMsgQueue2", fromPeer+", enqueue2", msg)
@@ -307,7 +309,7 @@ func Peers(fnode *FactomNode) {
 			} // For a peer read up to 100 messages {...}
 		} // for each peer {...}
 		if cnt == 0 {
-			time.Sleep(50 * time.Millisecond) // handled no message, sleep a bit
+			primitives.Sleep(50 * time.Millisecond) // handled no message, sleep a bit
 		}
 	} // forever {...}
 }
@@ -331,7 +333,7 @@ func NetworkOutputs(fnode *FactomNode) {
 			continue
 		}
 		// Don't do a rand int if drop rate is 0
-		if fnode.State.GetDropRate() > 0 && rand.Int()%1000 < fnode.State.GetDropRate() {
+		if fnode.State.GetDropRate() > 0 && primitives.RandIntn(1000) < fnode.State.GetDropRate() {
 			//drop the message, rather than processing it normally
 			fnode.State.LogMessage("NetworkOutputs", "Drop, simCtrl", msg)
 			continue
@@ -361,7 +363,7 @@ func NetworkOutputs(fnode *FactomNode) {
 			if len(fnode.Peers) > 0 {
 				if p < 0 {
 					fnode.P2PIndex = (fnode.P2PIndex + 1) % len(fnode.Peers)
-					p = rand.Int() % len(fnode.Peers)
+					p = primitives.RandIntn(len(fnode.Peers))
 				}
 				peer := fnode.Peers[p]
 				fnode.MLog.Add2(fnode, true, peer.GetNameTo(), "P2P out", true, msg)
@@ -409,7 +411,14 @@ func NetworkOutputs(fnode *FactomNode) {
 // Just throw away the trash
 func InvalidOutputs(fnode *FactomNode) {
 	for {
-		time.Sleep(1 * time.Millisecond)
+		primitives.Sleep(1 * time.Millisecond)
+		if primitives.IsClockVirtual() { // can't block on the channel under a virtual clock
+			select {
+			case <-fnode.State.NetworkInvalidMsgQueue():
+			default:
+			}
+			continue
+		}
 		_ = <-fnode.State.NetworkInvalidMsgQueue()
 		//fmt.Println(invalidMsg)
 
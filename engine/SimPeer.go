@@ -6,21 +6,25 @@ package engine
 
 import (
 	"bytes"
+	"container/heap"
 	"fmt"
-	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages/msgsupport"
+	"github.com/FactomProject/factomd/common/primitives"
 )
 
 var _ = fmt.Print
 var _ = bytes.Compare
 
 type SimPacket struct {
-	data []byte
-	sent int64 // Time in milliseconds
+	data    []byte
+	sent    int64 // Time in milliseconds
+	deliver int64 // Earliest delivery, in nanoseconds
+	seq     uint64
 }
 
 type SimPeer struct {
@@ -34,8 +38,8 @@ type SimPeer struct {
 	// Delay in Milliseconds
 	Delay    int64 // The maximum delay
 	DelayUse int64 // We actually select a random delay for each data element.
-	// Were we hold delayed packets
-	Delayed *SimPacket
+	// Were we hold packets until they are delivered
+	Pending simPacketQueue
 
 	bytesOut int // Bytes sent out
 	bytesIn  int // Bytes received
@@ -44,6 +48,11 @@ type SimPeer struct {
 
 	RateOut int // Rate of Bytes output per ms
 	RateIn  int // Rate of Bytes input per ms
+
+	faultMutex sync.Mutex
+	fault      interfaces.LinkFault // Fault model of the link to ToName, applied when sending
+	busyUntil  int64                // When everything sent so far has gone through the bandwidth cap (nano seconds)
+	sendSeq    uint64
 }
 
 var _ interfaces.IPeer = (*SimPeer)(nil)
@@ -82,14 +91,14 @@ func (f *SimPeer) Equals(ff interfaces.IPeer) bool {
 }
 
 func (f *SimPeer) Len() int {
-	return len(f.BroadcastIn)
+	return len(f.BroadcastIn) + len(f.Pending)
 }
 
 func (f *SimPeer) Init(fromName, toName string) interfaces.IPeer {
 	f.ToName = toName
 	f.FromName = fromName
 	f.BroadcastOut = make(chan *SimPacket, 10000)
-	f.Last = time.Now().UnixNano()
+	f.Last = primitives.Now().UnixNano()
 	return f
 }
 
@@ -101,7 +110,7 @@ func (f *SimPeer) GetNameTo() string {
 }
 
 func (f *SimPeer) computeBandwidth() {
-	now := time.Now().UnixNano()
+	now := primitives.Now().UnixNano()
 	delta := (now - f.Last) / 1000000000 // Make delta seconds
 	if delta < 5 {
 		// Wait atleast 5 seconds.
@@ -114,6 +123,31 @@ func (f *SimPeer) computeBandwidth() {
 	f.Last = now
 }
 
+// GetFault returns the fault model of the link from this node to the peer
+func (f *SimPeer) GetFault() interfaces.LinkFault {
+	f.faultMutex.Lock()
+	defer f.faultMutex.Unlock()
+	return f.fault
+}
+
+// SetFault changes the fault model of the link from this node to the peer
+func (f *SimPeer) SetFault(fault interfaces.LinkFault) {
+	f.faultMutex.Lock()
+	defer f.faultMutex.Unlock()
+	f.fault = fault
+}
+
+// IsCut returns true if nothing gets through from this node to the peer
+func (f *SimPeer) IsCut() bool {
+	return f.GetFault().Cut
+}
+
+func (f *SimPeer) setCut(cut bool) {
+	f.faultMutex.Lock()
+	defer f.faultMutex.Unlock()
+	f.fault.Cut = cut
+}
+
 func (f *SimPeer) Send(msg interfaces.IMsg) error {
 	data, err := msg.MarshalBinary()
 	f.bytesOut += len(data)
@@ -122,49 +156,73 @@ func (f *SimPeer) Send(msg interfaces.IMsg) error {
 		fmt.Println("ERROR on Send: ", err)
 		return err
 	}
-	if len(f.BroadcastOut) < 9000 {
-		packet := SimPacket{data: data, sent: time.Now().UnixNano() / 1000000}
-		f.BroadcastOut <- &packet
+	fault := f.GetFault()
+	if fault.Cut || (fault.DropRate > 0 && primitives.RandIntn(1000) < fault.DropRate) {
+		return nil
+	}
+
+	now := primitives.Now().UnixNano()
+	start := now
+	if fault.Bandwidth > 0 {
+		if f.busyUntil > start {
+			start = f.busyUntil
+		}
+		f.busyUntil = start + int64(len(data))*int64(time.Second)/int64(fault.Bandwidth)
+		start = f.busyUntil
+	}
+	copies := 1
+	if fault.Duplicate > 0 && primitives.RandIntn(1000) < fault.Duplicate {
+		copies = 2
+	}
+	for i := 0; i < copies; i++ {
+		deliver := start + linkDelay(fault)
+		if fault.Reorder > 0 && primitives.RandIntn(1000) < fault.Reorder {
+			deliver += int64(SimReorderHold)
+		}
+		if len(f.BroadcastOut) < 9000 {
+			f.sendSeq++
+			packet := SimPacket{data: data, sent: now / 1000000, deliver: deliver, seq: f.sendSeq}
+			f.BroadcastOut <- &packet
+		}
 	}
 	return nil
 }
 
 // Non-blocking return value from channel.
 func (f *SimPeer) Receive() (interfaces.IMsg, error) {
-	if f.Delayed == nil {
+	// Everything that arrived waits in the pending queue, in the order of delivery
+	for more := true; more; {
 		select {
 		case packet, ok := <-f.BroadcastIn:
-			if ok {
-				f.Delayed = packet
+			if !ok {
+				more = false
+				break
+			}
+			if f.Delay > 0 {
+				f.DelayUse = primitives.RandInt63n(f.Delay)
+			} else {
+				f.DelayUse = 0
 			}
+			packet.deliver += f.DelayUse * 1000000
+			heap.Push(&f.Pending, packet)
 		default:
-			return nil, nil // Nothing to do
+			more = false
 		}
-		if f.Delay > 0 {
-			f.DelayUse = rand.Int63n(f.Delay)
-		} else {
-			f.DelayUse = 0
-		}
-
 	}
 
-	now := time.Now().UnixNano() / 1000000
-
-	if f.Delayed != nil && now-f.Delayed.sent > f.DelayUse {
-		data := f.Delayed.data
-		f.Delayed = nil
-		msg, err := msgsupport.UnmarshalMessage(data)
-		if err != nil {
-			fmt.Printf("SimPeer ERROR: %s %x %s\n", err.Error(), data[:8], constants.MessageName(data[0]))
-		}
+	if len(f.Pending) == 0 || f.Pending[0].deliver > primitives.Now().UnixNano() {
+		return nil, nil // Nothing to do
+	}
 
-		f.bytesIn += len(data)
-		f.computeBandwidth()
-		return msg, err
-	} else {
-		// fmt.Println("dddd Delay: ", now-f.Delayed.sent)
+	data := heap.Pop(&f.Pending).(*SimPacket).data
+	msg, err := msgsupport.UnmarshalMessage(data)
+	if err != nil {
+		fmt.Printf("SimPeer ERROR: %s %x %s\n", err.Error(), data[:8], constants.MessageName(data[0]))
 	}
-	return nil, nil
+
+	f.bytesIn += len(data)
+	f.computeBandwidth()
+	return msg, err
 }
 
 func AddSimPeer(fnodes []*FactomNode, i1 int, i2 int) {
@@ -208,3 +266,23 @@ func AddSimPeer(fnodes []*FactomNode, i1 int, i2 int) {
 	// 	}
 
 }
+
+// PartitionSimPeers splits the simulated network: the nodes of each group only talk to each other, and
+// the nodes in no group form one more group. No groups heals the network.
+func PartitionSimPeers(fnodes []*FactomNode, groups [][]int) {
+	group := make(map[string]int)
+	for g, nodes := range groups {
+		for _, n := range nodes {
+			if n >= 0 && n < len(fnodes) {
+				group[fnodes[n].State.FactomNodeName] = g + 1
+			}
+		}
+	}
+	for _, fn := range fnodes {
+		for _, p := range fn.Peers {
+			if sim, ok := p.(*SimPeer); ok {
+				sim.setCut(group[sim.FromName] != group[sim.ToName])
+			}
+		}
+	}
+}
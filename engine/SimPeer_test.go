@@ -1,10 +1,17 @@
 package engine_test
 
 import (
+	"fmt"
 	"math"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
 	. "github.com/FactomProject/factomd/engine"
+	"github.com/FactomProject/factomd/state"
 )
 
 var fnodes []*FactomNode
@@ -28,3 +35,175 @@ func SimPeerTest(t *testing.T) {
 		t.Errorf("Should have %d nodes", cnt)
 	}
 }
+
+// linkedPeers returns the two ends of a simulated link, from node 0 to node 1
+func linkedPeers() ([]*FactomNode, *SimPeer, *SimPeer) {
+	var nodes []*FactomNode
+	for _, name := range []string{"FNode0", "FNode01"} {
+		s := new(state.State)
+		s.FactomNodeName = name
+		nodes = append(nodes, &FactomNode{State: s})
+	}
+	AddSimPeer(nodes, 0, 1)
+	return nodes, nodes[0].Peers[0].(*SimPeer), nodes[1].Peers[0].(*SimPeer)
+}
+
+func bounce(n int) interfaces.IMsg {
+	msg := new(messages.Bounce)
+	msg.Name = "link"
+	msg.Number = int32(n)
+	msg.Timestamp = primitives.NewTimestampNow()
+	return msg
+}
+
+// receiveAll returns the numbers of the bounces delivered so far
+func receiveAll(t *testing.T, peer *SimPeer) []int {
+	var got []int
+	for {
+		msg, err := peer.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg == nil {
+			return got
+		}
+		got = append(got, int(msg.(*messages.Bounce).Number))
+	}
+}
+
+func withVirtualClock(seed int64) *primitives.VirtualClock {
+	c := primitives.NewVirtualClock(seed, DeterministicSimStart)
+	primitives.SetClock(c)
+	return c
+}
+
+func TestSimPeerLatency(t *testing.T) {
+	c := withVirtualClock(1)
+	defer primitives.SetClock(nil)
+
+	_, from, to := linkedPeers()
+	from.SetFault(interfaces.LinkFault{Latency: 100})
+	for i := 0; i < 5; i++ {
+		from.Send(bounce(i))
+	}
+	c.RunFor(99 * time.Millisecond)
+	if got := receiveAll(t, to); len(got) != 0 {
+		t.Errorf("Delivered %v before the latency", got)
+	}
+	if to.Len() != 5 {
+		t.Errorf("Expected 5 messages waiting, got %d", to.Len())
+	}
+	c.RunFor(time.Millisecond)
+	if got := receiveAll(t, to); fmt.Sprint(got) != "[0 1 2 3 4]" {
+		t.Errorf("Delivered %v", got)
+	}
+}
+
+func TestSimPeerJitter(t *testing.T) {
+	run := func(seed int64, distribution string) []int {
+		c := withVirtualClock(seed)
+		defer primitives.SetClock(nil)
+
+		_, from, to := linkedPeers()
+		from.SetFault(interfaces.LinkFault{Latency: 10, Jitter: 500, Distribution: distribution})
+		for i := 0; i < 50; i++ {
+			from.Send(bounce(i))
+		}
+		c.RunFor(10 * time.Second)
+		return receiveAll(t, to)
+	}
+
+	for _, distribution := range []string{"uniform", "normal", "exponential"} {
+		got := run(3, distribution)
+		if len(got) != 50 {
+			t.Fatalf("%s: expected 50 messages, got %d", distribution, len(got))
+		}
+		if sort.IntsAreSorted(got) {
+			t.Errorf("%s: jitter didn't reorder the messages", distribution)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(run(3, distribution)) {
+			t.Errorf("%s: the same seed delivered in a different order", distribution)
+		}
+	}
+}
+
+func TestSimPeerFaults(t *testing.T) {
+	c := withVirtualClock(5)
+	defer primitives.SetClock(nil)
+
+	for _, test := range []struct {
+		fault    interfaces.LinkFault
+		expected int
+	}{
+		{interfaces.LinkFault{}, 100},
+		{interfaces.LinkFault{Cut: true}, 0},
+		{interfaces.LinkFault{DropRate: 1000}, 0},
+		{interfaces.LinkFault{Duplicate: 1000}, 200},
+	} {
+		_, from, to := linkedPeers()
+		from.SetFault(test.fault)
+		for i := 0; i < 100; i++ {
+			from.Send(bounce(i))
+		}
+		c.RunFor(time.Second)
+		if got := receiveAll(t, to); len(got) != test.expected {
+			t.Errorf("%s: expected %d messages, got %d", LinkFaultString(test.fault), test.expected, len(got))
+		}
+	}
+
+	// Messages held back are overtaken by the ones sent after them
+	_, from, to := linkedPeers()
+	from.SetFault(interfaces.LinkFault{Reorder: 500})
+	for i := 0; i < 100; i++ {
+		from.Send(bounce(i))
+	}
+	c.RunFor(time.Second)
+	if got := receiveAll(t, to); len(got) != 100 || sort.IntsAreSorted(got) {
+		t.Errorf("Expected 100 reordered messages, got %v", got)
+	}
+}
+
+func TestSimPeerBandwidth(t *testing.T) {
+	c := withVirtualClock(1)
+	defer primitives.SetClock(nil)
+
+	_, from, to := linkedPeers()
+	data, _ := bounce(0).MarshalBinary()
+	from.SetFault(interfaces.LinkFault{Bandwidth: len(data) * 10}) // 10 messages a second
+	for i := 0; i < 20; i++ {
+		from.Send(bounce(i))
+	}
+	c.RunFor(time.Second)
+	if got := receiveAll(t, to); len(got) != 10 {
+		t.Errorf("Expected 10 messages through in a second, got %d", len(got))
+	}
+	c.RunFor(time.Second)
+	if got := receiveAll(t, to); len(got) != 10 {
+		t.Errorf("Expected the other 10 messages in the next second, got %d", len(got))
+	}
+}
+
+func TestSetSimLinkFault(t *testing.T) {
+	nodes, from, to := linkedPeers()
+	fault := interfaces.LinkFault{Latency: 50, Jitter: 10, Distribution: "normal"}
+	if err := SetSimLinkFault(nodes, "FNode0", "FNode01", fault); err != nil {
+		t.Fatal(err)
+	}
+	if from.GetFault() != fault || to.GetFault() != (interfaces.LinkFault{}) {
+		t.Errorf("Only the link from FNode0 should have the fault")
+	}
+	if err := SetSimLinkFault(nodes, "", "", interfaces.LinkFault{Cut: true}); err != nil || !from.IsCut() || !to.IsCut() {
+		t.Errorf("Expected all links cut, got %v", err)
+	}
+	for _, bad := range []interfaces.LinkFault{{DropRate: 1001}, {Latency: -1}, {Distribution: "pareto"}} {
+		if SetSimLinkFault(nodes, "", "", bad) == nil {
+			t.Errorf("Accepted %+v", bad)
+		}
+	}
+	if SetSimLinkFault(nodes, "FNode01", "FNode02", fault) == nil {
+		t.Errorf("Set the fault of a link that doesn't exist")
+	}
+	if links := SimLinks(nodes); len(links) != 2 || !links[0].Fault.Cut {
+		t.Errorf("Wrong links %+v", links)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The deterministic simulator (-simseed) runs all the simulated nodes in virtual time on one seeded
+// scheduler. Every node's loops, the SimPeer message delivery and the timers are tasks of the
+// VirtualClock, so a run with the same seed and the same commands replays exactly, and runs as fast as
+// the CPU allows instead of waiting on the wall clock.
+//
+// Not under the scheduler: the web APIs, the control panel and the journal loader. Commands typed into
+// SimControl run between slices of the simulation, but the slice they land in depends on when they are
+// typed, so only runs without interactive commands replay exactly.
+
+// DeterministicSimStart is the virtual time the deterministic simulator starts at
+var DeterministicSimStart = time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SimSlice is the virtual time the simulator runs between the checks
+var SimSlice = 100 * time.Millisecond
+
+type simCheck struct {
+	name  string
+	check func() error
+}
+
+type deterministicSim struct {
+	clock  *primitives.VirtualClock
+	mutex  sync.Mutex // held while a slice runs, so checks and commands see the nodes parked
+	checks []simCheck
+	err    error  // the first failed check, stops the simulation
+	done   uint64 // SimControl commands run so far
+}
+
+var simulation *deterministicSim
+
+// setupDeterministicSim replaces the wall clock with a virtual clock. Must be called before the
+// first state is initialized.
+func setupDeterministicSim(seed int64) {
+	simulation = new(deterministicSim)
+	simulation.clock = primitives.NewVirtualClock(seed, DeterministicSimStart)
+	primitives.SetClock(simulation.clock)
+}
+
+// EndDeterministicSim goes back to the wall clock, the tasks of the simulation stay parked
+func EndDeterministicSim() {
+	simulation = nil
+	primitives.SetClock(nil)
+}
+
+// SimClock returns the virtual clock of the deterministic simulator, nil if running on the wall clock
+func SimClock() *primitives.VirtualClock {
+	if simulation == nil {
+		return nil
+	}
+	return simulation.clock
+}
+
+// AddSimCheck adds a check run between slices of the deterministic simulation. The first check to
+// return an error stops the simulation, and the error is returned by SimError.
+func AddSimCheck(name string, check func() error) {
+	if simulation == nil {
+		return
+	}
+	simulation.mutex.Lock()
+	defer simulation.mutex.Unlock()
+	simulation.checks = append(simulation.checks, simCheck{name, check})
+}
+
+// SimError returns the failure that stopped the deterministic simulation, if any
+func SimError() error {
+	if simulation == nil {
+		return nil
+	}
+	simulation.mutex.Lock()
+	defer simulation.mutex.Unlock()
+	return simulation.err
+}
+
+// RunSimFor runs the simulation for d of virtual time, then returns with the nodes parked.
+// Returns the error of the first failed check.
+func RunSimFor(d time.Duration) error {
+	_, err := runSim(func() bool { return false }, d)
+	return err
+}
+
+// RunSimUntil runs the simulation until done returns true (checked between slices). Returns an error if
+// a check fails, or if done isn't true after limit of virtual time.
+func RunSimUntil(done func() bool, limit time.Duration) error {
+	reached, err := runSim(done, limit)
+	if err == nil && !reached {
+		err = fmt.Errorf("Simulation ran for %s without reaching the condition", limit)
+	}
+	return err
+}
+
+// runSim runs slices until done returns true or limit has passed, and returns if done was reached
+func runSim(done func() bool, limit time.Duration) (bool, error) {
+	if simulation == nil {
+		return false, fmt.Errorf("Not running a deterministic simulation")
+	}
+	deadline := simulation.clock.Now().Add(limit)
+	for !done() {
+		if !simulation.clock.Now().Before(deadline) {
+			return false, nil
+		}
+		if err := simulation.slice(deadline); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// RunSimForever drives the simulation when factomd runs with -simseed, until a check fails
+func RunSimForever() {
+	for {
+		if err := simulation.slice(simulation.clock.Now().Add(SimSlice)); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("Simulation stopped at %s: %v\n", simulation.clock.Now(), err))
+			return
+		}
+	}
+}
+
+// slice runs one SimSlice of the simulation (but not past the deadline), then the checks
+func (d *deterministicSim) slice(deadline time.Time) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+	run := SimSlice
+	if left := deadline.Sub(d.clock.Now()); left < run {
+		run = left
+	}
+	d.clock.RunFor(run)
+	for _, c := range d.checks {
+		if err := c.check(); err != nil {
+			d.err = fmt.Errorf("%s failed at %s (seed %d, step %d): %v", c.name, d.clock.Now(), d.clock.Seed(), d.clock.Steps(), err)
+			return d.err
+		}
+	}
+	return nil
+}
+
+// pauseSim waits for the current slice to finish, and holds the simulation until the returned
+// function is called. SimControl holds the simulation while it runs a command.
+func pauseSim() (resume func()) {
+	if simulation == nil {
+		return func() {}
+	}
+	simulation.mutex.Lock()
+	return func() {
+		simulation.done++
+		simulation.mutex.Unlock()
+	}
+}
+
+// SimCommand sends a command to SimControl. In a deterministic simulation it waits for the command to
+// finish, so the command runs at the same virtual time on every run.
+func SimCommand(cmd string) {
+	if simulation == nil {
+		InputChan <- cmd
+		return
+	}
+	simulation.mutex.Lock()
+	done := simulation.done
+	simulation.mutex.Unlock()
+
+	InputChan <- cmd
+	for {
+		simulation.mutex.Lock()
+		finished := done < simulation.done
+		simulation.mutex.Unlock()
+		if finished {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// simSalt returns a salt for a node drawn from the simulation seed, so the acks are the same every run
+func simSalt() interfaces.IHash {
+	b := make([]byte, 32)
+	simulation.clock.Rand().Read(b)
+	return primitives.Sha(b)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/elections"
 	"github.com/FactomProject/factomd/p2p"
+	"github.com/FactomProject/factomd/state"
 )
 
 func init() {
@@ -29,6 +30,8 @@ func init() {
 	flag.StringVar(&p.DebugLogRegEx, "debuglog", "", "regex to pick which logs to save")
 	flag.IntVar(&p.FaultTimeout, "faulttimeout", 120, "Seconds before considering Federated servers at-fault. Default is 120.")
 	flag.IntVar(&p.RoundTimeout, "roundtimeout", 30, "Seconds before audit servers will increment rounds and volunteer.")
+	flag.IntVar(&p.FastFaultTimeout, "fastfaulttimeout", 10, "Seconds before a stalled Federated server is at-fault, once the fast failover is active.")
+	flag.IntVar(&p.FastRoundTimeout, "fastroundtimeout", 5, "Seconds before audit servers increment rounds, once the fast failover is active.")
 	flag.IntVar(&p2p.NumberPeersToBroadcast, "broadcastnum", 16, "Number of peers to broadcast to in the peer to peer networking")
 	flag.StringVar(&p.ConfigPath, "config", "", "Override the config file location (factomd.conf)")
 	flag.BoolVar(&p.CheckChainHeads, "checkheads", true, "Enables checking chain heads on boot")
@@ -50,13 +53,20 @@ func init() {
 	flag.StringVar(&p.NetworkName, "network", "", "Network to join: MAIN, TEST or LOCAL")
 	flag.StringVar(&p.Peers, "peers", "", "Array of peer addresses. ")
 	flag.IntVar(&p.BlkTime, "blktime", 0, "Seconds per block.  Production is 600.")
+	flag.StringVar(&p.CustomActivations, "activations", "", "Activation heights of a CUSTOM network, <name>:<height>,...  Overrides CustomActivations of the config.")
+	flag.StringVar(&p.BlockSchedule, "blockschedule", "", "Block time and minutes per block by height, <height>:<seconds>:<minutes>,...  Overrides BlockSchedule of the config.")
 	flag.BoolVar(&p.RuntimeLog, "runtimeLog", false, "If true, maintain runtime logs of messages passed.")
 	flag.BoolVar(&p.Exclusive, "exclusive", false, "If true, we only dial out to special/trusted peers.")
 	flag.BoolVar(&p.ExclusiveIn, "exclusive_in", false, "If true, we only dial out to special/trusted peers and no incoming connections are accepted.")
+	flag.StringVar(&p.NAT, "nat", "none", "NAT traversal for the network port: none, any, upnp, pmp, pmp:<gateway IP> or extip:<IP>")
+	flag.Int64Var(&p.SimSeed, "simseed", 0, "Run the simulated nodes deterministically in virtual time, scheduled with this seed. 0 runs on the wall clock.")
+	flag.StringVar(&p.Scenario, "scenario", "", "Run the simulator scenario in this YAML or JSON file, report the result and exit.")
 	flag.StringVar(&p.Prefix, "prefix", "", "Prefix the Factom Node Names with this value; used to create leaderless networks.")
 	flag.BoolVar(&p.Rotate, "rotate", false, "If true, responsibility is owned by one leader, and Rotated over the leaders.")
 	flag.IntVar(&p.TimeOffset, "timedelta", 0, "Maximum timeDelta in milliseconds to offset each node.  Simulates deltas in system clocks over a network.")
 	flag.BoolVar(&p.KeepMismatch, "keepmismatch", false, "If true, do not discard DBStates even when a majority of DBSignatures have a different hash")
+	flag.StringVar(&p.Invariants, "invariants", "off", "Check the invariants of the consensus as the node runs: off, log or halt on a violation")
+	flag.StringVar(&p.ConsensusTrace, "consensustrace", "", "Write a consensus trace of each node to this directory, see Utilities/TraceVisualizer")
 	flag.Int64Var(&p.StartDelay, "startdelay", 10, "Delay to start processing messages, in seconds")
 	flag.IntVar(&p.Deadline, "deadline", 1000, "Timeout Delay in milliseconds used on Reads and Writes to the network comm")
 	//flag.StringVar(&p.CustomNetName,"customnet", "", "This string specifies a custom blockchain network ID.")
@@ -98,6 +108,8 @@ func ParseCmdLine(args []string) *FactomParams {
 	flag.CommandLine.Parse(args)
 	elections.FaultTimeout = p.FaultTimeout
 	elections.RoundTimeout = p.RoundTimeout
+	elections.FastFaultTimeout = p.FastFaultTimeout
+	elections.FastRoundTimeout = p.FastRoundTimeout
 
 	p.CustomNetName = *CustomNetPtr
 	p.CustomNet = primitives.Sha([]byte(*CustomNetPtr)).Bytes()[:4]
@@ -121,6 +133,13 @@ func ParseCmdLine(args []string) *FactomParams {
 		os.Exit(1)
 	}
 
+	switch p.Invariants {
+	case state.InvariantsOff, state.InvariantsLog, state.InvariantsHalt:
+	default:
+		fmt.Printf("Unknown invariants mode %q, use off, log or halt\n", p.Invariants)
+		os.Exit(1)
+	}
+
 	// launch debug console if requested
 	if p.DebugConsole != "" {
 		launchDebugServer(p.DebugConsole)
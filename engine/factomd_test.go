@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"reflect"
 	"runtime"
 	"strconv"
@@ -60,7 +61,8 @@ func SetupSim(GivenNodes string, UserAddedOptions map[string]string, height int,
 		"--stderrlog":           "out.txt",
 		"--checkheads":          "false",
 		"--controlpanelsetting": "readwrite",
-		"--debuglog":            "faulting|bad",
+		"--debuglog":            "faulting|bad|invariants",
+		"--invariants":          "log",
 	}
 
 	// loop thru the test specific options and overwrite or append to the DefaultOptions
@@ -232,7 +234,7 @@ func WaitForAllNodes(state *state.State) {
 		s := simFnodes[i].State
 		height = ""
 		if s.LLeaderHeight != blk { // if not caught up, start over
-			time.Sleep(100 * time.Millisecond)
+			simSleep(100 * time.Millisecond)
 			i = 0 // start over
 			continue
 		}
@@ -241,6 +243,17 @@ func WaitForAllNodes(state *state.State) {
 	fmt.Printf("Wait for all nodes done\n%s", height)
 }
 
+// simSleep waits in real time, or runs a deterministic simulation for the time
+func simSleep(d time.Duration) {
+	if SimClock() == nil {
+		time.Sleep(d)
+		return
+	}
+	if err := RunSimFor(d); err != nil {
+		panic(err)
+	}
+}
+
 func TimeNow(s *state.State) {
 	fmt.Printf("%s:%d/%d\n", s.FactomNodeName, int(s.LLeaderHeight), s.CurrentMinute)
 }
@@ -287,7 +300,7 @@ func WaitBlocks(s *state.State, blks int) {
 	newBlock := int(s.LLeaderHeight) + blks
 	for i := int(s.LLeaderHeight); i < newBlock; i++ {
 		for int(s.LLeaderHeight) < i {
-			time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+			simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 		}
 		TimeNow(s)
 	}
@@ -300,7 +313,7 @@ func WaitForBlock(s *state.State, newBlock int) {
 	sleepTime := time.Duration(globals.Params.BlkTime) * 1000 / 40 // Figure out how long to sleep in milliseconds
 	for i := int(s.LLeaderHeight); i < newBlock; i++ {
 		for int(s.LLeaderHeight) < i {
-			time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+			simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 		}
 		TimeNow(s)
 	}
@@ -314,12 +327,12 @@ func WaitForMinute(s *state.State, min int) {
 	sleepTime := time.Duration(globals.Params.BlkTime) * 1000 / 40 // Figure out how long to sleep in milliseconds
 	if s.CurrentMinute >= min {
 		for s.CurrentMinute > 0 {
-			time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+			simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 		}
 	}
 
 	for min > s.CurrentMinute {
-		time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+		simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 	}
 	TimeNow(s)
 }
@@ -330,10 +343,10 @@ func WaitMinutesQuite(s *state.State, min int) {
 	newMinute := (s.CurrentMinute + min) % 10
 	newBlock := int(s.LLeaderHeight) + (s.CurrentMinute+min)/10
 	for int(s.LLeaderHeight) < newBlock {
-		time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+		simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 	}
 	for s.CurrentMinute != newMinute {
-		time.Sleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
+		simSleep(sleepTime * time.Millisecond) // wake up and about 4 times per minute
 	}
 }
 
@@ -382,7 +395,7 @@ var ranSimTest = false
 func runCmd(cmd string) {
 	os.Stdout.WriteString("Executing: " + cmd + "\n")
 	os.Stderr.WriteString("Executing: " + cmd + "\n")
-	InputChan <- cmd
+	SimCommand(cmd)
 	return
 }
 
@@ -396,13 +409,20 @@ func shutDownEverything(t *testing.T) {
 	}
 	currentHeight := statusState.LLeaderHeight
 	// Sleep one block
-	time.Sleep(time.Duration(globals.Params.BlkTime) * time.Second)
+	simSleep(time.Duration(globals.Params.BlkTime) * time.Second)
 
 	if currentHeight < statusState.LLeaderHeight {
 		t.Fatal("Failed to shut down factomd via ShutdownChan")
 	}
 
 	fmt.Printf("Test took %d blocks and %s time\n", GetFnodes()[0].State.LLeaderHeight, time.Now().Sub(startTime))
+	if err := SimError(); err != nil {
+		t.Fatal(err)
+	}
+	EndDeterministicSim()
+	if err := InvariantViolations(); err != nil {
+		t.Fatal(err)
+	}
 
 }
 func v2Request(req *primitives.JSON2Request, port int) (*primitives.JSON2Response, error) {
@@ -1552,3 +1572,68 @@ func TestRandom(t *testing.T) {
 		t.Fatal("Failed")
 	}
 }
+
+// TestDeterministicSim runs the same seeded simulation in two test processes, as the sim can only run
+// once in a process, and expects the same trace of both
+func TestDeterministicSim(t *testing.T) {
+	if out := os.Getenv("FACTOMD_SIM_TRACE"); out != "" {
+		runDeterministicSim(t, out)
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "simtrace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var traces []string
+	for i := 0; i < 2; i++ {
+		out := fmt.Sprintf("%s/trace%d", dir, i)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestDeterministicSim$", "-test.timeout=10m")
+		cmd.Env = append(os.Environ(), "FACTOMD_SIM_TRACE="+out)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Run %d failed: %v\n%s", i, err, output)
+		}
+		trace, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, string(trace))
+	}
+	if traces[0] != traces[1] {
+		t.Errorf("Two runs with the same seed differ: %s and %s", traces[0], traces[1])
+	}
+}
+
+// runDeterministicSim runs a few blocks with seed 42, and writes the seed, the steps and the trace of
+// the clock to out
+func runDeterministicSim(t *testing.T, out string) {
+	state0 := SetupSim("LLAF", map[string]string{"--simseed": "42"}, 8, 0, 0, t)
+	if SimClock() == nil {
+		t.Fatal("Expected a virtual clock")
+	}
+	AddSimCheck("heights agree", func() error {
+		for _, fn := range GetFnodes() {
+			behind := int(state0.LLeaderHeight) - int(fn.State.LLeaderHeight)
+			if behind > 1 || behind < -1 {
+				return fmt.Errorf("%s at height %d, %s at %d", state0.FactomNodeName, state0.LLeaderHeight, fn.State.FactomNodeName, fn.State.LLeaderHeight)
+			}
+		}
+		return nil
+	})
+
+	start := SimClock().Now()
+	WaitBlocks(state0, 3)
+	WaitForMinute(state0, 1)
+	WaitForAllNodes(state0)
+	if elapsed := SimClock().Now().Sub(start); elapsed < 2*time.Duration(globals.Params.BlkTime)*time.Second {
+		t.Errorf("2 blocks took %s of virtual time", elapsed)
+	}
+	trace := fmt.Sprintf("seed %d, %d steps, trace %x", SimClock().Seed(), SimClock().Steps(), SimClock().Trace())
+	if err := ioutil.WriteFile(out, []byte(trace), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	shutDownEverything(t)
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+const identityUsage = `Usage: factomd identity [flags] <type> [value]
+
+Builds an identity entry signed by the identity key of a key file, and shows the admin block entry it
+makes and the height it takes effect. With -submit the entry is committed and revealed, paid by the
+entry credit key of the key file. The key file never leaves this process, the node at -url only gets
+the signed entry and commit.
+
+Types and values:
+  block-signing-key [public key]         A new key is generated if none is given
+  bitcoin-key <key hash>                 With -level and -keytype
+  matryoshka-hash <hash>
+  coinbase-address <FA...>
+  efficiency <percent>                   Share of the payout given to grants, e.g. 12.34
+  coinbase-cancel <height>:<index>       Signed by an authority, cancels an output of a descriptor
+
+Key file:
+  {"identitychainid": "888888...", "managementchainid": "888888...",
+   "identitykey": "<hex private key of identity key 1>", "ecprivatekey": "Es..."}
+
+`
+
+// RunIdentityCommand runs "factomd identity", and returns the exit code
+func RunIdentityCommand(args []string) int {
+	flags := flag.NewFlagSet("identity", flag.ContinueOnError)
+	keys := flags.String("keys", "identity.json", "Key file of the identity")
+	url := flags.String("url", "http://localhost:8088/v2", "API of the node")
+	user := flags.String("rpcuser", "", "API user, if the node has one")
+	password := flags.String("rpcpass", "", "API password")
+	submit := flags.Bool("submit", false, "Commit and reveal the entry, instead of only showing it")
+	level := flags.Uint("level", 0, "Level of a Bitcoin key, 0 to 3")
+	keyType := flags.Uint("keytype", 0, "Type of a Bitcoin key, 0 P2PKH or 1 P2SH")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, identityUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 1
+	}
+
+	kf, err := identity.LoadKeyFile(*keys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	update := identity.IdentityUpdate{
+		Type:    flags.Arg(0),
+		Value:   flags.Arg(1),
+		Level:   byte(*level),
+		KeyType: byte(*keyType),
+	}
+	var newKey *primitives.PrivateKey
+	if update.Type == identity.UpdateBlockSigningKey && update.Value == "" {
+		newKey = identity.NewBlockSigningKey()
+		update.Value = newKey.PublicKeyString()
+	}
+
+	now := primitives.NewTimestampNow()
+	entry, err := kf.BuildIdentityUpdate(update, now)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	commit, err := kf.ComposeCommit(entry, now)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, _ := entry.MarshalBinary()
+	entryHex := hex.EncodeToString(data)
+	data, _ = commit.MarshalBinary()
+	commitHex := hex.EncodeToString(data)
+
+	p := new(identity.IdentityUpdatePreview)
+	if err := postAPIRequest(*url, *user, *password, "identity-update-preview", map[string]string{"entry": entryHex}, p); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s update of identity %s\n", p.Type, p.IdentityChainID)
+	fmt.Printf("Entry %s in chain %s\n", p.EntryHash, p.ChainID)
+	if p.AdminEntry != "" {
+		fmt.Printf("Admin block entry: %s\n", p.AdminEntry)
+		fmt.Printf("Entry in block %d, admin block entry in block %d, takes effect in block %d\n",
+			p.EntryHeight, p.AdminBlockHeight, p.EffectiveHeight)
+	}
+	if p.Note != "" {
+		fmt.Println(p.Note)
+	}
+	if newKey != nil {
+		fmt.Printf("New block signing private key, set LocalServerPrivKey to it from block %d:\n%s\n",
+			p.EffectiveHeight, newKey.PrivateKeyString())
+	}
+
+	if !*submit {
+		if newKey != nil {
+			fmt.Printf("Not submitted, run again with -submit and the public key %s to commit and reveal it\n", update.Value)
+		} else {
+			fmt.Println("Not submitted, run again with -submit to commit and reveal it")
+		}
+		return 0
+	}
+	if err := postAPIRequest(*url, *user, *password, "commit-entry", map[string]string{"message": commitHex}, new(interface{})); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := postAPIRequest(*url, *user, *password, "reveal-entry", map[string]string{"entry": entryHex}, new(interface{})); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("Submitted, commit", commit.GetSigHash().String())
+	return 0
+}
+
+// postAPIRequest calls a method of the API of a node and unmarshals its result
+func postAPIRequest(url string, user string, password string, method string, params interface{}, result interface{}) error {
+	data, err := json.Marshal(primitives.NewJSON2Request(method, 1, params))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	r := new(struct {
+		Error  *primitives.JSONError `json:"error"`
+		Result json.RawMessage       `json:"result"`
+	})
+	if err := json.Unmarshal(body, r); err != nil {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	if r.Error != nil {
+		if r.Error.Data != nil {
+			return fmt.Errorf("%s: %v", r.Error.Message, r.Error.Data)
+		}
+		return fmt.Errorf("%s", r.Error.Message)
+	}
+	return json.Unmarshal(r.Result, result)
+}
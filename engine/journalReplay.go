@@ -0,0 +1,240 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/messages/electionMsgs"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/elections"
+	"github.com/FactomProject/factomd/state"
+)
+
+// ReplayJournal feeds a journal into a fresh node, in virtual time: every message arrives at the time
+// it was recorded, and the node's loops run on a seeded VirtualClock, so a replay gives the same blocks
+// every time. The blocks it saves are compared to a golden file to catch regressions, and a journal of
+// an incident can be minimized to the few messages that still reproduce it (MinimizeJournal).
+//
+// The node replays on its own: nothing it sends goes anywhere, and nothing comes in that isn't in the
+// journal. The messages the node made itself are in the journal too, so the replay doesn't run the
+// node's minute timer.
+
+// ReplaySeed seeds the scheduler of the replays
+var ReplaySeed int64 = 1
+
+// ReplaySettle is how long a replay runs on after the last message of the journal, in virtual time
+var ReplaySettle = time.Minute
+
+// ReplayResult is what a node did with a journal
+type ReplayResult struct {
+	Blocks     []string // KeyMR of each saved directory block, by height
+	Violations []string // Of the invariants of the consensus
+}
+
+// NewReplayState returns a fresh node with an empty map database, configured by the config file of
+// the node that wrote the journal ("" for the default config) on the network of the journal.
+func NewReplayState(config string, network string) *state.State {
+	s := new(state.State)
+	s.EFactory = new(electionMsgs.ElectionsFactory)
+	s.LoadConfig(config, network)
+	if network != "" {
+		s.Network = network
+	}
+	s.DBType = "Map"
+	s.LogPath = "stdout"
+	s.Journaling = false
+	s.InvariantMode = state.InvariantsLog
+	s.Init()
+	state.LoadDatabase(s)
+	return s
+}
+
+// ReplayJournal replays the entries into the node made by newState, and returns the blocks it saved.
+// Can't run in a deterministic simulation, the replay has a clock of its own.
+func ReplayJournal(header *state.JournalHeader, entries []state.JournalEntry, newState func() *state.State) (*ReplayResult, error) {
+	if simulation != nil || primitives.IsClockVirtual() {
+		return nil, fmt.Errorf("Can't replay a journal in a simulation")
+	}
+
+	start := DeterministicSimStart
+	if header != nil && header.Start > 0 {
+		start = time.Unix(0, header.Start*1e6)
+	} else if len(entries) > 0 && entries[0].Time > 0 {
+		start = time.Unix(0, entries[0].Time*1e6)
+	}
+	clock := primitives.NewVirtualClock(ReplaySeed, start)
+	primitives.SetClock(clock)
+	defer primitives.SetClock(nil)
+
+	var s *state.State
+	var feedErr error
+	fed, stopped := false, false
+	primitives.Go(func() {
+		s = newState()
+		primitives.Go(s.ValidatorLoop)
+		primitives.Go(func() { elections.Run(s) })
+		primitives.Go(func() { drainReplayOutputs(s, &stopped) })
+		feedErr = feedJournal(s, entries)
+		fed = true
+	})
+
+	last := start
+	if n := len(entries); n > 0 && entries[n-1].Time > 0 {
+		last = time.Unix(0, entries[n-1].Time*1e6)
+	}
+	clock.RunUntil(func() bool { return fed }, last.Sub(start)+ReplaySettle)
+	if feedErr != nil {
+		return nil, feedErr
+	}
+	if !fed {
+		return nil, fmt.Errorf("The node didn't take all the messages of the journal")
+	}
+	clock.RunFor(ReplaySettle)
+
+	result := new(ReplayResult)
+	for h := uint32(0); h <= s.GetHighestSavedBlk(); h++ {
+		d := s.GetDirectoryBlockByHeight(h)
+		if d == nil {
+			break
+		}
+		result.Blocks = append(result.Blocks, d.GetKeyMR().String())
+	}
+	result.Violations = s.InvariantViolations()
+
+	stopped = true
+	s.ShutdownChan <- 0
+	clock.RunFor(time.Second)
+	return result, nil
+}
+
+// feedJournal enqueues the messages of the journal at the time they were recorded. Journals without
+// times are fed as fast as the node takes them.
+func feedJournal(s *state.State, entries []state.JournalEntry) error {
+	for i, e := range entries {
+		if e.Time > 0 {
+			if wait := time.Unix(0, e.Time*1e6).Sub(primitives.Now()); wait > 0 {
+				primitives.Sleep(wait)
+			}
+		}
+		msg, err := e.Message()
+		if err != nil {
+			return fmt.Errorf("Entry %d of the journal: %v", i, err)
+		}
+		if t := msg.Type(); t == constants.REVEAL_ENTRY_MSG || t == constants.COMMIT_CHAIN_MSG || t == constants.COMMIT_ENTRY_MSG {
+			s.InMsgQueue2().Enqueue(msg)
+		} else {
+			s.InMsgQueue().Enqueue(msg)
+		}
+		for s.InMsgQueue().Length() > constants.INMSGQUEUE_MED {
+			primitives.Sleep(10 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// drainReplayOutputs throws away what the node sends, there is no network
+func drainReplayOutputs(s *state.State, stopped *bool) {
+	for !*stopped {
+		for s.NetworkOutMsgQueue().Dequeue() != nil {
+		}
+		select {
+		case <-s.NetworkInvalidMsgQueue():
+		default:
+		}
+		primitives.Sleep(10 * time.Millisecond)
+	}
+}
+
+// WriteGolden writes the blocks of a replay as a golden file, one "<height> <keymr>" per line
+func WriteGolden(w io.Writer, blocks []string) error {
+	for h, keymr := range blocks {
+		if _, err := fmt.Fprintf(w, "%d %s\n", h, keymr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadGolden reads the blocks of a golden file, lines starting with # are comments
+func ReadGolden(r io.Reader) ([]string, error) {
+	var blocks []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %d: expected <height> <keymr>", line)
+		}
+		h, err := strconv.Atoi(fields[0])
+		if err != nil || h != len(blocks) {
+			return nil, fmt.Errorf("Line %d: expected height %d", line, len(blocks))
+		}
+		blocks = append(blocks, fields[1])
+	}
+	return blocks, scanner.Err()
+}
+
+// CompareGolden returns an error describing the first block of the replay that isn't the golden one
+func CompareGolden(golden []string, blocks []string) error {
+	for h := range golden {
+		if h >= len(blocks) {
+			return fmt.Errorf("The replay saved %d blocks, the golden file has %d", len(blocks), len(golden))
+		}
+		if blocks[h] != golden[h] {
+			return fmt.Errorf("Block %d is %s, the golden file has %s", h, blocks[h], golden[h])
+		}
+	}
+	if len(blocks) > len(golden) {
+		return fmt.Errorf("The replay saved %d blocks, the golden file has %d", len(blocks), len(golden))
+	}
+	return nil
+}
+
+// MinimizeJournal returns a smaller journal that still reproduces an issue, by delta debugging: it
+// keeps dropping chunks of the entries, and then single entries, as long as reproduces stays true.
+// reproduces must be true for the whole journal.
+func MinimizeJournal(entries []state.JournalEntry, reproduces func([]state.JournalEntry) bool) []state.JournalEntry {
+	n := 2
+	for len(entries) >= 2 {
+		chunk := (len(entries) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(entries); start += chunk {
+			end := start + chunk
+			if end > len(entries) {
+				end = len(entries)
+			}
+			without := append(append([]state.JournalEntry{}, entries[:start]...), entries[end:]...)
+			if len(without) > 0 && reproduces(without) {
+				entries = without
+				if n > 2 {
+					n--
+				}
+				reduced = true
+				break
+			}
+		}
+		if reduced {
+			continue
+		}
+		if n >= len(entries) {
+			break
+		}
+		n *= 2
+		if n > len(entries) {
+			n = len(entries)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,88 @@
+package engine_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/FactomProject/factomd/engine"
+	"github.com/FactomProject/factomd/state"
+)
+
+func TestReplayJournal(t *testing.T) {
+	newState := func() *state.State { return NewReplayState("", "LOCAL") }
+	result, err := ReplayJournal(nil, nil, newState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Blocks) == 0 {
+		t.Fatalf("The replay saved no blocks, not even the genesis block")
+	}
+	again, err := ReplayJournal(nil, nil, newState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareGolden(result.Blocks, again.Blocks); err != nil {
+		t.Errorf("Two replays of the same journal differ: %v", err)
+	}
+}
+
+func TestReplayJournalBadEntry(t *testing.T) {
+	entries := []state.JournalEntry{{Msg: "not hex"}}
+	if _, err := ReplayJournal(nil, entries, func() *state.State { return NewReplayState("", "LOCAL") }); err == nil || !strings.Contains(err.Error(), "Entry 0") {
+		t.Errorf("Expected an error on entry 0, got %v", err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	blocks := []string{"aa", "bb", "cc"}
+	var buf bytes.Buffer
+	if err := WriteGolden(&buf, blocks); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ReadGolden(strings.NewReader("# blocks of the replay\n" + buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareGolden(golden, blocks); err != nil {
+		t.Error(err)
+	}
+
+	if err := CompareGolden(golden, []string{"aa", "xx", "cc"}); err == nil || !strings.Contains(err.Error(), "Block 1") {
+		t.Errorf("Expected block 1 to differ, got %v", err)
+	}
+	if CompareGolden(golden, blocks[:2]) == nil || CompareGolden(golden, append(blocks, "dd")) == nil {
+		t.Errorf("Accepted a replay with a different number of blocks")
+	}
+	if _, err := ReadGolden(strings.NewReader("0 aa\n2 cc\n")); err == nil {
+		t.Errorf("Accepted a golden file with a missing height")
+	}
+}
+
+func TestMinimizeJournal(t *testing.T) {
+	var entries []state.JournalEntry
+	for i := 0; i < 50; i++ {
+		entries = append(entries, state.JournalEntry{Time: int64(i)})
+	}
+	// The issue needs the messages at 7 and 31, in that order
+	runs := 0
+	reproduces := func(entries []state.JournalEntry) bool {
+		runs++
+		seen7 := false
+		for _, e := range entries {
+			seen7 = seen7 || e.Time == 7
+			if e.Time == 31 && seen7 {
+				return true
+			}
+		}
+		return false
+	}
+
+	minimized := MinimizeJournal(entries, reproduces)
+	if len(minimized) != 2 || minimized[0].Time != 7 || minimized[1].Time != 31 {
+		t.Errorf("Minimized to %+v", minimized)
+	}
+	if runs > 100 {
+		t.Errorf("Took %d replays", runs)
+	}
+}
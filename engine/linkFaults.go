@@ -0,0 +1,165 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// Each SimPeer applies the fault model of its link when sending: the message is dropped, or stamped with
+// the time it is delivered. The receiving SimPeer holds the messages until their delivery time, so
+// messages with less delay overtake the others, just like on a real network.
+
+// SimReorderHold is how long a message picked by the Reorder rate is held back
+var SimReorderHold = 200 * time.Millisecond
+
+// SimLink is one direction of a link of the simulated network
+type SimLink struct {
+	From   string
+	To     string
+	Fault  interfaces.LinkFault
+	Queued int // Messages on their way
+}
+
+// ValidateLinkFault returns an error if the fault model makes no sense
+func ValidateLinkFault(fault interfaces.LinkFault) error {
+	for _, rate := range []int{fault.DropRate, fault.Reorder, fault.Duplicate} {
+		if rate < 0 || rate > 1000 {
+			return fmt.Errorf("Rates are out of 1000, got %d", rate)
+		}
+	}
+	if fault.Latency < 0 || fault.Jitter < 0 || fault.Bandwidth < 0 {
+		return fmt.Errorf("Latency, jitter and bandwidth can't be negative")
+	}
+	switch fault.Distribution {
+	case "", "uniform", "normal", "exponential":
+	default:
+		return fmt.Errorf("Unknown distribution %q, use uniform, normal or exponential", fault.Distribution)
+	}
+	return nil
+}
+
+// LinkFaultString describes the fault model, "perfect" for a link without faults
+func LinkFaultString(fault interfaces.LinkFault) string {
+	var parts []string
+	if fault.Cut {
+		parts = append(parts, "cut")
+	}
+	if fault.DropRate > 0 {
+		parts = append(parts, fmt.Sprintf("drop %d/1000", fault.DropRate))
+	}
+	if fault.Latency > 0 || fault.Jitter > 0 {
+		latency := fmt.Sprintf("latency %dms", fault.Latency)
+		if fault.Jitter > 0 {
+			distribution := fault.Distribution
+			if distribution == "" {
+				distribution = "uniform"
+			}
+			latency += fmt.Sprintf("+%s %dms", distribution, fault.Jitter)
+		}
+		parts = append(parts, latency)
+	}
+	if fault.Bandwidth > 0 {
+		parts = append(parts, fmt.Sprintf("bandwidth %dB/s", fault.Bandwidth))
+	}
+	if fault.Reorder > 0 {
+		parts = append(parts, fmt.Sprintf("reorder %d/1000", fault.Reorder))
+	}
+	if fault.Duplicate > 0 {
+		parts = append(parts, fmt.Sprintf("duplicate %d/1000", fault.Duplicate))
+	}
+	if len(parts) == 0 {
+		return "perfect"
+	}
+	return strings.Join(parts, " ")
+}
+
+// linkDelay draws the delay of one message on a link, in nanoseconds
+func linkDelay(fault interfaces.LinkFault) int64 {
+	delay := float64(fault.Latency)
+	if fault.Jitter > 0 {
+		switch fault.Distribution {
+		case "normal":
+			delay += math.Abs(primitives.RandNormFloat64()) * float64(fault.Jitter)
+		case "exponential":
+			delay += primitives.RandExpFloat64() * float64(fault.Jitter)
+		default:
+			delay += float64(primitives.RandInt63n(fault.Jitter))
+		}
+	}
+	return int64(delay * float64(time.Millisecond))
+}
+
+// SimLinks returns every link between the nodes
+func SimLinks(fnodes []*FactomNode) []SimLink {
+	var links []SimLink
+	for _, fn := range fnodes {
+		for _, p := range fn.Peers {
+			if sim, ok := p.(*SimPeer); ok {
+				links = append(links, SimLink{sim.FromName, sim.ToName, sim.GetFault(), len(sim.BroadcastOut)})
+			}
+		}
+	}
+	return links
+}
+
+// SetSimLinkFault sets the fault model of the links from one node to the other, by node name. An empty
+// name matches every node.
+func SetSimLinkFault(fnodes []*FactomNode, from, to string, fault interfaces.LinkFault) error {
+	if err := ValidateLinkFault(fault); err != nil {
+		return err
+	}
+	found := false
+	for _, fn := range fnodes {
+		for _, p := range fn.Peers {
+			if sim, ok := p.(*SimPeer); ok && (from == "" || from == sim.FromName) && (to == "" || to == sim.ToName) {
+				sim.SetFault(fault)
+				found = true
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("No link from %q to %q", from, to)
+	}
+	return nil
+}
+
+// simNetwork gives the states (and so the debug API) access to the links of the simulation
+type simNetwork struct{}
+
+var _ interfaces.ISimNetwork = simNetwork{}
+
+func (simNetwork) LinkFaults() interface{} {
+	return SimLinks(fnodes)
+}
+
+func (simNetwork) SetLinkFault(from, to string, fault interfaces.LinkFault) error {
+	return SetSimLinkFault(fnodes, from, to, fault)
+}
+
+// simPacketQueue is a heap of packets by delivery time, packets due at the same time stay in order
+type simPacketQueue []*SimPacket
+
+func (q simPacketQueue) Len() int { return len(q) }
+func (q simPacketQueue) Less(i, j int) bool {
+	if q[i].deliver != q[j].deliver {
+		return q[i].deliver < q[j].deliver
+	}
+	return q[i].seq < q[j].seq
+}
+func (q simPacketQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *simPacketQueue) Push(x interface{}) { *q = append(*q, x.(*SimPacket)) }
+func (q *simPacketQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
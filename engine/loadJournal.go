@@ -6,7 +6,6 @@ package engine
 
 import (
 	"bufio"
-	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
@@ -14,7 +13,7 @@ import (
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
-	"github.com/FactomProject/factomd/common/messages/msgsupport"
+	"github.com/FactomProject/factomd/state"
 )
 
 func LoadJournal(s interfaces.IState, journal string) {
@@ -39,44 +38,20 @@ func LoadJournalFromReader(s interfaces.IState, r *bufio.Reader) {
 	s.SetIsReplaying()
 	defer s.SetIsDoneReplaying()
 
+	_, entries, err := state.ReadJournal(r)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	fmt.Println("Replaying Journal")
 	time.Sleep(time.Second * 5)
 	fmt.Println("GO!")
-	t := 0
-	p := 0
-	for {
-		t++
-		fmt.Println("total: ", t, " processed: ", p, "            \r")
-
-		// line is empty if no more data
-		line, err := r.ReadBytes('\n')
-		if len(line) == 0 || err != nil {
-			break
-		}
-
-		// Get the next word.  If not MsgHex:, then go to next line.
-		adv, word, err := bufio.ScanWords(line, true)
-		if string(word) != "MsgHex:" {
-			continue // Go to next line.
-		}
-		line = line[adv:] // Remove "MsgHex:" from the line.
-
-		// Remove spaces.
-		adv, data, err := bufio.ScanWords(line, true)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-
-		// Decode the hex
-		binary, err := hex.DecodeString(string(data))
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+	for i, e := range entries {
+		fmt.Println("total: ", len(entries), " processed: ", i, "            \r")
 
 		// Unmarshal the message.
-		msg, err := msgsupport.UnmarshalMessage(binary)
+		msg, err := e.Message()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -84,7 +59,6 @@ func LoadJournalFromReader(s interfaces.IState, r *bufio.Reader) {
 
 		// Process the message.
 		s.InMsgQueue().Enqueue(msg)
-		p++
 		if s.InMsgQueue().Length() > constants.INMSGQUEUE_MED {
 			for s.InMsgQueue().Length() > constants.INMSGQUEUE_LOW {
 				time.Sleep(time.Millisecond * 10)
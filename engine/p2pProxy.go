@@ -124,6 +124,9 @@ func (f *P2PProxy) Send(msg interfaces.IMsg) error {
 		case !msg.IsPeer2Peer() && msg.IsFullBroadcast():
 			msgLogger.Debug("Sending full broadcast message")
 			message.PeerHash = p2p.FullBroadcastFlag
+		case !msg.IsPeer2Peer() && !msg.IsFullBroadcast() && constants.GossipPolicy(msg.Type()) == constants.GOSSIP_INVENTORY:
+			msgLogger.Debug("Sending inventory broadcast message")
+			message.PeerHash = p2p.InventoryBroadcastFlag
 		case !msg.IsPeer2Peer() && !msg.IsFullBroadcast():
 			msgLogger.Debug("Sending broadcast message")
 			message.PeerHash = p2p.BroadcastFlag
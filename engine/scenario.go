@@ -0,0 +1,729 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/FactomProject/factomd/common/globals"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/elections"
+	"github.com/FactomProject/factomd/state"
+	"gopkg.in/yaml.v2"
+)
+
+// A scenario is a declarative script for the simulator, in YAML (or JSON):
+//
+//	name: leader goes offline at minute 4
+//	seed: 42        # run deterministically in virtual time, 0 runs on the wall clock
+//	blocktime: 10   # seconds per block
+//	nodes: 6
+//	options:        # any other factomd flags, the command line wins
+//	  roundtimeout: 4
+//	steps:
+//	  - leaders: [1, 2]
+//	  - audits: [3, 4]
+//	  - wait: {blocks: 1, minute: 4}
+//	  - offline: [1]
+//	  - expect: {leaders: [3], within: {rounds: 2}}
+//	  - partition: [[1, 2]]
+//	  - wait: {minutes: 3}
+//	  - heal: true
+//	  - fault: {from: [1], latency: 2000, jitter: 500, distribution: normal}
+//	  - assert: {heights_equal: true}
+//
+// Each step does one thing. Nodes are numbered from 0, node 0 is the bootstrap leader and its height
+// and minute are the time the waits are measured against. Conditions are checked on every node
+// that is on the network.
+
+// ScenarioPoll is how often the conditions are checked when the scenario runs on the wall clock
+var ScenarioPoll = 100 * time.Millisecond
+
+type Scenario struct {
+	Name      string            `yaml:"name"`
+	Seed      int64             `yaml:"seed"`      // Run deterministically with this seed, 0 runs on the wall clock
+	BlockTime int               `yaml:"blocktime"` // Seconds per block, defaults to 10
+	Nodes     int               `yaml:"nodes"`     // Number of nodes, all start as followers
+	Options   map[string]string `yaml:"options"`   // Other factomd flags
+	Steps     []ScenarioStep    `yaml:"steps"`
+}
+
+type ScenarioStep struct {
+	Wait      *ScenarioTime      `yaml:"wait"`
+	Leaders   []int              `yaml:"leaders"`   // Promote the nodes to leaders
+	Audits    []int              `yaml:"audits"`    // Promote the nodes to audit servers
+	Offline   []int              `yaml:"offline"`   // Take the nodes off the network
+	Online    []int              `yaml:"online"`    // Bring the nodes back
+	Partition [][]int            `yaml:"partition"` // Each group only talks to itself, the nodes not listed form one more group
+	Heal      bool               `yaml:"heal"`      // Remove the partitions
+	Fault     *ScenarioFault     `yaml:"fault"`     // Change the fault model of links
+	Load      *int               `yaml:"load"`      // Entries per second, 0 stops the load
+	Command   string             `yaml:"command"`   // A SimControl command
+	Expect    *ScenarioCondition `yaml:"expect"`    // Wait for the condition, fail if it isn't met in time
+	Assert    *ScenarioCondition `yaml:"assert"`    // Fail if the condition isn't met now
+}
+
+// ScenarioFault sets the fault model of the links from some nodes to others, no nodes is every node
+type ScenarioFault struct {
+	From                 []int `yaml:"from"`
+	To                   []int `yaml:"to"`
+	Both                 bool  `yaml:"both"` // And the links back
+	interfaces.LinkFault `yaml:",inline"`
+}
+
+// ScenarioTime is a point (wait) or an amount of time (within). The fields add up.
+type ScenarioTime struct {
+	Block   int     `yaml:"block"`   // Wait for this height
+	Blocks  int     `yaml:"blocks"`  // Wait for this many blocks
+	Minute  *int    `yaml:"minute"`  // Then for this minute of the block, the next block's if it has passed
+	Minutes int     `yaml:"minutes"` // Wait for this many minutes
+	Seconds float64 `yaml:"seconds"`
+	Rounds  int     `yaml:"rounds"` // Election rounds: the fault timeout, plus a round timeout per round (within only)
+}
+
+type ScenarioCondition struct {
+	Leaders      []int         `yaml:"leaders"`       // The nodes are federated servers
+	Audits       []int         `yaml:"audits"`        // The nodes are audit servers
+	Followers    []int         `yaml:"followers"`     // The nodes are neither
+	Height       int           `yaml:"height"`        // Every node is at least at this height
+	HeightsEqual bool          `yaml:"heights_equal"` // Every node is at the same height
+	Within       *ScenarioTime `yaml:"within"`        // How long an expect may wait, defaults to a block
+}
+
+// ParseScenario reads a scenario from YAML or JSON, and validates it
+func ParseScenario(data []byte) (*Scenario, error) {
+	sc := new(Scenario)
+	if err := yaml.UnmarshalStrict(data, sc); err != nil {
+		return nil, err
+	}
+	if sc.BlockTime == 0 {
+		sc.BlockTime = 10
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// LoadScenario reads a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = path
+	}
+	return sc, nil
+}
+
+func (sc *Scenario) Validate() error {
+	if sc.Nodes < 1 {
+		return fmt.Errorf("A scenario needs at least one node")
+	}
+	if sc.BlockTime < 1 {
+		return fmt.Errorf("Invalid block time %d", sc.BlockTime)
+	}
+	checkNodes := func(nodes []int) error {
+		for _, n := range nodes {
+			if n < 0 || n >= sc.Nodes {
+				return fmt.Errorf("No node %d, there are %d nodes", n, sc.Nodes)
+			}
+		}
+		return nil
+	}
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
+		err := step.validate(checkNodes)
+		if err != nil {
+			return fmt.Errorf("Step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (step *ScenarioStep) validate(checkNodes func([]int) error) error {
+	actions := 0
+	count := func(set bool) {
+		if set {
+			actions++
+		}
+	}
+	count(step.Wait != nil)
+	count(step.Leaders != nil)
+	count(step.Audits != nil)
+	count(step.Offline != nil)
+	count(step.Online != nil)
+	count(step.Partition != nil)
+	count(step.Heal)
+	count(step.Fault != nil)
+	count(step.Load != nil)
+	count(step.Command != "")
+	count(step.Expect != nil)
+	count(step.Assert != nil)
+	if actions != 1 {
+		return fmt.Errorf("A step must do exactly one thing, found %d", actions)
+	}
+
+	for _, nodes := range append([][]int{step.Leaders, step.Audits, step.Offline, step.Online}, step.Partition...) {
+		if err := checkNodes(nodes); err != nil {
+			return err
+		}
+	}
+	if step.Fault != nil {
+		for _, nodes := range [][]int{step.Fault.From, step.Fault.To} {
+			if err := checkNodes(nodes); err != nil {
+				return err
+			}
+		}
+		if err := ValidateLinkFault(step.Fault.LinkFault); err != nil {
+			return err
+		}
+	}
+	if step.Wait != nil && step.Wait.Rounds != 0 {
+		return fmt.Errorf("Rounds are only for within")
+	}
+	if step.Load != nil && *step.Load < 0 {
+		return fmt.Errorf("Invalid load %d", *step.Load)
+	}
+	if step.Assert != nil && step.Assert.Within != nil {
+		return fmt.Errorf("An assert is checked at once, use expect to wait")
+	}
+	for _, c := range []*ScenarioCondition{step.Expect, step.Assert} {
+		if c == nil {
+			continue
+		}
+		for _, nodes := range [][]int{c.Leaders, c.Audits, c.Followers} {
+			if err := checkNodes(nodes); err != nil {
+				return err
+			}
+		}
+		if c.Within != nil && (c.Within.Block != 0 || c.Within.Minute != nil) {
+			return fmt.Errorf("Within is an amount of time, use blocks, minutes, seconds or rounds")
+		}
+	}
+	if m := step.Wait; m != nil && m.Minute != nil && (*m.Minute < 0 || *m.Minute >= state.MaxMinutesPerBlock) {
+		return fmt.Errorf("Invalid minute %d", *m.Minute)
+	}
+	return nil
+}
+
+func (step *ScenarioStep) String() string {
+	switch {
+	case step.Wait != nil:
+		return "wait " + step.Wait.String()
+	case step.Leaders != nil:
+		return fmt.Sprintf("leaders %v", step.Leaders)
+	case step.Audits != nil:
+		return fmt.Sprintf("audits %v", step.Audits)
+	case step.Offline != nil:
+		return fmt.Sprintf("offline %v", step.Offline)
+	case step.Online != nil:
+		return fmt.Sprintf("online %v", step.Online)
+	case step.Partition != nil:
+		return fmt.Sprintf("partition %v", step.Partition)
+	case step.Heal:
+		return "heal"
+	case step.Fault != nil:
+		return "fault " + step.Fault.String()
+	case step.Load != nil:
+		return fmt.Sprintf("load %d/s", *step.Load)
+	case step.Command != "":
+		return "command " + step.Command
+	case step.Expect != nil:
+		return "expect " + step.Expect.String()
+	case step.Assert != nil:
+		return "assert " + step.Assert.String()
+	}
+	return "nothing"
+}
+
+func (f *ScenarioFault) String() string {
+	nodes := func(list []int) string {
+		if len(list) == 0 {
+			return "all"
+		}
+		return fmt.Sprint(list)
+	}
+	arrow := "->"
+	if f.Both {
+		arrow = "<->"
+	}
+	return fmt.Sprintf("%s %s %s %s", nodes(f.From), arrow, nodes(f.To), LinkFaultString(f.LinkFault))
+}
+
+// apply sets the fault model of the links
+func (f *ScenarioFault) apply(nodes []*FactomNode) error {
+	for _, n := range append(append([]int{}, f.From...), f.To...) {
+		if n < 0 || n >= len(nodes) {
+			return fmt.Errorf("No node %d", n)
+		}
+	}
+	in := func(list []int, name string) bool {
+		for _, n := range list {
+			if nodes[n].State.FactomNodeName == name {
+				return true
+			}
+		}
+		return len(list) == 0
+	}
+	found := false
+	for _, fn := range nodes {
+		for _, p := range fn.Peers {
+			sim, ok := p.(*SimPeer)
+			if !ok {
+				continue
+			}
+			if (in(f.From, sim.FromName) && in(f.To, sim.ToName)) || (f.Both && in(f.To, sim.FromName) && in(f.From, sim.ToName)) {
+				sim.SetFault(f.LinkFault)
+				found = true
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("No links from %s", f)
+	}
+	return nil
+}
+
+func (t *ScenarioTime) String() string {
+	var parts []string
+	add := func(n int, unit string) {
+		if n != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+		}
+	}
+	add(t.Block, "block height")
+	add(t.Blocks, "blocks")
+	if t.Minute != nil {
+		parts = append(parts, fmt.Sprintf("minute %d", *t.Minute))
+	}
+	add(t.Minutes, "minutes")
+	if t.Seconds != 0 {
+		parts = append(parts, fmt.Sprintf("%gs", t.Seconds))
+	}
+	add(t.Rounds, "rounds")
+	return strings.Join(parts, " ")
+}
+
+func (c *ScenarioCondition) String() string {
+	var parts []string
+	if c.Leaders != nil {
+		parts = append(parts, fmt.Sprintf("leaders %v", c.Leaders))
+	}
+	if c.Audits != nil {
+		parts = append(parts, fmt.Sprintf("audits %v", c.Audits))
+	}
+	if c.Followers != nil {
+		parts = append(parts, fmt.Sprintf("followers %v", c.Followers))
+	}
+	if c.Height != 0 {
+		parts = append(parts, fmt.Sprintf("height %d", c.Height))
+	}
+	if c.HeightsEqual {
+		parts = append(parts, "heights equal")
+	}
+	if c.Within != nil {
+		parts = append(parts, "within "+c.Within.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// check returns an error describing the first part of the condition that isn't met
+func (c *ScenarioCondition) check(nodes []*FactomNode) error {
+	var online []*FactomNode
+	for _, fn := range nodes {
+		if !fn.State.GetNetStateOff() {
+			online = append(online, fn)
+		}
+	}
+	if len(online) == 0 {
+		return fmt.Errorf("All the nodes are off the network")
+	}
+
+	role := func(observer *FactomNode, fn *FactomNode) string {
+		pl := observer.State.LeaderPL
+		if pl == nil {
+			return "follower"
+		}
+		id := fn.State.IdentityChainID
+		for _, s := range pl.FedServers {
+			if s.GetChainID().IsSameAs(id) {
+				return "leader"
+			}
+		}
+		for _, s := range pl.AuditServers {
+			if s.GetChainID().IsSameAs(id) {
+				return "audit"
+			}
+		}
+		return "follower"
+	}
+	for _, expected := range []struct {
+		role  string
+		nodes []int
+	}{{"leader", c.Leaders}, {"audit", c.Audits}, {"follower", c.Followers}} {
+		for _, n := range expected.nodes {
+			for _, observer := range online {
+				if r := role(observer, nodes[n]); r != expected.role {
+					return fmt.Errorf("%s sees %s as %s, not %s", observer.State.FactomNodeName, nodes[n].State.FactomNodeName, r, expected.role)
+				}
+			}
+		}
+	}
+
+	for _, fn := range online {
+		if int(fn.State.LLeaderHeight) < c.Height {
+			return fmt.Errorf("%s at height %d", fn.State.FactomNodeName, fn.State.LLeaderHeight)
+		}
+		if c.HeightsEqual && fn.State.LLeaderHeight != online[0].State.LLeaderHeight {
+			return fmt.Errorf("%s at height %d, %s at %d", online[0].State.FactomNodeName, online[0].State.LLeaderHeight,
+				fn.State.FactomNodeName, fn.State.LLeaderHeight)
+		}
+	}
+	return nil
+}
+
+type ScenarioStepResult struct {
+	Step   int    // Counting from 1, 0 for the setup of the nodes
+	Action string // What the step does
+	Height uint32 // Height and minute of node 0 when the step finished
+	Minute int
+	Err    error
+}
+
+type ScenarioReport struct {
+	Name    string
+	Seed    int64
+	Results []ScenarioStepResult
+	Err     error // The first failure, nil if the scenario passed
+}
+
+func (r *ScenarioReport) Passed() bool {
+	return r.Err == nil
+}
+
+func (r *ScenarioReport) String() string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "Scenario %q", r.Name)
+	if r.Seed != 0 {
+		fmt.Fprintf(&out, " (seed %d)", r.Seed)
+	}
+	out.WriteString("\n")
+	for _, result := range r.Results {
+		status := "ok"
+		if result.Err != nil {
+			status = "FAIL: " + result.Err.Error()
+		}
+		fmt.Fprintf(&out, "%4d %5d-:-%d %-40s %s\n", result.Step, result.Height, result.Minute, result.Action, status)
+	}
+	if r.Passed() {
+		out.WriteString("PASS\n")
+	} else {
+		out.WriteString("FAIL\n")
+	}
+	return out.String()
+}
+
+// scenarioFlags returns the factomd flags the scenario runs with
+func (sc *Scenario) scenarioFlags() map[string]string {
+	flags := map[string]string{
+		"network":    "LOCAL",
+		"db":         "Map",
+		"net":        "alot+",
+		"enablenet":  "false",
+		"startdelay": "1",
+		"checkheads": "false",
+		"invariants": "log",
+		"count":      strconv.Itoa(sc.Nodes),
+		"blktime":    strconv.Itoa(sc.BlockTime),
+	}
+	// elections are timed off the block time, like the simulator tests
+	timeout := sc.BlockTime / 5
+	if timeout < 1 {
+		timeout = 1
+	}
+	flags["faulttimeout"] = strconv.Itoa(timeout)
+	flags["roundtimeout"] = strconv.Itoa(timeout)
+	if sc.Seed != 0 {
+		flags["simseed"] = strconv.FormatInt(sc.Seed, 10)
+	}
+	for name, value := range sc.Options {
+		flags[strings.TrimLeft(name, "-")] = value
+	}
+	return flags
+}
+
+// applyScenarioFlags sets the flags of the scenario that weren't given on the command line
+func applyScenarioFlags(sc *Scenario, p *FactomParams) error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for name, value := range sc.scenarioFlags() {
+		if given[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("Option %s=%s: %v", name, value, err)
+		}
+	}
+	elections.FaultTimeout = p.FaultTimeout
+	elections.RoundTimeout = p.RoundTimeout
+	elections.FastFaultTimeout = p.FastFaultTimeout
+	elections.FastRoundTimeout = p.FastRoundTimeout
+	return nil
+}
+
+// RunScenarioFile runs the scenario in the file, prints the report and returns the exit code
+func RunScenarioFile(path string, p *FactomParams) int {
+	sc, err := LoadScenario(path)
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintln("Scenario:", err))
+		return 2
+	}
+	if err := applyScenarioFlags(sc, p); err != nil {
+		os.Stderr.WriteString(fmt.Sprintln("Scenario:", err))
+		return 2
+	}
+	report := sc.Run(p)
+	os.Stdout.WriteString(report.String())
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+type scenarioRun struct {
+	scenario *Scenario
+	report   *ScenarioReport
+}
+
+// Run starts the nodes and runs the steps, until the first failure. The simulator can only run once
+// per process.
+func (sc *Scenario) Run(p *FactomParams) *ScenarioReport {
+	r := &scenarioRun{scenario: sc, report: &ScenarioReport{Name: sc.Name, Seed: p.SimSeed}}
+
+	Factomd(p, false)
+	r.result(0, fmt.Sprintf("start %d nodes", sc.Nodes), r.setup())
+	for i := range sc.Steps {
+		if r.report.Err != nil {
+			break
+		}
+		step := &sc.Steps[i]
+		err := r.step(step)
+		if err == nil {
+			err = InvariantViolations()
+		}
+		r.result(i+1, step.String(), err)
+	}
+
+	for _, fn := range fnodes {
+		select {
+		case fn.State.ShutdownChan <- 1:
+		default:
+		}
+	}
+	return r.report
+}
+
+// InvariantViolations returns the violations of the consensus invariants on the simulated nodes, if any
+func InvariantViolations() error {
+	var violations []string
+	for _, fn := range fnodes {
+		violations = append(violations, fn.State.InvariantViolations()...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d invariant violations:\n%s", len(violations), strings.Join(violations, "\n"))
+}
+
+func (r *scenarioRun) result(step int, action string, err error) {
+	s := fnodes[0].State
+	r.report.Results = append(r.report.Results, ScenarioStepResult{
+		Step:   step,
+		Action: action,
+		Height: s.LLeaderHeight,
+		Minute: s.CurrentMinute,
+		Err:    err,
+	})
+	if err != nil && r.report.Err == nil {
+		r.report.Err = fmt.Errorf("Step %d (%s): %v", step, action, err)
+	}
+}
+
+func (r *scenarioRun) block() time.Duration {
+	return time.Duration(fnodes[0].State.GetDirectoryBlockInSeconds()) * time.Second
+}
+
+func (r *scenarioRun) minute() time.Duration {
+	s := fnodes[0].State
+	return r.block() / time.Duration(s.GetMinutesPerBlock(s.LLeaderHeight))
+}
+
+// waitFor runs until done returns true, or fails after limit
+func (r *scenarioRun) waitFor(done func() bool, limit time.Duration) error {
+	if SimClock() != nil {
+		return RunSimUntil(done, limit)
+	}
+	deadline := time.Now().Add(limit)
+	for !done() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Not reached after %s", limit)
+		}
+		time.Sleep(ScenarioPoll)
+	}
+	return nil
+}
+
+// setup waits for the genesis block, and creates the identities the nodes need to be promoted
+func (r *scenarioRun) setup() error {
+	s := fnodes[0].State
+	if err := r.waitMinutes(1); err != nil {
+		return err
+	}
+	SimCommand(fmt.Sprintf("g%d", len(fnodes)))
+	idle := func() bool {
+		for _, fn := range fnodes {
+			if fn.State.InMsgQueue().Length() > 0 || fn.State.InMsgQueue2().Length() > 0 ||
+				len(fn.State.Holding) > 0 || fn.State.Commits.Len() > 0 {
+				return false
+			}
+		}
+		return true
+	}
+	if err := r.waitMinutes(1); err != nil {
+		return err
+	}
+	if err := r.waitFor(idle, 10*r.block()); err != nil {
+		return fmt.Errorf("Identities not processed: %v", err)
+	}
+	height := s.LLeaderHeight + 1
+	return r.waitFor(func() bool { return s.LLeaderHeight >= height }, 3*r.block())
+}
+
+// waitMinutes waits for node 0 to move n minutes on
+func (r *scenarioRun) waitMinutes(n int) error {
+	s := fnodes[0].State
+	minutes := s.GetMinutesPerBlock(s.LLeaderHeight)
+	target := int(s.LLeaderHeight)*minutes + s.CurrentMinute + n
+	return r.waitFor(func() bool { return int(s.LLeaderHeight)*minutes+s.CurrentMinute >= target }, time.Duration(n+20)*r.minute())
+}
+
+func (r *scenarioRun) wait(t *ScenarioTime) error {
+	s := fnodes[0].State
+	height := int(s.LLeaderHeight) + t.Blocks
+	if t.Block > height {
+		height = t.Block
+	}
+	if height > int(s.LLeaderHeight) {
+		blocks := height - int(s.LLeaderHeight)
+		if err := r.waitFor(func() bool { return int(s.LLeaderHeight) >= height }, time.Duration(blocks+2)*2*r.block()); err != nil {
+			return err
+		}
+	}
+	if t.Minute != nil {
+		minute := *t.Minute
+		if s.CurrentMinute >= minute && t.Blocks == 0 && t.Block == 0 {
+			// this minute has passed, wait for it in the next block
+			next := s.LLeaderHeight + 1
+			if err := r.waitFor(func() bool { return s.LLeaderHeight >= next }, 3*r.block()); err != nil {
+				return err
+			}
+		}
+		if err := r.waitFor(func() bool { return s.CurrentMinute >= minute }, 3*r.block()); err != nil {
+			return err
+		}
+	}
+	if t.Minutes > 0 {
+		if err := r.waitMinutes(t.Minutes); err != nil {
+			return err
+		}
+	}
+	if t.Seconds > 0 {
+		d := time.Duration(t.Seconds * float64(time.Second))
+		if SimClock() != nil {
+			return RunSimFor(d)
+		}
+		time.Sleep(d)
+	}
+	return nil
+}
+
+// duration is the amount of time of a within
+func (r *scenarioRun) duration(t *ScenarioTime) time.Duration {
+	d := time.Duration(t.Blocks)*r.block() + time.Duration(t.Minutes)*r.minute()
+	d += time.Duration(t.Seconds * float64(time.Second))
+	if t.Rounds > 0 {
+		d += time.Duration(elections.FaultTimeout+t.Rounds*elections.RoundTimeout) * time.Second
+	}
+	return d
+}
+
+func (r *scenarioRun) step(step *ScenarioStep) error {
+	switch {
+	case step.Wait != nil:
+		return r.wait(step.Wait)
+	case step.Leaders != nil:
+		for _, n := range step.Leaders {
+			SimCommand(strconv.Itoa(n))
+			SimCommand("l")
+		}
+	case step.Audits != nil:
+		for _, n := range step.Audits {
+			SimCommand(strconv.Itoa(n))
+			SimCommand("o")
+		}
+	case step.Offline != nil, step.Online != nil:
+		defer pauseSim()()
+		for _, n := range step.Offline {
+			fnodes[n].State.SetNetStateOff(true)
+		}
+		for _, n := range step.Online {
+			fnodes[n].State.SetNetStateOff(false)
+		}
+	case step.Partition != nil:
+		defer pauseSim()()
+		PartitionSimPeers(fnodes, step.Partition)
+	case step.Heal:
+		defer pauseSim()()
+		PartitionSimPeers(fnodes, nil)
+	case step.Fault != nil:
+		defer pauseSim()()
+		return step.Fault.apply(fnodes)
+	case step.Load != nil:
+		SimCommand(fmt.Sprintf("R%d", *step.Load))
+	case step.Command != "":
+		SimCommand(step.Command)
+	case step.Expect != nil:
+		within := r.block()
+		if step.Expect.Within != nil {
+			within = r.duration(step.Expect.Within)
+		}
+		var last error
+		err := r.waitFor(func() bool {
+			last = step.Expect.check(fnodes)
+			return last == nil
+		}, within)
+		if err != nil && last != nil {
+			return fmt.Errorf("%v within %s", last, within)
+		}
+		return err
+	case step.Assert != nil:
+		defer pauseSim()()
+		return step.Assert.check(fnodes)
+	}
+	return nil
+}
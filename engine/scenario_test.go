@@ -0,0 +1,145 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/FactomProject/factomd/engine"
+	"github.com/FactomProject/factomd/state"
+)
+
+func TestParseScenario(t *testing.T) {
+	sc, err := ParseScenario([]byte(`
+name: test
+seed: 42
+nodes: 4
+options:
+  roundtimeout: 4
+steps:
+  - leaders: [1, 2]
+  - wait: {blocks: 1, minute: 4}
+  - partition: [[1], [2, 3]]
+  - load: 0
+  - expect: {leaders: [3], within: {rounds: 2}}
+  - assert: {heights_equal: true}
+  - fault: {from: [1], to: [2], both: true, latency: 500, jitter: 100, distribution: normal}
+  - fault: {to: [3], cut: true}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Name != "test" || sc.Seed != 42 || sc.Nodes != 4 || sc.BlockTime != 10 || sc.Options["roundtimeout"] != "4" {
+		t.Errorf("Wrong scenario %+v", sc)
+	}
+	expected := []string{
+		"leaders [1 2]",
+		"wait 1 blocks minute 4",
+		"partition [[1] [2 3]]",
+		"load 0/s",
+		"expect leaders [3], within 2 rounds",
+		"assert heights equal",
+		"fault [1] <-> [2] latency 500ms+normal 100ms",
+		"fault all -> [3] cut",
+	}
+	if len(sc.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %d", len(expected), len(sc.Steps))
+	}
+	for i, step := range sc.Steps {
+		if step.String() != expected[i] {
+			t.Errorf("Step %d is %q, expected %q", i+1, step.String(), expected[i])
+		}
+	}
+}
+
+func TestParseScenarioJSON(t *testing.T) {
+	sc, err := ParseScenario([]byte(`{"nodes": 3, "blocktime": 6, "steps": [{"offline": [2]}, {"wait": {"seconds": 1.5}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.BlockTime != 6 || len(sc.Steps) != 2 || sc.Steps[1].String() != "wait 1.5s" {
+		t.Errorf("Wrong scenario %+v", sc)
+	}
+}
+
+func TestScenarioValidation(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		err      string
+	}{
+		{`steps: []`, "at least one node"},
+		{`{nodes: 2, steps: [{leaders: [2]}]}`, "No node 2"},
+		{`{nodes: 2, steps: [{leaders: [1], audits: [0]}]}`, "exactly one thing"},
+		{`{nodes: 2, steps: [{}]}`, "exactly one thing"},
+		{`{nodes: 2, steps: [{wait: {rounds: 1}}]}`, "only for within"},
+		{`{nodes: 2, steps: [{wait: {minute: 10}}]}`, "Invalid minute"},
+		{`{nodes: 2, steps: [{assert: {heights_equal: true, within: {blocks: 1}}}]}`, "use expect"},
+		{`{nodes: 2, steps: [{expect: {leaders: [0], within: {minute: 1}}}]}`, "amount of time"},
+		{`{nodes: 2, steps: [{partition: [[0], [5]]}]}`, "No node 5"},
+		{`{nodes: 2, steps: [{elect: [1]}]}`, "not found"},
+		{`{nodes: 2, steps: [{fault: {from: [3], cut: true}}]}`, "No node 3"},
+		{`{nodes: 2, steps: [{fault: {droprate: 2000}}]}`, "out of 1000"},
+		{`{nodes: 2, steps: [{fault: {latency: 5, spread: 2}}]}`, "not found"},
+	} {
+		_, err := ParseScenario([]byte(c.scenario))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected an error with %q, got %v", c.scenario, c.err, err)
+		}
+	}
+}
+
+func TestScenarioFiles(t *testing.T) {
+	files, err := filepath.Glob("scenarios/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No scenarios found")
+	}
+	for _, file := range files {
+		if _, err := LoadScenario(file); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestPartitionSimPeers(t *testing.T) {
+	var nodes []*FactomNode
+	for _, name := range []string{"FNode0", "FNode01", "FNode02", "FNode03"} {
+		s := new(state.State)
+		s.FactomNodeName = name
+		nodes = append(nodes, &FactomNode{State: s})
+	}
+	for i := range nodes {
+		for j := range nodes {
+			AddSimPeer(nodes, i, j)
+		}
+	}
+
+	cut := func() map[string]bool {
+		links := make(map[string]bool)
+		for _, fn := range nodes {
+			for _, p := range fn.Peers {
+				sim := p.(*SimPeer)
+				links[sim.FromName+"-"+sim.ToName] = sim.IsCut()
+			}
+		}
+		return links
+	}
+
+	PartitionSimPeers(nodes, [][]int{{1, 2}})
+	for link, isCut := range cut() {
+		inGroup := strings.Contains(link, "FNode01") || strings.Contains(link, "FNode02")
+		across := inGroup && !(strings.Contains(link, "FNode01") && strings.Contains(link, "FNode02"))
+		if isCut != across {
+			t.Errorf("Link %s cut %v", link, isCut)
+		}
+	}
+
+	PartitionSimPeers(nodes, nil)
+	for link, isCut := range cut() {
+		if isCut {
+			t.Errorf("Link %s still cut after healing", link)
+		}
+	}
+}
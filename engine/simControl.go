@@ -27,6 +27,7 @@ import (
 	elections2 "github.com/FactomProject/factomd/elections"
 	"github.com/FactomProject/factomd/p2p"
 	"github.com/FactomProject/factomd/wsapi"
+	"gopkg.in/yaml.v2"
 )
 
 var _ = fmt.Print
@@ -101,13 +102,16 @@ func SimControl(listenTo int, listenStdin bool) {
 
 	ListenTo = listenTo
 
+	resume := func() {}
 	for {
+		resume() // the simulation runs while we wait for the next command
 		// This splits up the command at anycodepoint that is not a letter, number or punctuation, so usually by spaces.
 		parseFunc := func(c rune) bool {
 			return !unicode.IsLetter(c) && !unicode.IsNumber(c) && !unicode.IsPunct(c)
 		}
 		// cmd is not a list of the parameters, much like command line args show up in args[]
 		cmd := strings.FieldsFunc(GetLine(listenStdin), parseFunc)
+		resume = pauseSim()
 		// fmt.Printf("Parsing command, found %d elements.  The first element is: %+v / %s \n Full command: %+v\n", len(cmd), b[0], string(b), cmd)
 
 		switch {
@@ -1121,6 +1125,26 @@ func SimControl(listenTo int, listenStdin bool) {
 						}
 					}
 				}
+			case 'X' == b[0]:
+				if line := strings.Join(cmd, " "); len(line) > 1 {
+					fault := new(ScenarioFault)
+					err := yaml.UnmarshalStrict([]byte(line[1:]), fault)
+					if err == nil {
+						err = ValidateLinkFault(fault.LinkFault)
+					}
+					if err == nil {
+						err = fault.apply(fnodes)
+					}
+					if err != nil {
+						os.Stderr.WriteString(fmt.Sprintf("Bad link fault: %v\n", err))
+						break
+					}
+				}
+				for _, link := range SimLinks(fnodes) {
+					if link.Fault != (interfaces.LinkFault{}) {
+						os.Stderr.WriteString(fmt.Sprintf("%10s -> %-10s %s\n", link.From, link.To, LinkFaultString(link.Fault)))
+					}
+				}
 			case 'J' == b[0]:
 				elect := fnodes[listenTo].State.Elections.(*elections2.Elections)
 				flist := elect.Federated
@@ -1393,6 +1417,8 @@ func SimControl(listenTo int, listenStdin bool) {
 				os.Stderr.WriteString("Onnn          Set Drop Rate to nnn on this node\n")
 				os.Stderr.WriteString("Dnnn          Set the Delay on messages from the current node to nnn milliseconds\n")
 				os.Stderr.WriteString("Fnnn          Set the Delay on messages from all nodes to nnn milliseconds\n")
+				os.Stderr.WriteString("X{...}        Set the fault model of links, eg: X{from: [1], to: [2], both: true, latency: 500, jitter: 100, droprate: 10}\n")
+				os.Stderr.WriteString("                 Also cut, distribution (uniform/normal/exponential), bandwidth, reorder, duplicate. X lists faulty links\n")
 				os.Stderr.WriteString("/             Toggle the sort order between ChainID and Factom Node Name\n")
 				os.Stderr.WriteString("Pnnn          Set's the efficiency of the given node to nnn\n")
 				os.Stderr.WriteString("B             Set's the coinbase address to a random one. Tyoe BFA... for a specific\n")
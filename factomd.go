@@ -16,7 +16,13 @@ import (
 
 func main() {
 	// uncomment StartProfiler() to run the pprof tool (for testing)
+	if len(os.Args) > 1 && os.Args[1] == "identity" {
+		os.Exit(RunIdentityCommand(os.Args[2:]))
+	}
 	params := ParseCmdLine(os.Args[1:])
+	if params.Scenario != "" {
+		os.Exit(RunScenarioFile(params.Scenario, params))
+	}
 
 	//  Go Optimizations...
 	runtime.GOMAXPROCS(runtime.NumCPU()) // TODO: should be *2 to use hyperthreadding? -- clay
@@ -26,6 +32,9 @@ func main() {
 	sim_Stdin := params.Sim_Stdin
 
 	state := Factomd(params, sim_Stdin)
+	if params.SimSeed != 0 {
+		go RunSimForever()
+	}
 	for state.Running() {
 		time.Sleep(time.Second)
 	}
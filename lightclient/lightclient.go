@@ -0,0 +1,298 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+// Package lightclient follows a Factom network without its full blocks. It syncs the directory block
+// headers and the admin blocks, tracks the authority set from the admin block entries, and checks the
+// DBSigs of every block. Entries and factoid transactions are then checked against the receipts of a
+// full node: the Merkle proof of a receipt has to end in the KeyMR of a signed directory block.
+package lightclient
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/receipts"
+)
+
+type Client struct {
+	Source Source
+
+	// Headers of the synced directory blocks, by height, and their KeyMRs
+	Headers []interfaces.IDirectoryBlockHeader
+	KeyMRs  []interfaces.IHash
+	// The authority set after the last synced block
+	Identities *identity.IdentityManager
+
+	bootstrapKey interfaces.IHash
+	heights      map[[32]byte]uint32 // KeyMR -> height
+}
+
+// New returns a client that starts from the genesis block of a network, trusting the identity and key
+// that sign its first blocks (see State.GetNetworkBootStrapIdentity)
+func New(source Source, bootstrapIdentity, bootstrapKey interfaces.IHash) *Client {
+	c := new(Client)
+	c.Source = source
+	c.bootstrapKey = bootstrapKey
+	c.Identities = identity.NewIdentityManager()
+	c.Identities.SetBootstrapIdentity(bootstrapIdentity, bootstrapKey)
+	c.heights = make(map[[32]byte]uint32)
+	return c
+}
+
+// VerifiedHeight returns the height of the highest directory block whose DBSigs were checked, -1 if
+// there is none. The DBSigs of a block are in the admin block of the next one.
+func (c *Client) VerifiedHeight() int {
+	return len(c.Headers) - 2
+}
+
+// Sync gets and checks the blocks from the last synced block to the highest block of the source, and
+// returns how many it added
+func (c *Client) Sync() (int, error) {
+	top, err := c.Source.Height()
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for h := uint32(len(c.Headers)); h <= top; h++ {
+		dBlock, err := c.Source.DBlock(h)
+		if err != nil {
+			return added, fmt.Errorf("DBlock %d: %v", h, err)
+		}
+		aBlock, err := c.Source.ABlock(h)
+		if err != nil {
+			return added, fmt.Errorf("ABlock %d: %v", h, err)
+		}
+		if err := c.AddBlock(dBlock, aBlock); err != nil {
+			return added, fmt.Errorf("Block %d: %v", h, err)
+		}
+		added++
+	}
+	return added, nil
+}
+
+// AddBlock checks the next directory block and its admin block, and adds the header of the directory
+// block. The DBSigs in the admin block must sign the previous header by a majority of the federated
+// servers, then the admin block entries update the authority set.
+func (c *Client) AddBlock(dBlock interfaces.IDirectoryBlock, aBlock interfaces.IAdminBlock) error {
+	height := uint32(len(c.Headers))
+	header := dBlock.GetHeader()
+	if header.GetDBHeight() != height {
+		return fmt.Errorf("Expected the directory block %d, got %d", height, header.GetDBHeight())
+	}
+
+	// BuildBodyMR overwrites the BodyMR of the header, keep the one the block claims
+	claimed := primitives.NewHash(header.GetBodyMR().Bytes())
+	bodyMR, err := dBlock.BuildBodyMR()
+	if err != nil {
+		return err
+	}
+	if !bodyMR.IsSameAs(claimed) {
+		return fmt.Errorf("The body of the directory block doesn't match its BodyMR")
+	}
+	keyMR := dBlock.GetKeyMR()
+
+	prevKeyMR := interfaces.IHash(primitives.NewZeroHash())
+	if height > 0 {
+		prevKeyMR = c.KeyMRs[height-1]
+	}
+	if !header.GetPrevKeyMR().IsSameAs(prevKeyMR) {
+		return fmt.Errorf("The directory block doesn't follow the block %d", height-1)
+	}
+
+	entries := dBlock.GetDBEntries()
+	if len(entries) == 0 || !entries[0].GetChainID().IsSameAs(primitives.NewHash(constants.ADMIN_CHAINID)) {
+		return fmt.Errorf("The directory block has no admin block")
+	}
+	if !entries[0].GetKeyMR().IsSameAs(aBlock.DatabasePrimaryIndex()) {
+		return fmt.Errorf("The admin block isn't the one of the directory block")
+	}
+	if aBlock.GetDBHeight() != height {
+		return fmt.Errorf("The admin block is for the height %d", aBlock.GetDBHeight())
+	}
+
+	if height > 0 {
+		if err := c.checkDBSigs(aBlock, c.Headers[height-1]); err != nil {
+			return err
+		}
+	}
+
+	// Like the node, an entry for an unknown authority changes nothing
+	for _, entry := range aBlock.GetABEntries() {
+		c.Identities.ProcessABlockEntry(entry, nil)
+	}
+
+	c.Headers = append(c.Headers, header)
+	c.KeyMRs = append(c.KeyMRs, keyMR)
+	c.heights[keyMR.Fixed()] = height
+	return nil
+}
+
+// checkDBSigs checks the DBSigs of an admin block sign the previous header, by more than half the
+// federated servers of the authority set
+func (c *Client) checkDBSigs(aBlock interfaces.IAdminBlock, prev interfaces.IDirectoryBlockHeader) error {
+	data, err := prev.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	signed := make(map[[32]byte]bool)
+	for _, entry := range aBlock.GetABEntries() {
+		if entry.Type() != constants.TYPE_DB_SIGNATURE {
+			continue
+		}
+		dbs, ok := entry.(*adminBlock.DBSignatureEntry)
+		if !ok {
+			continue
+		}
+		if signer, valid := c.validDBSig(dbs, data); valid {
+			signed[signer] = true
+		}
+	}
+
+	feds := c.Identities.FedServerCount()
+	if len(signed) <= feds/2 {
+		return fmt.Errorf("Only %d of %d federated servers signed the block %d", len(signed), feds, prev.GetDBHeight())
+	}
+	return nil
+}
+
+// validDBSig checks a DBSig, and returns who it counts for: the authority chain of a federated server,
+// or the bootstrap key. The bootstrap key counts once, whatever chain the DBSig claims.
+func (c *Client) validDBSig(dbs *adminBlock.DBSignatureEntry, data []byte) ([32]byte, bool) {
+	auth := c.Identities.GetAuthority(dbs.IdentityAdminChainID)
+	if auth != nil && auth.Status == constants.IDENTITY_FEDERATED_SERVER {
+		if valid, _ := auth.VerifySignature(data, dbs.PrevDBSig.GetSignature()); valid {
+			if c.isBootstrapKey(auth.SigningKey[:]) {
+				return c.bootstrapKey.Fixed(), true
+			}
+			return dbs.IdentityAdminChainID.Fixed(), true
+		}
+	}
+	// Like DBStateMsg.SigTally, the bootstrap key of the network can sign
+	if c.isBootstrapKey(dbs.PrevDBSig.GetKey()) && dbs.PrevDBSig.Verify(data) {
+		return c.bootstrapKey.Fixed(), true
+	}
+	return [32]byte{}, false
+}
+
+func (c *Client) isBootstrapKey(key []byte) bool {
+	return c.bootstrapKey != nil && bytes.Compare(key, c.bootstrapKey.Bytes()) == 0
+}
+
+// VerifyEntry gets the receipt of an entry and checks it, and returns the height of the directory block
+// the entry is in
+func (c *Client) VerifyEntry(entryHash interfaces.IHash) (uint32, error) {
+	receipt, err := c.Source.Receipt(entryHash)
+	if err != nil {
+		return 0, err
+	}
+	if receipt.Entry == nil || receipt.Entry.EntryHash != entryHash.String() {
+		return 0, fmt.Errorf("The receipt is not for the entry %x", entryHash.Bytes())
+	}
+	return c.VerifyReceipt(receipt)
+}
+
+// VerifyTransaction gets the receipt of a factoid transaction, by its transaction ID or its full hash,
+// and checks it. It returns the transaction and the height of the directory block it is in.
+func (c *Client) VerifyTransaction(txID interfaces.IHash) (interfaces.ITransaction, uint32, error) {
+	receipt, err := c.Source.Receipt(txID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if receipt.Entry == nil || receipt.Entry.Raw == "" {
+		return nil, 0, fmt.Errorf("The receipt has no transaction")
+	}
+	raw, err := primitives.DecodeBinary(receipt.Entry.Raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	tx := new(factoid.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, 0, err
+	}
+	// The receipt proves the full hash, which has to be the one of the transaction asked for
+	if receipt.Entry.EntryHash != tx.GetHash().String() {
+		return nil, 0, fmt.Errorf("The receipt doesn't prove the transaction it holds")
+	}
+	if !tx.GetSigHash().IsSameAs(txID) && !tx.GetHash().IsSameAs(txID) {
+		return nil, 0, fmt.Errorf("The receipt is not for the transaction %x", txID.Bytes())
+	}
+	height, err := c.VerifyReceipt(receipt)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, height, nil
+}
+
+// VerifyReceipt checks the Merkle proof of a receipt ends in a signed directory block, and returns the
+// height of the block
+func (c *Client) VerifyReceipt(receipt *receipts.Receipt) (uint32, error) {
+	if err := receipt.Validate(); err != nil {
+		return 0, err
+	}
+	height, ok := c.heights[receipt.DirectoryBlockKeyMR.Fixed()]
+	if !ok {
+		return 0, fmt.Errorf("The directory block %x is not a synced block", receipt.DirectoryBlockKeyMR.Bytes())
+	}
+	if int(height) > c.VerifiedHeight() {
+		return 0, fmt.Errorf("The directory block %d is not signed yet", height)
+	}
+	return height, nil
+}
+
+// MarshalBinary saves the headers and the authority set, so the client can go on from the last synced
+// block
+func (c *Client) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+	if err := buf.PushVarInt(uint64(len(c.Headers))); err != nil {
+		return nil, err
+	}
+	for _, header := range c.Headers {
+		if err := buf.PushBinaryMarshallable(header); err != nil {
+			return nil, err
+		}
+	}
+	if err := buf.PushBinaryMarshallable(c.Identities); err != nil {
+		return nil, err
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (c *Client) UnmarshalBinary(data []byte) error {
+	buf := primitives.NewBuffer(data)
+	count, err := buf.PopVarInt()
+	if err != nil {
+		return err
+	}
+	c.Headers = nil
+	c.KeyMRs = nil
+	c.heights = make(map[[32]byte]uint32)
+	for i := uint64(0); i < count; i++ {
+		header := directoryBlock.NewDBlockHeader()
+		if err := buf.PopBinaryMarshallable(header); err != nil {
+			return err
+		}
+		headerHash, err := header.GetHeaderHash()
+		if err != nil {
+			return err
+		}
+		keyMR := primitives.HashMerkleBranches(headerHash, header.GetBodyMR())
+		c.Headers = append(c.Headers, header)
+		c.KeyMRs = append(c.KeyMRs, keyMR)
+		c.heights[keyMR.Fixed()] = uint32(i)
+	}
+	im := identity.NewIdentityManager()
+	if err := buf.PopBinaryMarshallable(im); err != nil {
+		return err
+	}
+	c.Identities = im
+	return nil
+}
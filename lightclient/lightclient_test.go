@@ -0,0 +1,339 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package lightclient_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/database/databaseOverlay"
+	. "github.com/FactomProject/factomd/lightclient"
+	"github.com/FactomProject/factomd/receipts"
+	. "github.com/FactomProject/factomd/testHelper"
+)
+
+// The bootstrap identity and key of the local network
+var (
+	bootstrapID, _  = primitives.HexToHash("38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9")
+	bootstrapKey, _ = primitives.NewPrivateKeyFromHex("4c38c72fc5cdad68f13b74674d3ffb1f3d63a112710868c9b08946553448d26d")
+	bootstrap       = signer{bootstrapID, bootstrapKey}
+)
+
+type signer struct {
+	id  interfaces.IHash
+	key *primitives.PrivateKey
+}
+
+// memSource serves the blocks of a database, like the API of a full node
+type memSource struct {
+	dbo    *databaseOverlay.Overlay
+	height uint32
+}
+
+func (m *memSource) Height() (uint32, error) {
+	return m.height, nil
+}
+
+func (m *memSource) DBlock(height uint32) (interfaces.IDirectoryBlock, error) {
+	block, err := m.dbo.FetchDBlockByHeight(height)
+	if err == nil && block == nil {
+		err = fmt.Errorf("Block not found")
+	}
+	return block, err
+}
+
+func (m *memSource) ABlock(height uint32) (interfaces.IAdminBlock, error) {
+	block, err := m.dbo.FetchABlockByHeight(height)
+	if err == nil && block == nil {
+		err = fmt.Errorf("Block not found")
+	}
+	return block, err
+}
+
+func (m *memSource) Receipt(hash interfaces.IHash) (*receipts.Receipt, error) {
+	receipt, err := receipts.CreateFullReceipt(m.dbo, hash)
+	if err != nil {
+		return receipts.CreateTransactionReceipt(m.dbo, hash)
+	}
+	return receipt, nil
+}
+
+// newChain saves the test blocks, with the DBSigs of the signers of every height. edit can change the
+// admin block of a height before it is signed.
+func newChain(signers func(height int) []signer, edit func(height int, set *BlockSet)) (*memSource, []*BlockSet) {
+	sets := CreateFullTestBlockSet()
+	dbo := CreateEmptyTestDatabaseOverlay()
+	for i, set := range sets {
+		if edit != nil {
+			edit(i, set)
+		}
+		if i > 0 {
+			prev := sets[i-1].DBlock
+			prev.GetKeyMR()
+			data, err := prev.GetHeader().MarshalBinary()
+			if err != nil {
+				panic(err)
+			}
+			for _, s := range signers(i) {
+				set.ABlock.AddDBSig(s.id, s.key.Sign(data))
+			}
+			set.DBlock.GetHeader().SetPrevKeyMR(prev.GetKeyMR())
+			set.DBlock.GetHeader().SetPrevFullHash(prev.GetFullHash())
+		}
+		set.DBlock.GetDBEntries()[0].SetKeyMR(set.ABlock.DatabasePrimaryIndex())
+		set.DBlock.GetKeyMR()
+
+		save := []error{
+			dbo.ProcessABlockBatch(set.ABlock),
+			dbo.ProcessEBlockBatch(set.EBlock, true),
+			dbo.ProcessEBlockBatch(set.AnchorEBlock, true),
+			dbo.ProcessECBlockBatch(set.ECBlock, false),
+			dbo.ProcessFBlockBatch(set.FBlock),
+			dbo.ProcessDBlockBatch(set.DBlock),
+		}
+		for _, entry := range set.Entries {
+			save = append(save, dbo.InsertEntry(entry))
+		}
+		for _, err := range save {
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	return &memSource{dbo, uint32(len(sets) - 1)}, sets
+}
+
+func signedBy(signers ...signer) func(int) []signer {
+	return func(int) []signer { return signers }
+}
+
+func newClient(source Source) *Client {
+	return New(source, bootstrapID, primitives.NewHash(bootstrapKey.Pub[:]))
+}
+
+func TestSync(t *testing.T) {
+	source, sets := newChain(signedBy(bootstrap), nil)
+	c := newClient(source)
+	added, err := c.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != len(sets) || c.VerifiedHeight() != len(sets)-2 {
+		t.Errorf("Synced %d blocks to the verified height %d, expected %d to %d", added, c.VerifiedHeight(), len(sets), len(sets)-2)
+	}
+	for i, set := range sets {
+		if !c.KeyMRs[i].IsSameAs(set.DBlock.GetKeyMR()) {
+			t.Errorf("Wrong KeyMR at height %d", i)
+		}
+	}
+
+	if added, err := c.Sync(); added != 0 || err != nil {
+		t.Errorf("Synced %d blocks again, %v", added, err)
+	}
+}
+
+func TestSyncBadSignatures(t *testing.T) {
+	stranger := signer{primitives.Sha([]byte("stranger")), primitives.RandomPrivateKey()}
+	source, _ := newChain(func(height int) []signer {
+		if height == 3 {
+			return []signer{stranger}
+		}
+		return []signer{bootstrap}
+	}, nil)
+
+	c := newClient(source)
+	if _, err := c.Sync(); err == nil || !strings.Contains(err.Error(), "Block 3") {
+		t.Errorf("Expected the block 3 to fail, got %v", err)
+	}
+	if len(c.Headers) != 3 {
+		t.Errorf("Expected the blocks before the bad one, got %d", len(c.Headers))
+	}
+}
+
+func TestSyncBadBody(t *testing.T) {
+	source, sets := newChain(signedBy(bootstrap), nil)
+	c := newClient(source)
+	for i, set := range sets[:4] {
+		dBlock, aBlock := set.DBlock, interfaces.IAdminBlock(set.ABlock)
+		if i == 3 {
+			// The header doesn't commit to a body with another entry block
+			dBlock.GetHeader().SetBodyMR(primitives.Sha([]byte("body")))
+		}
+		err := c.AddBlock(dBlock, aBlock)
+		if i < 3 && err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 && err == nil {
+			t.Error("Added a directory block whose body doesn't match its header")
+		}
+	}
+}
+
+func TestSyncAuthoritySet(t *testing.T) {
+	fed := signer{primitives.Sha([]byte("fed")), primitives.RandomPrivateKey()}
+	promote := func(height int, set *BlockSet) {
+		if height == 4 {
+			set.ABlock.AddFedServer(fed.id)
+			set.ABlock.AddFederatedServerSigningKey(fed.id, *fed.key.Pub)
+			set.ABlock.InsertIdentityABEntries()
+		}
+	}
+
+	// From the block 5 on, both federated servers must sign
+	source, _ := newChain(signedBy(bootstrap), promote)
+	c := newClient(source)
+	if _, err := c.Sync(); err == nil || !strings.Contains(err.Error(), "Block 5") {
+		t.Errorf("Expected the block 5 to fail, got %v", err)
+	}
+
+	source, _ = newChain(func(height int) []signer {
+		if height > 4 {
+			return []signer{bootstrap, fed}
+		}
+		return []signer{bootstrap}
+	}, promote)
+	c = newClient(source)
+	if _, err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Identities.FedServerCount() != 2 {
+		t.Errorf("Expected 2 federated servers, got %d", c.Identities.FedServerCount())
+	}
+}
+
+func TestSyncBootstrapSignsOnce(t *testing.T) {
+	feds := []signer{
+		{primitives.Sha([]byte("fed1")), primitives.RandomPrivateKey()},
+		{primitives.Sha([]byte("fed2")), primitives.RandomPrivateKey()},
+	}
+	promote := func(height int, set *BlockSet) {
+		if height == 4 {
+			for _, fed := range feds {
+				set.ABlock.AddFedServer(fed.id)
+				set.ABlock.AddFederatedServerSigningKey(fed.id, *fed.key.Pub)
+			}
+			set.ABlock.InsertIdentityABEntries()
+		}
+	}
+
+	// The bootstrap key claims the chains of the other federated servers too
+	source, _ := newChain(func(height int) []signer {
+		if height > 4 {
+			return []signer{bootstrap, {feds[0].id, bootstrapKey}, {feds[1].id, bootstrapKey}}
+		}
+		return []signer{bootstrap}
+	}, promote)
+	c := newClient(source)
+	if _, err := c.Sync(); err == nil || !strings.Contains(err.Error(), "Block 5") {
+		t.Errorf("Expected the block 5 to fail, got %v", err)
+	}
+
+	source, _ = newChain(func(height int) []signer {
+		if height > 4 {
+			return []signer{bootstrap, {feds[0].id, bootstrapKey}, feds[1]}
+		}
+		return []signer{bootstrap}
+	}, promote)
+	c = newClient(source)
+	if _, err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyEntry(t *testing.T) {
+	source, sets := newChain(signedBy(bootstrap), nil)
+	c := newClient(source)
+	if _, err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, set := range sets {
+		entry := set.Entries[0].GetHash()
+		height, err := c.VerifyEntry(entry)
+		if i == len(sets)-1 {
+			// Nothing signs the last block yet
+			if err == nil {
+				t.Errorf("Verified an entry of a block that isn't signed")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Entry of the block %d: %v", i, err)
+		} else if height != uint32(i) {
+			t.Errorf("Entry of the block %d is at the height %d", i, height)
+		}
+	}
+
+	// A receipt of a block the client doesn't know
+	receipt, err := source.Receipt(sets[2].Entries[0].GetHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := newClient(source)
+	if _, err := fresh.VerifyReceipt(receipt); err == nil {
+		t.Error("Verified a receipt of a block that isn't synced")
+	}
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	source, sets := newChain(signedBy(bootstrap), nil)
+	c := newClient(source)
+	if _, err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sets[3].FBlock.GetTransactions()[0]
+	for _, id := range []interfaces.IHash{want.GetSigHash(), want.GetHash()} {
+		tx, height, err := c.VerifyTransaction(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if height != 3 || !tx.GetHash().IsSameAs(want.GetHash()) {
+			t.Errorf("Got the transaction %x at the height %d", tx.GetHash().Bytes(), height)
+		}
+	}
+
+	if _, _, err := c.VerifyTransaction(primitives.Sha([]byte("none"))); err == nil {
+		t.Error("Verified a transaction that doesn't exist")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	source, sets := newChain(signedBy(bootstrap), nil)
+	c := newClient(source)
+	source.height = 5
+	if _, err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c2 := newClient(source)
+	if err := c2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if c2.VerifiedHeight() != c.VerifiedHeight() || c2.Identities.FedServerCount() != 1 {
+		t.Fatalf("Loaded the verified height %d, expected %d", c2.VerifiedHeight(), c.VerifiedHeight())
+	}
+	for i := range c.KeyMRs {
+		if !c2.KeyMRs[i].IsSameAs(c.KeyMRs[i]) {
+			t.Errorf("Wrong KeyMR at height %d", i)
+		}
+	}
+
+	// The loaded client goes on from where it stopped
+	source.height = uint32(len(sets) - 1)
+	if added, err := c2.Sync(); err != nil || added != len(sets)-6 {
+		t.Errorf("Synced %d blocks, %v", added, err)
+	}
+	if _, err := c2.VerifyEntry(sets[1].Entries[0].GetHash()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package lightclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	"github.com/FactomProject/factomd/receipts"
+)
+
+// Source is where a light client gets its blocks and receipts, usually the API of a full node
+type Source interface {
+	// Height returns the height of the highest saved directory block
+	Height() (uint32, error)
+	DBlock(height uint32) (interfaces.IDirectoryBlock, error)
+	ABlock(height uint32) (interfaces.IAdminBlock, error)
+	// Receipt returns the receipt of an entry or a factoid transaction
+	Receipt(hash interfaces.IHash) (*receipts.Receipt, error)
+}
+
+// APISource asks a full node for blocks and receipts on its v2 API, i.e. http://localhost:8088/v2
+type APISource struct {
+	URL string
+}
+
+var _ Source = (*APISource)(nil)
+
+func (a *APISource) Height() (uint32, error) {
+	var heights struct {
+		DirectoryBlockHeight int64 `json:"directoryblockheight"`
+	}
+	if err := a.call("heights", nil, &heights); err != nil {
+		return 0, err
+	}
+	return uint32(heights.DirectoryBlockHeight), nil
+}
+
+func (a *APISource) DBlock(height uint32) (interfaces.IDirectoryBlock, error) {
+	raw, err := a.rawBlock("dblock-by-height", height)
+	if err != nil {
+		return nil, err
+	}
+	return directoryBlock.UnmarshalDBlock(raw)
+}
+
+func (a *APISource) ABlock(height uint32) (interfaces.IAdminBlock, error) {
+	raw, err := a.rawBlock("ablock-by-height", height)
+	if err != nil {
+		return nil, err
+	}
+	return adminBlock.UnmarshalABlock(raw)
+}
+
+func (a *APISource) Receipt(hash interfaces.IHash) (*receipts.Receipt, error) {
+	var resp struct {
+		Receipt *receipts.Receipt `json:"receipt"`
+	}
+	if err := a.call("receipt", map[string]string{"hash": hash.String()}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Receipt == nil {
+		return nil, fmt.Errorf("No receipt for %x", hash.Bytes())
+	}
+	return resp.Receipt, nil
+}
+
+// rawBlock returns the binary of a block from one of the "<block>-by-height" calls
+func (a *APISource) rawBlock(method string, height uint32) ([]byte, error) {
+	var resp struct {
+		RawData string `json:"rawdata"`
+	}
+	if err := a.call(method, map[string]uint32{"height": height}, &resp); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(resp.RawData)
+}
+
+func (a *APISource) call(method string, params interface{}, result interface{}) error {
+	req, err := json.Marshal(primitives.NewJSON2Request(method, 0, params))
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(a.URL, "application/json", bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Result json.RawMessage
+		Error  *primitives.JSONError
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s %s: %v", a.URL, method, err)
+	}
+	if r.Error != nil {
+		return fmt.Errorf("%s %s: %s", a.URL, method, r.Error.Message)
+	}
+	return json.Unmarshal(r.Result, result)
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/FactomProject/factomd/common/primitives"
@@ -48,6 +49,7 @@ type Connection struct {
 	isPersistent    bool              // Persistent connections we always redail.
 	notes           string            // Notes about the connection, for debugging (eg: error)
 	metrics         ConnectionMetrics // Metrics about this connection
+	peerVersion     uint32            // Protocol version of the peer, from the last valid parcel. Accessed atomically.
 
 	// logging
 	logger *log.Entry
@@ -309,6 +311,7 @@ func (c *Connection) dialLoop() {
 			c.goOnline()
 			return
 		}
+		c.peer.FailedConnections++
 		switch {
 		case c.isPersistent:
 		case ConnectionOffline == c.state: // We were online with the peer at one point.
@@ -351,6 +354,7 @@ func (c *Connection) goOnline() {
 	c.timeLastAttempt = now
 	c.timeLastUpdate = now
 	c.peer.LastContact = now
+	c.peer.Connections++
 
 	c.state = ConnectionOnline
 
@@ -585,6 +589,8 @@ func (c *Connection) handleParcel(parcel Parcel) {
 		c.peer.LastContact = time.Now() // We only update for valid messages (incluidng pings and heartbeats)
 		c.attempts = 0                  // reset since we are clearly in touch now.
 		c.peer.merit()                  // Increase peer quality score.
+		c.peer.Version = parcel.Header.Version
+		atomic.StoreUint32(&c.peerVersion, uint32(parcel.Header.Version))
 		c.logger.Debugf("Connection.handleParcel() got ParcelValid %s", parcel.MessageType())
 		c.handleParcelTypes(parcel) // handles both network commands and application messages
 		return
@@ -641,7 +647,8 @@ func (c *Connection) handleParcelTypes(parcel Parcel) {
 		pong := NewParcel(CurrentNetwork, []byte("Pong"))
 		pong.Header.Type = TypePong
 		BlockFreeChannelSend(c.SendChannel, ConnectionParcel{Parcel: *pong})
-	case TypePong: // all we need is the timestamp which is set already
+	case TypePong: // all we need is the timestamp which is set already, and the round trip time of our ping
+		c.peer.updateLatency(time.Since(c.timeLastPing))
 		return
 	case TypePeerRequest:
 		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
@@ -659,11 +666,20 @@ func (c *Connection) handleParcelTypes(parcel Parcel) {
 		parcel.Header.TargetPeer = c.peer.Hash
 		parcel.Header.NodeID = NodeID
 		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+	case TypeInventory:
+		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
+	case TypeGetData:
+		BlockFreeChannelSend(c.ReceiveChannel, ConnectionParcel{Parcel: parcel}) // Controller handles these.
 	default:
 		c.logger.Warn("Got message of unknown type?")
 	}
 }
 
+// SupportsInventory returns true if the peer understands inventory announcements
+func (c *Connection) SupportsInventory() bool {
+	return InventoryProtocolVersion <= uint16(atomic.LoadUint32(&c.peerVersion))
+}
+
 func (c *Connection) pingPeer() {
 	durationLastContact := time.Since(c.peer.LastContact)
 	durationLastPing := time.Since(c.timeLastPing)
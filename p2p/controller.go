@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
@@ -58,6 +59,12 @@ type Controller struct {
 	lastPeerRequest      time.Time        // Last time we asked peers about the peers they know about.
 	specialPeers         map[string]*Peer // special peers (from config file and from the command line params) by peer address
 	partsAssembler       *PartsAssembler  // a data structure that assembles full messages from received message parts
+	inventory            *Inventory       // messages we announced by hash, and the hashes we have seen (inventory gossip)
+	externalAddress      *ExternalAddress // the address other peers can reach us on
+	nat                  natConfig        // NAT traversal setting
+	natStop              chan struct{}    // closed on shutdown to remove the port mapping
+	activations          string           // hash of our activation schedule
+	activationMismatches map[string]bool  // peers with another activation schedule, by peer hash
 
 	// logging
 	logger *log.Entry
@@ -76,6 +83,8 @@ type ControllerInit struct {
 	ConnectionMetricsChannel chan interface{} // Channel on which we put the connection metrics map, periodically.
 	LogPath                  string           // Path for logs
 	LogLevel                 string           // Logging level
+	NAT                      string           // NAT traversal, see ParseNAT()
+	Activations              string           // Hash of our activation schedule, compared with the schedule of the peers
 }
 
 // CommandDialPeer is used to instruct the Controller to dial a peer address
@@ -132,6 +141,26 @@ func (e *CommandBan) String() string {
 	return str
 }
 
+// CommandAdjustAddressQuality is used to instruct the Controller to adjust the quality score
+// of all connections to a peer address
+type CommandAdjustAddressQuality struct {
+	Address    string
+	Adjustment int32
+}
+
+func (e *CommandAdjustAddressQuality) JSONByte() ([]byte, error) {
+	return primitives.EncodeJSON(e)
+}
+
+func (e *CommandAdjustAddressQuality) JSONString() (string, error) {
+	return primitives.EncodeJSONString(e)
+}
+
+func (e *CommandAdjustAddressQuality) String() string {
+	str, _ := e.JSONString()
+	return str
+}
+
 // CommandDisconnect is used to instruct the Controller to disconnect from a peer
 type CommandDisconnect struct {
 	PeerHash string
@@ -186,6 +215,19 @@ func (c *Controller) Init(ci ControllerInit) *Controller {
 	c.lastDiscoveryRequest = time.Now() // Discovery does its own on startup.
 	c.lastConnectionMetricsUpdate = time.Now()
 	c.partsAssembler = new(PartsAssembler).Init()
+	c.inventory = new(Inventory).Init()
+	c.externalAddress = new(ExternalAddress).Init()
+	c.activations = ci.Activations
+	c.activationMismatches = make(map[string]bool)
+	nat, err := ParseNAT(ci.NAT)
+	if err != nil {
+		c.logger.Errorf("Invalid NAT setting, NAT traversal is disabled: %v", err)
+	}
+	c.nat = nat
+	if nat.extIP != nil {
+		c.externalAddress.setFixed(nat.extIP)
+	}
+	c.natStop = make(chan struct{})
 	discovery := new(Discovery).Init(ci.PeersFile, ci.SeedURL)
 	c.discovery = *discovery
 	return c
@@ -199,6 +241,10 @@ func (c *Controller) StartNetwork() {
 	c.listen()
 	// Dial all the gathered special peers
 	c.dialSpecialPeers()
+	// Map our port on the router
+	if c.nat.discover != nil {
+		go c.manageNAT(c.nat.discover, c.natStop)
+	}
 	// Start the runloop
 	go c.runloop()
 }
@@ -229,6 +275,52 @@ func (c *Controller) Disconnect(peerHash string) {
 	BlockFreeChannelSend(c.commandChannel, CommandDisconnect{PeerHash: peerHash})
 }
 
+// KnownPeers returns a copy of the peer database, best reputation first
+func (c *Controller) KnownPeers() []Peer {
+	return c.discovery.knownPeerList()
+}
+
+// SetPeerQuality sets the quality score of a known peer, and adjusts its connections to match
+func (c *Controller) SetPeerQuality(address string, quality int32) error {
+	var delta int32
+	err := c.discovery.editPeer(address, func(peer *Peer) {
+		delta = quality - peer.QualityScore
+		peer.QualityScore = quality
+	})
+	if err != nil {
+		return err
+	}
+	BlockFreeChannelSend(c.commandChannel, CommandAdjustAddressQuality{Address: normalizeAddress(address), Adjustment: delta})
+	return nil
+}
+
+// BanPeer bans a known peer for PeerBanDuration, and disconnects it
+func (c *Controller) BanPeer(address string, reason string) error {
+	err := c.discovery.editPeer(address, func(peer *Peer) {
+		peer.ban(reason, PeerBanDuration)
+	})
+	if err != nil {
+		return err
+	}
+	BlockFreeChannelSend(c.commandChannel, CommandAdjustAddressQuality{Address: normalizeAddress(address), Adjustment: BannedQualityScore})
+	return nil
+}
+
+// UnbanPeer lifts the ban of a known peer. The ban stays in the peer's ban history.
+func (c *Controller) UnbanPeer(address string) error {
+	return c.discovery.editPeer(address, func(peer *Peer) {
+		peer.BannedUntil = time.Time{}
+		if MinumumQualityScore > peer.QualityScore { // give the peer a fresh start, or we won't talk to it
+			peer.QualityScore = 0
+		}
+	})
+}
+
+// RemovePeer deletes a peer from the peer database
+func (c *Controller) RemovePeer(address string) error {
+	return c.discovery.removePeer(address)
+}
+
 func (c *Controller) GetNumberOfConnections() int {
 	return c.connections.Count()
 }
@@ -377,7 +469,7 @@ func (c *Controller) parseSpecialPeers(peersString string, peerType uint8) []*Pe
 	for _, peerAddress := range peerAddresses {
 		address, port, err := net.SplitHostPort(peerAddress)
 		if err != nil {
-			c.logger.Errorf("%s is not a valid peer (%v), use format: 127.0.0.1:8999", peersString, err)
+			c.logger.Errorf("%s is not a valid peer (%v), use format: 127.0.0.1:8999 or [::1]:8999", peersString, err)
 		} else {
 			peer := new(Peer).Init(address, port, 0, peerType, 0)
 			peer.Source["Local-Configuration"] = time.Now()
@@ -423,6 +515,7 @@ func (c *Controller) runloop() {
 
 // Route pulls all of the messages from the application and sends them to the appropriate
 // peer. Broadcast messages go to everyone, directed messages go to the named peer.
+// Inventory broadcasts are announced by hash, and only sent to the peers asking for them.
 // route also passes incoming messages on to the application.
 func (c *Controller) route() {
 	// Receive messages from the peers & forward to application.
@@ -454,6 +547,9 @@ func (c *Controller) route() {
 		case BroadcastFlag: // Send to many peers
 			c.broadcast(parcel, false)
 
+		case InventoryBroadcastFlag: // Announce to many peers
+			c.announce(parcel)
+
 		case RandomPeerFlag: // Find a random peer, send to that peer.
 			c.sendToRandomPeer(parcel)
 		default: // Check if we're connected to the peer, if not drop message.
@@ -461,6 +557,7 @@ func (c *Controller) route() {
 			c.doDirectedSend(parcel)
 		}
 	}
+	c.inventory.cleanup()
 }
 
 func (c *Controller) doDirectedSend(parcel Parcel) {
@@ -479,20 +576,42 @@ func (c *Controller) handleParcelReceive(message interface{}, peerHash string, c
 	switch parcel.Header.Type {
 	case TypeMessage: // Application message, send it on.
 		ApplicationMessagesReceived++
+		c.inventory.markKnown(parcel.Header.AppHash)
 		BlockFreeChannelSend(c.FromNetwork, parcel)
 	case TypeMessagePart: // A part of the application message, handle by assembler and if we have the full message, send it on.
 		assembled := c.partsAssembler.handlePart(parcel)
 		if assembled != nil {
 			ApplicationMessagesReceived++
+			c.inventory.markKnown(parcel.Header.AppHash)
 			BlockFreeChannelSend(c.FromNetwork, *assembled)
 		}
+	case TypeInventory: // A peer announces an application message, ask for it if it is new to us.
+		if c.inventory.shouldRequest(parcel.Header.AppHash) {
+			p2pInventoryRequested.Inc()
+			request := NewParcel(CurrentNetwork, []byte("GetData"))
+			request.Header.Type = TypeGetData
+			request.Header.AppHash = parcel.Header.AppHash
+			request.Header.AppType = parcel.Header.AppType
+			BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: *request})
+		}
+	case TypeGetData: // A peer asks for an application message we announced, send it over its connection.SendChannel
+		for _, part := range c.inventory.get(parcel.Header.AppHash) {
+			p2pInventoryServed.Inc()
+			BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: part})
+		}
 	case TypePeerRequest: // send a response to the connection over its connection.SendChannel
 		// Get selection of peers from discovery
 		response := NewParcel(CurrentNetwork, c.discovery.SharePeers())
 		response.Header.Type = TypePeerResponse
+		response.Header.ObservedAddress = connection.peer.Address
+		response.Header.AdvertisedAddress = c.externalAddress.Address()
+		response.Header.Activations = c.activations
 		// Send them out to the network - on the connection that requested it!
 		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: *response})
 	case TypePeerResponse:
+		// The peer tells us where our connection comes from, which helps to find our external address
+		c.externalAddress.observed(parcel.Header.ObservedAddress, peerHash)
+		c.checkActivations(parcel.Header.Activations, peerHash)
 		// Add these peers to our known peers
 		c.discovery.LearnPeers(parcel)
 	default:
@@ -501,6 +620,17 @@ func (c *Controller) handleParcelReceive(message interface{}, peerHash string, c
 
 }
 
+// checkActivations warns once per peer about a peer with another activation schedule, which forks from us
+// at the first activation we disagree on
+func (c *Controller) checkActivations(activations string, peerHash string) {
+	if activations == "" || c.activations == "" || activations == c.activations || c.activationMismatches[peerHash] {
+		return
+	}
+	c.activationMismatches[peerHash] = true
+	p2pActivationMismatches.Inc()
+	c.logger.Errorf("Peer %s has the activation schedule %s, ours is %s. Check the activations of the network", peerHash, activations, c.activations)
+}
+
 func (c *Controller) handleConnectionCommand(command ConnectionCommand, connection *Connection) {
 	switch command.Command {
 	case ConnectionUpdateMetrics:
@@ -526,9 +656,23 @@ func (c *Controller) handleCommand(command interface{}) {
 
 		parameters := command.(CommandAddPeer)
 		conn := parameters.conn // net.Conn
-		addPort := strings.Split(conn.RemoteAddr().String(), ":")
+		address, port, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil {
+			c.logger.Errorf("handleCommand() CommandAddPeer got an invalid remote address %s: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
 		// Port initially stored will be the connection port (not the listen port), but peer will update it on first message.
-		peer := new(Peer).Init(addPort[0], addPort[1], 0, RegularPeer, 0)
+		peer := new(Peer).Init(address, port, 0, RegularPeer, 0)
+		if c.discovery.isPeerPresent(*peer) {
+			known := c.discovery.getPeer(peer.Address)
+			if known.IsBanned() {
+				c.logger.Infof("handleCommand() CommandAddPeer refused banned peer %s", address)
+				conn.Close()
+				return
+			}
+			peer.mergeHistory(known)
+		}
 		peer.Source["Accept()"] = time.Now()
 		connection := new(Connection).InitWithConn(conn, *peer)
 		c.handleNewConnection(connection)
@@ -541,7 +685,19 @@ func (c *Controller) handleCommand(command interface{}) {
 	case CommandBan:
 		parameters := command.(CommandBan)
 		peerHash := parameters.PeerHash
+		if connection, present := c.connections.GetByHash(peerHash); present {
+			c.discovery.editPeer(connection.peer.Address, func(peer *Peer) {
+				peer.ban("banned by the application", PeerBanDuration)
+			})
+		}
 		c.applicationPeerUpdate(BannedQualityScore, peerHash)
+	case CommandAdjustAddressQuality:
+		parameters := command.(CommandAdjustAddressQuality)
+		for peerHash, connection := range c.connections.All() {
+			if connection.peer.Address == parameters.Address {
+				c.applicationPeerUpdate(parameters.Adjustment, peerHash)
+			}
+		}
 	case CommandDisconnect:
 		parameters := command.(CommandDisconnect)
 		connection, present := c.connections.GetByHash(parameters.PeerHash)
@@ -613,6 +769,8 @@ func (c *Controller) managePeers() {
 
 func (c *Controller) fillOutgoingSlots(openSlots int) {
 	peers := c.discovery.GetOutgoingPeers()
+	// Of the diverse set of candidates, dial the ones with the best track record first
+	sort.Stable(sort.Reverse(PeerReputationSort(peers)))
 
 	// To avoid dialing "too many" peers, we are keeping a count and only dialing the number of peers we need to add.
 	newPeers := 0
@@ -654,12 +812,51 @@ func (c *Controller) updateMetrics() {
 func (c *Controller) shutdown() {
 	c.logger.Debug("Controller.shutdown()")
 	c.connections.SendToAll(ConnectionCommand{Command: ConnectionShutdownNow})
+	if c.natStop != nil {
+		close(c.natStop)
+		c.natStop = nil
+	}
 	c.keepRunning = false
 }
 
 // Broadcasts the parcel to a number of peers: all special peers and a random selection
 // of regular peers (total max NumberPeersToBroadcast).
 func (c *Controller) broadcast(parcel Parcel, full bool) {
+	c.inventory.markKnown(parcel.Header.AppHash) // so we don't ask for our own message when it's announced to us
+	c.sendToBroadcastPeers(full, func(connection *Connection) {
+		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+	})
+}
+
+// Announces the message to the broadcast peers once all its parcels are in the inventory.
+// Only the hash goes out, peers ask for the message with a TypeGetData parcel.
+// Peers running a protocol version without inventory support get the message itself.
+func (c *Controller) announce(parcel Parcel) {
+	if !c.inventory.add(parcel) {
+		return // wait for the remaining parts
+	}
+	parts := c.inventory.get(parcel.Header.AppHash)
+
+	announcement := NewParcel(CurrentNetwork, []byte("Inventory"))
+	announcement.Header.Type = TypeInventory
+	announcement.Header.AppHash = parcel.Header.AppHash
+	announcement.Header.AppType = parcel.Header.AppType
+
+	p2pInventoryAnnounced.Inc()
+	c.sendToBroadcastPeers(false, func(connection *Connection) {
+		if connection.SupportsInventory() {
+			BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: *announcement})
+			return
+		}
+		for _, part := range parts {
+			BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: part})
+		}
+	})
+}
+
+// Calls send for all special peers and a random selection of regular peers
+// (total max NumberPeersToBroadcast), or all regular peers if full is set.
+func (c *Controller) sendToBroadcastPeers(full bool, send func(connection *Connection)) {
 	numSent := 0
 
 	// always broadcast to special peers
@@ -669,7 +866,7 @@ func (c *Controller) broadcast(parcel Parcel, full bool) {
 			continue
 		}
 		numSent++
-		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+		send(connection)
 	}
 
 	// send also to a random selection of regular peers
@@ -686,7 +883,7 @@ func (c *Controller) broadcast(parcel Parcel, full bool) {
 		return
 	}
 	for _, connection := range randomSelection {
-		BlockFreeChannelSend(connection.SendChannel, ConnectionParcel{Parcel: parcel})
+		send(connection)
 	}
 
 	SentToPeers.Set(float64(numSent))
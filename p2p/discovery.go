@@ -8,6 +8,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net"
 	"net/http"
@@ -50,7 +51,7 @@ func (d *Discovery) Init(peersFile string, seed string) *Discovery {
 	d.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	d.peersFilePath = peersFile
 	d.seedURL = seed
-	//d.LoadPeers()
+	d.LoadPeers()
 	d.DiscoverPeersFromSeed()
 	return d
 }
@@ -59,12 +60,15 @@ func (d *Discovery) Init(peersFile string, seed string) *Discovery {
 // a concurrent read/write error, so isolating changes to knownPeers
 
 // UpdatePeer updates the values in our known peers. Creates peer if its not in there.
+// The reputation history of a known peer is merged in, see Peer.mergeHistory().
 func (d *Discovery) updatePeer(peer Peer) {
 	d.logger.Debugf("Updating peer: %v", peer)
 	UpdateKnownPeers.Lock()
 
-	_, ok := d.knownPeers[peer.Address]
-	if !ok {
+	known, ok := d.knownPeers[peer.Address]
+	if ok {
+		peer.mergeHistory(known)
+	} else {
 		d.logger.WithFields(log.Fields{
 			"address":     peer.Address,
 			"last_source": peer.LastSource()}).Infof("Discovered new peer")
@@ -90,6 +94,44 @@ func (d *Discovery) isPeerPresent(peer Peer) bool {
 	return present
 }
 
+// knownPeerList returns a copy of the known peers, best reputation first
+func (d *Discovery) knownPeerList() []Peer {
+	UpdateKnownPeers.Lock()
+	peers := make([]Peer, 0, len(d.knownPeers))
+	for _, peer := range d.knownPeers {
+		peers = append(peers, peer)
+	}
+	UpdateKnownPeers.Unlock()
+	sort.Sort(sort.Reverse(PeerReputationSort(peers)))
+	return peers
+}
+
+// editPeer applies the edit to a known peer. Returns an error if the peer is unknown.
+func (d *Discovery) editPeer(address string, edit func(peer *Peer)) error {
+	address = normalizeAddress(address)
+	UpdateKnownPeers.Lock()
+	defer UpdateKnownPeers.Unlock()
+	peer, present := d.knownPeers[address]
+	if !present {
+		return fmt.Errorf("Unknown peer: %s", address)
+	}
+	edit(&peer)
+	d.knownPeers[address] = peer
+	return nil
+}
+
+// removePeer deletes a peer from the known peers. Returns an error if the peer is unknown.
+func (d *Discovery) removePeer(address string) error {
+	address = normalizeAddress(address)
+	UpdateKnownPeers.Lock()
+	defer UpdateKnownPeers.Unlock()
+	if _, present := d.knownPeers[address]; !present {
+		return fmt.Errorf("Unknown peer: %s", address)
+	}
+	delete(d.knownPeers, address)
+	return nil
+}
+
 // LoadPeers loads the known peers from disk OVERWRITING PREVIOUS VALUES
 func (d *Discovery) LoadPeers() {
 	file, err := os.Open(d.peersFilePath)
@@ -98,12 +140,19 @@ func (d *Discovery) LoadPeers() {
 		return
 	}
 	dec := json.NewDecoder(bufio.NewReader(file))
+	var savedPeers map[string]Peer // saved by address and port, see SavePeers()
+	if err := dec.Decode(&savedPeers); err != nil {
+		d.logger.Errorf("Discover.LoadPeers() could not decode file: %s, Error: %+v", d.peersFilePath, err)
+	}
 	UpdateKnownPeers.Lock()
-	dec.Decode(&d.knownPeers)
-	// since this is run at startup, reset quality scores.
-	for _, peer := range d.knownPeers {
-		peer.QualityScore = 0
+	// The quality scores and reputation history are kept, so we prefer the peers that served us well
+	// before the restart, and still refuse the banned ones.
+	for _, peer := range savedPeers {
+		peer.logger = peerLogger.WithFields(log.Fields{"address": peer.Address, "port": peer.Port, "peerType": peer.Type})
 		peer.Location = peer.LocationFromAddress()
+		if peer.FirstSeen.IsZero() { // peers.json from before we kept the history
+			peer.FirstSeen = time.Now()
+		}
 		d.knownPeers[peer.Address] = peer
 	}
 	UpdateKnownPeers.Unlock()
@@ -130,6 +179,8 @@ func (d *Discovery) SavePeers() {
 		case peer.IsSpecial(): // always save special peers, even if we haven't talked in awhile.
 			qualityPeers[peer.AddressPort()] = peer
 			d.logger.Debugf("SavePeers() saved peer in peers.json: %+v", peer)
+		case peer.IsBanned(): // keep the ban across restarts
+			qualityPeers[peer.AddressPort()] = peer
 
 		case time.Since(peer.LastContact) > time.Hour*168:
 			d.logger.Debugf("SavePeers() DID NOT SAVE peer in peers.json. Last Contact greater than 168 hours. Peer: %+v", peer)
@@ -160,7 +211,7 @@ func (d *Discovery) LearnPeers(parcel Parcel) {
 	}
 	filteredArray := d.filterPeersFromOtherNetworks(peerArray)
 	for _, value := range filteredArray {
-		value.QualityScore = 0
+		value.clearHistory()
 		switch d.isPeerPresent(value) {
 		case true:
 			alreadyKnownPeer := d.getPeer(value.Address)
@@ -171,9 +222,34 @@ func (d *Discovery) LearnPeers(parcel Parcel) {
 			d.logger.Debugf("Discovery.LearnPeers !!!!!!!!!!!!! Discovered new PEER!   %+v ", value)
 		}
 	}
+	d.learnAdvertisedPeer(parcel)
 	d.SavePeers()
 }
 
+// learnAdvertisedPeer records the address the sender of a peer response advertises for itself. The address
+// is only accepted if it has the IP address we see the connection coming from, so a peer can't make
+// us dial someone else.
+func (d *Discovery) learnAdvertisedPeer(parcel Parcel) {
+	if parcel.Header.AdvertisedAddress == "" {
+		return
+	}
+	host, port, err := net.SplitHostPort(parcel.Header.AdvertisedAddress)
+	if err != nil || normalizeAddress(host) != normalizeAddress(parcel.Header.PeerAddress) {
+		d.logger.Debugf("Ignoring advertised address %s from %s", parcel.Header.AdvertisedAddress, parcel.Header.PeerAddress)
+		return
+	}
+	address := normalizeAddress(host)
+	if d.isPeerPresent(Peer{Address: address}) {
+		peer := d.getPeer(address)
+		peer.Port = port
+		d.updatePeer(d.updatePeerSource(peer, "Advertised"))
+		return
+	}
+	peer := new(Peer).Init(address, port, 0, RegularPeer, 0)
+	peer.Source = map[string]time.Time{"Advertised": time.Now()}
+	d.updatePeer(*peer)
+}
+
 // updatePeerSource checks to see if source is in peer's sources, and if not puts it in there with a value equal to time.Now()
 func (d *Discovery) updatePeerSource(peer Peer, source string) Peer {
 	if nil == peer.Source {
@@ -222,6 +298,7 @@ func (d *Discovery) GetOutgoingPeers() []Peer {
 	UpdateKnownPeers.Lock()
 	for _, peer := range d.knownPeers {
 		switch {
+		case peer.IsBanned():
 		case OnlySpecialPeers && peer.IsSpecial():
 			firstPassPeers = append(firstPassPeers, peer)
 		case !OnlySpecialPeers:
@@ -279,26 +356,26 @@ func (d *Discovery) getPeerSelection() []byte {
 	// var currentBestDistance float64
 	selectedPeers := []Peer{}
 	firstPassPeers := []Peer{}
-	specialPeersByLocation := map[uint32]Peer{}
+	specialPeersByLocation := map[string]Peer{}
 	UpdateKnownPeers.Lock()
 	for _, peer := range d.knownPeers {
-		if peer.QualityScore > MinumumSharingQualityScore { // Only share peers that have earned positive reputation
+		if peer.QualityScore > MinumumSharingQualityScore && !peer.IsBanned() { // Only share peers that have earned positive reputation
 			firstPassPeers = append(firstPassPeers, peer)
 		}
 	}
 	UpdateKnownPeers.Unlock()
 	peerPool := d.filterPeersFromOtherNetworks(firstPassPeers)
 	sort.Sort(PeerQualitySort(peerPool))
-	// Pull out special peers by location.  Use location because it should more accurately reflect IP address.
+	// Pull out special peers by location.  Use the parsed IP because it should more accurately reflect IP address.
 	// we check by location to keep from sharing special peers when they dial into us (in which case we wouldn't realize
-	// they were special by the flag.)
+	// they were special by the flag.)  IPv6 locations only cover the routing prefix, so the full address is used.
 	for _, peer := range peerPool {
 		if peer.IsSpecial() && peer.Location != 0 { // only include special peers that have IP address
-			specialPeersByLocation[peer.Location] = peer
+			specialPeersByLocation[peer.ip().String()] = peer
 		}
 	}
 	for _, peer := range peerPool {
-		_, present := specialPeersByLocation[peer.Location]
+		_, present := specialPeersByLocation[peer.ip().String()]
 		switch {
 		case peer.IsSpecial():
 			break
@@ -340,6 +417,9 @@ func (d *Discovery) DiscoverPeersFromSeed() {
 		if err == nil {
 			peerp := new(Peer).Init(address, port, 0, RegularPeer, 0)
 			peer := *peerp
+			if d.isPeerPresent(peer) { // don't lose the reputation of peers we already know
+				peer = d.getPeer(peer.Address)
+			}
 			peer.LastContact = time.Now()
 			d.updatePeer(d.updatePeerSource(peer, "DNS-Seed"))
 		} else {
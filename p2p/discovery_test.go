@@ -0,0 +1,128 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDiscovery(t *testing.T) (*Discovery, func()) {
+	dir, err := ioutil.TempDir("", "p2p-discovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := new(Discovery)
+	d.logger = discoLogger
+	d.knownPeers = map[string]Peer{}
+	d.peersFilePath = filepath.Join(dir, "peers.json")
+	return d, func() { os.RemoveAll(dir) }
+}
+
+func TestDiscoverySaveLoadPeerHistory(t *testing.T) {
+	d, cleanup := newTestDiscovery(t)
+	defer cleanup()
+
+	peer := *newPeer("2001:db8::1", "8108", RegularPeer)
+	peer.QualityScore = 30
+	peer.LastContact = time.Now()
+	peer.FirstSeen = time.Now().Add(-time.Hour)
+	peer.Connections = 3
+	peer.FailedConnections = 1
+	peer.Latency = 150 * time.Millisecond
+	peer.Version = ProtocolVersion
+	d.updatePeer(peer)
+
+	banned := *newPeer("1.2.3.4", "8108", RegularPeer)
+	banned.ban("test", time.Hour)
+	banned.QualityScore = BannedQualityScore
+	d.updatePeer(banned)
+
+	d.SavePeers()
+
+	loaded, cleanupLoaded := newTestDiscovery(t)
+	defer cleanupLoaded()
+	loaded.peersFilePath = d.peersFilePath
+	loaded.LoadPeers()
+
+	if len(loaded.knownPeers) != 2 {
+		t.Fatalf("Expected 2 peers, loaded %d", len(loaded.knownPeers))
+	}
+	got := loaded.getPeer("2001:db8::1")
+	if got.QualityScore != 30 || got.Connections != 3 || got.FailedConnections != 1 ||
+		got.Latency != peer.Latency || got.Version != ProtocolVersion || !got.FirstSeen.Equal(peer.FirstSeen) {
+		t.Errorf("Peer history was not restored: %+v", got)
+	}
+	if got.Location != peer.Location {
+		t.Errorf("Location was not restored: %x", got.Location)
+	}
+	if got := loaded.getPeer("1.2.3.4"); !got.IsBanned() || len(got.BanHistory) != 1 {
+		t.Errorf("Ban was not restored: %+v", got)
+	}
+}
+
+func TestDiscoveryBannedPeersAreNotDialed(t *testing.T) {
+	d, cleanup := newTestDiscovery(t)
+	defer cleanup()
+
+	d.updatePeer(*newPeer("1.2.3.4", "8108", RegularPeer))
+	d.updatePeer(*newPeer("2.3.4.5", "8108", RegularPeer))
+	err := d.editPeer("1.2.3.4", func(peer *Peer) { peer.ban("test", time.Hour) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers := d.GetOutgoingPeers()
+	if len(peers) != 1 || peers[0].Address != "2.3.4.5" {
+		t.Errorf("Banned peer is a dial candidate: %+v", peers)
+	}
+}
+
+func TestControllerEditPeerDatabase(t *testing.T) {
+	d, cleanup := newTestDiscovery(t)
+	defer cleanup()
+	c := new(Controller)
+	c.discovery = *d
+	c.commandChannel = make(chan interface{}, StandardChannelSize)
+
+	c.discovery.updatePeer(*newPeer("2001:db8::1", "8108", RegularPeer))
+
+	if err := c.SetPeerQuality("2001:0db8::0001", 50); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.KnownPeers()[0].QualityScore; got != 50 {
+		t.Errorf("Quality score was not set: %d", got)
+	}
+	command := (<-c.commandChannel).(CommandAdjustAddressQuality)
+	if command.Address != "2001:db8::1" || command.Adjustment != 50-100 { // newPeer() starts with a score of 100
+		t.Errorf("Connections were not adjusted: %+v", command)
+	}
+
+	if err := c.BanPeer("2001:db8::1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if peer := c.KnownPeers()[0]; !peer.IsBanned() || peer.BanHistory[0].Reason != "test" {
+		t.Errorf("Peer was not banned: %+v", peer)
+	}
+	if command := (<-c.commandChannel).(CommandAdjustAddressQuality); command.Adjustment != BannedQualityScore {
+		t.Errorf("Connections were not banned: %+v", command)
+	}
+
+	if err := c.UnbanPeer("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if peer := c.KnownPeers()[0]; peer.IsBanned() || len(peer.BanHistory) != 1 {
+		t.Errorf("Peer was not unbanned, or lost its ban history: %+v", peer)
+	}
+
+	if err := c.RemovePeer("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.KnownPeers()) != 0 {
+		t.Error("Peer was not removed")
+	}
+	if err := c.RemovePeer("2001:db8::1"); err == nil {
+		t.Error("Removing an unknown peer should fail")
+	}
+}
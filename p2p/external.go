@@ -0,0 +1,145 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExternalAddressMinVotes is the number of distinct peers that must observe the same address before
+// we believe it is our external address.
+const ExternalAddressMinVotes = 3
+
+// ExternalAddressVoteExpiration is the time after which a peer's observation is dropped, so we notice
+// when our external address changes.
+const ExternalAddressVoteExpiration = time.Hour
+
+// advertisedPort holds the external port mapped on the router when it differs from the listen port,
+// it replaces NetworkListenPort in the parcel headers. Empty when there is no such mapping.
+var advertisedPort atomic.Value
+
+// getAdvertisedPort returns the port other peers should dial us on
+func getAdvertisedPort() string {
+	if port, ok := advertisedPort.Load().(string); ok && port != "" {
+		return port
+	}
+	return NetworkListenPort
+}
+
+type externalVote struct {
+	address string
+	time    time.Time
+}
+
+// ExternalAddress works out the address other peers can reach us on. In order of preference:
+// the address set by the operator (nat extip:<IP>), the address of the router mapping our port,
+// or the address the most peers see our connections coming from.
+type ExternalAddress struct {
+	mutex      sync.Mutex
+	fixed      net.IP                  // set by the operator
+	mapped     net.IP                  // external address of the router mapping our port
+	mappedPort string                  // external port mapped on the router
+	votes      map[string]externalVote // address observed by each peer, by peer hash
+}
+
+// Initializes the external address
+func (e *ExternalAddress) Init() *ExternalAddress {
+	e.votes = make(map[string]externalVote)
+	return e
+}
+
+// setFixed sets the external address configured by the operator
+func (e *ExternalAddress) setFixed(ip net.IP) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.fixed = ip
+}
+
+// setMapped records the port mapping made on the router
+func (e *ExternalAddress) setMapped(ip net.IP, port string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.mapped = ip
+	e.mappedPort = port
+	if port == NetworkListenPort {
+		port = ""
+	}
+	advertisedPort.Store(port)
+}
+
+// observed records the address a peer sees our connection coming from
+func (e *ExternalAddress) observed(address string, peerHash string) {
+	ip := net.ParseIP(address)
+	if ip == nil || !isPublicIP(ip) {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.votes[peerHash] = externalVote{address: ip.String(), time: time.Now()}
+}
+
+// Address returns our external "host:port", or "" if we don't know it (yet)
+func (e *ExternalAddress) Address() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	port := e.mappedPort
+	if port == "" {
+		port = NetworkListenPort
+	}
+	switch {
+	case e.fixed != nil:
+		return net.JoinHostPort(e.fixed.String(), port)
+	case e.mapped != nil && isPublicIP(e.mapped): // not the case behind a second layer of NAT
+		return net.JoinHostPort(e.mapped.String(), port)
+	}
+
+	count := make(map[string]int)
+	best := ""
+	for peerHash, vote := range e.votes {
+		if ExternalAddressVoteExpiration < time.Since(vote.time) {
+			delete(e.votes, peerHash)
+			continue
+		}
+		count[vote.address]++
+		if count[best] < count[vote.address] {
+			best = vote.address
+		}
+	}
+	if best == "" || count[best] < ExternalAddressMinVotes {
+		return ""
+	}
+	return net.JoinHostPort(best, port)
+}
+
+// Networks that are not reachable from the internet
+var nonPublicNetworks []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", // private
+		"100.64.0.0/10", // carrier grade NAT
+		"fc00::/7",      // unique local
+	} {
+		_, network, _ := net.ParseCIDR(cidr)
+		nonPublicNetworks = append(nonPublicNetworks, network)
+	}
+}
+
+// isPublicIP checks if the address can be reached from the internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
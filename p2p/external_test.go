@@ -0,0 +1,132 @@
+package p2p
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExternalAddressVotes(t *testing.T) {
+	oldPort := NetworkListenPort
+	NetworkListenPort = "8108"
+	defer func() { NetworkListenPort = oldPort }()
+
+	e := new(ExternalAddress).Init()
+	if e.Address() != "" {
+		t.Errorf("Address should be unknown, got %q", e.Address())
+	}
+
+	e.observed("203.0.113.7", "peer1")
+	e.observed("203.0.113.7", "peer2")
+	e.observed("192.168.1.5", "peer3") // a peer on our LAN
+	e.observed("203.0.113.7", "peer1") // the same peer again
+	if e.Address() != "" {
+		t.Errorf("Two peers are not enough to trust the address, got %q", e.Address())
+	}
+
+	e.observed("203.0.113.7", "peer3")
+	e.observed("198.51.100.4", "peer4")
+	if e.Address() != "203.0.113.7:8108" {
+		t.Errorf("Expected 203.0.113.7:8108, got %q", e.Address())
+	}
+
+	// old observations expire
+	for hash, vote := range e.votes {
+		vote.time = time.Now().Add(-ExternalAddressVoteExpiration - time.Second)
+		e.votes[hash] = vote
+	}
+	if e.Address() != "" {
+		t.Errorf("Expired votes should not count, got %q", e.Address())
+	}
+}
+
+func TestExternalAddressPriority(t *testing.T) {
+	oldPort := NetworkListenPort
+	NetworkListenPort = "8108"
+	defer func() { NetworkListenPort = oldPort }()
+	defer advertisedPort.Store("")
+
+	e := new(ExternalAddress).Init()
+	for _, peer := range []string{"peer1", "peer2", "peer3"} {
+		e.observed("203.0.113.7", peer)
+	}
+
+	e.setMapped(net.ParseIP("10.0.0.2"), "8108") // the router is behind another NAT
+	if e.Address() != "203.0.113.7:8108" {
+		t.Errorf("Expected the observed address, got %q", e.Address())
+	}
+	e.setMapped(net.ParseIP("198.51.100.4"), "9000")
+	if e.Address() != "198.51.100.4:9000" {
+		t.Errorf("Expected the mapped address, got %q", e.Address())
+	}
+	e.setFixed(net.ParseIP("2001:db8::1"))
+	if e.Address() != "[2001:db8::1]:9000" {
+		t.Errorf("Expected the configured address, got %q", e.Address())
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for address, public := range map[string]bool{
+		"203.0.113.7": true,
+		"2001:db8::1": true,
+		"10.1.2.3":    false,
+		"172.20.0.1":  false,
+		"192.168.0.1": false,
+		"100.64.0.1":  false,
+		"127.0.0.1":   false,
+		"169.254.0.1": false,
+		"::1":         false,
+		"fd00::1":     false,
+		"fe80::1":     false,
+	} {
+		if isPublicIP(net.ParseIP(address)) != public {
+			t.Errorf("isPublicIP(%s) should be %t", address, public)
+		}
+	}
+}
+
+func newAdvertisingPeerResponse(from string, advertised string) Parcel {
+	payload, _ := json.Marshal([]Peer{})
+	parcel := NewParcel(CurrentNetwork, payload)
+	parcel.Header.Type = TypePeerResponse
+	parcel.Header.PeerAddress = from
+	parcel.Header.AdvertisedAddress = advertised
+	return *parcel
+}
+
+func TestLearnPeersAdvertisedAddress(t *testing.T) {
+	d, cleanup := newTestDiscovery(t)
+	defer cleanup()
+
+	d.LearnPeers(newAdvertisingPeerResponse("1.2.3.4", "1.2.3.4:9000"))
+	peer := d.getPeer("1.2.3.4")
+	if peer.Port != "9000" {
+		t.Fatalf("Advertised peer was not learned, got %+v", peer)
+	}
+	if _, ok := peer.Source["Advertised"]; !ok {
+		t.Errorf("Expected source Advertised, got %v", peer.Source)
+	}
+
+	// the peer moves its mapped port
+	d.LearnPeers(newAdvertisingPeerResponse("1.2.3.4", "1.2.3.4:9001"))
+	if peer = d.getPeer("1.2.3.4"); peer.Port != "9001" {
+		t.Errorf("Expected the port to be updated to 9001, got %s", peer.Port)
+	}
+
+	// IPv6 addresses in a different spelling are the same host
+	d.LearnPeers(newAdvertisingPeerResponse("2001:db8::1", "[2001:DB8:0::1]:8108"))
+	if peer = d.getPeer("2001:db8::1"); peer.Port != "8108" {
+		t.Errorf("Advertised IPv6 peer was not learned, got %+v", peer)
+	}
+
+	// a peer can only advertise its own IP address
+	d.LearnPeers(newAdvertisingPeerResponse("1.2.3.4", "5.6.7.8:8108"))
+	if d.isPeerPresent(Peer{Address: "5.6.7.8"}) {
+		t.Error("Advertised address of another host was accepted")
+	}
+	d.LearnPeers(newAdvertisingPeerResponse("1.2.3.4", "garbage"))
+	if peer = d.getPeer("1.2.3.4"); peer.Port != "9001" {
+		t.Errorf("Invalid advertised address changed the peer, got %+v", peer)
+	}
+}
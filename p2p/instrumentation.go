@@ -26,6 +26,26 @@ var (
 		Help: "Number of Peers to which we are broadcasting messages",
 	})
 
+	p2pInventoryAnnounced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_p2p_inventory_announced_total",
+		Help: "Number of messages announced by hash instead of broadcast",
+	})
+
+	p2pInventoryRequested = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_p2p_inventory_requested_total",
+		Help: "Number of announced messages we asked peers for",
+	})
+
+	p2pInventoryServed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_p2p_inventory_served_total",
+		Help: "Number of message parcels sent to peers asking for them",
+	})
+
+	p2pActivationMismatches = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "factomd_p2p_activation_schedule_mismatches_total",
+		Help: "Number of peers with an activation schedule different from ours",
+	})
+
 	StartingPoint = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "factomd_StartingPoint_peers_broadcast",
 		Help: "Number of msgs broadcasting",
@@ -108,6 +128,10 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(p2pControllerNumMetrics)
 	prometheus.MustRegister(p2pControllerNumConnectionsByAddress)
 	prometheus.MustRegister(SentToPeers)
+	prometheus.MustRegister(p2pInventoryAnnounced)
+	prometheus.MustRegister(p2pInventoryRequested)
+	prometheus.MustRegister(p2pInventoryServed)
+	prometheus.MustRegister(p2pActivationMismatches)
 	prometheus.MustRegister(StartingPoint)
 
 	// Connection Routines
@@ -0,0 +1,125 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var inventoryLogger = packageLogger.WithField("subpack", "inventory")
+
+// InventoryProtocolVersion is the first protocol version that understands inventory announcements.
+// Peers running an older version get the full message instead of the announcement.
+const InventoryProtocolVersion uint16 = 10
+
+// time we keep announced messages around to answer requests, and remember the hashes we have seen
+const InventoryExpiration time.Duration = time.Minute * 2
+
+// time after which we ask another peer for a message we requested but did not receive
+const InventoryRequestTimeout time.Duration = time.Second * 5
+
+type inventoryItem struct {
+	parcels []Parcel  // the parcels (parts) of the message
+	added   time.Time // a timestamp indicating when the message was last broadcast by the application
+}
+
+// Inventory supports the inventory/getdata gossip. Instead of sending a message to the broadcast peers,
+// we announce its hash, and only send the message to peers asking for it.
+// The inventory keeps the messages we announced so we can answer those requests, and the hashes of
+// the messages we already have or asked for so we don't request them again.
+type Inventory struct {
+	items       map[string]*inventoryItem // a map of app hashes to the messages we announced
+	known       map[string]time.Time      // a map of app hashes of messages we have, to when we got them
+	requested   map[string]time.Time      // a map of app hashes of messages we asked for, to when we asked
+	lastCleanup time.Time
+
+	// logging
+	logger *log.Entry
+}
+
+// Initializes the inventory
+func (inv *Inventory) Init() *Inventory {
+	inv.logger = inventoryLogger
+	inv.items = make(map[string]*inventoryItem)
+	inv.known = make(map[string]time.Time)
+	inv.requested = make(map[string]time.Time)
+	inv.lastCleanup = time.Now()
+	return inv
+}
+
+// add stores a parcel of a message we are going to announce. Returns true once all the parts
+// of the message are stored, and the message can be announced.
+func (inv *Inventory) add(parcel Parcel) bool {
+	hash := parcel.Header.AppHash
+	item, exists := inv.items[hash]
+	if !exists {
+		item = new(inventoryItem)
+		inv.items[hash] = item
+	}
+	if parcel.Header.PartNo == 0 { // the application broadcasts the message (again), start over
+		item.parcels = item.parcels[:0]
+		item.added = time.Now()
+	}
+	item.parcels = append(item.parcels, parcel)
+	inv.markKnown(hash)
+
+	return parcel.Header.Type != TypeMessagePart || int(parcel.Header.PartsTotal) <= len(item.parcels)
+}
+
+// get returns the parcels of an announced message, or nil if we don't have it (anymore)
+func (inv *Inventory) get(hash string) []Parcel {
+	item, exists := inv.items[hash]
+	if !exists {
+		return nil
+	}
+	return item.parcels
+}
+
+// markKnown remembers that we have the message with the given hash
+func (inv *Inventory) markKnown(hash string) {
+	inv.known[hash] = time.Now()
+	delete(inv.requested, hash)
+}
+
+// shouldRequest checks if a message announced by a peer is new to us, and we didn't just ask another
+// peer for it. If so, the request is recorded and true is returned.
+func (inv *Inventory) shouldRequest(hash string) bool {
+	if _, known := inv.known[hash]; known {
+		return false
+	}
+	if requested, exists := inv.requested[hash]; exists && time.Since(requested) < InventoryRequestTimeout {
+		return false
+	}
+	inv.requested[hash] = time.Now()
+	return true
+}
+
+// cleanup drops the messages and hashes older than InventoryExpiration. It does the work at most once
+// a second, so it can be called from the route loop.
+func (inv *Inventory) cleanup() {
+	if time.Since(inv.lastCleanup) < time.Second {
+		return
+	}
+	inv.lastCleanup = time.Now()
+
+	for hash, item := range inv.items {
+		if InventoryExpiration < time.Since(item.added) {
+			delete(inv.items, hash)
+		}
+	}
+	for hash, seen := range inv.known {
+		if InventoryExpiration < time.Since(seen) {
+			delete(inv.known, hash)
+		}
+	}
+	for hash, requested := range inv.requested {
+		if InventoryExpiration < time.Since(requested) {
+			delete(inv.requested, hash)
+		}
+	}
+	inv.logger.Debugf("Inventory has %d messages, %d known and %d requested hashes", len(inv.items), len(inv.known), len(inv.requested))
+}
@@ -0,0 +1,217 @@
+package p2p
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newInventoryTestParcels(hash string, payload []byte) []Parcel {
+	parcels := ParcelsForPayload(CurrentNetwork, payload)
+	for i := range parcels {
+		parcels[i].Header.AppHash = hash
+		parcels[i].Header.AppType = "9"
+		parcels[i].Header.TargetPeer = InventoryBroadcastFlag
+	}
+	return parcels
+}
+
+func TestInventoryAddAndGet(t *testing.T) {
+	inv := new(Inventory).Init()
+
+	if inv.get("abcd") != nil {
+		t.Error("Empty inventory returned a message")
+	}
+
+	parcel := newInventoryTestParcels("abcd", []byte("payload"))[0]
+	if !inv.add(parcel) {
+		t.Error("Single part message should be complete")
+	}
+	if len(inv.get("abcd")) != 1 {
+		t.Errorf("Expected 1 parcel, got %d", len(inv.get("abcd")))
+	}
+
+	// the application broadcasting the message again does not duplicate the parcels
+	inv.add(parcel)
+	if len(inv.get("abcd")) != 1 {
+		t.Errorf("Expected 1 parcel after a rebroadcast, got %d", len(inv.get("abcd")))
+	}
+}
+
+func TestInventoryAddParts(t *testing.T) {
+	inv := new(Inventory).Init()
+
+	first := newInventoryTestParcels("abcd", []byte("part one"))[0]
+	first.Header.PartsTotal = 2
+	second := newInventoryTestParcels("abcd", []byte("part two"))[0]
+	second.Header.PartNo = 1
+	second.Header.PartsTotal = 2
+
+	if inv.add(first) {
+		t.Error("Message should not be complete after the first of two parts")
+	}
+	if !inv.add(second) {
+		t.Error("Message should be complete after the second of two parts")
+	}
+	if len(inv.get("abcd")) != 2 {
+		t.Errorf("Expected 2 parcels, got %d", len(inv.get("abcd")))
+	}
+}
+
+func TestInventoryShouldRequest(t *testing.T) {
+	inv := new(Inventory).Init()
+
+	if !inv.shouldRequest("abcd") {
+		t.Error("Unknown message should be requested")
+	}
+	if inv.shouldRequest("abcd") {
+		t.Error("Message should not be requested twice within InventoryRequestTimeout")
+	}
+
+	// the request timed out, so ask the next peer announcing it
+	inv.requested["abcd"] = time.Now().Add(-InventoryRequestTimeout)
+	if !inv.shouldRequest("abcd") {
+		t.Error("Message should be requested again after InventoryRequestTimeout")
+	}
+
+	inv.markKnown("abcd")
+	if inv.shouldRequest("abcd") {
+		t.Error("Known message should not be requested")
+	}
+	if _, exists := inv.requested["abcd"]; exists {
+		t.Error("Received message is still marked as requested")
+	}
+}
+
+func TestInventoryCleanup(t *testing.T) {
+	inv := new(Inventory).Init()
+
+	inv.add(newInventoryTestParcels("old", []byte("old"))[0])
+	inv.add(newInventoryTestParcels("new", []byte("new"))[0])
+	inv.shouldRequest("requested")
+	expired := time.Now().Add(-InventoryExpiration - time.Second)
+	inv.items["old"].added = expired
+	inv.known["old"] = expired
+	inv.requested["requested"] = expired
+
+	inv.cleanup() // too soon after Init
+	if inv.get("old") == nil {
+		t.Error("Cleanup should run at most once a second")
+	}
+
+	inv.lastCleanup = time.Now().Add(-time.Second)
+	inv.cleanup()
+	if inv.get("old") != nil {
+		t.Error("Expired message was not dropped")
+	}
+	if _, known := inv.known["old"]; known {
+		t.Error("Expired hash was not dropped")
+	}
+	if _, requested := inv.requested["requested"]; requested {
+		t.Error("Expired request was not dropped")
+	}
+	if inv.get("new") == nil {
+		t.Error("Recent message was dropped")
+	}
+}
+
+func newInventoryTestController() *Controller {
+	c := new(Controller)
+	c.logger = controllerLogger
+	c.connections = new(ConnectionManager).Init()
+	c.specialPeers = make(map[string]*Peer)
+	c.partsAssembler = new(PartsAssembler).Init()
+	c.inventory = new(Inventory).Init()
+	c.FromNetwork = make(chan interface{}, StandardChannelSize)
+	return c
+}
+
+// newInventoryTestConnection returns a connection with the peer request it sends on creation drained
+func newInventoryTestConnection(address string, version uint16) *Connection {
+	connection := newIncomingActiveConnection(newPeer(address, "8108", RegularPeer))
+	atomic.StoreUint32(&connection.peerVersion, uint32(version))
+	sentParcels(connection)
+	return connection
+}
+
+func sentParcels(connection *Connection) []Parcel {
+	var parcels []Parcel
+	for 0 < len(connection.SendChannel) {
+		if parameters, ok := (<-connection.SendChannel).(ConnectionParcel); ok {
+			parcels = append(parcels, parameters.Parcel)
+		}
+	}
+	return parcels
+}
+
+func TestControllerAnnounce(t *testing.T) {
+	c := newInventoryTestController()
+	current := newInventoryTestConnection("1.2.3.4", InventoryProtocolVersion)
+	old := newInventoryTestConnection("2.3.4.5", ProtocolVersionMinimum)
+	c.connections.Add(current)
+	c.connections.Add(old)
+
+	parcel := newInventoryTestParcels("abcd", []byte("payload"))[0]
+	c.announce(parcel)
+
+	sent := sentParcels(current)
+	if len(sent) != 1 || sent[0].Header.Type != TypeInventory || sent[0].Header.AppHash != "abcd" {
+		t.Fatalf("Peer supporting inventory should get an announcement, got %+v", sent)
+	}
+	sent = sentParcels(old)
+	if len(sent) != 1 || sent[0].Header.Type != TypeMessagePart || string(sent[0].Payload) != "payload" {
+		t.Fatalf("Peer without inventory support should get the message, got %+v", sent)
+	}
+
+	// the peer asks for the message
+	request := NewParcel(CurrentNetwork, []byte("GetData"))
+	request.Header.Type = TypeGetData
+	request.Header.AppHash = "abcd"
+	c.handleParcelReceive(ConnectionParcel{Parcel: *request}, current.peer.Hash, current)
+	sent = sentParcels(current)
+	if len(sent) != 1 || string(sent[0].Payload) != "payload" {
+		t.Errorf("Requested message was not sent, got %+v", sent)
+	}
+
+	// our own message announced back to us is not requested
+	announcement := NewParcel(CurrentNetwork, []byte("Inventory"))
+	announcement.Header.Type = TypeInventory
+	announcement.Header.AppHash = "abcd"
+	c.handleParcelReceive(ConnectionParcel{Parcel: *announcement}, current.peer.Hash, current)
+	if sent = sentParcels(current); len(sent) != 0 {
+		t.Errorf("Known message was requested: %+v", sent)
+	}
+}
+
+func TestControllerRequestAnnounced(t *testing.T) {
+	c := newInventoryTestController()
+	first := newInventoryTestConnection("1.2.3.4", InventoryProtocolVersion)
+	second := newInventoryTestConnection("2.3.4.5", InventoryProtocolVersion)
+
+	announcement := NewParcel(CurrentNetwork, []byte("Inventory"))
+	announcement.Header.Type = TypeInventory
+	announcement.Header.AppHash = "abcd"
+
+	c.handleParcelReceive(ConnectionParcel{Parcel: *announcement}, first.peer.Hash, first)
+	sent := sentParcels(first)
+	if len(sent) != 1 || sent[0].Header.Type != TypeGetData || sent[0].Header.AppHash != "abcd" {
+		t.Fatalf("Announced message was not requested, got %+v", sent)
+	}
+
+	// a second peer announcing the message while we wait for it is ignored
+	c.handleParcelReceive(ConnectionParcel{Parcel: *announcement}, second.peer.Hash, second)
+	if sent = sentParcels(second); len(sent) != 0 {
+		t.Errorf("Message was requested twice: %+v", sent)
+	}
+
+	// the message arrives and goes to the application
+	for _, parcel := range newInventoryTestParcels("abcd", []byte("payload")) {
+		c.handleParcelReceive(ConnectionParcel{Parcel: parcel}, first.peer.Hash, first)
+	}
+	if len(c.FromNetwork) != 1 {
+		t.Errorf("Message was not passed on to the application")
+	}
+	if c.inventory.shouldRequest("abcd") {
+		t.Error("Received message should be known")
+	}
+}
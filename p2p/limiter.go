@@ -3,7 +3,6 @@ package p2p
 import (
 	"fmt"
 	"net"
-	"strings"
 	"time"
 )
 
@@ -30,9 +29,12 @@ func (l *limitListenerSources) Accept() (net.Conn, error) {
 	}
 
 	// Grab the address, check for last connection
-	addr := strings.Split(c.RemoteAddr().String(), ":")
-	if v, ok := l.accepted[addr[0]]; !ok || time.Since(v) > time.Second {
-		l.accepted[addr[0]] = time.Now()
+	addr, _, err := net.SplitHostPort(c.RemoteAddr().String())
+	if err != nil {
+		addr = c.RemoteAddr().String()
+	}
+	if v, ok := l.accepted[addr]; !ok || time.Since(v) > time.Second {
+		l.accepted[addr] = time.Now()
 		return c, nil
 	}
 	c.Close()
@@ -0,0 +1,164 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NAT traversal: nodes behind a home or office router can ask the router to forward our network
+// port to us, with UPnP (Internet Gateway Device) or NAT-PMP. Together with the external address
+// (see ExternalAddress) this lets other peers dial in.
+
+var natLogger = packageLogger.WithField("subpack", "nat")
+
+// NAT is a router between us and the internet that can forward a TCP port to us.
+type NAT interface {
+	// ExternalIP returns the address of the router on the internet side
+	ExternalIP() (net.IP, error)
+	// AddPortMapping forwards the external port to our internal port for the lifetime, and
+	// returns the external port the router actually used.
+	AddPortMapping(internalPort int, externalPort int, description string, lifetime time.Duration) (int, error)
+	// DeletePortMapping removes a mapping we added
+	DeletePortMapping(externalPort int) error
+	String() string
+}
+
+var (
+	NATMappingLifetime  = time.Minute * 20 // Lifetime we ask for, so a mapping doesn't outlive a crashed node by long.
+	NATMappingRefresh   = time.Minute * 15 // How often we renew the mapping.
+	NATDiscoveryTimeout = time.Second * 3  // How long we wait for a router to answer discovery.
+)
+
+// natConfig is the parsed nat setting, see ParseNAT()
+type natConfig struct {
+	discover func() (NAT, error) // finds the router to map our port on, nil for no port mapping
+	extIP    net.IP              // external address set by the operator, nil if not set
+}
+
+// ParseNAT parses the nat setting:
+//
+//	"" or "none"  no NAT traversal
+//	"any"         UPnP, or else NAT-PMP on the default gateway
+//	"upnp"        UPnP
+//	"pmp"         NAT-PMP on the default gateway, or "pmp:<gateway IP>"
+//	"extip:<IP>"  no port mapping, the router is set up by hand and the given IP is our external address
+func ParseNAT(spec string) (natConfig, error) {
+	var config natConfig
+	mechanism, parameter := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		mechanism, parameter = spec[:i], spec[i+1:]
+	}
+	switch strings.ToLower(mechanism) {
+	case "", "none":
+	case "any":
+		config.discover = func() (NAT, error) {
+			if nat, err := discoverUPnP(ssdpMulticastAddress, NATDiscoveryTimeout); err == nil {
+				return nat, nil
+			}
+			return discoverNATPMP(nil)
+		}
+	case "upnp":
+		config.discover = func() (NAT, error) {
+			return discoverUPnP(ssdpMulticastAddress, NATDiscoveryTimeout)
+		}
+	case "pmp":
+		var gateway net.IP
+		if parameter != "" {
+			if gateway = net.ParseIP(parameter); gateway == nil {
+				return config, fmt.Errorf("Invalid NAT-PMP gateway address: %s", parameter)
+			}
+		}
+		config.discover = func() (NAT, error) {
+			return discoverNATPMP(gateway)
+		}
+	case "extip":
+		if config.extIP = net.ParseIP(parameter); config.extIP == nil {
+			return config, fmt.Errorf("Invalid external address: %s", parameter)
+		}
+	default:
+		return config, fmt.Errorf("Unknown NAT mechanism %q, use none, any, upnp, pmp, pmp:<gateway IP> or extip:<IP>", mechanism)
+	}
+	return config, nil
+}
+
+// defaultGateway reads the IPv4 default gateway from the kernel routing table (linux only)
+func defaultGateway() (net.IP, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, fmt.Errorf("Can not find the default gateway, set it with pmp:<gateway IP>: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// the kernel writes the address in host byte order
+		gateway := make(net.IP, 4)
+		binary.BigEndian.PutUint32(gateway, binary.LittleEndian.Uint32(raw))
+		return gateway, nil
+	}
+	return nil, fmt.Errorf("No default gateway in the routing table, set it with pmp:<gateway IP>")
+}
+
+// manageNAT maps our listen port on the router and keeps the mapping alive until stop is closed.
+// It runs in its own goroutine as talking to the router can take a while.
+func (c *Controller) manageNAT(discover func() (NAT, error), stop chan struct{}) {
+	nat, err := discover()
+	if err != nil {
+		c.logger.Warnf("NAT traversal: no router found: %v", err)
+		return
+	}
+	c.logger.Infof("NAT traversal: using %s", nat)
+	internal, err := strconv.Atoi(c.listenPort)
+	if err != nil {
+		c.logger.Errorf("NAT traversal: invalid listen port %s", c.listenPort)
+		return
+	}
+	external := internal
+	for {
+		external = c.mapPort(nat, internal, external)
+		select {
+		case <-stop:
+			if err := nat.DeletePortMapping(external); err != nil {
+				c.logger.Warnf("NAT traversal: removing the port mapping failed: %v", err)
+			}
+			return
+		case <-time.After(NATMappingRefresh):
+		}
+	}
+}
+
+// mapPort asks the router to forward the external port to our listen port, and records the
+// external address for advertising. Returns the external port the router used.
+func (c *Controller) mapPort(nat NAT, internal int, external int) int {
+	mapped, err := nat.AddPortMapping(internal, external, "factomd p2p", NATMappingLifetime)
+	if err != nil {
+		c.logger.Warnf("NAT traversal: mapping port %d failed: %v", external, err)
+		return external
+	}
+	ip, err := nat.ExternalIP()
+	if err != nil {
+		c.logger.Warnf("NAT traversal: getting the external address failed: %v", err)
+	}
+	c.logger.Infof("NAT traversal: mapped external port %d (address %s) to port %d", mapped, ip, internal)
+	c.externalAddress.setMapped(ip, strconv.Itoa(mapped))
+	return mapped
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A NAT-PMP (RFC 6886) client for the router at the default gateway.
+
+const natPMPPort = 5351
+
+// NAT-PMP requests are retried with a doubling timeout, starting at natPMPRetryTimeout
+const natPMPRetryTimeout = time.Millisecond * 250
+const natPMPRetries = 4
+
+type natPMP struct {
+	gateway string // host:port of the router
+
+	mutex    sync.Mutex
+	internal map[int]int // internal port per external port we mapped, to delete the mappings
+}
+
+// discoverNATPMP checks the gateway (the default gateway if nil) answers NAT-PMP requests
+func discoverNATPMP(gateway net.IP) (NAT, error) {
+	if gateway == nil {
+		var err error
+		if gateway, err = defaultGateway(); err != nil {
+			return nil, err
+		}
+	}
+	nat := newNATPMP(net.JoinHostPort(gateway.String(), strconv.Itoa(natPMPPort)))
+	if _, err := nat.ExternalIP(); err != nil {
+		return nil, err
+	}
+	return nat, nil
+}
+
+func newNATPMP(gateway string) *natPMP {
+	return &natPMP{gateway: gateway, internal: make(map[int]int)}
+}
+
+func (n *natPMP) String() string {
+	return fmt.Sprintf("NAT-PMP at %s", n.gateway)
+}
+
+func (n *natPMP) ExternalIP() (net.IP, error) {
+	response, err := n.call([]byte{0, 0}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(response[8], response[9], response[10], response[11]), nil
+}
+
+func (n *natPMP) AddPortMapping(internalPort int, externalPort int, description string, lifetime time.Duration) (int, error) {
+	response, err := n.call(natPMPMapRequest(internalPort, externalPort, lifetime), 16)
+	if err != nil {
+		return 0, err
+	}
+	// the router may give us a different external port than we asked for
+	mapped := int(binary.BigEndian.Uint16(response[10:12]))
+	n.mutex.Lock()
+	n.internal[mapped] = internalPort
+	n.mutex.Unlock()
+	return mapped, nil
+}
+
+func (n *natPMP) DeletePortMapping(externalPort int) error {
+	n.mutex.Lock()
+	internalPort, exists := n.internal[externalPort]
+	delete(n.internal, externalPort)
+	n.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("No mapping for port %d", externalPort)
+	}
+	// a mapping request with a lifetime of 0 deletes the mapping
+	_, err := n.call(natPMPMapRequest(internalPort, 0, 0), 16)
+	return err
+}
+
+// natPMPMapRequest builds a TCP mapping request
+func natPMPMapRequest(internalPort int, externalPort int, lifetime time.Duration) []byte {
+	request := make([]byte, 12)
+	request[1] = 2 // opcode: map TCP
+	binary.BigEndian.PutUint16(request[4:6], uint16(internalPort))
+	binary.BigEndian.PutUint16(request[6:8], uint16(externalPort))
+	binary.BigEndian.PutUint32(request[8:12], uint32(lifetime/time.Second))
+	return request
+}
+
+// call sends the request to the router, and waits for a response of the given size.
+// Returns an error if the router doesn't answer or reports a failure.
+func (n *natPMP) call(request []byte, size int) ([]byte, error) {
+	conn, err := net.Dial("udp", n.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	response := make([]byte, 16)
+	timeout := natPMPRetryTimeout
+	for i := 0; i < natPMPRetries; i++ {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		timeout *= 2
+		read, err := conn.Read(response)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return nil, err
+		}
+		if read < size || response[0] != 0 || response[1] != request[1]|0x80 {
+			return nil, fmt.Errorf("Invalid NAT-PMP response: %x", response[:read])
+		}
+		if result := binary.BigEndian.Uint16(response[2:4]); result != 0 {
+			return nil, fmt.Errorf("NAT-PMP request failed with result code %d", result)
+		}
+		return response[:size], nil
+	}
+	return nil, fmt.Errorf("%s did not answer", n)
+}
@@ -0,0 +1,311 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockIGD is a UPnP Internet Gateway Device answering SSDP searches and port mapping requests
+type mockIGD struct {
+	server *httptest.Server
+	ssdp   net.PacketConn
+
+	mutex    sync.Mutex
+	mappings map[string]string // internal "client:port" by external port
+	fail     bool              // answer port mapping requests with an error
+}
+
+const mockIGDDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/control</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func newMockIGD(t *testing.T) *mockIGD {
+	igd := &mockIGD{mappings: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/root.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, mockIGDDescription)
+	})
+	mux.HandleFunc("/control", igd.control)
+	igd.server = httptest.NewServer(mux)
+
+	ssdp, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	igd.ssdp = ssdp
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			n, from, err := ssdp.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(string(buffer[:n]), "M-SEARCH") {
+				continue
+			}
+			ssdp.WriteTo([]byte("HTTP/1.1 200 OK\r\n"+
+				"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n"+
+				"LOCATION: "+igd.server.URL+"/root.xml\r\n\r\n"), from)
+		}
+	}()
+	return igd
+}
+
+func (igd *mockIGD) Close() {
+	igd.ssdp.Close()
+	igd.server.Close()
+}
+
+func (igd *mockIGD) control(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	values, err := soapValues(strings.NewReader(string(body)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := r.Header.Get("SOAPAction")
+	igd.mutex.Lock()
+	defer igd.mutex.Unlock()
+
+	response := ""
+	switch {
+	case igd.fail:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>`+
+			`<detail><UPnPError><errorCode>718</errorCode><errorDescription>ConflictInMappingEntry</errorDescription></UPnPError></detail>`+
+			`</s:Fault></s:Body></s:Envelope>`)
+		return
+	case strings.HasSuffix(action, `#GetExternalIPAddress"`):
+		response = "<NewExternalIPAddress>203.0.113.7</NewExternalIPAddress>"
+	case strings.HasSuffix(action, `#AddPortMapping"`):
+		igd.mappings[values["NewExternalPort"]] = values["NewInternalClient"] + ":" + values["NewInternalPort"]
+	case strings.HasSuffix(action, `#DeletePortMapping"`):
+		delete(igd.mappings, values["NewExternalPort"])
+	default:
+		http.Error(w, "unknown action "+action, http.StatusBadRequest)
+		return
+	}
+	fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:Response>`+
+		response+`</u:Response></s:Body></s:Envelope>`)
+}
+
+func (igd *mockIGD) mapping(externalPort string) string {
+	igd.mutex.Lock()
+	defer igd.mutex.Unlock()
+	return igd.mappings[externalPort]
+}
+
+// mockNATPMP is a NAT-PMP router, it maps external port 40000 + the internal port
+func newMockNATPMP(t *testing.T) (string, func()) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buffer := make([]byte, 16)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if n < 2 {
+				continue
+			}
+			response := make([]byte, 16)
+			response[1] = buffer[1] | 0x80
+			switch buffer[1] {
+			case 0:
+				copy(response[8:12], net.IPv4(198, 51, 100, 4).To4())
+				conn.WriteTo(response[:12], from)
+			case 2:
+				internal := binary.BigEndian.Uint16(buffer[4:6])
+				copy(response[8:10], buffer[4:6])
+				if binary.BigEndian.Uint32(buffer[8:12]) != 0 {
+					binary.BigEndian.PutUint16(response[10:12], 40000+internal)
+				}
+				copy(response[12:16], buffer[8:12])
+				conn.WriteTo(response, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestParseNAT(t *testing.T) {
+	for _, spec := range []string{"", "none", "NONE"} {
+		config, err := ParseNAT(spec)
+		if err != nil || config.discover != nil || config.extIP != nil {
+			t.Errorf("ParseNAT(%q) should disable NAT traversal, got %+v %v", spec, config, err)
+		}
+	}
+	for _, spec := range []string{"any", "upnp", "pmp", "pmp:192.168.1.1"} {
+		config, err := ParseNAT(spec)
+		if err != nil || config.discover == nil {
+			t.Errorf("ParseNAT(%q) should enable port mapping, got %+v %v", spec, config, err)
+		}
+	}
+	config, err := ParseNAT("extip:203.0.113.7")
+	if err != nil || config.discover != nil || !config.extIP.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("ParseNAT(extip) got %+v %v", config, err)
+	}
+	for _, spec := range []string{"extip:", "extip:nowhere", "pmp:nowhere", "stun"} {
+		if _, err := ParseNAT(spec); err == nil {
+			t.Errorf("ParseNAT(%q) should fail", spec)
+		}
+	}
+}
+
+func TestUPnPMapping(t *testing.T) {
+	igd := newMockIGD(t)
+	defer igd.Close()
+
+	nat, err := discoverUPnP(igd.ssdp.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := nat.ExternalIP()
+	if err != nil || !ip.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("Expected external address 203.0.113.7, got %s %v", ip, err)
+	}
+
+	mapped, err := nat.AddPortMapping(8108, 8109, "factomd p2p", time.Minute)
+	if err != nil || mapped != 8109 {
+		t.Fatalf("Expected port 8109 to be mapped, got %d %v", mapped, err)
+	}
+	if igd.mapping("8109") != "127.0.0.1:8108" {
+		t.Errorf("Expected 8109 to map to 127.0.0.1:8108, got %q", igd.mapping("8109"))
+	}
+
+	if err := nat.DeletePortMapping(8109); err != nil {
+		t.Error(err)
+	}
+	if igd.mapping("8109") != "" {
+		t.Error("Mapping was not deleted")
+	}
+
+	igd.mutex.Lock()
+	igd.fail = true
+	igd.mutex.Unlock()
+	if _, err := nat.AddPortMapping(8108, 8109, "factomd p2p", time.Minute); err == nil || !strings.Contains(err.Error(), "718") {
+		t.Errorf("Expected the UPnP error to be reported, got %v", err)
+	}
+}
+
+func TestUPnPNoGateway(t *testing.T) {
+	// nobody listens on the address
+	conn, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+	address := conn.LocalAddr().String()
+	conn.Close()
+
+	if _, err := discoverUPnP(address, time.Millisecond*100); err == nil {
+		t.Error("Discovery should fail without a gateway")
+	}
+}
+
+func TestNATPMPMapping(t *testing.T) {
+	gateway, stop := newMockNATPMP(t)
+	defer stop()
+
+	nat := newNATPMP(gateway)
+	ip, err := nat.ExternalIP()
+	if err != nil || !ip.Equal(net.ParseIP("198.51.100.4")) {
+		t.Errorf("Expected external address 198.51.100.4, got %s %v", ip, err)
+	}
+
+	mapped, err := nat.AddPortMapping(8108, 8108, "factomd p2p", time.Minute)
+	if err != nil || mapped != 48108 {
+		t.Fatalf("Expected the router to pick port 48108, got %d %v", mapped, err)
+	}
+	if err := nat.DeletePortMapping(mapped); err != nil {
+		t.Error(err)
+	}
+	if err := nat.DeletePortMapping(mapped); err == nil {
+		t.Error("Deleting an unknown mapping should fail")
+	}
+}
+
+func TestControllerManageNAT(t *testing.T) {
+	igd := newMockIGD(t)
+	defer igd.Close()
+	defer advertisedPort.Store("")
+
+	c := new(Controller)
+	c.logger = controllerLogger
+	c.listenPort = "8108"
+	c.externalAddress = new(ExternalAddress).Init()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.manageNAT(func() (NAT, error) { return discoverUPnP(igd.ssdp.LocalAddr().String(), time.Second) }, stop)
+		close(done)
+	}()
+
+	for i := 0; igd.mapping("8108") == "" && i < 100; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if igd.mapping("8108") == "" {
+		t.Fatal("Listen port was not mapped")
+	}
+	if address := c.externalAddress.Address(); address != "203.0.113.7:8108" {
+		t.Errorf("Expected external address 203.0.113.7:8108, got %q", address)
+	}
+
+	close(stop)
+	<-done
+	if igd.mapping("8108") != "" {
+		t.Error("Mapping was not deleted on shutdown")
+	}
+}
+
+func TestControllerMapPortAdvertised(t *testing.T) {
+	gateway, stop := newMockNATPMP(t)
+	defer stop()
+	defer advertisedPort.Store("")
+
+	oldPort := NetworkListenPort
+	NetworkListenPort = "8108"
+	defer func() { NetworkListenPort = oldPort }()
+
+	c := new(Controller)
+	c.logger = controllerLogger
+	c.externalAddress = new(ExternalAddress).Init()
+
+	if port := c.mapPort(newNATPMP(gateway), 8108, 8108); port != 48108 {
+		t.Fatalf("Expected port 48108, got %d", port)
+	}
+	if address := c.externalAddress.Address(); address != "198.51.100.4:48108" {
+		t.Errorf("Expected external address 198.51.100.4:48108, got %q", address)
+	}
+	// peers learn the port to dial us on from the parcel headers
+	if parcel := NewParcel(CurrentNetwork, []byte("test")); parcel.Header.PeerPort != "48108" {
+		t.Errorf("Expected the mapped port in the header, got %s", parcel.Header.PeerPort)
+	}
+}
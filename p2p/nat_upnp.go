@@ -0,0 +1,279 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A minimal UPnP Internet Gateway Device client: SSDP discovery, the device description,
+// and the WANIPConnection (or WANPPPConnection) port mapping actions.
+
+const ssdpMulticastAddress = "239.255.255.250:1900"
+
+// The services of an IGD that can map ports, in order of preference
+var upnpServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+type upnpNAT struct {
+	serviceType  string // the port mapping service of the router
+	controlURL   string // where to send the SOAP requests for the service
+	internalHost string // our address on the LAN, as seen by the router
+	client       *http.Client
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type upnpDevice struct {
+	DeviceType string        `xml:"deviceType"`
+	Services   []upnpService `xml:"serviceList>service"`
+	Devices    []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+// findService searches the device tree for the service of the given type
+func (d *upnpDevice) findService(serviceType string) *upnpService {
+	for i := range d.Services {
+		if d.Services[i].ServiceType == serviceType {
+			return &d.Services[i]
+		}
+	}
+	for i := range d.Devices {
+		if service := d.Devices[i].findService(serviceType); service != nil {
+			return service
+		}
+	}
+	return nil
+}
+
+// discoverUPnP looks for an Internet Gateway Device by sending an SSDP search to the address
+func discoverUPnP(ssdpAddress string, timeout time.Duration) (NAT, error) {
+	destination, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpMulticastAddress + "\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), destination); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buffer := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("No UPnP gateway answered: %v", err)
+		}
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buffer[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := response.Header.Get("Location")
+		if location == "" {
+			continue
+		}
+		nat, err := newUPnPNAT(location, timeout)
+		if err != nil {
+			natLogger.Debugf("UPnP device at %s is not usable: %v", location, err)
+			continue
+		}
+		return nat, nil
+	}
+}
+
+// newUPnPNAT reads the device description at the location, and sets up the port mapping service
+func newUPnPNAT(location string, timeout time.Duration) (*upnpNAT, error) {
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Device description: %s", response.Status)
+	}
+	var root upnpRoot
+	if err := xml.NewDecoder(response.Body).Decode(&root); err != nil {
+		return nil, err
+	}
+
+	for _, serviceType := range upnpServiceTypes {
+		service := root.Device.findService(serviceType)
+		if service == nil {
+			continue
+		}
+		base := location
+		if root.URLBase != "" {
+			base = root.URLBase
+		}
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+		controlURL, err := baseURL.Parse(service.ControlURL)
+		if err != nil {
+			return nil, err
+		}
+		internalHost, err := localAddressTowards(controlURL.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &upnpNAT{
+			serviceType:  serviceType,
+			controlURL:   controlURL.String(),
+			internalHost: internalHost,
+			client:       client,
+		}, nil
+	}
+	return nil, fmt.Errorf("No port mapping service")
+}
+
+// localAddressTowards returns our address on the interface used to reach the host
+func localAddressTowards(hostPort string) (string, error) {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = hostPort, "80"
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(host, port)) // no packets are sent
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+func (n *upnpNAT) String() string {
+	return fmt.Sprintf("UPnP %s at %s", n.serviceType, n.controlURL)
+}
+
+func (n *upnpNAT) ExternalIP() (net.IP, error) {
+	values, err := n.soapCall("GetExternalIPAddress", nil)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(values["NewExternalIPAddress"])
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid external address: %q", values["NewExternalIPAddress"])
+	}
+	return ip, nil
+}
+
+func (n *upnpNAT) AddPortMapping(internalPort int, externalPort int, description string, lifetime time.Duration) (int, error) {
+	_, err := n.soapCall("AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(externalPort)},
+		{"NewProtocol", "TCP"},
+		{"NewInternalPort", strconv.Itoa(internalPort)},
+		{"NewInternalClient", n.internalHost},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", description},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second))},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return externalPort, nil // UPnP maps the port we ask for, or fails
+}
+
+func (n *upnpNAT) DeletePortMapping(externalPort int) error {
+	_, err := n.soapCall("DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(externalPort)},
+		{"NewProtocol", "TCP"},
+	})
+	return err
+}
+
+// soapCall calls the action of the port mapping service with the arguments (in order), and returns
+// the values of the response
+func (n *upnpNAT) soapCall(action string, arguments [][2]string) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + n.serviceType + `">`)
+	for _, argument := range arguments {
+		body.WriteString("<" + argument[0] + ">")
+		xml.EscapeText(&body, []byte(argument[1]))
+		body.WriteString("</" + argument[0] + ">")
+	}
+	body.WriteString(`</u:` + action + `></s:Body></s:Envelope>`)
+
+	request, err := http.NewRequest("POST", n.controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", `"`+n.serviceType+"#"+action+`"`)
+	response, err := n.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	values, err := soapValues(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("UPnP %s failed: %s %s %s", action, response.Status, values["errorCode"], values["errorDescription"])
+	}
+	return values, nil
+}
+
+// soapValues collects the text of all the leaf elements of a SOAP response by name
+func soapValues(reader io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	decoder := xml.NewDecoder(reader)
+	var name string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			name = element.Name.Local
+		case xml.CharData:
+			if name != "" {
+				values[name] = strings.TrimSpace(string(element))
+			}
+		case xml.EndElement:
+			name = ""
+		}
+	}
+}
@@ -38,6 +38,11 @@ type ParcelHeader struct {
 	PeerPort    string // port of the peer , or we are listening on
 	AppHash     string // Application specific message hash, for tracing
 	AppType     string // Application specific message type, for tracing
+
+	// Set in peer responses only
+	ObservedAddress   string // address we see the requesting peer's connection coming from
+	AdvertisedAddress string // "host:port" other peers can reach us on, "" if we don't know
+	Activations       string // hash of the activation schedule of the peer, "" if it doesn't say
 }
 
 type ParcelCommandType uint16
@@ -52,6 +57,8 @@ const ( // iota is reset to 0
 	TypeAlert                                 // network wide alerts (used in bitcoin to indicate criticalities)
 	TypeMessage                               // Application level message
 	TypeMessagePart                           // Application level message that was split into multiple parts
+	TypeInventory                             // "I have this application message" (announces the AppHash)
+	TypeGetData                               // "Please send me this application message"
 )
 
 // CommandStrings is a Map of command ids to strings for easy printing of network comands
@@ -64,6 +71,8 @@ var CommandStrings = map[ParcelCommandType]string{
 	TypeAlert:        "Alert",         // network wide alerts (used in bitcoin to indicate criticalities)
 	TypeMessage:      "Message",       // Application level message
 	TypeMessagePart:  "MessagePart",   // Application level message that was split into multiple parts
+	TypeInventory:    "Inventory",     // "I have this application message" (announces the AppHash)
+	TypeGetData:      "GetData",       // "Please send me this application message"
 }
 
 // MaxPayloadSize is the maximum bytes a message can be at the networking level.
@@ -128,8 +137,8 @@ func (p *ParcelHeader) Init(network NetworkID) *ParcelHeader {
 	p.Network = network
 	p.Version = ProtocolVersion
 	p.Type = TypeMessage
-	p.TargetPeer = ""              // initially no target
-	p.PeerPort = NetworkListenPort // store our listening port
+	p.TargetPeer = ""                // initially no target
+	p.PeerPort = getAdvertisedPort() // store our listening port, or the port mapped to it on the router
 	return p
 }
 
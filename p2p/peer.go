@@ -6,6 +6,7 @@ package p2p
 
 import (
 	"fmt"
+	"math/big"
 	"math/rand"
 	"net"
 	"time"
@@ -19,21 +20,36 @@ var peerLogger = packageLogger.WithField("subpack", "peer")
 
 type Peer struct {
 	QualityScore int32     // 0 is neutral quality, negative is a bad peer.
-	Address      string    // Must be in form of x.x.x.x
+	Address      string    // Must be in form of x.x.x.x or an IPv6 address (without brackets)
 	Port         string    // Must be in form of xxxx
 	NodeID       uint64    // a nonce to distinguish multiple nodes behind one IP address
 	Hash         string    // This is more of a connection ID than hash right now.
-	Location     uint32    // IP address as an int.
+	Location     uint32    // IP address as an int (IPv4), or the leading 32 bits of the routing prefix (IPv6).
 	Network      NetworkID // The network this peer reference lives on.
 	Type         uint8
 	Connections  int                  // Number of successful connections.
 	LastContact  time.Time            // Keep track of how long ago we talked to the peer.
 	Source       map[string]time.Time // source where we heard from the peer.
 
+	// Reputation history, kept in the peer database (peers.json) across restarts
+	FirstSeen         time.Time     // When we first heard about the peer.
+	FailedConnections int           // Number of failed dial attempts.
+	Latency           time.Duration // Observed ping round trip time (smoothed).
+	Version           uint16        // Protocol version the peer last talked to us with.
+	BannedUntil       time.Time     // The peer is banned until this time.
+	BanHistory        []PeerBan     // All the times the peer was banned.
+
 	// logging
 	logger *log.Entry
 }
 
+// PeerBan records a ban of a peer
+type PeerBan struct {
+	Time   time.Time // When the peer was banned
+	Until  time.Time // When the ban ends
+	Reason string
+}
+
 const (
 	RegularPeer        uint8 = iota
 	SpecialPeerConfig        // special peer defined in the config file
@@ -56,6 +72,8 @@ func (p *Peer) Init(address string, port string, quality int32, peerType uint8,
 			address = ipAddress[0]
 		}
 	}
+	// Use the canonical text form so the same IPv6 host is never known under two spellings
+	address = normalizeAddress(address)
 
 	p.Address = address
 	p.Port = port
@@ -65,23 +83,36 @@ func (p *Peer) Init(address string, port string, quality int32, peerType uint8,
 	p.Location = p.LocationFromAddress()
 	p.Source = map[string]time.Time{}
 	p.Network = CurrentNetwork
+	p.FirstSeen = time.Now()
 	return p
 }
 
+// normalizeAddress returns the canonical text form of an IP address, which is how peers are known
+func normalizeAddress(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
 func (p *Peer) generatePeerHash() {
-	p.Hash = fmt.Sprintf("%s:%s %x", p.Address, p.Port, rand.Int63())
+	p.Hash = fmt.Sprintf("%s %x", p.AddressPort(), rand.Int63())
 }
 
+// AddressPort returns the dialable "host:port" form of the peer, bracketing IPv6 addresses
 func (p *Peer) AddressPort() string {
-	return p.Address + ":" + p.Port
+	return net.JoinHostPort(p.Address, p.Port)
 }
 
 func (p *Peer) PeerIdent() string {
-	return p.Hash[0:12] + "-" + p.Address + ":" + p.Port
+	return p.Hash[0:12] + "-" + p.AddressPort()
 }
 
 func (p *Peer) PeerFixedIdent() string {
 	address := fmt.Sprintf("%16s", p.Address)
+	if p.IsIPv6() {
+		address = "[" + address + "]"
+	}
 	return p.Hash[0:12] + "-" + address + ":" + p.Port
 }
 
@@ -107,21 +138,35 @@ func (p *Peer) LastSource() (result string) {
 	return
 }
 
-// TODO Hadn't considered IPV6 address support.
-// TODO Need to audit all the net code to check IPv6 addresses
-// Here's an IPv6 conversion:
+// ipToInt converts an IP address into its numeric value. IPv4 addresses (including
+// IPv4-mapped IPv6 addresses) use their 4 byte form, IPv6 addresses their 16 byte form.
 // Ref: http://stackoverflow.com/questions/23297141/golang-net-ip-to-ipv6-from-mysql-as-decimal39-0-conversion
-// func ipv6ToInt(IPv6Addr net.IP) *big.Int {
-//     IPv6Int := big.NewInt(0)
-//     IPv6Int.SetBytes(IPv6Addr)
-//     return IPv6Int
-// }
+func ipToInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return new(big.Int).SetBytes(ip4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// ip returns the parsed address of the peer, or nil if the address is not an IP address
+func (p *Peer) ip() net.IP {
+	return net.ParseIP(p.Address)
+}
+
+// IsIPv6 returns true if the peer has an IPv6 address that is not an IPv4-mapped address
+func (p *Peer) IsIPv6() bool {
+	ip := p.ip()
+	return ip != nil && ip.To4() == nil
+}
+
 // Problem is we're working with string addresses, may never have made a connection.
 // TODO - we might have a DNS address, not iP address and need to resolve it!
 // locationFromAddress converts the peers address into a uint32 "location" numeric
+// For IPv4 this is the address itself. For IPv6 it is the leading 32 bits of the address, which is
+// the part of the routing prefix that is allocated to a provider, so that peers in the same
+// network share a location. The trailing bits are usually a random interface identifier.
 func (p *Peer) LocationFromAddress() (location uint32) {
 	location = 0
-	// Split the IPv4 octets
 	ip := net.ParseIP(p.Address)
 	if ip == nil {
 		ipAddress, err := net.LookupHost(p.Address)
@@ -133,16 +178,19 @@ func (p *Peer) LocationFromAddress() (location uint32) {
 		}
 		p.Address = ipAddress[0]
 		ip = net.ParseIP(p.Address)
+		if ip == nil {
+			return 0
+		}
 	}
-	if len(ip) == 16 { // If we got back an IP6 (16 byte) address, use the last 4 byte
-		ip = ip[12:]
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
 	}
 	// Turn into uint32
 	location += uint32(ip[0]) << 24
 	location += uint32(ip[1]) << 16
 	location += uint32(ip[2]) << 8
 	location += uint32(ip[3])
-	p.logger.Debugf("peer", "Peer: %s has Location: %d", p.Hash, location)
+	p.logger.Debugf("Peer: %s has Location: %d", p.Hash, location)
 	return location
 }
 
@@ -151,6 +199,9 @@ func (p *Peer) IsSamePeerAs(netAddress net.Addr) bool {
 	if err != nil {
 		return false
 	}
+	if ip, own := net.ParseIP(address), p.ip(); ip != nil && own != nil {
+		return ip.Equal(own)
+	}
 	return address == p.Address
 }
 
@@ -168,6 +219,81 @@ func (p *Peer) demerit() {
 	}
 }
 
+// IsBanned returns true if the peer is banned
+func (p *Peer) IsBanned() bool {
+	return time.Now().Before(p.BannedUntil)
+}
+
+// ban bans the peer for the given duration, and records it in the ban history
+func (p *Peer) ban(reason string, duration time.Duration) {
+	now := time.Now()
+	p.BannedUntil = now.Add(duration)
+	p.BanHistory = append(p.BanHistory, PeerBan{Time: now, Until: p.BannedUntil, Reason: reason})
+}
+
+// updateLatency smooths a newly observed round trip time into the peer's latency
+func (p *Peer) updateLatency(rtt time.Duration) {
+	if p.Latency == 0 {
+		p.Latency = rtt
+		return
+	}
+	p.Latency = (3*p.Latency + rtt) / 4
+}
+
+// mergeHistory fills in the reputation history from the peer database. The connection's copy of a
+// peer has the live values (quality, latency, version, counts), while bans are owned by the database.
+func (p *Peer) mergeHistory(known Peer) {
+	if !known.FirstSeen.IsZero() && (p.FirstSeen.IsZero() || known.FirstSeen.Before(p.FirstSeen)) {
+		p.FirstSeen = known.FirstSeen
+	}
+	if known.Connections > p.Connections {
+		p.Connections = known.Connections
+	}
+	if known.FailedConnections > p.FailedConnections {
+		p.FailedConnections = known.FailedConnections
+	}
+	if p.Latency == 0 {
+		p.Latency = known.Latency
+	}
+	if p.Version == 0 {
+		p.Version = known.Version
+	}
+	if known.LastContact.After(p.LastContact) {
+		p.LastContact = known.LastContact
+	}
+	if nil == p.Source {
+		p.Source = map[string]time.Time{}
+	}
+	for source, seen := range known.Source {
+		if _, present := p.Source[source]; !present {
+			p.Source[source] = seen
+		}
+	}
+	p.BannedUntil = known.BannedUntil
+	p.BanHistory = known.BanHistory
+}
+
+// clearHistory resets the reputation history of a peer we learned about from another node,
+// their view of the peer is not ours.
+func (p *Peer) clearHistory() {
+	p.QualityScore = 0
+	p.FirstSeen = time.Now()
+	p.Connections = 0
+	p.FailedConnections = 0
+	p.Latency = 0
+	p.Version = 0
+	p.BannedUntil = time.Time{}
+	p.BanHistory = nil
+}
+
+// reputation ranks peers for dialing, between 0 and 1. Peers we reliably connected to and with a
+// low latency rank higher. Peers we never tried rank 0.5.
+func (p *Peer) reputation() float64 {
+	// successful connections out of all the attempts, with one of each assumed so new peers are neutral
+	reputation := float64(p.Connections+1) / float64(p.Connections+p.FailedConnections+2)
+	return reputation / (1 + p.Latency.Seconds())
+}
+
 func (p *Peer) IsSpecial() bool {
 	return p.Type == SpecialPeerConfig || p.Type == SpecialPeerCmdLine
 }
@@ -198,6 +324,23 @@ func (p PeerQualitySort) Less(i, j int) bool {
 	return p[i].QualityScore < p[j].QualityScore
 }
 
+// sort.Sort interface implementation
+type PeerReputationSort []Peer
+
+func (p PeerReputationSort) Len() int {
+	return len(p)
+}
+func (p PeerReputationSort) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+func (p PeerReputationSort) Less(i, j int) bool {
+	a, b := p[i].reputation(), p[j].reputation()
+	if a == b {
+		return p[i].QualityScore < p[j].QualityScore
+	}
+	return a < b
+}
+
 // sort.Sort interface implementation
 type PeerDistanceSort []Peer
 
@@ -207,6 +350,17 @@ func (p PeerDistanceSort) Len() int {
 func (p PeerDistanceSort) Swap(i, j int) {
 	p[i], p[j] = p[j], p[i]
 }
+
+// Less orders IPv4 peers before IPv6 peers, and then by numeric address, so that
+// peers next to each other in the sorted list are also close in the address space.
 func (p PeerDistanceSort) Less(i, j int) bool {
-	return p[i].Location < p[j].Location
+	a, b := p[i].ip(), p[j].ip()
+	switch {
+	case a == nil || b == nil:
+		return p[i].Location < p[j].Location
+	case (a.To4() == nil) != (b.To4() == nil):
+		return a.To4() != nil
+	default:
+		return ipToInt(a).Cmp(ipToInt(b)) < 0
+	}
 }
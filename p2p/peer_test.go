@@ -0,0 +1,187 @@
+package p2p
+
+import (
+	"net"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPeerLocationFromAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		location uint32
+	}{
+		{"1.2.3.4", 0x01020304},
+		{"::ffff:1.2.3.4", 0x01020304}, // IPv4-mapped address is treated as IPv4
+		{"2001:db8:aaaa:1::1", 0x20010db8},
+		{"2001:db8:bbbb:2::2", 0x20010db8},
+		{"fe80::1", 0xfe800000},
+	}
+	for _, test := range tests {
+		peer := newPeer(test.address, "8108", RegularPeer)
+		if peer.Location != test.location {
+			t.Errorf("Location of %s was %x, expected %x", test.address, peer.Location, test.location)
+		}
+	}
+}
+
+func TestPeerAddressNormalization(t *testing.T) {
+	peer := newPeer("2001:0DB8:0000:0000:0000:0000:0000:0001", "8108", RegularPeer)
+	if peer.Address != "2001:db8::1" {
+		t.Errorf("Address was not normalized: %s", peer.Address)
+	}
+	if !peer.IsIPv6() {
+		t.Error("IPv6 peer not detected as IPv6")
+	}
+	if peer.AddressPort() != "[2001:db8::1]:8108" {
+		t.Errorf("AddressPort not bracketed: %s", peer.AddressPort())
+	}
+
+	v4 := newPeer("::ffff:10.0.0.1", "8108", RegularPeer)
+	if v4.IsIPv6() {
+		t.Error("IPv4-mapped peer detected as IPv6")
+	}
+	if v4.AddressPort() != "10.0.0.1:8108" {
+		t.Errorf("AddressPort wrong for IPv4 peer: %s", v4.AddressPort())
+	}
+}
+
+func TestPeerIsSamePeerAs(t *testing.T) {
+	peer := newPeer("2001:db8::1", "8108", RegularPeer)
+	addr := &net.TCPAddr{IP: net.ParseIP("2001:db8:0::1"), Port: 51234}
+	if !peer.IsSamePeerAs(addr) {
+		t.Errorf("%s should be the same peer as %s", peer.Address, addr)
+	}
+	other := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 8108}
+	if peer.IsSamePeerAs(other) {
+		t.Errorf("%s should not be the same peer as %s", peer.Address, other)
+	}
+
+	v4 := newPeer("1.2.3.4", "8108", RegularPeer)
+	if !v4.IsSamePeerAs(&net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 1}) {
+		t.Error("IPv4 peer not matched")
+	}
+}
+
+func TestPeerDistanceSortMixedStack(t *testing.T) {
+	peers := []Peer{
+		*newPeer("2001:db8::ffff", "8108", RegularPeer),
+		*newPeer("10.0.0.2", "8108", RegularPeer),
+		*newPeer("2001:db8::1", "8108", RegularPeer),
+		*newPeer("1.2.3.4", "8108", RegularPeer),
+		*newPeer("2001:db8:1::", "8108", RegularPeer),
+	}
+	sort.Sort(PeerDistanceSort(peers))
+
+	expected := []string{"1.2.3.4", "10.0.0.2", "2001:db8::1", "2001:db8::ffff", "2001:db8:1::"}
+	for i, address := range expected {
+		if peers[i].Address != address {
+			t.Errorf("Position %d: got %s, expected %s", i, peers[i].Address, address)
+		}
+	}
+}
+
+func TestParseSpecialPeersIPv6(t *testing.T) {
+	c := new(Controller)
+	c.logger = controllerLogger
+
+	peers := c.parseSpecialPeers("1.2.3.4:8108 [2001:db8::1]:8110 [::ffff:5.6.7.8]:8111 2001:db8::2", SpecialPeerConfig)
+	if len(peers) != 3 {
+		t.Fatalf("Expected 3 peers, got %d", len(peers))
+	}
+	expected := []string{"1.2.3.4:8108", "[2001:db8::1]:8110", "5.6.7.8:8111"}
+	for i, addressPort := range expected {
+		if peers[i].AddressPort() != addressPort {
+			t.Errorf("Peer %d: got %s, expected %s", i, peers[i].AddressPort(), addressPort)
+		}
+		if peers[i].Type != SpecialPeerConfig {
+			t.Errorf("Peer %d has type %d", i, peers[i].Type)
+		}
+	}
+}
+
+func TestLimitListenerSourcesIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	limited := LimitListenerSources(listener)
+	defer limited.Close()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err == nil {
+				defer conn.Close()
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	conn, err := limited.Accept()
+	if err != nil {
+		t.Fatalf("First connection should be accepted: %v", err)
+	}
+	conn.Close()
+	if _, err := limited.Accept(); err == nil {
+		t.Error("Second connection from the same IPv6 address within a second should be rate limited")
+	}
+	if _, ok := limited.(*limitListenerSources).accepted["::1"]; !ok {
+		t.Error("Rate limiter did not record the IPv6 source address")
+	}
+}
+
+func TestPeerMergeHistory(t *testing.T) {
+	known := *newPeer("1.2.3.4", "8108", RegularPeer)
+	known.FirstSeen = time.Now().Add(-time.Hour)
+	known.Connections = 5
+	known.FailedConnections = 2
+	known.Latency = time.Second
+	known.Version = 9
+	known.ban("test", time.Hour)
+
+	live := *newPeer("1.2.3.4", "8108", RegularPeer)
+	live.QualityScore = 42
+	live.Connections = 1
+	live.Version = 10
+	live.mergeHistory(known)
+
+	if !live.FirstSeen.Equal(known.FirstSeen) {
+		t.Errorf("FirstSeen was not kept: %s", live.FirstSeen)
+	}
+	if live.Connections != 5 || live.FailedConnections != 2 {
+		t.Errorf("Connection counts were not kept: %d/%d", live.Connections, live.FailedConnections)
+	}
+	if live.Latency != time.Second {
+		t.Errorf("Latency was not kept: %s", live.Latency)
+	}
+	if live.Version != 10 || live.QualityScore != 42 {
+		t.Errorf("Live values were overwritten: version %d quality %d", live.Version, live.QualityScore)
+	}
+	if !live.IsBanned() || len(live.BanHistory) != 1 || live.BanHistory[0].Reason != "test" {
+		t.Errorf("Ban was not kept: %+v", live.BanHistory)
+	}
+}
+
+func TestPeerReputationSort(t *testing.T) {
+	reliable := *newPeer("1.1.1.1", "8108", RegularPeer)
+	reliable.Connections = 10
+	untried := *newPeer("2.2.2.2", "8108", RegularPeer)
+	flaky := *newPeer("3.3.3.3", "8108", RegularPeer)
+	flaky.Connections = 1
+	flaky.FailedConnections = 10
+	slow := *newPeer("4.4.4.4", "8108", RegularPeer)
+	slow.Connections = 10
+	slow.Latency = 2 * time.Second
+
+	peers := []Peer{flaky, slow, untried, reliable}
+	sort.Sort(sort.Reverse(PeerReputationSort(peers)))
+
+	expected := []string{"1.1.1.1", "2.2.2.2", "4.4.4.4", "3.3.3.3"}
+	for i, address := range expected {
+		if peers[i].Address != address {
+			t.Errorf("Position %d: got %s, expected %s", i, peers[i].Address, address)
+		}
+	}
+}
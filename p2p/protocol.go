@@ -48,6 +48,7 @@ var (
 	NetworkListenPort                   = "8108"
 	BroadcastFlag                       = "<BROADCAST>"
 	FullBroadcastFlag                   = "<FULLBORADCAST>"
+	InventoryBroadcastFlag              = "<INVBROADCAST>"
 	RandomPeerFlag                      = "<RANDOMPEER>"
 	NodeID                       uint64 = 0           // Random number used for loopback protection
 	MinumumQualityScore          int32  = -200        // if a peer's score is less than this we ignore them.
@@ -68,6 +69,7 @@ var (
 	PeerSaveInterval                    = time.Second * 30
 	PeerRequestInterval                 = time.Second * 180
 	PeerDiscoveryInterval               = time.Hour * 4
+	PeerBanDuration                     = time.Hour * 168
 
 	// Testing metrics
 	TotalMessagesReceived       uint64
@@ -81,7 +83,7 @@ var (
 
 const (
 	// ProtocolVersion is the latest version this package supports
-	ProtocolVersion uint16 = 9
+	ProtocolVersion uint16 = 10
 	// ProtocolVersionMinimum is the earliest version this package supports
 	ProtocolVersionMinimum uint16 = 9
 )
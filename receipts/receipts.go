@@ -5,9 +5,11 @@
 package receipts
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
+	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/directoryBlock/dbInfo"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
@@ -83,7 +85,7 @@ func (e *Receipt) Validate() error {
 				right = node.Right
 			}
 		}
-		if left.IsSameAs(currentEntry) == false && left.IsSameAs(currentEntry) {
+		if left.IsSameAs(currentEntry) == false && right.IsSameAs(currentEntry) == false {
 			return fmt.Errorf("Entry %v not found in node %v/%v", currentEntry, i, len(e.MerkleBranch))
 		}
 		top := primitives.HashMerkleBranches(left, right)
@@ -332,40 +334,130 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 	//str, _ := eBlock.JSONString()
 	//fmt.Printf("eBlock - %v\n\n", str)
 
-	//DBlock
+	err = appendDBlockBranch(dbo, receipt)
+	if err != nil {
+		return nil, err
+	}
 
-	hash, err = dbo.FetchIncludedIn(hash)
+	return receipt, nil
+}
+
+// CreateTransactionReceipt creates a receipt for a factoid transaction, by its transaction ID or its
+// full hash. The receipt proves the full hash into the factoid block, the EntryBlockKeyMR of the
+// receipt is the KeyMR of the factoid block, and Entry.Raw has the transaction so the full hash can
+// be tied to the transaction ID.
+func CreateTransactionReceipt(dbo interfaces.DBOverlaySimple, txID interfaces.IHash) (*Receipt, error) {
+	hash, err := dbo.FetchIncludedIn(txID)
 	if err != nil {
 		return nil, err
 	}
+	if hash == nil {
+		return nil, fmt.Errorf("Block containing transaction not found")
+	}
+
+	fBlock, err := dbo.FetchFBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	if fBlock == nil {
+		return nil, fmt.Errorf("FBlock not found")
+	}
+
+	tx := fBlock.GetTransactionByHash(txID)
+	if tx == nil {
+		return nil, fmt.Errorf("Transaction not found in FBlock")
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := new(Receipt)
+	receipt.Entry = new(JSON)
+	receipt.Entry.EntryHash = tx.GetHash().String()
+	receipt.Entry.Raw = hex.EncodeToString(raw)
+	receipt.EntryBlockKeyMR = fBlock.GetKeyMR().(*primitives.Hash)
+
+	branch := primitives.BuildMerkleBranchForEntryHash(FBlockBodyHashes(fBlock), tx.GetHash(), true)
+	if branch == nil {
+		return nil, fmt.Errorf("Transaction not found in the body of the FBlock")
+	}
+	header, err := fBlock.MarshalHeader()
+	if err != nil {
+		return nil, err
+	}
+	blockNode := new(primitives.MerkleNode)
+	blockNode.Left = primitives.Sha(header).(*primitives.Hash)
+	blockNode.Right = fBlock.GetBodyMR().(*primitives.Hash)
+	blockNode.Top = receipt.EntryBlockKeyMR
+	branch = append(branch, blockNode)
+	receipt.MerkleBranch = append(receipt.MerkleBranch, branch...)
+
+	err = appendDBlockBranch(dbo, receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// FBlockBodyHashes returns the leaves of the body Merkle tree of a factoid block: the full hashes of
+// the transactions, and a marker for the end of every minute
+func FBlockBodyHashes(fBlock interfaces.IFBlock) []interfaces.IHash {
+	endOfPeriod := fBlock.GetEndOfPeriod()
+	var hashes []interfaces.IHash
+	marker := 0
+	for i, tx := range fBlock.GetTransactions() {
+		for marker < len(endOfPeriod) && i != 0 && i == endOfPeriod[marker] {
+			marker++
+			hashes = append(hashes, primitives.Sha(constants.ZERO))
+		}
+		hashes = append(hashes, tx.GetHash())
+	}
+	for marker < len(endOfPeriod) {
+		marker++
+		hashes = append(hashes, primitives.Sha(constants.ZERO))
+	}
+	return hashes
+}
+
+// appendDBlockBranch appends the branch from the block the receipt proves into (EntryBlockKeyMR) to
+// the directory block that holds it
+func appendDBlockBranch(dbo interfaces.DBOverlaySimple, receipt *Receipt) error {
+	//DBlock
+
+	hash, err := dbo.FetchIncludedIn(receipt.EntryBlockKeyMR)
+	if err != nil {
+		return err
+	}
 
 	if hash == nil {
-		return nil, fmt.Errorf("Block containing EBlock not found")
+		return fmt.Errorf("Block containing EBlock not found")
 	}
 
 	dBlock, err := dbo.FetchDBlock(hash)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if dBlock == nil {
-		return nil, fmt.Errorf("DBlock not found")
+		return fmt.Errorf("DBlock not found")
 	}
 
 	//str, _ = dBlock.JSONString()
 	//fmt.Printf("dBlock - %v\n\n", str)
 
-	entries = dBlock.GetEntryHashesForBranch()
+	entries := dBlock.GetEntryHashesForBranch()
 	//fmt.Printf("dBlock entries - %v\n\n", entries)
 
 	//merkleTree := primitives.BuildMerkleTreeStore(entries)
 	//fmt.Printf("dBlock merkleTree - %v\n\n", merkleTree)
 
-	branch = primitives.BuildMerkleBranchForEntryHash(entries, receipt.EntryBlockKeyMR, true)
-	blockNode = new(primitives.MerkleNode)
-	left, err = dBlock.GetHeaderHash()
+	branch := primitives.BuildMerkleBranchForEntryHash(entries, receipt.EntryBlockKeyMR, true)
+	blockNode := new(primitives.MerkleNode)
+	left, err := dBlock.GetHeaderHash()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	blockNode.Left = left.(*primitives.Hash)
 	blockNode.Right = dBlock.BodyKeyMR().(*primitives.Hash)
@@ -381,7 +473,7 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 
 	dirBlockInfo, err := dbo.FetchDirBlockInfoByKeyMR(hash)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if dirBlockInfo != nil {
@@ -391,7 +483,7 @@ func CreateReceipt(dbo interfaces.DBOverlaySimple, entryID interfaces.IHash) (*R
 		receipt.BitcoinBlockHash = dbi.BTCBlockHash.(*primitives.Hash)
 	}
 
-	return receipt, nil
+	return nil
 }
 
 func VerifyFullReceipt(dbo interfaces.DBOverlaySimple, receiptStr string) error {
@@ -1,9 +1,12 @@
 package state
 
 import (
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
 )
 
 // This identifies a specific process list slot
@@ -24,14 +27,54 @@ type MMRInfo struct {
 	asks      chan askRef // Requests to ask for missing messages
 	adds      chan plRef  // notices of slots filled in the process list
 	dbheights chan int    // Notice that this DBHeight is done
+
+	// The pending asks, for the process list API
+	mmrMutex   sync.Mutex
+	mmrPending map[plRef]*int64
+}
+
+// MissingAsk is a process list slot the node is missing, and when it asks the network for it
+type MissingAsk struct {
+	DBHeight int   `json:"dbheight"`
+	VM       int   `json:"vm"`
+	Height   int   `json:"height"`
+	When     int64 `json:"when"` // Milliseconds
+}
+
+// GetMissingAsks returns the pending asks for missing messages of a height, of every height if dbheight
+// is negative
+func (s *State) GetMissingAsks(dbheight int) []MissingAsk {
+	s.mmrMutex.Lock()
+	defer s.mmrMutex.Unlock()
+	asks := []MissingAsk{}
+	for ref, when := range s.mmrPending {
+		if dbheight < 0 || ref.DBH == dbheight {
+			asks = append(asks, MissingAsk{ref.DBH, ref.VM, ref.H, *when})
+		}
+	}
+	sort.Slice(asks, func(i, j int) bool {
+		a, b := asks[i], asks[j]
+		if a.DBHeight != b.DBHeight {
+			return a.DBHeight < b.DBHeight
+		}
+		if a.VM != b.VM {
+			return a.VM < b.VM
+		}
+		return a.Height < b.Height
+	})
+	return asks
 }
 
 // starts the MMR processing for this state
 func (s *State) startMMR() {
-	s.asks = make(chan askRef, 1)
-	s.adds = make(chan plRef, 1)
-	s.dbheights = make(chan int, 1)
-	go s.makeMMRs(s.asks, s.adds, s.dbheights)
+	size := 1
+	if primitives.IsClockVirtual() {
+		size = 1000 // the senders can't block waiting for makeMMRs under a virtual clock
+	}
+	s.asks = make(chan askRef, size)
+	s.adds = make(chan plRef, size)
+	s.dbheights = make(chan int, size)
+	primitives.Go(func() { s.makeMMRs(s.asks, s.adds, s.dbheights) })
 }
 
 // Ask VM for an MMR for this height with delay ms before asking the network
@@ -89,11 +132,16 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 	var dbheight int // current process list height
 
 	pending := make(map[plRef]*int64)
+	s.mmrMutex.Lock()
+	s.mmrPending = pending
+	s.mmrMutex.Unlock()
 	ticker := make(chan int64, 50)               // this should deep enough you know that the reading thread is dead if it fills up
 	mmrs := make(map[dbhvm]*messages.MissingMsg) // an MMR per DBH/VM
 	logname := "missing_messages"
 
 	addAsk := func(ask askRef) {
+		s.mmrMutex.Lock()
+		defer s.mmrMutex.Unlock()
 		_, ok := pending[ask.plRef]
 		if !ok {
 			when := ask.When
@@ -103,6 +151,8 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 	}
 
 	addAdd := func(add plRef) {
+		s.mmrMutex.Lock()
+		defer s.mmrMutex.Unlock()
 		delete(pending, add) // Delete request that was just added to the process list in the map
 		s.LogPrintf(logname, "Add %d/%d/%d %d", add.DBH, add.VM, add.H, len(pending))
 	}
@@ -146,23 +196,23 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 	}
 
 	// tick ever second to check the  pending MMRs
-	go func() {
+	primitives.Go(func() {
 		for {
 			if len(ticker) == cap(ticker) {
 				return
 			} // time to die, no one is listening
 
 			ticker <- s.GetTimestamp().GetTimeMilli()
-			time.Sleep(20 * time.Millisecond)
+			primitives.Sleep(20 * time.Millisecond)
 		}
-	}()
+	})
 
 	lastAskDelay := int64(0)
 	for {
 		// You have to compute this at every cycle as you can change the block time
 		// in sim control.
 		// blocktime in milliseconds
-		askDelay := int64(s.DirectoryBlockInSeconds*1000) / 50
+		askDelay := int64(s.GetDirectoryBlockInSeconds()*1000) / 50
 		// Take 1/5 of 1 minute boundary (DBlock is 10*min)
 		//		This means on 10min block, 12 second delay
 		//					  1min block, 1.2 second delay
@@ -172,20 +222,26 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 		}
 
 		if askDelay != lastAskDelay {
-			s.LogPrintf(logname, "AskDelay %d BlockTime %d", askDelay, s.DirectoryBlockInSeconds)
+			s.LogPrintf(logname, "AskDelay %d BlockTime %d", askDelay, s.GetDirectoryBlockInSeconds())
 			lastAskDelay = askDelay
 		}
 
+		if primitives.IsClockVirtual() && len(dbheights) == 0 && len(asks) == 0 && len(adds) == 0 && len(ticker) == 0 {
+			primitives.Sleep(QueuePollInterval) // can't block on the channels under a virtual clock
+			continue
+		}
 		select {
 		case dbheight = <-dbheights:
 			// toss any old pending requests when the height moves up
 			// todo: Keep asks in a  list so cleanup is more efficient
+			s.mmrMutex.Lock()
 			for ask, _ := range pending {
 				if int(ask.DBH) < dbheight {
 					s.LogPrintf(logname, "Expire %d/%d/%d %d", ask.DBH, ask.VM, ask.H, len(pending))
 					delete(pending, ask)
 				}
 			}
+			s.mmrMutex.Unlock()
 		case ask := <-asks:
 			addAsk(ask)
 			addAllAsks()
@@ -204,6 +260,7 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 			// time offset to pick asks to
 
 			//build MMRs with all the asks expired asks.
+			s.mmrMutex.Lock()
 			for ref, when := range pending {
 				var index dbhvm = dbhvm{ref.DBH, ref.VM}
 				// if ask is expired or we have an MMR for this DBH/VM and it's not a brand new ask
@@ -218,6 +275,7 @@ func (s *State) makeMMRs(asks <-chan askRef, adds <-chan plRef, dbheights <-chan
 					// Maybe when asking for past the end of the list we should not ask again?
 				}
 			} //build a MMRs with all the expired asks in that VM at that DBH.
+			s.mmrMutex.Unlock()
 
 			for index, mmr := range mmrs {
 				s.LogMessage(logname, "sendout", mmr)
@@ -2,6 +2,7 @@ package state
 
 import (
 	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
 )
 
 // APIMSGQueue counts incoming and outgoing messages for API queue
@@ -43,6 +44,9 @@ func (q APIMSGQueue) Dequeue() interfaces.IMsg {
 
 // BlockingDequeue will block until it retrieves from queue
 func (q APIMSGQueue) BlockingDequeue() interfaces.IMsg {
+	if primitives.IsClockVirtual() {
+		return pollDequeue(q)
+	}
 	v := <-q
 	measureMessage(CurrentMessageQueueApiGeneralVec, v, false)
 	return v
@@ -0,0 +1,191 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/interfaces"
+)
+
+// AuthorityChange is an admin block entry that changed the authority set or the keys of an authority
+type AuthorityChange struct {
+	Height          uint32              `json:"height"` // Admin block of the entry
+	Type            string              `json:"type"`
+	IdentityChainID string              `json:"identitychainid"`
+	Entry           interfaces.IABEntry `json:"entry"`
+}
+
+// AuthoritySet is the authority set that signs the directory block at Height, made by the admin blocks
+// below it
+type AuthoritySet struct {
+	Height      uint32                `json:"height"`
+	ServerCount int                   `json:"servercount"` // Added by the admin blocks, on top of the default
+	Federated   []*identity.Authority `json:"federated"`
+	Audit       []*identity.Authority `json:"audit"`
+}
+
+// AuthorityHistory indexes the authority changes of the saved admin blocks. It is built from the database
+// the first time it is asked for, and catches up with the blocks saved since on every call.
+type AuthorityHistory struct {
+	mutex   sync.Mutex
+	next    uint32 // Next admin block to index
+	Changes []AuthorityChange
+}
+
+// authorityChange returns the change an admin block entry makes, and false for the entries that do not
+// change authorities
+func authorityChange(height uint32, abe interfaces.IABEntry) (AuthorityChange, bool) {
+	c := AuthorityChange{Height: height, Entry: abe}
+	var chainID interfaces.IHash
+	switch e := abe.(type) {
+	case *adminBlock.AddFederatedServer:
+		c.Type, chainID = "add-federated-server", e.IdentityChainID
+	case *adminBlock.AddAuditServer:
+		c.Type, chainID = "add-audit-server", e.IdentityChainID
+	case *adminBlock.RemoveFederatedServer:
+		c.Type, chainID = "remove-federated-server", e.IdentityChainID
+	case *adminBlock.AddFederatedServerSigningKey:
+		c.Type, chainID = "signing-key", e.IdentityChainID
+	case *adminBlock.AddFederatedServerBitcoinAnchorKey:
+		c.Type, chainID = "bitcoin-key", e.IdentityChainID
+	case *adminBlock.AddReplaceMatryoshkaHash:
+		c.Type, chainID = "matryoshka-hash", e.IdentityChainID
+	case *adminBlock.AddFactoidAddress:
+		c.Type, chainID = "coinbase-address", e.IdentityChainID
+	case *adminBlock.AddEfficiency:
+		c.Type, chainID = "efficiency", e.IdentityChainID
+	case *adminBlock.ServerFault:
+		c.Type, chainID = "server-fault", e.ServerID
+	case *adminBlock.IncreaseServerCount:
+		c.Type = "server-count"
+	default:
+		return c, false
+	}
+	if chainID != nil {
+		c.IdentityChainID = chainID.String()
+	}
+	return c, true
+}
+
+// update indexes the admin blocks saved since the last call, and returns the highest saved block
+func (h *AuthorityHistory) update(db interfaces.DBOverlaySimple) (uint32, error) {
+	head, err := db.FetchDBlockHead()
+	if err != nil {
+		return 0, err
+	}
+	if head == nil {
+		return 0, fmt.Errorf("No directory blocks saved")
+	}
+	top := head.GetDatabaseHeight()
+
+	// The database was rewound under us, start over
+	if h.next > top+1 {
+		h.next = 0
+		h.Changes = nil
+	}
+	for ; h.next <= top; h.next++ {
+		ablock, err := db.FetchABlockByHeight(h.next)
+		if err != nil {
+			return 0, err
+		}
+		if ablock == nil {
+			return 0, fmt.Errorf("Admin block %d not found", h.next)
+		}
+		for _, abe := range ablock.GetABEntries() {
+			if c, ok := authorityChange(h.next, abe); ok {
+				h.Changes = append(h.Changes, c)
+			}
+		}
+	}
+	return top, nil
+}
+
+// AuthoritySetAt replays the indexed changes below the height on top of the bootstrap identity of the
+// network, and returns the authorities it leaves, sorted by chain ID. Heights past the last saved block
+// return the current set.
+func (h *AuthorityHistory) AuthoritySetAt(db interfaces.DBOverlaySimple, bootstrapIdentity interfaces.IHash, bootstrapKey interfaces.IHash, height uint32) (*AuthoritySet, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	top, err := h.update(db)
+	if err != nil {
+		return nil, err
+	}
+	if height > top+1 {
+		height = top + 1
+	}
+
+	im := identity.NewIdentityManager()
+	im.SetBootstrapIdentity(bootstrapIdentity, bootstrapKey)
+	for _, c := range h.Changes {
+		if c.Height >= height {
+			break
+		}
+		// Keys of authorities removed since fail to apply, as they do in the node
+		im.ProcessABlockEntry(c.Entry, nil)
+	}
+
+	s := new(AuthoritySet)
+	s.Height = height
+	s.ServerCount = im.AuthorityServerCount
+	s.Federated = []*identity.Authority{}
+	s.Audit = []*identity.Authority{}
+	for _, a := range im.Authorities {
+		switch a.Status {
+		case constants.IDENTITY_FEDERATED_SERVER:
+			s.Federated = append(s.Federated, a)
+		case constants.IDENTITY_AUDIT_SERVER:
+			s.Audit = append(s.Audit, a)
+		}
+	}
+	sortAuthorities(s.Federated)
+	sortAuthorities(s.Audit)
+	return s, nil
+}
+
+func sortAuthorities(list []*identity.Authority) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].AuthorityChainID.String() < list[j].AuthorityChainID.String()
+	})
+}
+
+// ChangesBetween returns the indexed changes from the admin blocks from to to, of one identity if
+// identityChainID is not nil
+func (h *AuthorityHistory) ChangesBetween(db interfaces.DBOverlaySimple, identityChainID interfaces.IHash, from uint32, to uint32) ([]AuthorityChange, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, err := h.update(db); err != nil {
+		return nil, err
+	}
+	changes := []AuthorityChange{}
+	for _, c := range h.Changes {
+		if c.Height < from || c.Height > to {
+			continue
+		}
+		if identityChainID != nil && c.IdentityChainID != identityChainID.String() {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// GetAuthoritySet returns the *AuthoritySet that signs the directory block at the height
+func (s *State) GetAuthoritySet(height uint32) (interface{}, error) {
+	return s.AuthorityHistory.AuthoritySetAt(s.DB, s.GetNetworkBootStrapIdentity(), s.GetNetworkBootStrapKey(), height)
+}
+
+// GetAuthorityChanges returns the []AuthorityChange of the admin blocks from to to, of one identity if
+// identityChainID is not nil
+func (s *State) GetAuthorityChanges(identityChainID interfaces.IHash, from uint32, to uint32) (interface{}, error) {
+	return s.AuthorityHistory.ChangesBetween(s.DB, identityChainID, from, to)
+}
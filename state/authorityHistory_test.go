@@ -0,0 +1,123 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestAuthorityHistory(t *testing.T) {
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	a, _ := primitives.HexToHash("888888aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b, _ := primitives.HexToHash("888888bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	key1 := primitives.RandomPrivateKey().Pub
+	key2 := primitives.RandomPrivateKey().Pub
+
+	updateABlock := func(height uint32, add func(a *adminBlock.AdminBlock)) {
+		ablock, err := dbo.FetchABlockByHeight(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add(ablock.(*adminBlock.AdminBlock))
+		ablock.InsertIdentityABEntries()
+		if err := dbo.ProcessABlockBatchWithoutHead(ablock); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updateABlock(2, func(ab *adminBlock.AdminBlock) {
+		ab.AddFedServer(a)
+		ab.AddFederatedServerSigningKey(a, key1.Fixed())
+		ab.AddAuditServer(b)
+	})
+	updateABlock(4, func(ab *adminBlock.AdminBlock) { ab.AddFederatedServerSigningKey(a, key2.Fixed()) })
+	updateABlock(6, func(ab *adminBlock.AdminBlock) {
+		ab.AddAuditServer(a)
+		ab.AddFedServer(b)
+	})
+	updateABlock(8, func(ab *adminBlock.AdminBlock) { ab.RemoveFederatedServer(b) })
+
+	bootstrap, _ := primitives.HexToHash("38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9")
+	bootstrapPub := primitives.RandomPrivateKey().Pub
+	bootstrapKey := primitives.NewHash(bootstrapPub[:])
+
+	h := new(AuthorityHistory)
+	s, err := h.AuthoritySetAt(dbo, bootstrap, bootstrapKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Height != 0 || len(s.Federated) != 1 || len(s.Audit) != 0 {
+		t.Fatalf("Expected only the bootstrap server before the genesis block, got %+v", s)
+	}
+	if !s.Federated[0].AuthorityChainID.IsSameAs(bootstrap) || !s.Federated[0].SigningKey.IsSameAs(bootstrapPub) {
+		t.Errorf("Expected the bootstrap server, got %v", s.Federated[0])
+	}
+	// Leave out the bootstrap server and the server of the genesis block
+	s, err = h.AuthoritySetAt(dbo, bootstrap, bootstrapKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := make(map[string]bool)
+	for _, f := range s.Federated {
+		genesis[f.AuthorityChainID.String()] = true
+	}
+
+	check := func(height uint32, fed []string, audit []string, key *primitives.PublicKey) {
+		s, err := h.AuthoritySetAt(dbo, bootstrap, bootstrapKey, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotFed, gotAudit []string
+		for _, f := range s.Federated {
+			if genesis[f.AuthorityChainID.String()] {
+				continue
+			}
+			gotFed = append(gotFed, f.AuthorityChainID.String())
+			if f.AuthorityChainID.IsSameAs(a) && !f.SigningKey.IsSameAs(key) {
+				t.Errorf("Wrong signing key at %d", height)
+			}
+		}
+		for _, f := range s.Audit {
+			gotAudit = append(gotAudit, f.AuthorityChainID.String())
+		}
+		if len(gotFed) != len(fed) || len(gotAudit) != len(audit) {
+			t.Fatalf("At %d expected %v and %v, got %v and %v", height, fed, audit, gotFed, gotAudit)
+		}
+		for i := range fed {
+			if gotFed[i] != fed[i] {
+				t.Errorf("At %d expected federated %v, got %v", height, fed, gotFed)
+			}
+		}
+		for i := range audit {
+			if gotAudit[i] != audit[i] {
+				t.Errorf("At %d expected audit %v, got %v", height, audit, gotAudit)
+			}
+		}
+	}
+
+	// A change signs from the block after its admin block
+	check(2, nil, nil, nil)
+	check(3, []string{a.String()}, []string{b.String()}, key1)
+	check(5, []string{a.String()}, []string{b.String()}, key2)
+	check(7, []string{b.String()}, []string{a.String()}, nil)
+	check(9, nil, []string{a.String()}, nil)
+	check(100, nil, []string{a.String()}, nil)
+
+	changes, err := h.ChangesBetween(dbo, a, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Type != "signing-key" || changes[0].Height != 4 || changes[1].Type != "add-audit-server" {
+		t.Errorf("Wrong changes %+v", changes)
+	}
+	changes, err = h.ChangesBetween(dbo, nil, 8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Type != "remove-federated-server" || changes[0].IdentityChainID != b.String() {
+		t.Errorf("Wrong changes %+v", changes)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A block is ten minutes of DirectoryBlockInSeconds/10 each, unless the network has a block schedule.
+// A schedule (BlockSchedule in the config, -blockschedule on the command line) is a list of periods,
+// "<height>:<seconds>:<minutes>,...", each giving the block time and the number of minutes of the blocks
+// from its height on, i.e. "0:600:10,20000:60:6" switches to one minute blocks of six 10 second minutes
+// at height 20000. A period with 0 seconds keeps the DirectoryBlockInSeconds of the node.
+//
+// Every node of a network must run the same schedule, like the same block time. Main net ignores it.
+
+// MaxMinutesPerBlock is the most minutes a block can have, the factoid, entry credit and entry blocks
+// have room for ten minute markers
+const MaxMinutesPerBlock = 10
+
+// BlockPeriod is the block time and the number of minutes of the blocks from a height on
+type BlockPeriod struct {
+	Height  uint32
+	Seconds int // 0 for the DirectoryBlockInSeconds of the node
+	Minutes int
+}
+
+func (p BlockPeriod) String() string {
+	return fmt.Sprintf("%d:%d:%d", p.Height, p.Seconds, p.Minutes)
+}
+
+// ParseBlockSchedule parses "<height>:<seconds>:<minutes>,..." into the periods of a schedule
+func ParseBlockSchedule(schedule string) ([]BlockPeriod, error) {
+	var periods []BlockPeriod
+	for _, field := range strings.Split(schedule, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.Split(field, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("Block period %q is not <height>:<seconds>:<minutes>", field)
+		}
+		height, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Block period %q: bad height", field)
+		}
+		seconds, err := strconv.Atoi(parts[1])
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("Block period %q: bad block time", field)
+		}
+		minutes, err := strconv.Atoi(parts[2])
+		if err != nil || minutes < 1 || minutes > MaxMinutesPerBlock {
+			return nil, fmt.Errorf("Block period %q: minutes must be 1 to %d", field, MaxMinutesPerBlock)
+		}
+		if seconds > 0 && seconds < minutes {
+			return nil, fmt.Errorf("Block period %q: minutes shorter than a second", field)
+		}
+		if n := len(periods); n > 0 && uint32(height) <= periods[n-1].Height {
+			return nil, fmt.Errorf("Block period %q: heights must increase", field)
+		}
+		periods = append(periods, BlockPeriod{Height: uint32(height), Seconds: seconds, Minutes: minutes})
+	}
+	return periods, nil
+}
+
+// SetBlockSchedule sets the block schedule of the node, "" for ten minute blocks
+func (s *State) SetBlockSchedule(schedule string) error {
+	periods, err := ParseBlockSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	s.BlockSchedule = periods
+	return nil
+}
+
+// GetBlockPeriod returns the period of the schedule the block at the height is in
+func (s *State) GetBlockPeriod(dbheight uint32) BlockPeriod {
+	period := BlockPeriod{Minutes: MaxMinutesPerBlock}
+	for _, p := range s.BlockSchedule {
+		if p.Height > dbheight {
+			break
+		}
+		period = p
+	}
+	if period.Seconds == 0 {
+		period.Seconds = s.DirectoryBlockInSeconds
+	}
+	return period
+}
+
+// GetMinutesPerBlock returns the number of minutes of the block at the height
+func (s *State) GetMinutesPerBlock(dbheight uint32) int {
+	return s.GetBlockPeriod(dbheight).Minutes
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state_test
+
+import (
+	"testing"
+
+	. "github.com/FactomProject/factomd/state"
+)
+
+func TestParseBlockSchedule(t *testing.T) {
+	periods, err := ParseBlockSchedule(" 0:600:10, 20000:60:6 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BlockPeriod{{0, 600, 10}, {20000, 60, 6}}
+	if len(periods) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, periods)
+	}
+	for i := range want {
+		if periods[i] != want[i] {
+			t.Errorf("Period %d is %v, expected %v", i, periods[i], want[i])
+		}
+	}
+
+	if periods, err := ParseBlockSchedule(""); err != nil || len(periods) != 0 {
+		t.Errorf("An empty schedule should have no periods, got %v %v", periods, err)
+	}
+
+	for _, bad := range []string{
+		"0:600",         // No minutes
+		"x:600:10",      // Bad height
+		"0:-1:10",       // Bad block time
+		"0:600:0",       // No minutes
+		"0:600:11",      // More minutes than the blocks have room for
+		"0:5:6",         // Minutes shorter than a second
+		"10:60:6,5:0:8", // Heights out of order
+		"10:60:6,10:0:8",
+	} {
+		if _, err := ParseBlockSchedule(bad); err == nil {
+			t.Errorf("Schedule %q should not parse", bad)
+		}
+	}
+}
+
+func TestGetBlockPeriod(t *testing.T) {
+	s := new(State)
+	s.DirectoryBlockInSeconds = 600
+
+	// No schedule, ten minute blocks
+	if p := s.GetBlockPeriod(1000); p.Seconds != 600 || p.Minutes != 10 {
+		t.Errorf("Expected the default block period, got %v", p)
+	}
+
+	if err := s.SetBlockSchedule("100:60:6,200:0:5"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		height  uint32
+		seconds int
+		minutes int
+	}{
+		{0, 600, 10},
+		{99, 600, 10},
+		{100, 60, 6},
+		{199, 60, 6},
+		{200, 600, 5}, // 0 seconds keeps the block time of the node
+		{5000, 600, 5},
+	} {
+		if p := s.GetBlockPeriod(c.height); p.Seconds != c.seconds || p.Minutes != c.minutes {
+			t.Errorf("Height %d has period %v, expected %d seconds of %d minutes", c.height, p, c.seconds, c.minutes)
+		}
+		if m := s.GetMinutesPerBlock(c.height); m != c.minutes {
+			t.Errorf("Height %d has %d minutes, expected %d", c.height, m, c.minutes)
+		}
+	}
+
+	s.LLeaderHeight = 150
+	if s.GetDirectoryBlockInSeconds() != 60 {
+		t.Errorf("The block time of the current block is %d, expected 60", s.GetDirectoryBlockInSeconds())
+	}
+	// The schedule wins over the block time of the node, but not in the periods without one
+	s.SetDirectoryBlockInSeconds(30)
+	if s.GetDirectoryBlockInSeconds() != 60 || s.GetBlockPeriod(200).Seconds != 30 {
+		t.Errorf("Expected 60 and 30 second blocks, got %d and %d", s.GetDirectoryBlockInSeconds(), s.GetBlockPeriod(200).Seconds)
+	}
+
+	if err := s.SetBlockSchedule("0:60:12"); err == nil {
+		t.Error("Set an invalid schedule")
+	}
+	if s.GetMinutesPerBlock(150) != 6 {
+		t.Error("An invalid schedule replaced the schedule")
+	}
+}
+
+func TestMakeMapMinutes(t *testing.T) {
+	// Blocks with fewer minutes use the first minutes of the map, so the rotation of the VMs is the
+	// same as the one of ten minute blocks
+	m := MakeMap(5, 12)
+	for minute := 0; minute < MaxMinutesPerBlock; minute++ {
+		for fed := 0; fed < 5; fed++ {
+			vm := FedServerVM(m, 5, minute, fed)
+			if vm < 0 || m[minute][vm] != fed {
+				t.Errorf("Minute %d: federated server %d has no VM", minute, fed)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// CoinbasePeriod is the output of an authority in the coinbase descriptor of a payout period, and
+// what became of it
+type CoinbasePeriod struct {
+	DescriptorHeight uint32 `json:"descriptorheight"`       // Admin block of the coinbase descriptor
+	PayoutHeight     uint32 `json:"payoutheight"`           // Factoid block of the coinbase transaction
+	Index            uint32 `json:"index"`                  // Output of the identity in the descriptor
+	Address          string `json:"address"`                // Coinbase address of the identity
+	Efficiency       uint16 `json:"efficiency"`             // Share given to grants, 10000 == 100%
+	Amount           uint64 `json:"amount"`                 // Factoshis declared
+	GrantShare       uint64 `json:"grantshare"`             // Factoshis of the payout the efficiency left for grants
+	CancelHeight     uint32 `json:"cancelheight,omitempty"` // Admin block that cancelled the output
+	Paid             bool   `json:"paid"`                   // The coinbase transaction paid the output
+}
+
+// CoinbaseReport is what an authority identity earned in the coinbase descriptors from From to To
+type CoinbaseReport struct {
+	IdentityChainID string           `json:"identitychainid"`
+	From            uint32           `json:"from"`
+	To              uint32           `json:"to"`
+	Height          uint32           `json:"height"` // Highest saved block
+	Periods         []CoinbasePeriod `json:"periods"`
+	Paid            uint64           `json:"paid"`       // Factoshis paid
+	Cancelled       uint64           `json:"cancelled"`  // Factoshis cancelled
+	Pending         uint64           `json:"pending"`    // Factoshis declared and not yet due
+	GrantShare      uint64           `json:"grantshare"` // Factoshis left for grants
+}
+
+// coinbaseIdentity is the coinbase address and efficiency of an identity as the admin blocks set them
+type coinbaseIdentity struct {
+	chainID    interfaces.IHash
+	address    interfaces.IAddress
+	efficiency uint16
+}
+
+// NewCoinbaseReport builds the coinbase report of an identity from the saved admin and factoid blocks.
+// Descriptors do not name the identities of their outputs, so it replays the coinbase addresses and
+// efficiencies from the genesis block, and matches the outputs in the order of the sorted authorities
+// the descriptor was built from.
+func NewCoinbaseReport(db interfaces.DBOverlaySimple, identityChainID interfaces.IHash, from uint32, to uint32) (*CoinbaseReport, error) {
+	head, err := db.FetchDBlockHead()
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("No directory blocks saved")
+	}
+	top := head.GetDatabaseHeight()
+	if to > top {
+		to = top
+	}
+	if from > to {
+		return nil, fmt.Errorf("From %d is past to %d", from, to)
+	}
+
+	r := new(CoinbaseReport)
+	r.IdentityChainID = identityChainID.String()
+	r.From = from
+	r.To = to
+	r.Height = top
+	r.Periods = []CoinbasePeriod{}
+
+	ids := make(map[[32]byte]*coinbaseIdentity)
+	getIdentity := func(chainID interfaces.IHash) *coinbaseIdentity {
+		id, ok := ids[chainID.Fixed()]
+		if !ok {
+			id = &coinbaseIdentity{chainID: chainID, efficiency: 10000}
+			ids[chainID.Fixed()] = id
+		}
+		return id
+	}
+
+	// Cancels come at most a declaration after the end of the range
+	last := to + constants.COINBASE_DECLARATION
+	if last > top {
+		last = top
+	}
+	for h := uint32(0); h <= last; h++ {
+		ablock, err := db.FetchABlockByHeight(h)
+		if err != nil {
+			return nil, err
+		}
+		if ablock == nil {
+			return nil, fmt.Errorf("Admin block %d not found", h)
+		}
+
+		// The descriptor is built before the identity entries of its own block are applied
+		if h >= from && h <= to && h > constants.COINBASE_ACTIVATION && h%constants.COINBASE_PAYOUT_FREQUENCY == 0 {
+			if abe := ablock.FetchCoinbaseDescriptor(); abe != nil {
+				desc := abe.(*adminBlock.CoinbaseDescriptor)
+				if p := matchCoinbaseOutput(desc, ids, identityChainID); p != nil {
+					p.DescriptorHeight = h
+					p.PayoutHeight = h + constants.COINBASE_DECLARATION
+					r.Periods = append(r.Periods, *p)
+				}
+			}
+		}
+
+		for _, e := range ablock.GetABEntries() {
+			switch e := e.(type) {
+			case *adminBlock.AddEfficiency:
+				getIdentity(e.IdentityChainID).efficiency = e.Efficiency
+			case *adminBlock.AddFactoidAddress:
+				getIdentity(e.IdentityChainID).address = e.FactoidAddress
+			case *adminBlock.CancelCoinbaseDescriptor:
+				for i := range r.Periods {
+					p := &r.Periods[i]
+					if p.DescriptorHeight == e.DescriptorHeight && p.Index == e.DescriptorIndex && p.CancelHeight == 0 {
+						p.CancelHeight = h
+					}
+				}
+			}
+		}
+	}
+
+	for i := range r.Periods {
+		p := &r.Periods[i]
+		r.GrantShare += p.GrantShare
+		switch {
+		case p.CancelHeight != 0:
+			r.Cancelled += p.Amount
+		case p.PayoutHeight > top:
+			r.Pending += p.Amount
+		default:
+			fblock, err := db.FetchFBlockByHeight(p.PayoutHeight)
+			if err != nil {
+				return nil, err
+			}
+			if fblock != nil && len(fblock.GetTransactions()) > 0 {
+				for _, o := range fblock.GetTransactions()[0].GetOutputs() {
+					if primitives.ConvertFctAddressToUserStr(o.GetAddress()) == p.Address && o.GetAmount() == p.Amount {
+						p.Paid = true
+						r.Paid += p.Amount
+						break
+					}
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// matchCoinbaseOutput finds the output of the identity in a descriptor. The outputs are in the order of
+// the authority chain IDs, an identity sharing its address with another takes the first unclaimed output
+// of its payout.
+func matchCoinbaseOutput(desc *adminBlock.CoinbaseDescriptor, ids map[[32]byte]*coinbaseIdentity, identityChainID interfaces.IHash) *CoinbasePeriod {
+	sorted := make([]*coinbaseIdentity, 0, len(ids))
+	for _, id := range ids {
+		if id.address != nil {
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].chainID.Bytes(), sorted[j].chainID.Bytes()) < 0
+	})
+
+	claimed := make(map[[32]byte]bool)
+	for i, o := range desc.Outputs {
+		var match *coinbaseIdentity
+		for _, id := range sorted {
+			if claimed[id.chainID.Fixed()] || !id.address.IsSameAs(o.GetAddress()) {
+				continue
+			}
+			if primitives.CalculateCoinbasePayout(id.efficiency) == o.GetAmount() {
+				match = id
+				break
+			}
+			if match == nil {
+				match = id
+			}
+		}
+		if match == nil {
+			continue
+		}
+		claimed[match.chainID.Fixed()] = true
+		if !match.chainID.IsSameAs(identityChainID) {
+			continue
+		}
+
+		p := new(CoinbasePeriod)
+		p.Index = uint32(i)
+		p.Address = primitives.ConvertFctAddressToUserStr(o.GetAddress())
+		p.Efficiency = match.efficiency
+		p.Amount = o.GetAmount()
+		if p.Amount < constants.COINBASE_PAYOUT_AMOUNT {
+			p.GrantShare = constants.COINBASE_PAYOUT_AMOUNT - p.Amount
+		}
+		return p
+	}
+	return nil
+}
+
+// GetCoinbaseReport returns the *CoinbaseReport of an identity, see NewCoinbaseReport
+func (s *State) GetCoinbaseReport(identityChainID interfaces.IHash, from uint32, to uint32) (interface{}, error) {
+	return NewCoinbaseReport(s.DB, identityChainID, from, to)
+}
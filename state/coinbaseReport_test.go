@@ -0,0 +1,119 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/adminBlock"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+// setCoinbaseConstants pays every 2 blocks, 4 blocks after the descriptor
+func setCoinbaseConstants() func() {
+	frequency, declaration, activation := constants.COINBASE_PAYOUT_FREQUENCY, constants.COINBASE_DECLARATION, constants.COINBASE_ACTIVATION
+	constants.COINBASE_PAYOUT_FREQUENCY = 2
+	constants.COINBASE_DECLARATION = 4
+	constants.COINBASE_ACTIVATION = 0
+	return func() {
+		constants.COINBASE_PAYOUT_FREQUENCY, constants.COINBASE_DECLARATION, constants.COINBASE_ACTIVATION = frequency, declaration, activation
+	}
+}
+
+func TestCoinbaseReport(t *testing.T) {
+	defer setCoinbaseConstants()()
+
+	dbo := testHelper.CreateAndPopulateTestDatabaseOverlay()
+	a, _ := primitives.HexToHash("888888aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	b, _ := primitives.HexToHash("888888bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	address := testHelper.NewFactoidAddress(1) // Shared by both identities
+
+	outputs := func(effA, effB uint16) []interfaces.ITransAddress {
+		return []interfaces.ITransAddress{
+			factoid.NewOutAddress(address, primitives.CalculateCoinbasePayout(effA)),
+			factoid.NewOutAddress(address, primitives.CalculateCoinbasePayout(effB)),
+		}
+	}
+	updateABlock := func(height uint32, add func(a *adminBlock.AdminBlock)) {
+		ablock, err := dbo.FetchABlockByHeight(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add(ablock.(*adminBlock.AdminBlock))
+		ablock.InsertIdentityABEntries()
+		if err := dbo.ProcessABlockBatchWithoutHead(ablock); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updateABlock(1, func(ab *adminBlock.AdminBlock) {
+		ab.AddCoinbaseAddress(a, address)
+		ab.AddEfficiency(a, 2000)
+		ab.AddCoinbaseAddress(b, address)
+		ab.AddEfficiency(b, 0)
+	})
+	updateABlock(2, func(ab *adminBlock.AdminBlock) { ab.AddCoinbaseDescriptor(outputs(2000, 0)) })
+	updateABlock(4, func(ab *adminBlock.AdminBlock) {
+		// The new efficiency applies from the next descriptor
+		ab.AddCoinbaseDescriptor(outputs(2000, 0))
+		ab.AddEfficiency(a, 5000)
+	})
+	updateABlock(5, func(ab *adminBlock.AdminBlock) { ab.AddCancelCoinbaseDescriptor(4, 0) })
+	updateABlock(6, func(ab *adminBlock.AdminBlock) { ab.AddCoinbaseDescriptor(outputs(5000, 0)) })
+
+	// The coinbase of the descriptor at 2
+	prev, err := dbo.FetchFBlockByHeight(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fblock := testHelper.CreateTestFactoidBlockWithCoinbase(prev, address, primitives.CalculateCoinbasePayout(2000))
+	if err := dbo.SaveFactoidBlockHead(fblock); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewCoinbaseReport(dbo, a, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.To != 9 || r.Height != 9 || len(r.Periods) != 3 {
+		t.Fatalf("Expected 3 periods to 9, got %d to %d", len(r.Periods), r.To)
+	}
+
+	paid := primitives.CalculateCoinbasePayout(2000)
+	pending := primitives.CalculateCoinbasePayout(5000)
+	expected := []CoinbasePeriod{
+		{DescriptorHeight: 2, PayoutHeight: 6, Efficiency: 2000, Amount: paid, Paid: true},
+		{DescriptorHeight: 4, PayoutHeight: 8, Efficiency: 2000, Amount: paid, CancelHeight: 5},
+		{DescriptorHeight: 6, PayoutHeight: 10, Efficiency: 5000, Amount: pending},
+	}
+	for i, e := range expected {
+		p := r.Periods[i]
+		e.Address = primitives.ConvertFctAddressToUserStr(address)
+		e.GrantShare = constants.COINBASE_PAYOUT_AMOUNT - e.Amount
+		if p != e {
+			t.Errorf("Expected %+v, got %+v", e, p)
+		}
+	}
+	if r.Paid != paid || r.Cancelled != paid || r.Pending != pending {
+		t.Errorf("Expected %d paid %d cancelled %d pending, got %d %d %d", paid, paid, pending, r.Paid, r.Cancelled, r.Pending)
+	}
+	if r.GrantShare != 3*constants.COINBASE_PAYOUT_AMOUNT-2*paid-pending {
+		t.Errorf("Wrong grant share %d", r.GrantShare)
+	}
+
+	// The other identity has the other output
+	r, err = NewCoinbaseReport(dbo, b, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Periods) != 2 || r.Periods[0].Index != 1 || r.Periods[0].CancelHeight != 0 || r.Periods[0].Amount != constants.COINBASE_PAYOUT_AMOUNT {
+		t.Errorf("Wrong periods %+v", r.Periods)
+	}
+
+	if _, err := NewCoinbaseReport(dbo, a, 8, 4); err == nil {
+		t.Error("Expected an error for a range past its end")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The consensus trace (-consensustrace <dir>) is a structured log of what each node did to build the
+// blocks, one JSON event per line in <dir>/<node>_trace.jsonl. The traces of many nodes merge into one
+// timeline, see Utilities/TraceVisualizer. The times come from the node's clock, so the traces of a
+// deterministic simulation (-simseed) line up exactly.
+
+// The events of the consensus trace
+const (
+	TraceReceived = "received" // A message came in from a peer
+	TraceAck      = "ack"      // This node, a leader, acknowledged a message
+	TraceVMHeight = "vmheight" // A VM of a process list executed a message
+	TraceEOM      = "eom"      // An EOM was processed
+	TraceDBSig    = "dbsig"    // A DBSig was processed
+	TraceRound    = "round"    // An election round started
+	TraceVote     = "vote"     // This node cast a vote in an election
+	TraceSaved    = "saved"    // A block was saved
+)
+
+type TraceEvent struct {
+	Node     string `json:"node"`
+	Time     int64  `json:"time"` // Milliseconds
+	Seq      uint64 `json:"seq"`  // Order of the events of the node
+	Event    string `json:"event"`
+	DBHeight uint32 `json:"dbheight"`
+	Minute   int    `json:"minute"`
+	VM       int    `json:"vm"`
+	Height   int    `json:"height,omitempty"` // Height in the VM
+	Msg      string `json:"msg,omitempty"`    // Type of the message
+	Hash     string `json:"hash,omitempty"`   // Hash of the message, KeyMR of a saved block
+	From     string `json:"from,omitempty"`   // Peer the message came from
+	Info     string `json:"info,omitempty"`
+}
+
+func (e *TraceEvent) String() string {
+	s := fmt.Sprintf("%8d %-10s %d-:-%d %-8s", e.Time, e.Node, e.DBHeight, e.Minute, e.Event)
+	if e.Msg != "" {
+		s += fmt.Sprintf(" %s vm %d/%d", e.Msg, e.VM, e.Height)
+	}
+	if len(e.Hash) > 12 {
+		s += " " + e.Hash[:12]
+	} else if e.Hash != "" {
+		s += " " + e.Hash
+	}
+	if e.From != "" {
+		s += " from " + e.From
+	}
+	if e.Info != "" {
+		s += " " + e.Info
+	}
+	return s
+}
+
+// Tracing returns true if the node writes a consensus trace
+func (s *State) Tracing() bool {
+	return s.ConsensusTraceDir != ""
+}
+
+// Trace adds an event to the consensus trace of the node
+func (s *State) Trace(e TraceEvent) {
+	if !s.Tracing() {
+		return
+	}
+	s.traceMutex.Lock()
+	defer s.traceMutex.Unlock()
+
+	if s.traceFile == nil {
+		name := filepath.Join(s.ConsensusTraceDir, s.FactomNodeName+"_trace.jsonl")
+		f, err := os.Create(name)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("%s: no consensus trace: %v\n", s.FactomNodeName, err))
+			s.ConsensusTraceDir = ""
+			return
+		}
+		s.traceFile = json.NewEncoder(f)
+	}
+	s.traceSeq++
+	e.Node = s.FactomNodeName
+	e.Time = primitives.Now().UnixNano() / 1e6
+	e.Seq = s.traceSeq
+	s.traceFile.Encode(e)
+}
+
+// TraceMsg adds an event about a message to the consensus trace, at the node's current height and minute
+func (s *State) TraceMsg(event string, msg interfaces.IMsg, from string, info string) {
+	if !s.Tracing() {
+		return
+	}
+	e := TraceEvent{
+		Event:    event,
+		DBHeight: s.LLeaderHeight,
+		Minute:   s.CurrentMinute,
+		VM:       msg.GetVMIndex(),
+		Msg:      constants.MessageName(msg.Type()),
+		From:     from,
+		Info:     info,
+	}
+	if h := msg.GetMsgHash(); h != nil {
+		e.Hash = h.String()
+	}
+	s.Trace(e)
+}
+
+// ReadTrace reads the events of a consensus trace
+func ReadTrace(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("Line %d: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// MergeTraces merges the traces of many nodes into one timeline. The events of one node stay in order.
+func MergeTraces(traces ...[]TraceEvent) []TraceEvent {
+	var all []TraceEvent
+	for _, t := range traces {
+		all = append(all, t...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		a, b := &all[i], &all[j]
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		return a.Seq < b.Seq
+	})
+	return all
+}
@@ -0,0 +1,77 @@
+package state_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+)
+
+func TestConsensusTrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := new(State)
+	s.FactomNodeName = "FNode0"
+	s.Trace(TraceEvent{Event: TraceSaved}) // Not tracing, nothing written
+
+	s.ConsensusTraceDir = dir
+	s.Trace(TraceEvent{Event: TraceEOM, DBHeight: 7, Minute: 3, VM: 1})
+	eom := new(messages.EOM)
+	eom.Timestamp = primitives.NewTimestampNow()
+	eom.ChainID = primitives.NewZeroHash()
+	s.TraceMsg(TraceReceived, eom, "FNode1", "")
+	s.Trace(TraceEvent{Event: TraceSaved, DBHeight: 7, Hash: "abcd"})
+
+	f, err := os.Open(filepath.Join(dir, "FNode0_trace.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	events, err := ReadTrace(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Read %d events, expected 3", len(events))
+	}
+	for i, e := range events {
+		if e.Node != "FNode0" || e.Seq != uint64(i+1) || e.Time == 0 {
+			t.Errorf("Event %d not stamped: %+v", i, e)
+		}
+	}
+	if e := events[1]; e.Event != TraceReceived || e.Msg != "EOM" || e.From != "FNode1" {
+		t.Errorf("Wrong message event %+v", e)
+	}
+	if e := events[2]; e.DBHeight != 7 || e.Hash != "abcd" || !strings.Contains(e.String(), "saved") {
+		t.Errorf("Wrong saved event %s", e.String())
+	}
+
+	if _, err := ReadTrace(strings.NewReader("{\"event\":\"eom\"}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "Line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+func TestMergeTraces(t *testing.T) {
+	a := []TraceEvent{{Node: "FNode0", Time: 10, Seq: 1}, {Node: "FNode0", Time: 10, Seq: 2}, {Node: "FNode0", Time: 30, Seq: 3}}
+	b := []TraceEvent{{Node: "FNode1", Time: 5, Seq: 1}, {Node: "FNode1", Time: 10, Seq: 2}}
+
+	merged := MergeTraces(b, a)
+	expected := []string{"FNode1/1", "FNode0/1", "FNode0/2", "FNode1/2", "FNode0/3"}
+	if len(merged) != len(expected) {
+		t.Fatalf("Merged %d events, expected %d", len(merged), len(expected))
+	}
+	for i, e := range merged {
+		if got := e.Node + "/" + string('0'+byte(e.Seq)); got != expected[i] {
+			t.Errorf("Event %d is %s, expected %s", i, got, expected[i])
+		}
+	}
+}
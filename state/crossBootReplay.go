@@ -9,6 +9,7 @@ import (
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/database/boltdb"
 	"github.com/FactomProject/factomd/database/mapdb"
 )
@@ -34,7 +35,7 @@ func (s *State) SetupCrossBootReplay(path string) {
 	}
 	s.CrossReplay = NewCrossReplayFilter(path)
 	// This thread will terminate itself
-	go s.CrossReplay.Run()
+	primitives.Go(s.CrossReplay.Run)
 }
 
 // CrossReplayAddSalt adds the salt to the DB
@@ -74,7 +75,7 @@ func NewCrossReplayFilter(path string) *CrossReplayFilter {
 	c.oldSaltCache = make(map[[8]byte]bool)
 	// Load the old salts into the map
 	c.loadOldSalts()
-	c.bootTime = time.Now()
+	c.bootTime = primitives.Now()
 
 	var m MarshalableUint32
 	c.db.Get(heightBucket, lowest, &m)
@@ -133,8 +134,8 @@ func (c *CrossReplayFilter) ExistSalt(salt [8]byte) (bool, error) {
 // Run is a simple loop that ensures we discard old data we do not need.
 func (c *CrossReplayFilter) Run() {
 	for {
-		time.Sleep(time.Second * 5)
-		if time.Now().Before(c.bootTime.Add(constants.CROSSBOOT_SALT_REPLAY_DURATION * -1)) {
+		primitives.Sleep(time.Second * 5)
+		if primitives.Now().Before(c.bootTime.Add(constants.CROSSBOOT_SALT_REPLAY_DURATION * -1)) {
 			// We no longer need to add salts
 			c.stopAddingSalts = true
 			return
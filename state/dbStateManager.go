@@ -455,6 +455,7 @@ type DBStateList struct {
 
 	LastEnd       int
 	LastBegin     int
+	LastCompact   bool // The last ask was for compact DBStates
 	TimeToAsk     interfaces.Timestamp
 	ProcessHeight uint32
 	SavedHeight   uint32
@@ -951,7 +952,7 @@ func (list *DBStateList) FixupLinks(p *DBState, d *DBState) (progress bool) {
 	// every 25 blocks +1 we add grant payouts
 	if currentDBHeight > constants.COINBASE_ACTIVATION && currentDBHeight%constants.COINBASE_PAYOUT_FREQUENCY == 1 {
 		// Add the grants to the list
-		grantPayouts := GetGrantPayoutsFor(currentDBHeight)
+		grantPayouts := list.State.GrantPayoutsFor(currentDBHeight)
 		if len(grantPayouts) > 0 {
 			err := d.AdminBlock.AddCoinbaseDescriptor(grantPayouts)
 			if err != nil {
@@ -1052,6 +1053,30 @@ func (list *DBStateList) ProcessBlocks(d *DBState) (progress bool) {
 		}
 	}
 
+	// The delegations of the saved block below pay from the block after this one, so wait until all their
+	// entries are in the database
+	var delegations []interfaces.IEBEntry
+	if dbht > 0 {
+		var err error
+		if delegations, err = list.State.ECDelegationEntries(dbht - 1); err != nil {
+			list.State.LogPrintf("ecdelegation", "Waiting to process block %d: %v", dbht, err)
+			return
+		}
+	}
+
+	// Likewise for the grant proposals of the saved block below. If it is the cutoff of a payout height,
+	// the proposals are checked against the authority set of the node before this block changes it.
+	var grants []interfaces.IEBEntry
+	var grantAuthorities *AuthoritySet
+	if dbht > 0 {
+		var err error
+		if grants, err = list.State.GrantProposalEntries(dbht - 1); err != nil {
+			list.State.LogPrintf("grants", "Waiting to process block %d: %v", dbht, err)
+			return
+		}
+		grantAuthorities = list.State.GrantCutoffAuthorities(dbht - 1)
+	}
+
 	// Bring the current federated servers and audit servers forward to the
 	// next block.
 
@@ -1190,6 +1215,11 @@ func (list *DBStateList) ProcessBlocks(d *DBState) (progress bool) {
 	// Promote the currently scheduled next FER
 
 	list.State.ProcessRecentFERChainEntries()
+	// Delegations of the saved block below pay from the block after this one
+	list.State.ProcessECDelegationEntries(delegations, dbht+1)
+	if dbht > 0 {
+		list.State.ProcessGrantProposalEntries(grants, dbht-1, grantAuthorities)
+	}
 	// Step my counter of Complete blocks
 	i := d.DirectoryBlock.GetHeader().GetDBHeight() - list.Base
 	if uint32(i) > list.Complete {
@@ -1571,6 +1601,11 @@ func (list *DBStateList) SaveDBStateToDB(d *DBState) (progress bool) {
 	progress = true
 	d.ReadyToSave = false
 	d.Saved = true
+	list.State.checkBlockInvariants(d)
+	if list.State.Tracing() {
+		list.State.Trace(TraceEvent{Event: TraceSaved, DBHeight: uint32(dbheight), Minute: list.State.CurrentMinute,
+			Hash: d.DirectoryBlock.GetKeyMR().String()})
+	}
 
 	// Now that we have saved the perm balances, we can clear the api hashmaps that held the differences
 	// between the actual saved block prior, and this saved block.  If you are looking for balances of
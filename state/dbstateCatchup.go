@@ -1,6 +1,8 @@
 package state
 
 import (
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
 )
 
@@ -64,7 +66,16 @@ func (list *DBStateList) Catchup(justDoIt bool) {
 			}
 
 			if list.State.RunLeader && !list.State.IgnoreMissing {
-				msg := messages.NewDBStateMissing(list.State, uint32(begin), uint32(end+5))
+				// If we are almost in sync, we have seen most of the transactions and entries
+				// already, so ask for compact DBStates.  If that didn't get us the block, or
+				// our peers don't know compact DBStates, ask for the full DBStates next time.
+				compact := hk-hs <= constants.DBSTATE_COMPACT_WINDOW && !(list.LastCompact && list.LastBegin == begin)
+				var msg interfaces.IMsg
+				if compact {
+					msg = messages.NewDBStateCompactMissing(list.State, uint32(begin), uint32(end+5))
+				} else {
+					msg = messages.NewDBStateMissing(list.State, uint32(begin), uint32(end+5))
+				}
 
 				if msg != nil {
 					//		list.State.RunLeader = false
@@ -74,6 +85,7 @@ func (list *DBStateList) Catchup(justDoIt bool) {
 					list.TimeToAsk.SetTimeSeconds(now.GetTimeSeconds() + 6)
 					list.LastBegin = begin
 					list.LastEnd = end
+					list.LastCompact = compact
 				}
 			}
 		}
@@ -0,0 +1,377 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/FactomProject/ed25519"
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The owner of an entry credit address can let another key spend its entry credits, by an entry in
+// the delegation chain:
+//
+//	ExtIDs[0]  0x00, the version
+//	ExtIDs[1]  "EC Delegation"
+//	ExtIDs[2]  the EC public key of the owner
+//	ExtIDs[3]  the public key of the delegate
+//	ExtIDs[4]  the limit, entry credits, 8 bytes big endian
+//	ExtIDs[5]  the expiry height, 4 bytes big endian
+//	ExtIDs[6]  the sequence, 4 bytes big endian
+//	ExtIDs[7]  the signature of ExtIDs[0:7] by the owner
+//
+// Commits signed by the delegate are paid by the owner, up to the limit and in the blocks below the
+// expiry, and by the delegate itself after that. A key can be the delegate of several owners: its commits
+// are paid by the first of its delegations that is active and has enough of its limit left, in the order
+// of their active heights and then of the owner keys. A later delegation of the same owner to the same
+// delegate replaces the last one if its sequence is higher, and starts its limit over; a limit of 0
+// revokes the delegation.
+//
+// Like identity entries, the entries of a block are read once it is saved, with the next block, and
+// the delegation pays for commits from the block after that. The next block is not processed until all
+// the delegation entries of the saved block are in the database, so every node reads the same ones.
+
+var ECDelegationChainID = entryBlock.ExternalIDsToChainID([][]byte{[]byte("Factom EC Delegations")})
+
+const ECDelegationType = "EC Delegation"
+
+// ECDelegation lets Delegate spend up to Limit entry credits of Owner, in the blocks from
+// ActiveHeight and below Expires
+type ECDelegation struct {
+	Owner        [32]byte
+	Delegate     [32]byte
+	Limit        uint64
+	Expires      uint32
+	Sequence     uint32
+	ActiveHeight uint32
+	Spent        uint64 // As of the last completed block
+}
+
+var _ interfaces.BinaryMarshallable = (*ECDelegation)(nil)
+
+// ECDelegationKey identifies the delegation of an owner to a delegate
+type ECDelegationKey struct {
+	Owner    [32]byte
+	Delegate [32]byte
+}
+
+func (d *ECDelegation) Key() ECDelegationKey {
+	return ECDelegationKey{d.Owner, d.Delegate}
+}
+
+func (d *ECDelegation) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+	if err := buf.Push(d.Owner[:]); err != nil {
+		return nil, err
+	}
+	if err := buf.Push(d.Delegate[:]); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt64(d.Limit); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(d.Expires); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(d.Sequence); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(d.ActiveHeight); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt64(d.Spent); err != nil {
+		return nil, err
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (d *ECDelegation) UnmarshalBinaryData(p []byte) (newData []byte, err error) {
+	buf := primitives.NewBuffer(p)
+	if err = buf.Pop(d.Owner[:]); err != nil {
+		return
+	}
+	if err = buf.Pop(d.Delegate[:]); err != nil {
+		return
+	}
+	if d.Limit, err = buf.PopUInt64(); err != nil {
+		return
+	}
+	if d.Expires, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if d.Sequence, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if d.ActiveHeight, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if d.Spent, err = buf.PopUInt64(); err != nil {
+		return
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (d *ECDelegation) UnmarshalBinary(p []byte) error {
+	_, err := d.UnmarshalBinaryData(p)
+	return err
+}
+
+func (d *ECDelegation) String() string {
+	return fmt.Sprintf("EC delegation %x to %x: %d of %d ECs, from %d to %d, sequence %d",
+		d.Owner[:6], d.Delegate[:6], d.Spent, d.Limit, d.ActiveHeight, d.Expires, d.Sequence)
+}
+
+// ParseECDelegation checks the structure and the signature of a delegation entry
+func ParseECDelegation(entry interfaces.IEBEntry) (*ECDelegation, error) {
+	extIDs := entry.ExternalIDs()
+	if len(extIDs) != 8 {
+		return nil, fmt.Errorf("Wrong number of ExtIDs %d", len(extIDs))
+	}
+	if !bytes.Equal(extIDs[0], []byte{0}) || string(extIDs[1]) != ECDelegationType {
+		return nil, fmt.Errorf("Not an EC delegation")
+	}
+	if len(extIDs[2]) != 32 || len(extIDs[3]) != 32 || len(extIDs[4]) != 8 || len(extIDs[5]) != 4 || len(extIDs[6]) != 4 ||
+		len(extIDs[7]) != constants.SIGNATURE_LENGTH {
+		return nil, fmt.Errorf("Bad EC delegation fields")
+	}
+
+	d := new(ECDelegation)
+	copy(d.Owner[:], extIDs[2])
+	copy(d.Delegate[:], extIDs[3])
+	d.Limit = binary.BigEndian.Uint64(extIDs[4])
+	d.Expires = binary.BigEndian.Uint32(extIDs[5])
+	d.Sequence = binary.BigEndian.Uint32(extIDs[6])
+	if d.Owner == d.Delegate {
+		return nil, fmt.Errorf("An EC address cannot delegate to itself")
+	}
+
+	var sig [constants.SIGNATURE_LENGTH]byte
+	copy(sig[:], extIDs[7])
+	if !ed25519.VerifyCanonical(&d.Owner, ecDelegationSigData(extIDs[:7]), &sig) {
+		return nil, fmt.Errorf("Bad signature of the EC delegation")
+	}
+	return d, nil
+}
+
+func ecDelegationSigData(extIDs [][]byte) []byte {
+	var data []byte
+	for _, extID := range extIDs {
+		data = append(data, extID...)
+	}
+	return data
+}
+
+// NewECDelegationEntry returns a delegation entry signed by the owner, the private key of an EC address
+func NewECDelegationEntry(owner *primitives.PrivateKey, delegate [32]byte, limit uint64, expires uint32, sequence uint32) *entryBlock.Entry {
+	e := entryBlock.NewEntry()
+	e.ChainID = ECDelegationChainID
+	fields := make([]byte, 16)
+	binary.BigEndian.PutUint64(fields[0:8], limit)
+	binary.BigEndian.PutUint32(fields[8:12], expires)
+	binary.BigEndian.PutUint32(fields[12:16], sequence)
+	extIDs := [][]byte{{0}, []byte(ECDelegationType), owner.Pub[:], delegate[:], fields[0:8], fields[8:12], fields[12:16]}
+	extIDs = append(extIDs, owner.Sign(ecDelegationSigData(extIDs)).GetSignature()[:])
+	for _, extID := range extIDs {
+		e.ExtIDs = append(e.ExtIDs, primitives.ByteSlice{Bytes: extID})
+	}
+	return e
+}
+
+// ECDelegationEntries returns the delegation entries of the saved block at height, or an error if the
+// entry block or one of its entries is not in the database yet
+func (s *State) ECDelegationEntries(height uint32) ([]interfaces.IEBEntry, error) {
+	if !activations.IsActive(activations.EC_DELEGATION, int(height)) {
+		return nil, nil
+	}
+	dblock, err := s.DB.FetchDBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	if dblock == nil {
+		return nil, fmt.Errorf("Missing directory block %d", height)
+	}
+	var keyMR interfaces.IHash
+	for _, e := range dblock.GetDBEntries() {
+		if e.GetChainID().IsSameAs(ECDelegationChainID) {
+			keyMR = e.GetKeyMR()
+		}
+	}
+	if keyMR == nil {
+		return nil, nil
+	}
+	eblock, err := s.DB.FetchEBlock(keyMR)
+	if err != nil || eblock == nil {
+		return nil, fmt.Errorf("Missing delegation eblock %x at %d", keyMR.Bytes()[:6], height)
+	}
+
+	var entries []interfaces.IEBEntry
+	for _, hash := range eblock.GetEntryHashes() {
+		if hash.IsMinuteMarker() {
+			continue
+		}
+		entry, err := s.DB.FetchEntry(hash)
+		if err != nil || entry == nil {
+			return nil, fmt.Errorf("Missing delegation entry %x at %d", hash.Bytes()[:6], height)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ProcessECDelegationEntries adds the delegations of the entries of a saved block (see
+// ECDelegationEntries), for the blocks from activeHeight
+func (s *State) ProcessECDelegationEntries(entries []interfaces.IEBEntry, activeHeight uint32) {
+	for _, entry := range entries {
+		d, err := ParseECDelegation(entry)
+		if err != nil {
+			s.LogPrintf("ecdelegation", "Delegation entry %x: %v", entry.GetHash().Bytes()[:6], err)
+			continue
+		}
+		d.ActiveHeight = activeHeight
+		if err := s.addECDelegation(d); err != nil {
+			s.LogPrintf("ecdelegation", "Delegation entry %x: %v", entry.GetHash().Bytes()[:6], err)
+		}
+	}
+}
+
+// addECDelegation adds or replaces the delegation of its owner to its delegate, keeping the delegations of
+// the delegate in the order they pay
+func (s *State) addECDelegation(d *ECDelegation) error {
+	s.ecDelegationsMutex.Lock()
+	defer s.ecDelegationsMutex.Unlock()
+	if s.ECDelegations == nil {
+		s.ECDelegations = make(map[[32]byte][]*ECDelegation)
+	}
+	list := s.ECDelegations[d.Delegate]
+	for i, last := range list {
+		if last.Owner != d.Owner {
+			continue
+		}
+		if d.Sequence <= last.Sequence {
+			return fmt.Errorf("Sequence %d is not past %d", d.Sequence, last.Sequence)
+		}
+		list = append(list[:i], list[i+1:]...)
+		break
+	}
+	list = append(list, d)
+	sortECDelegations(list)
+	s.ECDelegations[d.Delegate] = list
+	return nil
+}
+
+// sortECDelegations sorts the delegations of a delegate in the order they pay
+func sortECDelegations(list []*ECDelegation) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ActiveHeight != list[j].ActiveHeight {
+			return list[i].ActiveHeight < list[j].ActiveHeight
+		}
+		return bytes.Compare(list[i].Owner[:], list[j].Owner[:]) < 0
+	})
+}
+
+// getECDelegationSpent returns the entry credits a delegate has spent, in the block being built if rt
+// is true, as of the last completed block if false.  Call with ecDelegationsMutex held.
+func (s *State) getECDelegationSpent(rt bool, d *ECDelegation) uint64 {
+	if rt {
+		pl := s.ProcessLists.Get(s.LLeaderHeight)
+		if pl != nil {
+			pl.ECBalancesTMutex.Lock()
+			v, ok := pl.ECDelegatedT[d.Key()]
+			pl.ECBalancesTMutex.Unlock()
+			if ok {
+				return v
+			}
+		}
+	}
+	return d.Spent
+}
+
+// GetECPayer returns the EC address that pays the credits of a commit signed by the key at the height:
+// the owner of the first active delegation to the key with enough of its limit left, or the key itself
+func (s *State) GetECPayer(rt bool, key [32]byte, credits uint64, height uint32) (payer [32]byte, delegated bool) {
+	if !activations.IsActive(activations.EC_DELEGATION, int(height)) {
+		return key, false
+	}
+	s.ecDelegationsMutex.Lock()
+	defer s.ecDelegationsMutex.Unlock()
+	for _, d := range s.ECDelegations[key] {
+		if height >= d.ActiveHeight && height < d.Expires && s.getECDelegationSpent(rt, d)+credits <= d.Limit {
+			return d.Owner, true
+		}
+	}
+	return key, false
+}
+
+// SpendECDelegation counts credits against the limit of the delegation of the owner to the key
+func (s *State) SpendECDelegation(rt bool, owner [32]byte, key [32]byte, credits uint64) {
+	s.ecDelegationsMutex.Lock()
+	defer s.ecDelegationsMutex.Unlock()
+	var d *ECDelegation
+	for _, od := range s.ECDelegations[key] {
+		if od.Owner == owner {
+			d = od
+		}
+	}
+	if d == nil {
+		return
+	}
+	spent := s.getECDelegationSpent(rt, d) + credits
+	if !rt {
+		d.Spent = spent
+		return
+	}
+	if pl := s.ProcessLists.Get(s.LLeaderHeight); pl != nil {
+		pl.ECBalancesTMutex.Lock()
+		pl.ECDelegatedT[d.Key()] = spent
+		pl.ECBalancesTMutex.Unlock()
+	}
+}
+
+// GetECCommitBalance returns the balance of the EC address that pays for a commit signed by the key in
+// the block being built
+func (s *State) GetECCommitBalance(key [32]byte, credits uint8) int64 {
+	payer, _ := s.GetECPayer(true, key, uint64(credits), s.LLeaderHeight)
+	return s.GetE(true, payer)
+}
+
+// getECDelegationOwners returns the owners the key is a delegate of
+func (s *State) getECDelegationOwners(key [32]byte) [][32]byte {
+	s.ecDelegationsMutex.Lock()
+	defer s.ecDelegationsMutex.Unlock()
+	var owners [][32]byte
+	for _, d := range s.ECDelegations[key] {
+		owners = append(owners, d.Owner)
+	}
+	return owners
+}
+
+// GetECDelegationList returns the delegations sorted by delegate, and then in the order they pay, for the
+// save state
+func (s *State) GetECDelegationList() []ECDelegation {
+	s.ecDelegationsMutex.Lock()
+	defer s.ecDelegationsMutex.Unlock()
+	var delegates [][32]byte
+	for delegate := range s.ECDelegations {
+		delegates = append(delegates, delegate)
+	}
+	sort.Slice(delegates, func(i, j int) bool {
+		return bytes.Compare(delegates[i][:], delegates[j][:]) < 0
+	})
+	var list []ECDelegation
+	for _, delegate := range delegates {
+		for _, d := range s.ECDelegations[delegate] {
+			list = append(list, *d)
+		}
+	}
+	return list
+}
@@ -0,0 +1,173 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestParseECDelegation(t *testing.T) {
+	owner := primitives.RandomPrivateKey()
+	delegate := primitives.RandomPrivateKey().Pub.Fixed()
+
+	e := NewECDelegationEntry(owner, delegate, 500, 1000, 3)
+	d, err := ParseECDelegation(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Owner != owner.Pub.Fixed() || d.Delegate != delegate || d.Limit != 500 || d.Expires != 1000 || d.Sequence != 3 {
+		t.Errorf("Wrong delegation %v", d)
+	}
+
+	d.ActiveHeight, d.Spent = 20, 40
+	data, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2 := new(ECDelegation)
+	if err := d2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if *d2 != *d {
+		t.Errorf("Expected %v, got %v", d, d2)
+	}
+
+	// Changing the limit breaks the signature
+	e.ExtIDs[4].Bytes[0] = 1
+	if _, err := ParseECDelegation(e); err == nil {
+		t.Error("Expected an error for a bad signature")
+	}
+	if _, err := ParseECDelegation(NewECDelegationEntry(owner, owner.Pub.Fixed(), 500, 1000, 3)); err == nil {
+		t.Error("Expected an error for a delegation to the owner")
+	}
+}
+
+func TestECDelegation(t *testing.T) {
+	defer activations.SetTestActivationHeight(activations.EC_DELEGATION, 0)()
+	s := testHelper.CreateEmptyTestState()
+	fs := s.GetFactoidState().(*FactoidState)
+
+	owner := primitives.RandomPrivateKey()
+	other := primitives.RandomPrivateKey()
+	delegate := primitives.RandomPrivateKey()
+	s.PutE(false, owner.Pub.Fixed(), 100)
+	s.PutE(false, other.Pub.Fixed(), 100)
+
+	// saveEBlock puts the entries in the delegation chain of the block at height, and saves the first
+	// stored of them
+	saveEBlock := func(height uint32, stored int, entries ...*entryBlock.Entry) {
+		s.DB.StartMultiBatch()
+		eb := entryBlock.NewEBlock()
+		eb.GetHeader().SetChainID(ECDelegationChainID)
+		eb.GetHeader().SetDBHeight(height)
+		for i, e := range entries {
+			eb.AddEBEntry(e)
+			if i >= stored {
+				continue
+			}
+			if err := s.DB.InsertEntryMultiBatch(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.DB.ProcessEBlockMultiBatch(eb, true); err != nil {
+			t.Fatal(err)
+		}
+		keyMR, _ := eb.KeyMR()
+		dblock := directoryBlock.NewDirectoryBlock(nil)
+		dblock.GetHeader().SetDBHeight(height)
+		dblock.AddEntry(ECDelegationChainID, keyMR)
+		if err := s.DB.ProcessDBlockMultiBatch(dblock); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DB.ExecuteMultiBatch(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save := func(height uint32, entries ...*entryBlock.Entry) {
+		saveEBlock(height, len(entries), entries...)
+	}
+	process := func(height uint32, activeHeight uint32) {
+		entries, err := s.ECDelegationEntries(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.ProcessECDelegationEntries(entries, activeHeight)
+	}
+	commit := func(height uint32, credits uint8) error {
+		ce := entryCreditBlock.NewCommitEntry()
+		ce.EntryHash = primitives.RandomHash()
+		ce.Credits = credits
+		ce.Sign(delegate.Key[:32])
+		fs.DBHeight = height
+		return fs.UpdateECTransaction(false, ce)
+	}
+
+	// Read with block 6, pays from block 7 to 19
+	save(5, NewECDelegationEntry(owner, delegate.Pub.Fixed(), 25, 20, 1))
+	process(5, 7)
+
+	if err := commit(6, 10); err == nil {
+		t.Error("Expected the delegate to pay before the delegation is active")
+	}
+	for _, height := range []uint32{7, 8} {
+		if err := commit(height, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := s.GetE(false, owner.Pub.Fixed()); v != 80 {
+		t.Errorf("Expected the owner to have 80 ECs left, got %d", v)
+	}
+	if err := commit(9, 10); err == nil {
+		t.Error("Expected the delegate to pay past the limit")
+	}
+	if err := commit(9, 5); err != nil {
+		t.Errorf("Expected the owner to pay up to the limit: %v", err)
+	}
+
+	// A replayed entry changes nothing, and the delegate can serve another owner too
+	save(10, NewECDelegationEntry(owner, delegate.Pub.Fixed(), 25, 20, 1), NewECDelegationEntry(other, delegate.Pub.Fixed(), 10, 30, 2))
+	process(10, 12)
+	if payer, ok := s.GetECPayer(false, delegate.Pub.Fixed(), 1, 12); !ok || payer != other.Pub.Fixed() {
+		t.Errorf("Expected the other owner to pay, got %x", payer)
+	}
+	save(11, NewECDelegationEntry(owner, delegate.Pub.Fixed(), 50, 20, 2))
+	process(11, 13)
+	if payer, ok := s.GetECPayer(false, delegate.Pub.Fixed(), 50, 13); !ok || payer != owner.Pub.Fixed() {
+		t.Errorf("Expected the owner to pay the new limit")
+	}
+	if err := commit(13, 5); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.GetE(false, other.Pub.Fixed()); v != 95 {
+		t.Errorf("Expected the other owner to pay first, got %d ECs left", v)
+	}
+	if payer, ok := s.GetECPayer(false, delegate.Pub.Fixed(), 1, 20); !ok || payer != other.Pub.Fixed() {
+		t.Errorf("Expected the other owner to pay past the expiry of the owner")
+	}
+	if _, ok := s.GetECPayer(false, delegate.Pub.Fixed(), 1, 30); ok {
+		t.Errorf("Expected the delegate to pay from the last expiry")
+	}
+
+	// The delegations of a block are not read until all its entries are saved
+	missing := NewECDelegationEntry(owner, delegate.Pub.Fixed(), 50, 40, 3)
+	saveEBlock(14, 0, missing)
+	if _, err := s.ECDelegationEntries(14); err == nil {
+		t.Errorf("Expected an error for a missing entry")
+	}
+	save(14, missing)
+	if entries, err := s.ECDelegationEntries(14); err != nil || len(entries) != 1 {
+		t.Errorf("Expected the entry once saved, got %d entries, %v", len(entries), err)
+	}
+
+	// Before the activation, the delegate pays
+	defer activations.SetTestActivationHeight(activations.EC_DELEGATION, 100)()
+	if _, ok := s.GetECPayer(false, delegate.Pub.Fixed(), 1, 13); ok {
+		t.Errorf("Expected the delegate to pay before the activation")
+	}
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+)
+
+// A commit can pay entry credits over the max of its entry (10, 20 for a chain) as a tip, up to
+// constants.MAX_EC_TIP.  The tip is charged like the rest of the credits, so it is in the ECBlock
+// with its commit.  Leaders take the commits with the higher tips first, and the reveals of their
+// entries.  Followers reject tips before the activation of EC_TIPS, see messages.MaxECTip().
+
+// GetECTip returns the tip a message pays for priority, the tip of its commit for a reveal
+func (s *State) GetECTip(msg interfaces.IMsg) int {
+	switch m := msg.(type) {
+	case *messages.CommitEntryMsg:
+		return int(m.CommitEntry.GetTip())
+	case *messages.CommitChainMsg:
+		return int(m.CommitChain.GetTip())
+	case *messages.RevealEntryMsg:
+		if commit := s.Commits.Get(m.GetHash().Fixed()); commit != nil {
+			return s.GetECTip(commit)
+		}
+	}
+	return 0
+}
+
+// SortByECTip orders the messages a leader is about to execute by tip, highest first.  Messages of the
+// same tip stay in the order they came in.
+func (s *State) SortByECTip(msgs []interfaces.IMsg) {
+	if !s.Leader || !activations.IsActive(activations.EC_TIPS, int(s.LLeaderHeight)) {
+		return
+	}
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return s.GetECTip(msgs[i]) > s.GetECTip(msgs[j])
+	})
+}
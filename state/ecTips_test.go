@@ -0,0 +1,83 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func tipCommit(credits uint8, entryHash interfaces.IHash) *messages.CommitEntryMsg {
+	ce := entryCreditBlock.NewCommitEntry()
+	ce.MilliTime.UnmarshalBinary([]byte{0, 0, 0, 0, 1, credits})
+	ce.EntryHash = entryHash
+	ce.Credits = credits
+	testHelper.SignCommit(0, ce)
+	m := new(messages.CommitEntryMsg)
+	m.CommitEntry = ce
+	return m
+}
+
+func TestValidateECTip(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	c := tipCommit(1, testHelper.NewRepeatingHash(1))
+	s.PutE(true, c.CommitEntry.ECPubKey.Fixed(), 1000)
+
+	defer activations.SetTestActivationHeight(activations.EC_TIPS, int(s.GetLLeaderHeight())+1)()
+	if v := tipCommit(constants.MAX_ENTRY_CREDITS, testHelper.NewRepeatingHash(1)).Validate(s); v != 1 {
+		t.Errorf("Commit without a tip is %d", v)
+	}
+	if v := tipCommit(constants.MAX_ENTRY_CREDITS+1, testHelper.NewRepeatingHash(1)).Validate(s); v != -1 {
+		t.Errorf("Tip before the activation is %d", v)
+	}
+
+	activations.SetTestActivationHeight(activations.EC_TIPS, int(s.GetLLeaderHeight()))
+	tipped := tipCommit(constants.MAX_ENTRY_CREDITS+constants.MAX_EC_TIP, testHelper.NewRepeatingHash(1))
+	if v := tipped.Validate(s); v != 1 {
+		t.Errorf("Tip after the activation is %d", v)
+	}
+	if tipped.CommitEntry.GetTip() != constants.MAX_EC_TIP {
+		t.Errorf("Wrong tip %d", tipped.CommitEntry.GetTip())
+	}
+	if v := tipCommit(constants.MAX_ENTRY_CREDITS+constants.MAX_EC_TIP+1, testHelper.NewRepeatingHash(1)).Validate(s); v != -1 {
+		t.Errorf("Tip over the max is %d", v)
+	}
+}
+
+func TestSortByECTip(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	defer activations.SetTestActivationHeight(activations.EC_TIPS, 0)()
+
+	entry := entryBlock.NewEntry()
+	entry.Content.Bytes = []byte("priority")
+	reveal := new(messages.RevealEntryMsg)
+	reveal.Entry = entry
+	s.Commits.Put(entry.GetHash().Fixed(), tipCommit(15, entry.GetHash()))
+
+	eom := new(messages.EOM)
+	c10, c12, c11 := tipCommit(10, testHelper.NewRepeatingHash(1)), tipCommit(12, testHelper.NewRepeatingHash(2)), tipCommit(11, testHelper.NewRepeatingHash(3))
+	in := []interfaces.IMsg{c10, eom, c12, reveal, c11}
+
+	msgs := append([]interfaces.IMsg(nil), in...)
+	s.Leader = false
+	s.SortByECTip(msgs)
+	for i := range msgs {
+		if msgs[i] != in[i] {
+			t.Fatalf("A follower reordered the messages")
+		}
+	}
+
+	s.Leader = true
+	s.SortByECTip(msgs)
+	expected := []interfaces.IMsg{reveal, c12, c11, c10, eom}
+	for i := range msgs {
+		if msgs[i] != expected[i] {
+			t.Errorf("Message %d has the tip %d, expected %d", i, s.GetECTip(msgs[i]), s.GetECTip(expected[i]))
+		}
+	}
+}
@@ -6,12 +6,12 @@ package state
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/database/databaseOverlay"
 )
 
@@ -20,7 +20,7 @@ func has(s *State, entry interfaces.IHash) bool {
 		if s.UsingTorrent() {
 			// Torrents complete second pass
 		} else {
-			time.Sleep(30 * time.Millisecond)
+			primitives.Sleep(30 * time.Millisecond)
 		}
 	}
 	exists, err := s.DB.DoesKeyExist(databaseOverlay.ENTRY, entry.Bytes())
@@ -50,7 +50,7 @@ func (s *State) MakeMissingEntryRequests() {
 	MissingEntryMap := make(map[[32]byte]*MissingEntry)
 
 	for {
-		now := time.Now()
+		now := primitives.Now()
 
 		newrequest := 0
 
@@ -103,7 +103,7 @@ func (s *State) MakeMissingEntryRequests() {
 
 				if et.Cnt == 0 {
 					et.Cnt = 1
-					et.LastTime = now.Add(time.Duration((rand.Int() % 5000)) * time.Millisecond)
+					et.LastTime = now.Add(time.Duration((primitives.RandIntn(5000))) * time.Millisecond)
 					continue
 				}
 
@@ -119,13 +119,13 @@ func (s *State) MakeMissingEntryRequests() {
 					entryRequest := messages.NewMissingData(s, et.EntryHash)
 					entryRequest.SendOut(s, entryRequest)
 					newrequest++
-					et.LastTime = now.Add(time.Duration((rand.Int() % 5000)) * time.Millisecond)
+					et.LastTime = now.Add(time.Duration((primitives.RandIntn(5000))) * time.Millisecond)
 					et.Cnt++
 				}
 
 			}
 		} else {
-			time.Sleep(20 * time.Second)
+			primitives.Sleep(20 * time.Second)
 		}
 
 		// Insert the entries we have found into the database.
@@ -156,19 +156,19 @@ func (s *State) MakeMissingEntryRequests() {
 		}
 		if sent == 0 {
 			if s.GetHighestKnownBlock()-s.GetHighestSavedBlk() > 100 {
-				time.Sleep(10 * time.Second)
+				primitives.Sleep(10 * time.Second)
 			} else {
-				time.Sleep(100 * time.Millisecond)
+				primitives.Sleep(100 * time.Millisecond)
 			}
 			if s.EntryDBHeightComplete == s.GetHighestSavedBlk() {
-				time.Sleep(20 * time.Second)
+				primitives.Sleep(20 * time.Second)
 			}
 		}
 	}
 }
 
 func (s *State) GoSyncEntries() {
-	go s.MakeMissingEntryRequests()
+	primitives.Go(s.MakeMissingEntryRequests)
 
 	// Map to track what I know is missing
 	missingMap := make(map[[32]byte]interfaces.IHash)
@@ -226,7 +226,7 @@ func (s *State) GoSyncEntries() {
 
 			// Wait for the database if we have to
 			for db == nil {
-				time.Sleep(1 * time.Second)
+				primitives.Sleep(1 * time.Second)
 				db = s.GetDirectoryBlockByHeight(scan)
 			}
 
@@ -240,7 +240,7 @@ func (s *State) GoSyncEntries() {
 				// Dont have an eBlock?  Huh. We can go on, but we can't advance.  We just wait until it
 				// does show up.
 				for eBlock == nil {
-					time.Sleep(1 * time.Second)
+					primitives.Sleep(1 * time.Second)
 					eBlock, _ = s.DB.FetchEBlock(ebKeyMR)
 				}
 
@@ -251,7 +251,7 @@ func (s *State) GoSyncEntries() {
 					}
 
 					// Only update the replay hashes in the last 24 hours.
-					if time.Now().Unix()-db.GetTimestamp().GetTimeSeconds() < 24*60*60 {
+					if primitives.Now().Unix()-db.GetTimestamp().GetTimeSeconds() < 24*60*60 {
 						ueh := new(EntryUpdate)
 						ueh.Hash = entryhash
 						ueh.Timestamp = db.GetTimestamp()
@@ -304,10 +304,10 @@ func (s *State) GoSyncEntries() {
 		lastfirstmissing = firstMissing
 		if firstMissing < 0 {
 			s.EntryDBHeightComplete = s.GetHighestSavedBlk()
-			time.Sleep(5 * time.Second)
+			primitives.Sleep(5 * time.Second)
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		primitives.Sleep(100 * time.Millisecond)
 
 	}
 }
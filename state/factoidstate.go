@@ -271,29 +271,37 @@ func (fs *FactoidState) UpdateECTransaction(rt bool, trans interfaces.IECBlockEn
 		return nil
 	case constants.ECIDChainCommit:
 		t := trans.(*entryCreditBlock.CommitChain)
-		v := fs.State.GetE(rt, t.ECPubKey.Fixed()) - int64(t.Credits)
+		payer, delegated := fs.ecPayer(rt, t.ECPubKey.Fixed(), t.Credits)
+		v := fs.State.GetE(rt, payer) - int64(t.Credits)
 		if (fs.DBHeight > 97886 || fs.State.GetNetworkID() != constants.MAIN_NETWORK_ID) && v < 0 {
 			return fmt.Errorf("%29s dbht %d: Not enough ECs (%d) to cover a chain commit (%d)",
 				fs.State.GetFactomNodeName(),
 				fs.DBHeight,
-				fs.State.GetE(rt, t.ECPubKey.Fixed()),
+				fs.State.GetE(rt, payer),
 				t.Credits)
 		}
-		fs.State.PutE(rt, t.ECPubKey.Fixed(), v)
+		fs.State.PutE(rt, payer, v)
+		if delegated {
+			fs.State.SpendECDelegation(rt, payer, t.ECPubKey.Fixed(), uint64(t.Credits))
+		}
 		fs.State.NumTransactions++
 		fs.State.Replay.IsTSValid(constants.INTERNAL_REPLAY, t.GetSigHash(), t.GetTimestamp())
 		fs.State.Replay.IsTSValid(constants.NETWORK_REPLAY, t.GetSigHash(), t.GetTimestamp())
 	case constants.ECIDEntryCommit:
 		t := trans.(*entryCreditBlock.CommitEntry)
-		v := fs.State.GetE(rt, t.ECPubKey.Fixed()) - int64(t.Credits)
+		payer, delegated := fs.ecPayer(rt, t.ECPubKey.Fixed(), t.Credits)
+		v := fs.State.GetE(rt, payer) - int64(t.Credits)
 		if (fs.DBHeight > 97886 || fs.State.GetNetworkID() != constants.MAIN_NETWORK_ID) && v < 0 {
 			return fmt.Errorf("%29s dbht %d: Not enough ECs (%d) to cover a entry commit (%d)",
 				fs.State.GetFactomNodeName(),
 				fs.DBHeight,
-				fs.State.GetE(rt, t.ECPubKey.Fixed()),
+				fs.State.GetE(rt, payer),
 				t.Credits)
 		}
-		fs.State.PutE(rt, t.ECPubKey.Fixed(), v)
+		fs.State.PutE(rt, payer, v)
+		if delegated {
+			fs.State.SpendECDelegation(rt, payer, t.ECPubKey.Fixed(), uint64(t.Credits))
+		}
 		fs.State.NumTransactions++
 		fs.State.Replay.IsTSValid(constants.INTERNAL_REPLAY, t.GetSigHash(), t.GetTimestamp())
 		fs.State.Replay.IsTSValid(constants.NETWORK_REPLAY, t.GetSigHash(), t.GetTimestamp())
@@ -304,6 +312,16 @@ func (fs *FactoidState) UpdateECTransaction(rt bool, trans interfaces.IECBlockEn
 	return nil
 }
 
+// ecPayer returns the EC address paying for a commit signed by the key, see GetECPayer.  Commits in the
+// process list are paid as of the block being built, the commits of a block as of its height.
+func (fs *FactoidState) ecPayer(rt bool, key [32]byte, credits uint8) ([32]byte, bool) {
+	height := fs.DBHeight
+	if rt {
+		height = fs.State.LLeaderHeight
+	}
+	return fs.State.GetECPayer(rt, key, uint64(credits), height)
+}
+
 // Assumes validation has already been done.
 func (fs *FactoidState) UpdateTransaction(rt bool, trans interfaces.ITransaction) error {
 
@@ -384,9 +402,52 @@ func (fs *FactoidState) ProcessEndOfBlock(state interfaces.IState) {
 	fs.State.CurrentBlockStartTime = time.Now().UnixNano()
 }
 
+// ValidateRCD checks the RCD types are active, and that no time lock holds the
+// RCD in the current block
+func (fs *FactoidState) ValidateRCD(rcd interfaces.IRCD) error {
+	err := factoid.WalkRCD(rcd, func(r interfaces.IRCD) error {
+		switch r.(type) {
+		case *factoid.RCD_2:
+			if !activations.IsActive(activations.MULTISIG_RCD, int(fs.DBHeight)) {
+				return fmt.Errorf("Multisig RCDs are not active yet")
+			}
+		case *factoid.RCD_3:
+			if !activations.IsActive(activations.TIMELOCK_RCD, int(fs.DBHeight)) {
+				return fmt.Errorf("Time locked RCDs are not active yet")
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = factoid.CheckLocks(rcd, fs.DBHeight, fs.blockTime())
+	}
+	if err != nil {
+		return fmt.Errorf("%20s DBHT %d %v", fs.State.GetFactomNodeName(), fs.DBHeight, err)
+	}
+	return nil
+}
+
+// blockTime is the time of the current block in milliseconds, the time of its
+// coinbase transaction
+func (fs *FactoidState) blockTime() uint64 {
+	if fs.CurrentBlock == nil {
+		return 0
+	}
+	ts := fs.CurrentBlock.GetCoinbaseTimestamp()
+	if ts == nil || ts.GetTimeMilli() < 0 {
+		return 0
+	}
+	return ts.GetTimeMilliUInt64()
+}
+
 // Returns an error message about what is wrong with the transaction if it is
 // invalid, otherwise you are good to go.
 func (fs *FactoidState) Validate(index int, trans interfaces.ITransaction) error {
+	for _, rcd := range trans.GetRCDs() {
+		if err := fs.ValidateRCD(rcd); err != nil {
+			return err
+		}
+	}
 	var sums = make(map[[32]byte]uint64, 10)  // Look at the sum of an address's inputs
 	for _, input := range trans.GetInputs() { //    to a transaction.
 		bal, err := factoid.ValidateAmounts(sums[input.GetAddress().Fixed()], input.GetAmount())
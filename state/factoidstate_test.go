@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/FactomProject/factomd/activations"
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/entryCreditBlock"
 	"github.com/FactomProject/factomd/common/factoid"
@@ -545,3 +546,59 @@ func Test_updating_balances_FactoidState(test *testing.T) {
 
 }
 */
+
+func TestValidateMultisig(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	fs := s.FactoidState
+
+	rcd, err := factoid.NewRCD_2(1, []interfaces.IRCD{testHelper.NewFactoidRCDAddress(1), testHelper.NewFactoidRCDAddress(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, _ := rcd.GetAddress()
+	s.PutF(true, address.Fixed(), 1000)
+
+	ft := new(factoid.Transaction)
+	ft.AddInput(address, 1000)
+	ft.AddAuthorization(rcd)
+
+	defer activations.SetTestActivationHeight(activations.MULTISIG_RCD, int(fs.(*FactoidState).DBHeight)+1)()
+	if err := fs.Validate(1, ft); err == nil {
+		t.Error("Accepted a multisig transaction below the activation height")
+	}
+	activations.SetTestActivationHeight(activations.MULTISIG_RCD, int(fs.(*FactoidState).DBHeight))
+	if err := fs.Validate(1, ft); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateTimelock(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	fs := s.FactoidState
+	height := fs.(*FactoidState).DBHeight
+
+	spend := func(lockHeight uint32) interfaces.ITransaction {
+		rcd, err := factoid.NewRCD_3(lockHeight, 0, testHelper.NewFactoidRCDAddress(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, _ := rcd.GetAddress()
+		s.PutF(true, address.Fixed(), 1000)
+		ft := new(factoid.Transaction)
+		ft.AddInput(address, 1000)
+		ft.AddAuthorization(rcd)
+		return ft
+	}
+
+	defer activations.SetTestActivationHeight(activations.TIMELOCK_RCD, int(height)+1)()
+	if err := fs.Validate(1, spend(0)); err == nil {
+		t.Error("Accepted a time locked transaction below the activation height")
+	}
+	activations.SetTestActivationHeight(activations.TIMELOCK_RCD, int(height))
+	if err := fs.Validate(1, spend(height)); err != nil {
+		t.Error(err)
+	}
+	if err := fs.Validate(1, spend(height+1)); err == nil {
+		t.Error("Accepted a transaction before its lock height")
+	}
+}
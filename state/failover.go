@@ -0,0 +1,93 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"sort"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// With the fast failover (activation FastFailover) the elections hand a stalled VM to a backup audit
+// server within seconds. For the debug API, every node keeps the cadence of the authority set: when it
+// last got an ack from each federated server, and a heartbeat from each audit server. It is what this
+// node saw, so the elections don't decide anything by it. The elections publish the backups of the
+// block here too.
+
+// ServerCadence is when the node last heard from a server of the authority set
+type ServerCadence struct {
+	ChainID       string `json:"chainid"`
+	Acks          int64  `json:"acks"`
+	LastAck       int64  `json:"lastack"` // Milliseconds
+	Heartbeats    int64  `json:"heartbeats"`
+	LastHeartbeat int64  `json:"lastheartbeat"` // Milliseconds
+}
+
+// FailoverStatus is the fast failover as the elections run it for the current block
+type FailoverStatus struct {
+	Active       bool            `json:"active"`
+	DBHeight     int             `json:"dbheight"`
+	FaultTimeout int             `json:"faulttimeout"` // Seconds
+	RoundTimeout int             `json:"roundtimeout"` // Seconds
+	Backups      [][]string      `json:"backups"`      // Ranked backup audit servers of each federated server
+	Servers      []ServerCadence `json:"servers"`
+}
+
+func (s *State) cadenceOf(id interfaces.IHash) *ServerCadence {
+	if s.cadence == nil {
+		s.cadence = make(map[[32]byte]*ServerCadence)
+	}
+	c := s.cadence[id.Fixed()]
+	if c == nil {
+		c = &ServerCadence{ChainID: id.String()}
+		s.cadence[id.Fixed()] = c
+	}
+	return c
+}
+
+// NoteAck records an ack from a federated server
+func (s *State) NoteAck(leader interfaces.IHash) {
+	if leader == nil {
+		return
+	}
+	s.failoverMutex.Lock()
+	defer s.failoverMutex.Unlock()
+	c := s.cadenceOf(leader)
+	c.Acks++
+	c.LastAck = primitives.Now().UnixNano() / 1e6
+}
+
+// NoteHeartbeat records a heartbeat from an audit server
+func (s *State) NoteHeartbeat(id interfaces.IHash) {
+	if id == nil {
+		return
+	}
+	s.failoverMutex.Lock()
+	defer s.failoverMutex.Unlock()
+	c := s.cadenceOf(id)
+	c.Heartbeats++
+	c.LastHeartbeat = primitives.Now().UnixNano() / 1e6
+}
+
+// SetFailoverStatus is called by the elections at the start of every block
+func (s *State) SetFailoverStatus(status FailoverStatus) {
+	s.failoverMutex.Lock()
+	defer s.failoverMutex.Unlock()
+	s.failover = status
+}
+
+// GetFailoverStatus returns the fast failover status and the cadence of the authority set
+func (s *State) GetFailoverStatus() interface{} {
+	s.failoverMutex.Lock()
+	defer s.failoverMutex.Unlock()
+	status := s.failover
+	status.Servers = nil
+	for _, c := range s.cadence {
+		status.Servers = append(status.Servers, *c)
+	}
+	sort.Slice(status.Servers, func(i, j int) bool { return status.Servers[i].ChainID < status.Servers[j].ChainID })
+	return &status
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state_test
+
+import (
+	"testing"
+
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+)
+
+func TestServerCadence(t *testing.T) {
+	s := new(State)
+	fed := primitives.Sha([]byte("fed"))
+	aud := primitives.Sha([]byte("aud"))
+
+	s.NoteAck(fed)
+	s.NoteAck(fed)
+	s.NoteHeartbeat(aud)
+	s.NoteAck(nil)
+
+	s.SetFailoverStatus(FailoverStatus{Active: true, DBHeight: 7})
+	status := s.GetFailoverStatus().(*FailoverStatus)
+	if !status.Active || status.DBHeight != 7 {
+		t.Errorf("Wrong status %+v", status)
+	}
+	if len(status.Servers) != 2 {
+		t.Fatalf("Expected the cadence of 2 servers, got %d", len(status.Servers))
+	}
+	for _, c := range status.Servers {
+		switch c.ChainID {
+		case fed.String():
+			if c.Acks != 2 || c.LastAck == 0 || c.Heartbeats != 0 {
+				t.Errorf("Wrong cadence of the federated server %+v", c)
+			}
+		case aud.String():
+			if c.Acks != 0 || c.Heartbeats != 1 || c.LastHeartbeat == 0 {
+				t.Errorf("Wrong cadence of the audit server %+v", c)
+			}
+		default:
+			t.Errorf("Unknown server %s", c.ChainID)
+		}
+	}
+}
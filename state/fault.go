@@ -94,8 +94,8 @@ func markFault(pl *ProcessList, vmIndex int, faultReason int) {
 	}
 
 	c := pl.State.CurrentMinute
-	if c > 9 {
-		c = 9
+	if last := pl.State.GetMinutesPerBlock(pl.DBHeight) - 1; c > last {
+		c = last
 	}
 	index := pl.ServerMap[c][vmIndex]
 	if index < len(pl.FedServers) {
@@ -115,8 +115,8 @@ func markNoFault(pl *ProcessList, vmIndex int) {
 	}
 
 	c := pl.State.CurrentMinute
-	if c > 9 {
-		c = 9
+	if last := pl.State.GetMinutesPerBlock(pl.DBHeight) - 1; c > last {
+		c = last
 	}
 	index := pl.ServerMap[c][vmIndex]
 	if index < len(pl.FedServers) {
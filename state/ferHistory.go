@@ -0,0 +1,140 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// FERChange is an FER chain entry that scheduled a change of the exchange rate, see
+// ProcessRecentFERChainEntries.  A change is in effect from its ActivatedHeight, pending while it is the
+// scheduled change, and superseded if a higher priority entry replaced it before its target height.
+type FERChange struct {
+	EntryHash       interfaces.IHash `json:"entryhash"`       // The authorizing entry of the FER chain
+	EntryHeight     uint32           `json:"entryheight"`     // Height the entry was processed at
+	Priority        uint32           `json:"priority"`        //
+	TargetPrice     uint64           `json:"targetprice"`     // Factoshis per EC
+	TargetHeight    uint32           `json:"targetheight"`    // Height the rate takes effect, adjusted to at least EntryHeight+2
+	ActivatedHeight uint32           `json:"activatedheight"` // Height the rate took effect, 0 if it did not
+}
+
+var _ interfaces.BinaryMarshallable = (*FERChange)(nil)
+
+func (c *FERChange) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+	if err := buf.PushIHash(c.EntryHash); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(c.EntryHeight); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(c.Priority); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt64(c.TargetPrice); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(c.TargetHeight); err != nil {
+		return nil, err
+	}
+	if err := buf.PushUInt32(c.ActivatedHeight); err != nil {
+		return nil, err
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (c *FERChange) UnmarshalBinaryData(p []byte) (newData []byte, err error) {
+	buf := primitives.NewBuffer(p)
+	if c.EntryHash, err = buf.PopIHash(); err != nil {
+		return
+	}
+	if c.EntryHeight, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if c.Priority, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if c.TargetPrice, err = buf.PopUInt64(); err != nil {
+		return
+	}
+	if c.TargetHeight, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	if c.ActivatedHeight, err = buf.PopUInt32(); err != nil {
+		return
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+func (c *FERChange) UnmarshalBinary(p []byte) error {
+	_, err := c.UnmarshalBinaryData(p)
+	return err
+}
+
+func (c *FERChange) String() string {
+	return fmt.Sprintf("FER entry %x at %d priority %d: %d factoshis per EC at %d, activated at %d",
+		c.EntryHash.Bytes()[:6], c.EntryHeight, c.Priority, c.TargetPrice, c.TargetHeight, c.ActivatedHeight)
+}
+
+// FERHistoryStatus is a page of the FER history, newest first, with the rate now and the pending change
+type FERHistoryStatus struct {
+	CurrentRate   uint64      `json:"currentrate"`
+	PredictedRate uint64      `json:"predictedrate"` // The rate commits must pay to stay valid over the change, see GetPredictiveFER
+	Pending       *FERChange  `json:"pending,omitempty"`
+	Total         int         `json:"total"`
+	Offset        int         `json:"offset"`
+	Changes       []FERChange `json:"changes"`
+}
+
+// recordFERChange adds an accepted FER entry to the history
+func (s *State) recordFERChange(c FERChange) {
+	s.ferHistoryMutex.Lock()
+	defer s.ferHistoryMutex.Unlock()
+	s.FERHistory = append(s.FERHistory, c)
+}
+
+// activateFERChange marks the scheduled change as in effect from the height
+func (s *State) activateFERChange(height uint32, price uint64) {
+	s.ferHistoryMutex.Lock()
+	defer s.ferHistoryMutex.Unlock()
+	for i := len(s.FERHistory) - 1; i >= 0; i-- {
+		c := &s.FERHistory[i]
+		if c.ActivatedHeight == 0 && c.TargetHeight == height && c.TargetPrice == price {
+			c.ActivatedHeight = height
+			return
+		}
+	}
+}
+
+// GetFERHistory returns a page of the FER history as a *FERHistoryStatus, newest first
+func (s *State) GetFERHistory(offset int, limit int) interface{} {
+	s.ferHistoryMutex.Lock()
+	defer s.ferHistoryMutex.Unlock()
+
+	r := new(FERHistoryStatus)
+	r.CurrentRate = s.GetFactoshisPerEC()
+	r.PredictedRate = s.GetPredictiveFER()
+	r.Total = len(s.FERHistory)
+	r.Offset = offset
+	r.Changes = []FERChange{}
+
+	if s.FERChangeHeight > s.GetDBHeightComplete() {
+		for i := len(s.FERHistory) - 1; i >= 0; i-- {
+			c := s.FERHistory[i]
+			if c.ActivatedHeight == 0 && c.TargetHeight == s.FERChangeHeight && c.TargetPrice == s.FERChangePrice {
+				r.Pending = &c
+				break
+			}
+		}
+	}
+
+	for i := len(s.FERHistory) - 1 - offset; i >= 0 && len(r.Changes) < limit; i-- {
+		r.Changes = append(r.Changes, s.FERHistory[i])
+	}
+	return r
+}
@@ -0,0 +1,144 @@
+package state_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock/specialEntries"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+const ferChainID = "111111118d918a8be684e0dac725493a75862ef96d2d3f43f84b26969329bf03"
+
+// newFEREntry makes an FER chain entry signed by the exchange rate authority of LOCAL, the zero key
+func newFEREntry(target uint32, price uint64, expiration uint32, priority uint32) interfaces.IEBEntry {
+	fer := new(specialEntries.FEREntry)
+	fer.SetTargetActivationHeight(target)
+	fer.SetTargetPrice(price)
+	fer.SetExpirationHeight(expiration)
+	fer.SetPriority(priority)
+	content, _ := json.Marshal(fer)
+
+	key := primitives.NewPrivateKeyFromHexBytes(make([]byte, 32))
+	e := entryBlock.NewEntry()
+	e.ChainID, _ = primitives.HexToHash(ferChainID)
+	e.ExtIDs = []primitives.ByteSlice{{Bytes: key.Sign(content).GetSignature()[:]}}
+	e.Content = primitives.ByteSlice{Bytes: content}
+	return e
+}
+
+// saveFEREntries puts the entries in an FER chain eblock at the height
+func saveFEREntries(t *testing.T, s *State, height uint32, entries ...interfaces.IEBEntry) {
+	chainID, _ := primitives.HexToHash(ferChainID)
+	eb := entryBlock.NewEBlock()
+	eb.GetHeader().SetChainID(chainID)
+	eb.GetHeader().SetDBHeight(height)
+
+	s.DB.StartMultiBatch()
+	for _, e := range entries {
+		if err := eb.AddEBEntry(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DB.InsertEntryMultiBatch(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DB.ProcessEBlockMultiBatch(eb, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.ExecuteMultiBatch(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFERHistory(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	s.FERChainId = ferChainID
+	s.FERChangeHeight = 0
+	s.FERChangePrice = 0
+	s.FERPriority = 0
+	rate := s.GetFactoshisPerEC()
+
+	h := s.GetDBHeightComplete()
+	e := newFEREntry(h+3, rate+5000, h+1, 1)
+	saveFEREntries(t, s, h-1, e)
+	s.ProcessRecentFERChainEntries()
+
+	r := s.GetFERHistory(0, 10).(*FERHistoryStatus)
+	if r.Total != 1 || len(r.Changes) != 1 {
+		t.Fatalf("Expected one change, got %d of %d", len(r.Changes), r.Total)
+	}
+	c := r.Changes[0]
+	if !c.EntryHash.IsSameAs(e.GetHash()) || c.EntryHeight != h || c.TargetHeight != h+3 ||
+		c.TargetPrice != rate+5000 || c.Priority != 1 || c.ActivatedHeight != 0 {
+		t.Errorf("Wrong change %s", c.String())
+	}
+	if r.Pending == nil || r.Pending.TargetHeight != h+3 {
+		t.Errorf("Expected the change to be pending, got %v", r.Pending)
+	}
+	if r.CurrentRate != rate || r.PredictedRate != rate+5000 {
+		t.Errorf("Expected the rate %d predicted %d, got %d predicted %d", rate, rate+5000, r.CurrentRate, r.PredictedRate)
+	}
+
+	// A change targeting the next block takes effect
+	s.FERHistory[0].TargetHeight = h + 1
+	s.FERChangeHeight = h + 1
+	s.ProcessRecentFERChainEntries()
+
+	r = s.GetFERHistory(0, 10).(*FERHistoryStatus)
+	if r.Total != 1 || r.Changes[0].ActivatedHeight != h+1 {
+		t.Errorf("Expected the change to be activated at %d, got %s", h+1, r.Changes[0].String())
+	}
+	if r.Pending != nil {
+		t.Errorf("Expected nothing pending, got %s", r.Pending.String())
+	}
+	if r.CurrentRate != rate+5000 {
+		t.Errorf("Expected the rate %d, got %d", rate+5000, r.CurrentRate)
+	}
+}
+
+func TestFERHistoryPages(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	for i := 0; i < 5; i++ {
+		s.FERHistory = append(s.FERHistory, FERChange{EntryHash: primitives.RandomHash(), TargetPrice: uint64(i)})
+	}
+
+	r := s.GetFERHistory(1, 3).(*FERHistoryStatus)
+	if r.Total != 5 || r.Offset != 1 || len(r.Changes) != 3 {
+		t.Fatalf("Expected 3 of 5 changes from 1, got %d of %d from %d", len(r.Changes), r.Total, r.Offset)
+	}
+	for i, c := range r.Changes {
+		if c.TargetPrice != uint64(3-i) {
+			t.Errorf("Expected the changes newest first, got %d at %d", c.TargetPrice, i)
+		}
+	}
+
+	r = s.GetFERHistory(10, 3).(*FERHistoryStatus)
+	if len(r.Changes) != 0 {
+		t.Errorf("Expected no changes past the end, got %d", len(r.Changes))
+	}
+}
+
+func TestFERChangeMarshal(t *testing.T) {
+	c := FERChange{EntryHash: primitives.RandomHash(), EntryHeight: 10, Priority: 2, TargetPrice: 3000, TargetHeight: 12, ActivatedHeight: 12}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2 := new(FERChange)
+	rest, err := c2.UnmarshalBinaryData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes left", len(rest))
+	}
+	if !c.EntryHash.IsSameAs(c2.EntryHash) || c.EntryHeight != c2.EntryHeight || c.Priority != c2.Priority ||
+		c.TargetPrice != c2.TargetPrice || c.TargetHeight != c2.TargetHeight || c.ActivatedHeight != c2.ActivatedHeight {
+		t.Errorf("Expected %s, got %s", c.String(), c2.String())
+	}
+}
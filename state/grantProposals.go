@@ -0,0 +1,368 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/FactomProject/ed25519"
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/globals"
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// Grants can be proposed on chain, by entries in the grant chain:
+//
+//	ExtIDs[0]  0x00, the version
+//	ExtIDs[1]  "Grant Payout"
+//	ExtIDs[2]  the payout height, 4 bytes big endian
+//	ExtIDs[3:] pairs of a signer and its signature of ExtIDs[0:3] and the content
+//	Content    the payouts, 32 bytes of factoid address (the RCD hash) and 8 bytes of factoshis each
+//
+// A signer is either the identity chain ID of an authority server, signing with its block signing
+// key, or a key of the grant key set of the network. A proposal is paid if more than half the
+// authority servers sign it, or enough keys of the grant key set. Like the hard coded grants, the
+// payouts go in the coinbase descriptor of their height.
+//
+// A proposal must be on chain a payout period before its height. The node keeps the proposals of the
+// saved blocks as it processes them, and does not process a block until every entry of the grant chain
+// in the block below is in the database. At the last block a proposal can be in, its cutoff block, it is
+// checked against the authority servers the admin blocks up to that block make, so every node pays the
+// same proposals.
+
+var GrantChainID = entryBlock.ExternalIDsToChainID([][]byte{[]byte("Factom Grants")})
+
+const GrantProposalType = "Grant Payout"
+
+type GrantKeySet struct {
+	Keys      [][32]byte // ed25519 public keys
+	Threshold int        // Keys that must sign, 0 if the network has no grant keys
+}
+
+// Return the grant key set of the network. Buried in a func like the hard coded grants.
+func GetGrantKeySet() GrantKeySet {
+	switch globals.Params.NetworkName {
+	case "LOCAL":
+		// The bootstrap key of the local network
+		key, _ := primitives.HexToHash("cc1985cdfae4e32b5a454dfda8ce5e1361558482684f3367649c3ad852c8e31a")
+		return GrantKeySet{[][32]byte{key.Fixed()}, 1}
+	default:
+		return GrantKeySet{}
+	}
+}
+
+type GrantSignature struct {
+	Signer    [32]byte
+	Signature [constants.SIGNATURE_LENGTH]byte
+}
+
+type GrantProposal struct {
+	DBHeight   uint32
+	Outputs    []interfaces.ITransAddress
+	Signatures []GrantSignature
+	SigData    []byte              // What the signers sign
+	Entry      interfaces.IEBEntry // Of the proposal, what the saved state keeps
+	Authorized bool                // By the authority servers of its cutoff block
+}
+
+// ParseGrantProposal checks the structure of a grant proposal entry, not its signatures
+func ParseGrantProposal(entry interfaces.IEBEntry) (*GrantProposal, error) {
+	extIDs := entry.ExternalIDs()
+	if len(extIDs) < 3 || len(extIDs)%2 != 1 {
+		return nil, fmt.Errorf("Wrong number of ExtIDs %d", len(extIDs))
+	}
+	if !bytes.Equal(extIDs[0], []byte{0}) || string(extIDs[1]) != GrantProposalType || len(extIDs[2]) != 4 {
+		return nil, fmt.Errorf("Not a grant proposal")
+	}
+	p := new(GrantProposal)
+	p.DBHeight = binary.BigEndian.Uint32(extIDs[2])
+
+	content := entry.GetContent()
+	if len(content) == 0 || len(content)%40 != 0 {
+		return nil, fmt.Errorf("Bad payouts, %d bytes", len(content))
+	}
+	for i := 0; i < len(content); i += 40 {
+		amount := binary.BigEndian.Uint64(content[i+32 : i+40])
+		if amount == 0 || int64(amount) < 0 {
+			return nil, fmt.Errorf("Bad payout amount %d", amount)
+		}
+		p.Outputs = append(p.Outputs, factoid.NewOutAddress(factoid.NewAddress(content[i:i+32]), amount))
+	}
+
+	for i := 3; i < len(extIDs); i += 2 {
+		if len(extIDs[i]) != 32 || len(extIDs[i+1]) != constants.SIGNATURE_LENGTH {
+			return nil, fmt.Errorf("Bad signature %d", (i-3)/2)
+		}
+		var sig GrantSignature
+		copy(sig.Signer[:], extIDs[i])
+		copy(sig.Signature[:], extIDs[i+1])
+		p.Signatures = append(p.Signatures, sig)
+	}
+	p.SigData = grantSigData(extIDs[:3], content)
+	p.Entry = entry
+	return p, nil
+}
+
+func (p *GrantProposal) MarshalBinary() ([]byte, error) {
+	buf := primitives.NewBuffer(nil)
+	if err := buf.PushBool(p.Authorized); err != nil {
+		return nil, err
+	}
+	data, err := p.Entry.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if err := buf.PushBytes(data); err != nil {
+		return nil, err
+	}
+	return buf.DeepCopyBytes(), nil
+}
+
+// UnmarshalBinaryData parses the entry of the proposal again
+func (p *GrantProposal) UnmarshalBinaryData(data []byte) (newData []byte, err error) {
+	buf := primitives.NewBuffer(data)
+	authorized, err := buf.PopBool()
+	if err != nil {
+		return nil, err
+	}
+	b, err := buf.PopBytes()
+	if err != nil {
+		return nil, err
+	}
+	entry := entryBlock.NewEntry()
+	if err := entry.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	parsed, err := ParseGrantProposal(entry)
+	if err != nil {
+		return nil, err
+	}
+	*p = *parsed
+	p.Authorized = authorized
+	return buf.DeepCopyBytes(), nil
+}
+
+func (p *GrantProposal) UnmarshalBinary(data []byte) error {
+	_, err := p.UnmarshalBinaryData(data)
+	return err
+}
+
+func grantSigData(extIDs [][]byte, content []byte) []byte {
+	var data []byte
+	for _, extID := range extIDs {
+		data = append(data, extID...)
+	}
+	return append(data, content...)
+}
+
+// NewGrantProposalEntry returns an unsigned grant proposal entry, to sign with SignGrantProposal
+func NewGrantProposalEntry(dbheight uint32, outputs []interfaces.ITransAddress) *entryBlock.Entry {
+	e := entryBlock.NewEntry()
+	e.ChainID = GrantChainID
+	height := make([]byte, 4)
+	binary.BigEndian.PutUint32(height, dbheight)
+	e.ExtIDs = []primitives.ByteSlice{{Bytes: []byte{0}}, {Bytes: []byte(GrantProposalType)}, {Bytes: height}}
+	for _, o := range outputs {
+		amount := make([]byte, 8)
+		binary.BigEndian.PutUint64(amount, o.GetAmount())
+		e.Content.Bytes = append(e.Content.Bytes, o.GetAddress().Bytes()...)
+		e.Content.Bytes = append(e.Content.Bytes, amount...)
+	}
+	return e
+}
+
+// SignGrantProposal adds a signature to a grant proposal entry. The signer is the identity chain ID
+// of an authority server, or the public key of a grant key.
+func SignGrantProposal(e *entryBlock.Entry, signer interfaces.IHash, key *primitives.PrivateKey) {
+	data := grantSigData(e.ExternalIDs()[:3], e.GetContent())
+	sig := key.Sign(data)
+	e.ExtIDs = append(e.ExtIDs, primitives.ByteSlice{Bytes: signer.Bytes()}, primitives.ByteSlice{Bytes: sig.GetSignature()[:]})
+}
+
+// AuthorizeGrantProposal checks the signatures of a grant proposal against an authority set and the
+// grant key set
+func AuthorizeGrantProposal(p *GrantProposal, authorities *AuthoritySet) error {
+	servers := make(map[[32]byte]*identity.Authority)
+	for _, list := range [][]*identity.Authority{authorities.Federated, authorities.Audit} {
+		for _, a := range list {
+			servers[a.AuthorityChainID.Fixed()] = a
+		}
+	}
+
+	keys := GetGrantKeySet()
+	byAuthority := make(map[[32]byte]bool)
+	byKey := make(map[[32]byte]bool)
+	for _, sig := range p.Signatures {
+		sig := sig
+		if auth := servers[sig.Signer]; auth != nil {
+			if valid, _ := auth.VerifySignature(p.SigData, &sig.Signature); valid {
+				byAuthority[sig.Signer] = true
+			}
+			continue
+		}
+		for _, key := range keys.Keys {
+			if key == sig.Signer && ed25519.VerifyCanonical(&key, p.SigData, &sig.Signature) {
+				byKey[sig.Signer] = true
+			}
+		}
+	}
+
+	if len(servers) > 0 && len(byAuthority)*2 > len(servers) {
+		return nil
+	}
+	if keys.Threshold > 0 && len(byKey) >= keys.Threshold {
+		return nil
+	}
+	return fmt.Errorf("Signed by %d of %d authority servers and %d of %d grant keys", len(byAuthority), len(servers), len(byKey), keys.Threshold)
+}
+
+// GrantProposalEntries returns the entries of the grant chain in a saved block, from a payout period
+// before the activation of the grant proposals. It returns an error if one of them is not in the
+// database yet.
+func (s *State) GrantProposalEntries(height uint32) ([]interfaces.IEBEntry, error) {
+	if !activations.IsActive(activations.GRANT_PROPOSALS, int(height+constants.COINBASE_PAYOUT_FREQUENCY)) {
+		return nil, nil
+	}
+	dblock, err := s.DB.FetchDBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	if dblock == nil {
+		return nil, fmt.Errorf("Missing directory block %d", height)
+	}
+	var keyMR interfaces.IHash
+	for _, e := range dblock.GetDBEntries() {
+		if e.GetChainID().IsSameAs(GrantChainID) {
+			keyMR = e.GetKeyMR()
+		}
+	}
+	if keyMR == nil {
+		return nil, nil
+	}
+	eblock, err := s.DB.FetchEBlock(keyMR)
+	if err != nil || eblock == nil {
+		return nil, fmt.Errorf("Missing grant eblock %x at %d", keyMR.Bytes()[:6], height)
+	}
+
+	var entries []interfaces.IEBEntry
+	for _, hash := range eblock.GetEntryHashes() {
+		if hash.IsMinuteMarker() {
+			continue
+		}
+		entry, err := s.DB.FetchEntry(hash)
+		if err != nil || entry == nil {
+			return nil, fmt.Errorf("Missing grant entry %x at %d", hash.Bytes()[:6], height)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GrantCutoffAuthorities returns the authority servers of the node, if the saved block is the cutoff
+// of a payout height of the grant proposals, and nil if it is not. Taken before the next block is
+// processed, they are the servers the admin blocks up to the cutoff make.
+func (s *State) GrantCutoffAuthorities(height uint32) *AuthoritySet {
+	payout := height + constants.COINBASE_PAYOUT_FREQUENCY
+	if payout <= constants.COINBASE_ACTIVATION || payout%constants.COINBASE_PAYOUT_FREQUENCY != 1 ||
+		!activations.IsActive(activations.GRANT_PROPOSALS, int(payout)) {
+		return nil
+	}
+
+	set := new(AuthoritySet)
+	set.Height = height + 1
+	set.ServerCount = s.IdentityControl.AuthorityServerCount
+	set.Federated = []*identity.Authority{}
+	set.Audit = []*identity.Authority{}
+	for _, a := range s.IdentityControl.Authorities {
+		// Copies, as the next block can change the keys
+		switch a.Status {
+		case constants.IDENTITY_FEDERATED_SERVER:
+			set.Federated = append(set.Federated, a.Clone())
+		case constants.IDENTITY_AUDIT_SERVER:
+			set.Audit = append(set.Audit, a.Clone())
+		}
+	}
+	sortAuthorities(set.Federated)
+	sortAuthorities(set.Audit)
+	return set
+}
+
+// ProcessGrantProposalEntries adds the proposals of the entries of a saved block (see
+// GrantProposalEntries), and drops the ones of the payout heights already built. If the block is the
+// cutoff of a payout height, authorities is its authority set (see GrantCutoffAuthorities): the
+// proposals of that height it signs are kept to pay, the others dropped.
+func (s *State) ProcessGrantProposalEntries(entries []interfaces.IEBEntry, height uint32, authorities *AuthoritySet) {
+	freq := constants.COINBASE_PAYOUT_FREQUENCY
+	var proposals []*GrantProposal
+	for _, p := range s.GrantProposals {
+		if p.DBHeight > height {
+			proposals = append(proposals, p)
+		}
+	}
+	for _, entry := range entries {
+		p, err := ParseGrantProposal(entry)
+		if err != nil {
+			s.LogPrintf("grants", "Grant entry %x: %v", entry.GetHash().Bytes()[:6], err)
+			continue
+		}
+		if p.DBHeight%freq != 1 || p.DBHeight < height+freq {
+			s.LogPrintf("grants", "Grant proposal %x for %d at %d is not for a payout height a period ahead", entry.GetHash().Bytes()[:6], p.DBHeight, height)
+			continue
+		}
+		proposals = append(proposals, p)
+	}
+
+	if authorities != nil {
+		payout := height + freq
+		checked := proposals[:0:0]
+		for _, p := range proposals {
+			if p.DBHeight == payout {
+				if err := AuthorizeGrantProposal(p, authorities); err != nil {
+					s.LogPrintf("grants", "Grant proposal %x for %d not authorized: %v", p.Entry.GetHash().Bytes()[:6], p.DBHeight, err)
+					continue
+				}
+				// A copy, as the saved states share the proposals
+				authorized := *p
+				authorized.Authorized = true
+				p = &authorized
+			}
+			checked = append(checked, p)
+		}
+		proposals = checked
+	}
+	s.GrantProposals = proposals
+}
+
+// GetGrantProposalPayoutsFor returns the payouts of the proposals for a height the authority servers
+// of its cutoff block signed. The same proposal posted twice pays once.
+func (s *State) GetGrantProposalPayoutsFor(currentDBHeight uint32) []interfaces.ITransAddress {
+	outputs := make([]interfaces.ITransAddress, 0)
+	paid := make(map[[32]byte]bool)
+	for _, p := range s.GrantProposals {
+		if p.DBHeight != currentDBHeight || !p.Authorized {
+			continue
+		}
+		id := primitives.Sha(p.SigData).Fixed()
+		if paid[id] {
+			continue
+		}
+		paid[id] = true
+		outputs = append(outputs, p.Outputs...)
+	}
+	return outputs
+}
+
+// GrantPayoutsFor returns the hard coded grants of a height, and from the activation of the grant
+// proposals, the grants proposed on chain
+func (s *State) GrantPayoutsFor(currentDBHeight uint32) []interfaces.ITransAddress {
+	outputs := GetGrantPayoutsFor(currentDBHeight)
+	if activations.IsActive(activations.GRANT_PROPOSALS, int(currentDBHeight)) {
+		outputs = append(outputs, s.GetGrantProposalPayoutsFor(currentDBHeight)...)
+	}
+	return outputs
+}
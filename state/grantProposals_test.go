@@ -0,0 +1,235 @@
+package state_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/entryBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/globals"
+	"github.com/FactomProject/factomd/common/identity"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+// The bootstrap identity and key of the local network
+var (
+	grantAuthority, _ = primitives.HexToHash("38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9")
+	grantKey          = newGrantKey("4c38c72fc5cdad68f13b74674d3ffb1f3d63a112710868c9b08946553448d26d")
+)
+
+func newGrantKey(priv string) *primitives.PrivateKey {
+	b, _ := hex.DecodeString(priv)
+	return primitives.NewPrivateKeyFromHexBytes(b)
+}
+
+// grantAuthoritySigner returns an authority server of the identity, signing with the key
+func grantAuthoritySigner(chainID interfaces.IHash, key *primitives.PrivateKey, status uint8) *identity.Authority {
+	auth := identity.NewAuthority()
+	auth.AuthorityChainID = chainID
+	auth.SigningKey = *key.Pub
+	auth.Status = status
+	return auth
+}
+
+func grantProposal(dbheight uint32, amount uint64) *entryBlock.Entry {
+	outputs := []interfaces.ITransAddress{factoid.NewOutAddress(testHelper.NewFactoidAddress(1), amount)}
+	return NewGrantProposalEntry(dbheight, outputs)
+}
+
+func TestParseGrantProposal(t *testing.T) {
+	e := grantProposal(26, 1000)
+	SignGrantProposal(e, grantAuthority, grantKey)
+
+	p, err := ParseGrantProposal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DBHeight != 26 || len(p.Outputs) != 1 || p.Outputs[0].GetAmount() != 1000 {
+		t.Errorf("Wrong proposal %+v", p)
+	}
+	if !p.Outputs[0].GetAddress().IsSameAs(testHelper.NewFactoidAddress(1)) {
+		t.Errorf("Wrong payout address %x", p.Outputs[0].GetAddress().Bytes())
+	}
+	if len(p.Signatures) != 1 || p.Signatures[0].Signer != grantAuthority.Fixed() {
+		t.Errorf("Wrong signatures %+v", p.Signatures)
+	}
+
+	bad := []*entryBlock.Entry{grantProposal(26, 0), entryBlock.NewEntry()}
+	e = grantProposal(26, 1000)
+	e.ExtIDs = append(e.ExtIDs, primitives.ByteSlice{Bytes: grantAuthority.Bytes()}) // A signer without signature
+	bad = append(bad, e)
+	for i, e := range bad {
+		if _, err := ParseGrantProposal(e); err == nil {
+			t.Errorf("Parsed the bad proposal %d", i)
+		}
+	}
+}
+
+func TestAuthorizeGrantProposal(t *testing.T) {
+	authorities := &AuthoritySet{Federated: []*identity.Authority{grantAuthoritySigner(grantAuthority, grantKey, constants.IDENTITY_FEDERATED_SERVER)}}
+	name := globals.Params.NetworkName
+	defer func() { globals.Params.NetworkName = name }()
+	globals.Params.NetworkName = "MAIN" // No grant keys
+
+	stranger := primitives.RandomPrivateKey()
+	tests := []struct {
+		signer interfaces.IHash
+		key    *primitives.PrivateKey
+		valid  bool
+	}{
+		{grantAuthority, grantKey, true},
+		{grantAuthority, stranger, false}, // Not the key of the authority
+		{primitives.NewHash(stranger.Pub[:]), stranger, false},
+		{primitives.NewHash(grantKey.Pub[:]), grantKey, false}, // Not a grant key on this network
+	}
+	for i, test := range tests {
+		e := grantProposal(26, 1000)
+		SignGrantProposal(e, test.signer, test.key)
+		p, _ := ParseGrantProposal(e)
+		if err := AuthorizeGrantProposal(p, authorities); (err == nil) != test.valid {
+			t.Errorf("Test %d: expected valid %v, got %v", i, test.valid, err)
+		}
+	}
+
+	// Half of the authority servers is not enough
+	audit := primitives.RandomPrivateKey()
+	authorities.Audit = append(authorities.Audit, grantAuthoritySigner(primitives.NewHash(audit.Pub[:]), audit, constants.IDENTITY_AUDIT_SERVER))
+	e := grantProposal(26, 1000)
+	SignGrantProposal(e, grantAuthority, grantKey)
+	p, _ := ParseGrantProposal(e)
+	if err := AuthorizeGrantProposal(p, authorities); err == nil {
+		t.Errorf("Authorized by half of the authority servers")
+	}
+
+	// The local network has the bootstrap key as its grant key
+	globals.Params.NetworkName = "LOCAL"
+	e = grantProposal(26, 1000)
+	SignGrantProposal(e, primitives.NewHash(grantKey.Pub[:]), grantKey)
+	p, _ = ParseGrantProposal(e)
+	if err := AuthorizeGrantProposal(p, authorities); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetGrantProposalPayoutsFor(t *testing.T) {
+	defer activations.SetTestActivationHeight(activations.GRANT_PROPOSALS, 0)()
+	s := testHelper.CreateEmptyTestState()
+	s.IdentityControl.Authorities = make(map[[32]byte]*identity.Authority)
+	s.IdentityControl.SetAuthority(grantAuthority, grantAuthoritySigner(grantAuthority, grantKey, constants.IDENTITY_FEDERATED_SERVER))
+	freq := constants.COINBASE_PAYOUT_FREQUENCY
+	height := 10*freq + 1
+
+	// saveEBlock puts the entries in the grant chain of the block at dbheight, and saves the first
+	// stored of them
+	saveEBlock := func(dbheight uint32, stored int, entries ...*entryBlock.Entry) {
+		s.DB.StartMultiBatch()
+		eb := entryBlock.NewEBlock()
+		eb.GetHeader().SetChainID(GrantChainID)
+		eb.GetHeader().SetDBHeight(dbheight)
+		for i, e := range entries {
+			eb.AddEBEntry(e)
+			if i >= stored {
+				continue
+			}
+			if err := s.DB.InsertEntryMultiBatch(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.DB.ProcessEBlockMultiBatch(eb, true); err != nil {
+			t.Fatal(err)
+		}
+		keyMR, _ := eb.KeyMR()
+		dblock := directoryBlock.NewDirectoryBlock(nil)
+		dblock.GetHeader().SetDBHeight(dbheight)
+		dblock.AddEntry(GrantChainID, keyMR)
+		if err := s.DB.ProcessDBlockMultiBatch(dblock); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DB.ExecuteMultiBatch(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// process saves the entries in the block at dbheight, and processes the block like the node does
+	process := func(dbheight uint32, entries ...*entryBlock.Entry) {
+		saveEBlock(dbheight, len(entries), entries...)
+		saved, err := s.GrantProposalEntries(dbheight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.ProcessGrantProposalEntries(saved, dbheight, s.GrantCutoffAuthorities(dbheight))
+	}
+	signed := func(dbheight uint32, amount uint64) *entryBlock.Entry {
+		e := grantProposal(dbheight, amount)
+		SignGrantProposal(e, grantAuthority, grantKey)
+		return e
+	}
+	unsigned := grantProposal(height, 3)
+	SignGrantProposal(unsigned, grantAuthority, primitives.RandomPrivateKey())
+
+	process(height-2*freq, signed(height, 1), signed(height+freq, 2), unsigned)
+	process(height-freq, signed(height, 1), signed(height, 4))
+	process(height-freq+1, signed(height, 5)) // Too late for the height
+
+	payouts := s.GetGrantProposalPayoutsFor(height)
+	if len(payouts) != 2 || payouts[0].GetAmount() != 1 || payouts[1].GetAmount() != 4 {
+		for _, p := range payouts {
+			t.Logf("Payout %d", p.GetAmount())
+		}
+		t.Errorf("Expected the payouts 1 and 4, got %d payouts", len(payouts))
+	}
+
+	// The saved state keeps the proposals
+	for _, p := range s.GrantProposals {
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2 := new(GrantProposal)
+		if err := p2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if p2.Authorized != p.Authorized || p2.DBHeight != p.DBHeight || !p2.Entry.GetHash().IsSameAs(p.Entry.GetHash()) {
+			t.Errorf("Wrong proposal back %+v", p2)
+		}
+	}
+
+	// Authority servers added after the cutoff of a height don't change its payouts, but count for the
+	// next height
+	s.ProcessGrantProposalEntries(nil, height, s.GrantCutoffAuthorities(height))
+	others := []*primitives.PrivateKey{primitives.RandomPrivateKey(), primitives.RandomPrivateKey()}
+	for _, key := range others {
+		id := primitives.NewHash(key.Pub[:])
+		s.IdentityControl.SetAuthority(id, grantAuthoritySigner(id, key, constants.IDENTITY_FEDERATED_SERVER))
+	}
+	if payouts := s.GetGrantProposalPayoutsFor(height + freq); len(payouts) != 1 || payouts[0].GetAmount() != 2 {
+		t.Errorf("Expected the payout 2 at the next payout height, got %d payouts", len(payouts))
+	}
+
+	// A missing entry holds up the block, until it is saved
+	byOne := signed(height+2*freq, 6)
+	byTwo := signed(height+2*freq, 7)
+	SignGrantProposal(byTwo, primitives.NewHash(others[0].Pub[:]), others[0])
+	saveEBlock(height+freq, 1, byOne, byTwo)
+	if _, err := s.GrantProposalEntries(height + freq); err == nil {
+		t.Errorf("Expected an error for a missing grant entry")
+	}
+	process(height+freq, byOne, byTwo)
+	if payouts := s.GetGrantProposalPayoutsFor(height + 2*freq); len(payouts) != 1 || payouts[0].GetAmount() != 7 {
+		t.Errorf("Expected the payout 7 signed by two of three servers, got %d payouts", len(payouts))
+	}
+
+	// Before the activation, only the hard coded grants
+	defer activations.SetTestActivationHeight(activations.GRANT_PROPOSALS, int(height+3*freq))()
+	if grants := s.GrantPayoutsFor(height + 2*freq); len(grants) != len(GetGrantPayoutsFor(height+2*freq)) {
+		t.Errorf("Paid the grant proposals before their activation")
+	}
+	if entries, err := s.GrantProposalEntries(height + freq); err != nil || entries != nil {
+		t.Errorf("Read the grant chain before the activation")
+	}
+}
@@ -201,6 +201,12 @@ func (st *State) VerifyIsAuthority(cid interfaces.IHash) bool {
 	return st.IdentityControl.GetAuthority(cid) != nil
 }
 
+// PreviewIdentityUpdate returns the *identity.IdentityUpdatePreview of an identity entry going in the
+// current block
+func (st *State) PreviewIdentityUpdate(entry interfaces.IEBEntry) (interface{}, error) {
+	return st.IdentityControl.PreviewIdentityUpdate(entry, st.GetLLeaderHeight())
+}
+
 // AddIdentityFromChainID will add an identity to our list to watch and sync it.
 func (st *State) AddIdentityFromChainID(cid interfaces.IHash) error {
 	id := st.IdentityControl.GetIdentity(cid)
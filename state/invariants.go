@@ -0,0 +1,248 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+)
+
+// Invariants are properties of the consensus the node checks while it runs (-invariants). Each one is
+// checked after every message a process list executes, and/or after every block saved to the database.
+// A violation is logged to the "invariants" log, and halts the node if running with -invariants=halt.
+
+const (
+	InvariantsOff  = "off"
+	InvariantsLog  = "log"
+	InvariantsHalt = "halt"
+)
+
+// MaxInvariantViolations is how many violations a node remembers
+var MaxInvariantViolations = 100
+
+// An Invariant is one property of the consensus. Step is checked after a process list executes the
+// message at the height of a VM, Block after a block is saved. Either can be nil.
+type Invariant struct {
+	Name  string
+	Step  func(s *State, p *ProcessList, vm int, height int, msg interfaces.IMsg) error
+	Block func(s *State, d *DBState) error
+}
+
+var invariants []Invariant
+var invariantsMutex sync.Mutex
+
+// RegisterInvariant adds an invariant checked by every node
+func RegisterInvariant(inv Invariant) {
+	invariantsMutex.Lock()
+	defer invariantsMutex.Unlock()
+	invariants = append(invariants, inv)
+}
+
+// Invariants returns the names of the registered invariants
+func Invariants() []string {
+	invariantsMutex.Lock()
+	defer invariantsMutex.Unlock()
+	var names []string
+	for _, inv := range invariants {
+		names = append(names, inv.Name)
+	}
+	return names
+}
+
+func registeredInvariants() []Invariant {
+	invariantsMutex.Lock()
+	defer invariantsMutex.Unlock()
+	return invariants
+}
+
+// CheckingInvariants returns true if the node checks its invariants
+func (s *State) CheckingInvariants() bool {
+	return s.InvariantMode == InvariantsLog || s.InvariantMode == InvariantsHalt
+}
+
+// CheckInvariant checks an invariant that isn't tied to a process list step or a block, like the ones
+// of the elections
+func (s *State) CheckInvariant(name string, check func() error) {
+	if !s.CheckingInvariants() {
+		return
+	}
+	if err := check(); err != nil {
+		s.InvariantViolated(name, err)
+	}
+}
+
+// InvariantViolated records a violation, and halts the node if running with -invariants=halt
+func (s *State) InvariantViolated(name string, err error) {
+	violation := fmt.Sprintf("%s: invariant %q violated at %d-:-%d: %v", s.FactomNodeName, name, s.LLeaderHeight, s.CurrentMinute, err)
+	s.LogPrintf("invariants", "%s", violation)
+	os.Stderr.WriteString(violation + "\n")
+
+	s.invariantMutex.Lock()
+	s.invariantViolations = append(s.invariantViolations, violation)
+	if len(s.invariantViolations) > MaxInvariantViolations {
+		s.invariantViolations = s.invariantViolations[1:]
+	}
+	s.invariantMutex.Unlock()
+
+	if s.InvariantMode == InvariantsHalt {
+		panic(violation)
+	}
+}
+
+// InvariantViolations returns the last violations of the invariants on this node
+func (s *State) InvariantViolations() []string {
+	s.invariantMutex.Lock()
+	defer s.invariantMutex.Unlock()
+	return append([]string{}, s.invariantViolations...)
+}
+
+// checkStepInvariants is called after a process list executed a message
+func (s *State) checkStepInvariants(p *ProcessList, vm int, height int, msg interfaces.IMsg) {
+	if !s.CheckingInvariants() {
+		return
+	}
+	for _, inv := range registeredInvariants() {
+		if inv.Step == nil {
+			continue
+		}
+		if err := inv.Step(s, p, vm, height, msg); err != nil {
+			s.InvariantViolated(inv.Name, err)
+		}
+	}
+}
+
+// checkBlockInvariants is called after a block is saved
+func (s *State) checkBlockInvariants(d *DBState) {
+	if !s.CheckingInvariants() {
+		return
+	}
+	for _, inv := range registeredInvariants() {
+		if inv.Block == nil {
+			continue
+		}
+		if err := inv.Block(s, d); err != nil {
+			s.InvariantViolated(inv.Name, err)
+		}
+	}
+}
+
+func init() {
+	RegisterInvariant(Invariant{Name: "acks contiguous", Step: acksContiguous})
+	RegisterInvariant(Invariant{Name: "balances not negative", Step: stepBalancesNotNegative, Block: blockBalancesNotNegative})
+	RegisterInvariant(Invariant{Name: "dbsig majority", Block: dbsigMajority})
+}
+
+// acksContiguous checks the acks of a VM are numbered one after the other, for this VM and process list
+func acksContiguous(s *State, p *ProcessList, vm int, height int, msg interfaces.IMsg) error {
+	acks := p.VMs[vm].ListAck
+	ack := acks[height]
+	if ack == nil {
+		return fmt.Errorf("No ack for the message at %d/%d/%d", p.DBHeight, vm, height)
+	}
+	if ack.DBHeight != p.DBHeight || ack.VMIndex != vm || ack.Height != uint32(height) {
+		return fmt.Errorf("Ack for %d/%d/%d found at %d/%d/%d", ack.DBHeight, ack.VMIndex, ack.Height, p.DBHeight, vm, height)
+	}
+	if height > 0 && acks[height-1] != nil && acks[height-1].Minute > ack.Minute {
+		return fmt.Errorf("Ack at %d/%d/%d is for minute %d, after an ack for minute %d", p.DBHeight, vm, height, ack.Minute, acks[height-1].Minute)
+	}
+	return nil
+}
+
+// ecCanGoNegative is true for the early blocks of the main net, where commits could overdraw
+func ecCanGoNegative(s *State, dbheight uint32) bool {
+	return s.GetNetworkID() == constants.MAIN_NETWORK_ID && dbheight <= 97886
+}
+
+// stepBalancesNotNegative checks the balances a message spends from
+func stepBalancesNotNegative(s *State, p *ProcessList, vm int, height int, msg interfaces.IMsg) error {
+	var ecPubKey [32]byte
+	switch m := msg.(type) {
+	case *messages.FactoidTransaction:
+		for _, in := range m.Transaction.GetInputs() {
+			if v := s.GetF(true, in.GetAddress().Fixed()); v < 0 {
+				return fmt.Errorf("Factoid address %x has a balance of %d", in.GetAddress().Bytes(), v)
+			}
+		}
+		return nil
+	case *messages.CommitChainMsg:
+		ecPubKey = m.CommitChain.ECPubKey.Fixed()
+	case *messages.CommitEntryMsg:
+		ecPubKey = m.CommitEntry.ECPubKey.Fixed()
+	default:
+		return nil
+	}
+	if v := s.GetE(true, ecPubKey); v < 0 && !ecCanGoNegative(s, p.DBHeight) {
+		return fmt.Errorf("Entry credit address %x has a balance of %d", ecPubKey, v)
+	}
+	// A delegate spends from its owners
+	for _, owner := range s.getECDelegationOwners(ecPubKey) {
+		if v := s.GetE(true, owner); v < 0 && !ecCanGoNegative(s, p.DBHeight) {
+			return fmt.Errorf("Entry credit address %x of delegate %x has a balance of %d", owner, ecPubKey, v)
+		}
+	}
+	return nil
+}
+
+// blockBalancesNotNegative checks all the balances once a block is saved
+func blockBalancesNotNegative(s *State, d *DBState) error {
+	s.FactoidBalancesPMutex.Lock()
+	for adr, v := range s.FactoidBalancesP {
+		if v < 0 {
+			s.FactoidBalancesPMutex.Unlock()
+			return fmt.Errorf("Factoid address %x has a balance of %d", adr, v)
+		}
+	}
+	s.FactoidBalancesPMutex.Unlock()
+
+	if ecCanGoNegative(s, d.DirectoryBlock.GetHeader().GetDBHeight()) {
+		return nil
+	}
+	s.ECBalancesPMutex.Lock()
+	defer s.ECBalancesPMutex.Unlock()
+	for adr, v := range s.ECBalancesP {
+		if v < 0 {
+			return fmt.Errorf("Entry credit address %x has a balance of %d", adr, v)
+		}
+	}
+	return nil
+}
+
+// dbsigMajority checks the block saved before this one is the block the majority of the DBSigs signed.
+// The DBSigs in the process list of a block sign the previous block, and they have all been processed
+// by the time the block is saved. Blocks synced from DBStates may have no DBSigs to check.
+func dbsigMajority(s *State, d *DBState) error {
+	dbheight := d.DirectoryBlock.GetHeader().GetDBHeight()
+	if dbheight == 0 {
+		return nil
+	}
+	pl := s.ProcessLists.Get(dbheight)
+	prev := s.GetDBState(dbheight - 1)
+	if pl == nil || prev == nil || prev.DirectoryBlock == nil {
+		return nil // Synced from DBStates, no process list to check against
+	}
+	signed, matches := 0, 0
+	for i := range pl.FedServers {
+		if i >= len(pl.VMs) || len(pl.VMs[i].List) == 0 {
+			continue
+		}
+		dbs, ok := pl.VMs[i].List[0].(*messages.DirectoryBlockSignature)
+		if !ok || dbs.DirectoryBlockHeader == nil {
+			continue
+		}
+		signed++
+		if dbs.DirectoryBlockHeader.GetBodyMR().Fixed() == prev.DirectoryBlock.GetHeader().GetBodyMR().Fixed() {
+			matches++
+		}
+	}
+	if signed > 0 && matches <= signed/2 {
+		return fmt.Errorf("Only %d of %d DBSigs at %d sign the saved block %d", matches, signed, dbheight, dbheight-1)
+	}
+	return nil
+}
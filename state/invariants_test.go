@@ -0,0 +1,130 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/directoryBlock"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+func TestInvariantModes(t *testing.T) {
+	s := new(State)
+	s.FactomNodeName = "FNode0"
+	fail := func() error { return fmt.Errorf("broken") }
+
+	s.CheckInvariant("test", fail)
+	if len(s.InvariantViolations()) != 0 {
+		t.Errorf("Invariants checked while off")
+	}
+
+	s.InvariantMode = InvariantsLog
+	s.CheckInvariant("test", fail)
+	s.CheckInvariant("test", func() error { return nil })
+	if v := s.InvariantViolations(); len(v) != 1 || !strings.Contains(v[0], `"test"`) || !strings.Contains(v[0], "broken") {
+		t.Errorf("Wrong violations %v", v)
+	}
+
+	s.InvariantMode = InvariantsHalt
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("A violation didn't halt")
+		}
+	}()
+	s.CheckInvariant("test", fail)
+}
+
+func TestInvariantsRegistered(t *testing.T) {
+	names := strings.Join(Invariants(), ",")
+	for _, name := range []string{"acks contiguous", "balances not negative", "dbsig majority"} {
+		if !strings.Contains(names, name) {
+			t.Errorf("Invariant %q not registered", name)
+		}
+	}
+}
+
+func TestAcksContiguous(t *testing.T) {
+	ack := func(dbheight uint32, vm int, height uint32, minute byte) *messages.Ack {
+		a := new(messages.Ack)
+		a.DBHeight, a.VMIndex, a.Height, a.Minute = dbheight, vm, height, minute
+		return a
+	}
+	p := &ProcessList{DBHeight: 5, VMs: []*VM{{}, {}}}
+
+	p.VMs[1].ListAck = []*messages.Ack{ack(5, 1, 0, 0), ack(5, 1, 1, 2)}
+	for h := range p.VMs[1].ListAck {
+		if err := acksContiguous(nil, p, 1, h, nil); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, bad := range []*messages.Ack{nil, ack(4, 1, 2, 3), ack(5, 0, 2, 3), ack(5, 1, 3, 3), ack(5, 1, 2, 1)} {
+		p.VMs[1].ListAck = []*messages.Ack{ack(5, 1, 0, 0), ack(5, 1, 1, 2), bad}
+		if acksContiguous(nil, p, 1, 2, nil) == nil {
+			t.Errorf("Accepted ack %+v", bad)
+		}
+	}
+}
+
+func TestBalancesNotNegative(t *testing.T) {
+	s := new(State)
+	s.FactoidBalancesP = map[[32]byte]int64{{1}: 10}
+	s.ECBalancesP = map[[32]byte]int64{{2}: 0}
+	d := &DBState{DirectoryBlock: directoryBlock.NewDirectoryBlock(nil)}
+	d.DirectoryBlock.GetHeader().SetDBHeight(200000)
+
+	if err := blockBalancesNotNegative(s, d); err != nil {
+		t.Error(err)
+	}
+	s.ECBalancesP[[32]byte{3}] = -1
+	if blockBalancesNotNegative(s, d) == nil {
+		t.Errorf("Accepted a negative entry credit balance")
+	}
+	delete(s.ECBalancesP, [32]byte{3})
+	s.FactoidBalancesP[[32]byte{4}] = -5
+	if blockBalancesNotNegative(s, d) == nil {
+		t.Errorf("Accepted a negative factoid balance")
+	}
+}
+
+func TestDBSigMajority(t *testing.T) {
+	s := new(State)
+	s.DBStates = new(DBStateList)
+	s.DBStates.State = s
+	s.ProcessLists = NewProcessLists(s)
+
+	prev := &DBState{DirectoryBlock: directoryBlock.NewDirectoryBlock(nil)}
+	prev.DirectoryBlock.GetHeader().SetBodyMR(primitives.Sha([]byte("saved")))
+	other := directoryBlock.NewDirectoryBlock(nil)
+	other.GetHeader().SetBodyMR(primitives.Sha([]byte("other")))
+	s.DBStates.DBStates = []*DBState{prev}
+
+	d := &DBState{DirectoryBlock: directoryBlock.NewDirectoryBlock(nil)}
+	d.DirectoryBlock.GetHeader().SetDBHeight(1)
+
+	dbsig := func(header interfaces.IDirectoryBlockHeader) interfaces.IMsg {
+		m := new(messages.DirectoryBlockSignature)
+		m.DirectoryBlockHeader = header
+		return m
+	}
+	pl := &ProcessList{DBHeight: 1, FedServers: make([]interfaces.IServer, 3), VMs: []*VM{{}, {}, {}}}
+	s.ProcessLists.Lists = []*ProcessList{pl}
+	s.ProcessLists.DBHeightBase = 1
+
+	good, bad := prev.DirectoryBlock.GetHeader(), other.GetHeader()
+	for i, header := range []interfaces.IDirectoryBlockHeader{good, good, bad} {
+		pl.VMs[i].List = []interfaces.IMsg{dbsig(header)}
+	}
+	if err := dbsigMajority(s, d); err != nil {
+		t.Error(err)
+	}
+	for i, header := range []interfaces.IDirectoryBlockHeader{good, bad, bad} {
+		pl.VMs[i].List = []interfaces.IMsg{dbsig(header)}
+	}
+	if dbsigMajority(s, d) == nil {
+		t.Errorf("Accepted a block only a minority signed")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
+)
+
+// The journal (-journaling) records every message the node gets, in the order the node gets them, so
+// an incident can be replayed into a fresh node (see engine.ReplayJournal). A journal is a header line
+// followed by one entry per line, all JSON. Journals written before the header existed ("MsgHex: ..."
+// lines) are read as version 0, without times or peers.
+
+// JournalVersion is the version of the journals this node writes
+const JournalVersion = 1
+
+// JournalHeader is the first line of a journal
+type JournalHeader struct {
+	Version int    `json:"journal"`
+	Node    string `json:"node"`
+	Network string `json:"network"`
+	Start   int64  `json:"start"` // Milliseconds
+}
+
+// JournalEntry is one message the node got
+type JournalEntry struct {
+	Time int64  `json:"time"` // Milliseconds, when the node got the message
+	Peer string `json:"peer"` // Where the message came from, "local" for the node and its API
+	Type byte   `json:"type"`
+	Msg  string `json:"msg"` // Hex of the marshaled message
+}
+
+// NewJournalEntry records the message as received now
+func NewJournalEntry(msg interfaces.IMsg) (e *JournalEntry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, err = nil, fmt.Errorf("Error marshalling %s for the journal: %v", constants.MessageName(msg.Type()), r)
+		}
+	}()
+
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e = new(JournalEntry)
+	e.Time = primitives.Now().UnixNano() / 1e6
+	e.Peer = msg.GetNetworkOrigin()
+	if e.Peer == "" || msg.IsLocal() {
+		e.Peer = "local"
+	}
+	e.Type = msg.Type()
+	e.Msg = hex.EncodeToString(data)
+	return e, nil
+}
+
+// Message unmarshals the message of the entry
+func (e *JournalEntry) Message() (interfaces.IMsg, error) {
+	if messages.General == nil {
+		return nil, fmt.Errorf("No message factory to unmarshal the journal with")
+	}
+	data, err := hex.DecodeString(e.Msg)
+	if err != nil {
+		return nil, err
+	}
+	return messages.General.UnmarshalMessage(data)
+}
+
+// ReadJournal reads a journal, of any version
+func ReadJournal(r io.Reader) (*JournalHeader, []JournalEntry, error) {
+	header := new(JournalHeader)
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		switch {
+		case len(text) == 0:
+		case bytes.HasPrefix(text, []byte("MsgHex:")):
+			entries = append(entries, JournalEntry{Msg: string(bytes.TrimSpace(text[len("MsgHex:"):]))})
+		case line == 1 && bytes.Contains(text, []byte(`"journal"`)):
+			if err := json.Unmarshal(text, header); err != nil {
+				return nil, nil, fmt.Errorf("Line %d: %v", line, err)
+			}
+			if header.Version > JournalVersion {
+				return nil, nil, fmt.Errorf("Journal version %d is newer than %d", header.Version, JournalVersion)
+			}
+		default:
+			var e JournalEntry
+			if err := json.Unmarshal(text, &e); err != nil {
+				return nil, nil, fmt.Errorf("Line %d: %v", line, err)
+			}
+			if e.Msg == "" {
+				continue // Journal lines of old versions that don't carry the message bytes
+			}
+			entries = append(entries, e)
+		}
+	}
+	return header, entries, scanner.Err()
+}
+
+// WriteJournal writes a journal in the current version
+func WriteJournal(w io.Writer, header *JournalHeader, entries []JournalEntry) error {
+	h := *header
+	h.Version = JournalVersion
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startJournal creates the journal file with its header
+func (s *State) startJournal() {
+	f, err := os.Create(s.JournalFile)
+	if err != nil {
+		fmt.Println("Could not create the journal file:", s.JournalFile)
+		s.JournalFile = ""
+		return
+	}
+	defer f.Close()
+	header := &JournalHeader{Version: JournalVersion, Node: s.FactomNodeName, Network: s.Network, Start: primitives.Now().UnixNano() / 1e6}
+	json.NewEncoder(f).Encode(header)
+}
+
+// JournalMessage writes the message to the message journal for debugging
+func (s *State) JournalMessage(msg interfaces.IMsg) {
+	if s.Journaling && len(s.JournalFile) != 0 {
+		f, err := os.OpenFile(s.JournalFile, os.O_APPEND+os.O_WRONLY, 0666)
+		if err != nil {
+			s.JournalFile = ""
+			return
+		}
+		defer f.Close()
+
+		e, err := NewJournalEntry(msg)
+		if err != nil {
+			return
+		}
+		json.NewEncoder(f).Encode(e)
+	}
+}
+
+// GetJournalMessages gets all the lines of the message journal, header included
+func (s *State) GetJournalMessages() [][]byte {
+	ret := make([][]byte, 0)
+	if !s.Journaling || len(s.JournalFile) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(s.JournalFile)
+	if err != nil {
+		s.JournalFile = ""
+		return nil
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		p, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		ret = append(ret, p)
+	}
+
+	return ret
+}
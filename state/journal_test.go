@@ -5,11 +5,15 @@
 package state_test
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/messages/msgsupport"
 	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
 	. "github.com/FactomProject/factomd/testHelper"
 )
 
@@ -37,3 +41,53 @@ func TestJournal(t *testing.T) {
 		t.Error("No messages returned from journal")
 	}
 }
+
+func TestReadJournal(t *testing.T) {
+	messages.General = new(msgsupport.GeneralFactory)
+
+	eom := new(messages.EOM)
+	eom.Timestamp = primitives.NewTimestampNow()
+	eom.ChainID = primitives.NewZeroHash()
+	eom.Minute = 3
+	entry, err := NewJournalEntry(eom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Peer != "local" || entry.Type != eom.Type() || entry.Time == 0 {
+		t.Errorf("Wrong entry %+v", entry)
+	}
+
+	var buf bytes.Buffer
+	header := &JournalHeader{Node: "FNode0", Network: "LOCAL", Start: 1000}
+	if err := WriteJournal(&buf, header, []JournalEntry{*entry, {Time: 2000, Peer: "peer-1", Msg: entry.Msg}}); err != nil {
+		t.Fatal(err)
+	}
+	h, entries, err := ReadJournal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != JournalVersion || h.Node != "FNode0" || h.Start != 1000 || len(entries) != 2 || entries[1].Peer != "peer-1" {
+		t.Errorf("Read back %+v %+v", h, entries)
+	}
+	msg, err := entries[0].Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := msg.(*messages.EOM); !ok || m.Minute != 3 {
+		t.Errorf("Read back the message %v", msg)
+	}
+
+	// Journals of the old loader
+	h, entries, err = ReadJournal(strings.NewReader("MsgHex: " + entry.Msg + "\nnoise\nMsgHex: " + entry.Msg + "\n"))
+	if err == nil {
+		t.Errorf("Accepted a line that isn't a journal entry")
+	}
+	h, entries, err = ReadJournal(strings.NewReader("MsgHex: " + entry.Msg + "\n\nMsgHex: " + entry.Msg + "\n"))
+	if err != nil || h.Version != 0 || len(entries) != 2 || entries[0].Msg != entry.Msg {
+		t.Errorf("Read back the old journal %+v %v", entries, err)
+	}
+
+	if _, _, err := ReadJournal(strings.NewReader(`{"journal": 99}`)); err == nil {
+		t.Errorf("Accepted a journal newer than this node")
+	}
+}
@@ -32,7 +32,7 @@ func LoadDatabase(s *State) {
 	// prevent MMR processing from happening for blocks being loaded from the database
 	s.DBHeightAtBoot = blkCnt
 
-	last := time.Now()
+	last := primitives.Now()
 
 	//msg, err := s.LoadDBState(blkCnt)
 	start := s.GetDBHeightComplete()
@@ -44,7 +44,7 @@ func LoadDatabase(s *State) {
 		if i > 0 && i%1000 == 0 {
 			bps := float64(1000) / time.Since(last).Seconds()
 			os.Stderr.WriteString(fmt.Sprintf("%20s Loading Block %7d / %v. Blocks per second %8.2f\n", s.FactomNodeName, i, blkCnt, bps))
-			last = time.Now()
+			last = primitives.Now()
 		}
 
 		msg, err := s.LoadDBState(uint32(i))
@@ -65,7 +65,7 @@ func LoadDatabase(s *State) {
 				msg.SetLocal(true)
 				if s.InMsgQueue().Length() > constants.INMSGQUEUE_MED {
 					for s.InMsgQueue().Length() > constants.INMSGQUEUE_LOW {
-						time.Sleep(10 * time.Millisecond)
+						primitives.Sleep(10 * time.Millisecond)
 					}
 				}
 			} else {
@@ -2,6 +2,7 @@ package state
 
 import (
 	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
 )
 
 // NetOutMsgQueue counts incoming and outgoing messages for netout queue
@@ -42,6 +43,9 @@ func (q NetOutMsgQueue) Dequeue() interfaces.IMsg {
 
 // BlockingDequeue will block until it retrieves from queue
 func (q NetOutMsgQueue) BlockingDequeue() interfaces.IMsg {
+	if primitives.IsClockVirtual() {
+		return pollDequeue(q)
+	}
 	v := <-q
 	//NetOutMsgQueueRateKeeper.Complete()
 	return v
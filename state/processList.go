@@ -37,12 +37,13 @@ type ProcessList struct {
 	FactoidBalancesT      map[[32]byte]int64
 	FactoidBalancesTMutex sync.Mutex
 	ECBalancesT           map[[32]byte]int64
+	ECDelegatedT          map[ECDelegationKey]uint64 // Entry credits spent by delegates, under ECBalancesTMutex
 	ECBalancesTMutex      sync.Mutex
 
 	State        *State
-	VMs          []*VM       // Process list for each server (up to 32)
-	ServerMap    [10][64]int // Map of FedServers to all Servers for each minute
-	System       VM          // System Faults and other system wide messages
+	VMs          []*VM                       // Process list for each server (up to 32)
+	ServerMap    [MaxMinutesPerBlock][64]int // Map of FedServers to all Servers for each minute
+	System       VM                          // System Faults and other system wide messages
 	SysHighest   int
 	diffSigTally int /* Tally of how many VMs have provided different
 		                    					             Directory Block Signatures than what we have
@@ -156,7 +157,7 @@ func (p *ProcessList) Complete() bool {
 	}
 	for i := 0; i < len(p.FedServers); i++ {
 		vm := p.VMs[i]
-		if vm.LeaderMinute < 10 {
+		if vm.LeaderMinute < p.State.GetMinutesPerBlock(p.DBHeight) {
 			return false
 		}
 		if vm.Height < len(vm.List) {
@@ -253,7 +254,7 @@ func (p *ProcessList) FedServerFor(minute int, hash []byte) interfaces.IServer {
 	return p.FedServers[fedIndex]
 }
 
-func FedServerVM(serverMap [10][64]int, numberOfFedServers int, minute int, fedIndex int) int {
+func FedServerVM(serverMap [MaxMinutesPerBlock][64]int, numberOfFedServers int, minute int, fedIndex int) int {
 	for i := 0; i < numberOfFedServers; i++ {
 		if serverMap[minute][i] == fedIndex {
 			return i
@@ -319,10 +320,11 @@ func (p *ProcessList) GetAuditServerIndexHash(identityChainID interfaces.IHash)
 // This function will be replaced by a calculation from the Matryoshka hashes from the servers
 // but for now, we are just going to make it a function of the dbheight.
 // serverMap[minute][vmIndex] => Index of the Federated Server responsible for that minute
-func MakeMap(numberFedServers int, dbheight uint32) (serverMap [10][64]int) {
+// The map always has MaxMinutesPerBlock minutes, blocks with fewer minutes use the first ones.
+func MakeMap(numberFedServers int, dbheight uint32) (serverMap [MaxMinutesPerBlock][64]int) {
 	if numberFedServers > 0 {
 		indx := int(dbheight*131) % numberFedServers
-		for i := 0; i < 10; i++ {
+		for i := 0; i < MaxMinutesPerBlock; i++ {
 			indx = (indx + 1) % numberFedServers
 			for j := 0; j < numberFedServers; j++ {
 				serverMap[i][j] = indx
@@ -348,7 +350,7 @@ func (p *ProcessList) PrintMap() string {
 		prt = fmt.Sprintf("%s%3d", prt, i)
 	}
 	prt = prt + "\ndddd "
-	for i := 0; i < 10; i++ {
+	for i := 0; i < p.State.GetMinutesPerBlock(p.DBHeight); i++ {
 		prt = fmt.Sprintf("%s%3d  ", prt, i)
 		for j := 0; j < len(p.FedServers); j++ {
 			prt = fmt.Sprintf("%s%2d ", prt, p.ServerMap[i][j])
@@ -624,15 +626,8 @@ var decodeMap map[foo]string = map[foo]string{
 	//foo{true, false, true, true, true, false, true, false, true}:      "Syncing EOM ... ",                //0x15d
 }
 
-func (p *ProcessList) decodeState(Syncing bool, DBSig bool, EOM bool, DBSigDone bool, EOMDone bool, FedServers int, EOMProcessed int, DBSigProcessed int) string {
-
-	if EOMProcessed > FedServers || EOMProcessed < 0 {
-		p.State.LogPrintf("process", "Unexpected EOMProcessed %v of %v", EOMProcessed, FedServers)
-	}
-	if DBSigProcessed > FedServers || DBSigProcessed < 0 {
-		p.State.LogPrintf("process", "Unexpected DBSigProcessed %v of %v", DBSigProcessed, FedServers)
-	}
-
+// syncState returns the syncing flags, their code and the name of the state they are in
+func syncState(Syncing bool, DBSig bool, EOM bool, DBSigDone bool, EOMDone bool, FedServers int, EOMProcessed int, DBSigProcessed int) (foo, int, string) {
 	var x foo = foo{Syncing, DBSig, EOM, DBSigDone, EOMDone,
 		EOMProcessed == FedServers, EOMProcessed == 0, DBSigProcessed == FedServers, DBSigProcessed == 0}
 
@@ -646,9 +641,23 @@ func (p *ProcessList) decodeState(Syncing bool, DBSig bool, EOM bool, DBSigDone
 
 	s, ok := decodeMap[x]
 	if !ok {
+		s = "Unknown"
+	}
+	return x, xx, s
+}
 
+func (p *ProcessList) decodeState(Syncing bool, DBSig bool, EOM bool, DBSigDone bool, EOMDone bool, FedServers int, EOMProcessed int, DBSigProcessed int) string {
+
+	if EOMProcessed > FedServers || EOMProcessed < 0 {
+		p.State.LogPrintf("process", "Unexpected EOMProcessed %v of %v", EOMProcessed, FedServers)
+	}
+	if DBSigProcessed > FedServers || DBSigProcessed < 0 {
+		p.State.LogPrintf("process", "Unexpected DBSigProcessed %v of %v", DBSigProcessed, FedServers)
+	}
+
+	x, xx, s := syncState(Syncing, DBSig, EOM, DBSigDone, EOMDone, FedServers, EOMProcessed, DBSigProcessed)
+	if s == "Unknown" {
 		p.State.LogPrintf("process", "Unexpected 0x%03x %v", xx, x)
-		s = "Unknown"
 	}
 	// divide processCnt by a big number to make it not change the status string very often
 	return fmt.Sprintf("SyncingStatus: %d-:-%d 0x%03x %25s EOM/DBSIG %02d/%02d of %02d -- %d",
@@ -809,6 +818,11 @@ func (p *ProcessList) Process(s *State) (progress bool) {
 
 					vm.heartBeat = 0
 					vm.Height = j + 1 // Don't process it again if the process worked.
+					s.checkStepInvariants(p, i, j, msg)
+					if s.Tracing() {
+						s.Trace(TraceEvent{Event: TraceVMHeight, DBHeight: p.DBHeight, Minute: int(thisAck.Minute), VM: i, Height: vm.Height,
+							Msg: constants.MessageName(msg.Type()), Hash: msg.GetMsgHash().String()})
+					}
 					s.LogMessage("process", fmt.Sprintf("done %v/%v/%v", p.DBHeight, i, j), msg)
 					s.LogPrintf("process", "thisAck  %x", thisAck.SerialHash.Bytes())
 
@@ -1111,6 +1125,7 @@ func NewProcessList(state interfaces.IState, previous *ProcessList, dbheight uin
 
 	pl.FactoidBalancesT = map[[32]byte]int64{}
 	pl.ECBalancesT = map[[32]byte]int64{}
+	pl.ECDelegatedT = map[ECDelegationKey]uint64{}
 
 	if previous != nil {
 		pl.FedServers = append(pl.FedServers, previous.FedServers...)
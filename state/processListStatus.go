@@ -0,0 +1,157 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state
+
+import (
+	"github.com/FactomProject/factomd/common/constants"
+)
+
+// ProcessListStatus is a process list as structured data, for the debug API. ProcessList.String has the
+// same for people to read.
+type ProcessListStatus struct {
+	DBHeight     uint32         `json:"dbheight"`
+	Complete     bool           `json:"complete"`
+	PrevDBState  string         `json:"prevdbstate"` // nil, constructing, saved or signed
+	Minutes      int            `json:"minutes"`     // Minutes of the block
+	Sync         *SyncStatus    `json:"sync,omitempty"`
+	VMs          []VMStatus     `json:"vms"`
+	FedServers   []ServerStatus `json:"fedservers"`
+	AuditServers []ServerStatus `json:"auditservers"`
+	MissingAsks  []MissingAsk   `json:"missingasks"`
+}
+
+// SyncStatus is where the node is in syncing the EOMs and DBSigs of the block it is building, the flags
+// ProcessList.decodeState reports
+type SyncStatus struct {
+	Minute         int    `json:"minute"`
+	Syncing        bool   `json:"syncing"`
+	DBSig          bool   `json:"dbsig"`
+	EOM            bool   `json:"eom"`
+	DBSigDone      bool   `json:"dbsigdone"`
+	EOMDone        bool   `json:"eomdone"`
+	EOMProcessed   int    `json:"eomprocessed"`
+	DBSigProcessed int    `json:"dbsigprocessed"`
+	FedServers     int    `json:"fedservers"`
+	Code           int    `json:"code"`
+	State          string `json:"state"`
+}
+
+type VMStatus struct {
+	VM           int             `json:"vm"`
+	Height       int             `json:"height"` // Messages processed
+	Length       int             `json:"length"` // Messages acked
+	LeaderMinute int             `json:"leaderminute"`
+	Synced       bool            `json:"synced"`
+	Leaders      []string        `json:"leaders"` // Identity of the leader of the VM in each minute
+	Messages     []MessageStatus `json:"messages"`
+}
+
+// MessageStatus is a slot of a VM, Missing if the node has no message for it
+type MessageStatus struct {
+	Height    int    `json:"height"`
+	Processed bool   `json:"processed"`
+	Missing   bool   `json:"missing,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Leader    string `json:"leader,omitempty"` // Identity that acked it
+}
+
+type ServerStatus struct {
+	ChainID string `json:"chainid"`
+	Online  bool   `json:"online"`
+}
+
+// Status returns the process list as structured data
+func (p *ProcessList) Status() *ProcessListStatus {
+	status := new(ProcessListStatus)
+	status.DBHeight = p.DBHeight
+	status.Complete = p.Complete()
+	status.Minutes = p.State.GetMinutesPerBlock(p.DBHeight)
+
+	pdbs := p.State.DBStates.Get(int(p.DBHeight - 1))
+	switch {
+	case pdbs == nil:
+		status.PrevDBState = "nil"
+	case pdbs.Signed:
+		status.PrevDBState = "signed"
+	case pdbs.Saved:
+		status.PrevDBState = "saved"
+	default:
+		status.PrevDBState = "constructing"
+	}
+
+	s := p.State
+	if p == s.LeaderPL {
+		_, code, name := syncState(s.Syncing, s.DBSig, s.EOM, s.DBSigDone, s.EOMDone, len(p.FedServers), s.EOMProcessed, s.DBSigProcessed)
+		status.Sync = &SyncStatus{
+			Minute:         s.CurrentMinute,
+			Syncing:        s.Syncing,
+			DBSig:          s.DBSig,
+			EOM:            s.EOM,
+			DBSigDone:      s.DBSigDone,
+			EOMDone:        s.EOMDone,
+			EOMProcessed:   s.EOMProcessed,
+			DBSigProcessed: s.DBSigProcessed,
+			FedServers:     len(p.FedServers),
+			Code:           code,
+			State:          name,
+		}
+	}
+
+	status.VMs = []VMStatus{}
+	for i := 0; i < len(p.FedServers) && i < len(p.VMs); i++ {
+		vm := p.VMs[i]
+		v := VMStatus{VM: i, Height: vm.Height, Length: len(vm.List), LeaderMinute: vm.LeaderMinute, Synced: vm.Synced}
+		for minute := 0; minute < status.Minutes; minute++ {
+			leader := ""
+			if fed := p.ServerMap[minute][i]; fed < len(p.FedServers) {
+				leader = p.FedServers[fed].GetChainID().String()
+			}
+			v.Leaders = append(v.Leaders, leader)
+		}
+		v.Messages = []MessageStatus{}
+		for j, msg := range vm.List {
+			m := MessageStatus{Height: j, Processed: j < vm.Height}
+			if msg == nil {
+				m.Missing = true
+			} else {
+				m.Type = constants.MessageName(msg.Type())
+				m.Hash = msg.GetMsgHash().String()
+				if j < len(vm.ListAck) && vm.ListAck[j] != nil {
+					m.Leader = vm.ListAck[j].LeaderChainID.String()
+				}
+			}
+			v.Messages = append(v.Messages, m)
+		}
+		status.VMs = append(status.VMs, v)
+	}
+
+	status.FedServers = []ServerStatus{}
+	for _, fed := range p.FedServers {
+		status.FedServers = append(status.FedServers, ServerStatus{fed.GetChainID().String(), fed.IsOnline()})
+	}
+	status.AuditServers = []ServerStatus{}
+	for _, aud := range p.AuditServers {
+		status.AuditServers = append(status.AuditServers, ServerStatus{aud.GetChainID().String(), aud.IsOnline()})
+	}
+	status.MissingAsks = s.GetMissingAsks(int(p.DBHeight))
+	return status
+}
+
+// GetProcessListStatus returns the process lists the node holds as structured data: the recent ones,
+// the one it is building and the next one. With a dbheight of 0 or more, only the one of that height.
+func (s *State) GetProcessListStatus(dbheight int) interface{} {
+	lists := []*ProcessListStatus{}
+	if s.ProcessLists == nil {
+		return lists
+	}
+	for _, pl := range s.ProcessLists.Lists {
+		if pl == nil || (dbheight >= 0 && int(pl.DBHeight) != dbheight) {
+			continue
+		}
+		lists = append(lists, pl.Status())
+	}
+	return lists
+}
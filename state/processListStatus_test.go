@@ -0,0 +1,82 @@
+// Copyright 2017 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package state_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
+	. "github.com/FactomProject/factomd/state"
+	"github.com/FactomProject/factomd/testHelper"
+)
+
+func TestProcessListStatus(t *testing.T) {
+	state := testHelper.CreateEmptyTestState()
+	pl := NewProcessList(state, nil, 1)
+	one := primitives.Sha([]byte("one"))
+	pl.AddFedServer(one)
+	pl.AddFedServer(primitives.Sha([]byte("two")))
+	pl.AddAuditServer(primitives.Sha([]byte("three")))
+
+	eom := new(messages.EOM)
+	eom.ChainID = one
+	eom.Timestamp = primitives.NewTimestampNow()
+	ack := new(messages.Ack)
+	ack.LeaderChainID = one
+	vm := pl.VMs[0]
+	vm.List = append(vm.List, eom, nil)
+	vm.ListAck = append(vm.ListAck, ack, nil)
+	vm.Height = 1
+
+	status := pl.Status()
+	// The bootstrap identity of the test state is a federated server too
+	if status.DBHeight != 1 || status.Minutes != 10 || len(status.FedServers) != 3 || len(status.AuditServers) != 1 {
+		t.Fatalf("Wrong status %+v", status)
+	}
+	if len(status.VMs) != 3 {
+		t.Fatalf("Expected 3 VMs, got %d", len(status.VMs))
+	}
+	v := status.VMs[0]
+	if v.Height != 1 || v.Length != 2 || len(v.Leaders) != 10 || len(v.Messages) != 2 {
+		t.Fatalf("Wrong VM %+v", v)
+	}
+	m := v.Messages[0]
+	if m.Type != "EOM" || m.Hash != eom.GetMsgHash().String() || m.Leader != one.String() || !m.Processed || m.Missing {
+		t.Errorf("Wrong message %+v", m)
+	}
+	if !v.Messages[1].Missing || v.Messages[1].Processed {
+		t.Errorf("Wrong missing message %+v", v.Messages[1])
+	}
+	for minute, leader := range v.Leaders {
+		if leader != status.FedServers[pl.ServerMap[minute][0]].ChainID {
+			t.Errorf("Wrong leader of minute %d", minute)
+		}
+	}
+
+	// Not the process list the node is building, no sync flags
+	if status.Sync != nil {
+		t.Error("Sync flags on a process list that isn't the leader's")
+	}
+	if _, err := json.Marshal(status); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetProcessListStatus(t *testing.T) {
+	s := testHelper.CreateAndPopulateTestState()
+	all := s.GetProcessListStatus(-1).([]*ProcessListStatus)
+	if len(all) == 0 {
+		t.Fatal("No process lists")
+	}
+	leader := s.GetProcessListStatus(int(s.LLeaderHeight)).([]*ProcessListStatus)
+	if len(leader) != 1 || leader[0].DBHeight != s.LLeaderHeight {
+		t.Fatalf("Expected the process list of the height %d, got %v", s.LLeaderHeight, leader)
+	}
+	if leader[0].Sync == nil || leader[0].Sync.State == "" {
+		t.Errorf("No sync flags on the process list the node is building")
+	}
+}
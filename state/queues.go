@@ -16,8 +16,11 @@ package state
 // 	BenchmarkCompetingQueues-4     	 1000000	      1302 ns/op
 
 import (
+	"time"
+
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -51,9 +54,26 @@ func (q GeneralMSGQueue) Dequeue() interfaces.IMsg {
 }
 
 func (q GeneralMSGQueue) BlockingDequeue() interfaces.IMsg {
+	if primitives.IsClockVirtual() {
+		return pollDequeue(q)
+	}
 	return <-q
 }
 
+// QueuePollInterval is how often BlockingDequeue checks an empty queue under a virtual clock
+const QueuePollInterval = time.Millisecond
+
+// pollDequeue waits for a message by polling the queue. Under a virtual clock (the deterministic
+// simulator) a task can't block on a channel, it has to sleep on the clock.
+func pollDequeue(q interfaces.IQueue) interfaces.IMsg {
+	for {
+		if msg := q.Dequeue(); msg != nil {
+			return msg
+		}
+		primitives.Sleep(QueuePollInterval)
+	}
+}
+
 // measureMessage will increment/decrement prometheus based on type
 func measureMessage(counter *prometheus.GaugeVec, msg interfaces.IMsg, increment bool) {
 	if msg == nil {
@@ -98,6 +118,10 @@ func measureMessage(counter *prometheus.GaugeVec, msg interfaces.IMsg, increment
 			counter.WithLabelValues("dbstatmissing").Add(amt)
 		case constants.DBSTATE_MSG: // 20
 			counter.WithLabelValues("dbstate").Add(amt)
+		case constants.DBSTATE_COMPACT_MSG: // 42
+			counter.WithLabelValues("dbstatecompact").Add(amt)
+		case constants.DBSTATE_COMPACT_MISSING_MSG: // 43
+			counter.WithLabelValues("dbstatecompactmissing").Add(amt)
 		default: // 23
 			counter.WithLabelValues("misc").Add(amt)
 		}
@@ -103,6 +103,9 @@ type SaveState struct {
 	FERChangePrice       uint64
 	FERPriority          uint32
 	FERPrioritySetHeight uint32
+	FERHistory           []FERChange
+	ECDelegations        []ECDelegation
+	GrantProposals       []*GrantProposal
 }
 
 var _ interfaces.BinaryMarshallable = (*SaveState)(nil)
@@ -293,6 +296,33 @@ func (a *SaveState) IsSameAs(b *SaveState) bool {
 	if a.FERPrioritySetHeight != b.FERPrioritySetHeight {
 		return false
 	}
+	if len(a.FERHistory) != len(b.FERHistory) {
+		return false
+	}
+	for i := range a.FERHistory {
+		ac, bc := a.FERHistory[i], b.FERHistory[i]
+		if !ac.EntryHash.IsSameAs(bc.EntryHash) || ac.EntryHeight != bc.EntryHeight || ac.Priority != bc.Priority ||
+			ac.TargetPrice != bc.TargetPrice || ac.TargetHeight != bc.TargetHeight || ac.ActivatedHeight != bc.ActivatedHeight {
+			return false
+		}
+	}
+	if len(a.ECDelegations) != len(b.ECDelegations) {
+		return false
+	}
+	for i := range a.ECDelegations {
+		if a.ECDelegations[i] != b.ECDelegations[i] {
+			return false
+		}
+	}
+	if len(a.GrantProposals) != len(b.GrantProposals) {
+		return false
+	}
+	for i := range a.GrantProposals {
+		ap, bp := a.GrantProposals[i], b.GrantProposals[i]
+		if ap.Authorized != bp.Authorized || !ap.Entry.GetHash().IsSameAs(bp.Entry.GetHash()) {
+			return false
+		}
+	}
 
 	return true
 }
@@ -392,6 +422,11 @@ func SaveFactomdState(state *State, d *DBState) (ss *SaveState) {
 	ss.FERChangePrice = state.FERChangePrice
 	ss.FERPriority = state.FERPriority
 	ss.FERPrioritySetHeight = state.FERPrioritySetHeight
+	state.ferHistoryMutex.Lock()
+	ss.FERHistory = append([]FERChange(nil), state.FERHistory...)
+	state.ferHistoryMutex.Unlock()
+	ss.ECDelegations = state.GetECDelegationList()
+	ss.GrantProposals = append([]*GrantProposal(nil), state.GrantProposals...)
 
 	/*
 		err := SaveTheState(ss)
@@ -664,6 +699,17 @@ func (ss *SaveState) RestoreFactomdState(s *State) { //, d *DBState) {
 	s.FERChangePrice = ss.FERChangePrice
 	s.FERPriority = ss.FERPriority
 	s.FERPrioritySetHeight = ss.FERPrioritySetHeight
+	s.ferHistoryMutex.Lock()
+	s.FERHistory = append([]FERChange(nil), ss.FERHistory...)
+	s.ferHistoryMutex.Unlock()
+	s.ecDelegationsMutex.Lock()
+	s.ECDelegations = make(map[[32]byte][]*ECDelegation)
+	for i := range ss.ECDelegations {
+		d := ss.ECDelegations[i]
+		s.ECDelegations[d.Delegate] = append(s.ECDelegations[d.Delegate], &d)
+	}
+	s.ecDelegationsMutex.Unlock()
+	s.GrantProposals = append([]*GrantProposal(nil), ss.GrantProposals...)
 }
 
 func (ss *SaveState) MarshalBinary() (rval []byte, err error) {
@@ -896,6 +942,36 @@ func (ss *SaveState) MarshalBinary() (rval []byte, err error) {
 	if err != nil {
 		return nil, err
 	}
+	err = buf.PushVarInt(uint64(len(ss.FERHistory)))
+	if err != nil {
+		return nil, err
+	}
+	for i := range ss.FERHistory {
+		err = buf.PushBinaryMarshallable(&ss.FERHistory[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = buf.PushVarInt(uint64(len(ss.ECDelegations)))
+	if err != nil {
+		return nil, err
+	}
+	for i := range ss.ECDelegations {
+		err = buf.PushBinaryMarshallable(&ss.ECDelegations[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = buf.PushVarInt(uint64(len(ss.GrantProposals)))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ss.GrantProposals {
+		err = buf.PushBinaryMarshallable(p)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return buf.DeepCopyBytes(), nil
 }
@@ -1166,6 +1242,40 @@ func (ss *SaveState) UnmarshalBinaryData(p []byte) (newData []byte, err error) {
 	if err != nil {
 		return
 	}
+	l, err = buf.PopVarInt()
+	if err != nil {
+		return
+	}
+	ss.FERHistory = make([]FERChange, int(l))
+	for i := range ss.FERHistory {
+		err = buf.PopBinaryMarshallable(&ss.FERHistory[i])
+		if err != nil {
+			return
+		}
+	}
+	l, err = buf.PopVarInt()
+	if err != nil {
+		return
+	}
+	ss.ECDelegations = make([]ECDelegation, int(l))
+	for i := range ss.ECDelegations {
+		err = buf.PopBinaryMarshallable(&ss.ECDelegations[i])
+		if err != nil {
+			return
+		}
+	}
+	l, err = buf.PopVarInt()
+	if err != nil {
+		return
+	}
+	ss.GrantProposals = make([]*GrantProposal, int(l))
+	for i := range ss.GrantProposals {
+		ss.GrantProposals[i] = new(GrantProposal)
+		err = buf.PopBinaryMarshallable(ss.GrantProposals[i])
+		if err != nil {
+			return
+		}
+	}
 
 	newData = buf.DeepCopyBytes()
 	return
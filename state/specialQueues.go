@@ -1,6 +1,9 @@
 package state
 
-import "github.com/FactomProject/factomd/common/interfaces"
+import (
+	"github.com/FactomProject/factomd/common/interfaces"
+	"github.com/FactomProject/factomd/common/primitives"
+)
 
 // InMsgMSGQueue counts incoming and outgoing messages for inmsg queue
 type InMsgMSGQueue chan interfaces.IMsg
@@ -41,6 +44,9 @@ func (q InMsgMSGQueue) Dequeue() interfaces.IMsg {
 
 // BlockingDequeue will block until it retrieves from queue
 func (q InMsgMSGQueue) BlockingDequeue() interfaces.IMsg {
+	if primitives.IsClockVirtual() {
+		return pollDequeue(q)
+	}
 	v := <-q
 	measureMessage(CurrentMessageQueueInMsgGeneralVec, v, false)
 	return v
@@ -85,6 +91,9 @@ func (q ElectionQueue) Dequeue() interfaces.IMsg {
 
 // BlockingDequeue will block until it retrieves from queue
 func (q ElectionQueue) BlockingDequeue() interfaces.IMsg {
+	if primitives.IsClockVirtual() {
+		return pollDequeue(q)
+	}
 	v := <-q
 	//measureMessage(CurrentMessageQueueInMsgGeneralVec, v, false)
 	return v
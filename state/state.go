@@ -5,7 +5,6 @@
 package state
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
@@ -51,6 +50,7 @@ type State struct {
 	Logger            *log.Entry
 	IsRunning         bool
 	NetworkController *p2p.Controller
+	SimNetwork        interfaces.ISimNetwork // Links to the other nodes of a simulation, nil outside of one
 	Salt              interfaces.IHash
 	Cfg               interfaces.IFactomConfig
 	ConfigFilePath    string // $HOME/.factom/m2/factomd.conf by default
@@ -80,6 +80,7 @@ type State struct {
 	DBStatesReceived        []*messages.DBStateMsg
 	LocalServerPrivKey      string
 	DirectoryBlockInSeconds int
+	BlockSchedule           []BlockPeriod // Block time and minutes per block by height, see blockSchedule.go
 	PortNumber              int
 	Replay                  *Replay
 	FReplay                 *Replay
@@ -112,12 +113,15 @@ type State struct {
 	CustomNetworkID         []byte
 	CustomBootstrapIdentity string
 	CustomBootstrapKey      string
+	CustomActivations       string // Activation heights of the CUSTOM network, see activations/schedule.go
+	CustomActivationsFile   string // Signed file of activation heights of the CUSTOM network
 
 	IdentityChainID interfaces.IHash // If this node has an identity, this is it
 	//Identities      []*Identity      // Identities of all servers in management chain
 	// Authorities          []*Authority     // Identities of all servers in management chain
 	AuthorityServerCount int // number of federated or audit servers allowed
 	IdentityControl      *IdentityManager
+	AuthorityHistory     AuthorityHistory // Authority changes of the saved admin blocks, see authorityHistory.go
 
 	// Just to print (so debugging doesn't drive functionality)
 	Status      int // Return a status (0 do nothing, 1 provide queues, 2 provide consensus data)
@@ -156,6 +160,9 @@ type State struct {
 	DBStateIgnoreCnt  int
 	DBStateAppliedCnt int
 
+	DBStateCompactCnt     int // Compact DBStates rebuilt from the messages we had
+	DBStateCompactMissCnt int // Compact DBStates we could not rebuild, so asked for the full DBState
+
 	MissingRequestAskCnt      int
 	MissingRequestReplyCnt    int
 	MissingRequestIgnoreCnt   int
@@ -243,6 +250,19 @@ type State struct {
 	// when a majority of leaders disagree with the hash we have via DBSigs
 	KeepMismatch bool
 
+	InvariantMode       string // off, log or halt on violations, see invariants.go
+	invariantMutex      sync.Mutex
+	invariantViolations []string
+
+	ConsensusTraceDir string // Write the consensus trace here, see consensusTrace.go
+	traceMutex        sync.Mutex
+	traceFile         *json.Encoder
+	traceSeq          uint64
+
+	failoverMutex sync.Mutex // Cadence of the authority set and fast failover status, see failover.go
+	failover      FailoverStatus
+	cadence       map[[32]byte]*ServerCadence
+
 	DBSigFails int // Keep track of how many blockhash mismatches we've had to correct
 
 	Saving  bool // True if we are in the process of saving to the database
@@ -363,6 +383,13 @@ type State struct {
 	FERChangePrice       uint64
 	FERPriority          uint32
 	FERPrioritySetHeight uint32
+	FERHistory           []FERChange // Accepted FER entries, oldest first, see ferHistory.go
+	ferHistoryMutex      sync.Mutex
+
+	ECDelegations      map[[32]byte][]*ECDelegation // By delegate key, in the order they pay, see ecDelegation.go
+	ecDelegationsMutex sync.Mutex
+
+	GrantProposals []*GrantProposal // Proposals for the payout heights not built yet, see grantProposals.go
 
 	AckChange uint32
 
@@ -455,6 +482,8 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.CloneDBType = s.CloneDBType
 	newState.DBType = s.CloneDBType
 	newState.CheckChainHeads = s.CheckChainHeads
+	newState.InvariantMode = s.InvariantMode
+	newState.ConsensusTraceDir = s.ConsensusTraceDir
 	newState.ExportData = s.ExportData
 	newState.ExportDataSubpath = s.ExportDataSubpath + "sim-" + number
 	newState.Network = s.Network
@@ -475,6 +504,7 @@ func (s *State) Clone(cloneNumber int) interfaces.IState {
 	newState.CustomNetworkID = s.CustomNetworkID
 
 	newState.DirectoryBlockInSeconds = s.DirectoryBlockInSeconds
+	newState.BlockSchedule = append([]BlockPeriod(nil), s.BlockSchedule...)
 	newState.PortNumber = s.PortNumber
 
 	newState.ControlPanelPort = s.ControlPanelPort
@@ -638,7 +668,7 @@ func (s *State) GetCurrentMinuteStartTime() int64 {
 }
 
 func (s *State) GetCurrentTime() int64 {
-	return time.Now().UnixNano()
+	return primitives.Now().UnixNano()
 }
 
 func (s *State) IncDBStateAnswerCnt() {
@@ -713,6 +743,8 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		s.TestSpecialPeers = cfg.App.TestSpecialPeers
 		s.CustomBootstrapIdentity = cfg.App.CustomBootstrapIdentity
 		s.CustomBootstrapKey = cfg.App.CustomBootstrapKey
+		s.CustomActivations = cfg.App.CustomActivations
+		s.CustomActivationsFile = cfg.App.CustomActivationsFile
 		s.LocalNetworkPort = cfg.App.LocalNetworkPort
 		s.LocalSeedURL = cfg.App.LocalSeedURL
 		s.LocalSpecialPeers = cfg.App.LocalSpecialPeers
@@ -722,6 +754,9 @@ func (s *State) LoadConfig(filename string, networkFlag string) {
 		s.CustomSpecialPeers = cfg.App.CustomSpecialPeers
 		s.FactoshisPerEC = cfg.App.ExchangeRate
 		s.DirectoryBlockInSeconds = cfg.App.DirectoryBlockInSeconds
+		if err := s.SetBlockSchedule(cfg.App.BlockSchedule); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("BlockSchedule %q ignored: %v\n", cfg.App.BlockSchedule, err))
+		}
 		s.PortNumber = cfg.App.PortNumber
 		s.ControlPanelPort = cfg.App.ControlPanelPort
 		s.RpcUser = cfg.App.FactomdRpcUser
@@ -870,12 +905,7 @@ func (s *State) Init() {
 	s.WriteEntry = make(chan interfaces.IEBEntry, 3000)            //Entries to be written to the database
 
 	if s.Journaling {
-		f, err := os.Create(s.JournalFile)
-		if err != nil {
-			fmt.Println("Could not create the journal file:", s.JournalFile)
-			s.JournalFile = ""
-		}
-		f.Close()
+		s.startJournal()
 	}
 	// Set up struct to stop replay attacks
 	s.Replay = new(Replay)
@@ -964,6 +994,7 @@ func (s *State) Init() {
 	case "MAIN":
 		s.NetworkNumber = constants.NETWORK_MAIN
 		s.DirectoryBlockInSeconds = 600
+		s.BlockSchedule = nil
 	case "TEST":
 		s.NetworkNumber = constants.NETWORK_TEST
 	case "LOCAL":
@@ -1351,13 +1382,13 @@ func (s *State) LoadHoldingMap() map[[32]byte]interfaces.IMsg {
 func (s *State) fillHoldingMap() {
 	// once a second is often enough to rebuild the Ack list exposed to api
 
-	if s.HoldingLast < time.Now().Unix() {
+	if s.HoldingLast < primitives.Now().Unix() {
 
 		localMap := make(map[[32]byte]interfaces.IMsg)
 		for i, msg := range s.Holding {
 			localMap[i] = msg
 		}
-		s.HoldingLast = time.Now().Unix()
+		s.HoldingLast = primitives.Now().Unix()
 		s.HoldingMutex.Lock()
 		defer s.HoldingMutex.Unlock()
 		s.HoldingMap = localMap
@@ -1380,12 +1411,12 @@ func (s *State) LoadAcksMap() map[[32]byte]interfaces.IMsg {
 //  This is what fills the AcksMap requested in LoadAcksMap
 func (s *State) fillAcksMap() {
 	// once a second is often enough to rebuild the Ack list exposed to api
-	if s.AcksLast < time.Now().Unix() {
+	if s.AcksLast < primitives.Now().Unix() {
 		localMap := make(map[[32]byte]interfaces.IMsg)
 		for i, msg := range s.Acks {
 			localMap[i] = msg
 		}
-		s.AcksLast = time.Now().Unix()
+		s.AcksLast = primitives.Now().Unix()
 		s.AcksMutex.Lock()
 		defer s.AcksMutex.Unlock()
 		s.AcksMap = localMap
@@ -1705,14 +1736,14 @@ func (s *State) IsStalled() bool {
 		return true
 	}
 
-	//use 1/10 of the block time times 1.5 in seconds as a timeout on the 'minutes'
+	//use a minute of the block time times 1.5 in seconds as a timeout on the 'minutes'
 	var stalltime float64
 
-	stalltime = float64(int64(s.GetDirectoryBlockInSeconds())) / 10
+	stalltime = float64(int64(s.GetDirectoryBlockInSeconds())) / float64(s.GetMinutesPerBlock(s.LLeaderHeight))
 	stalltime = stalltime * 1.5 * 1e9
 	//fmt.Println("STALL 2", s.CurrentMinuteStartTime/1e9, time.Now().UnixNano()/1e9, stalltime/1e9, (float64(time.Now().UnixNano())-stalltime)/1e9)
 
-	if float64(s.CurrentMinuteStartTime) < float64(time.Now().UnixNano())-stalltime { //-90 seconds was arbitrary
+	if float64(s.CurrentMinuteStartTime) < float64(primitives.Now().UnixNano())-stalltime { //-90 seconds was arbitrary
 		return true
 	}
 
@@ -1727,59 +1758,6 @@ func (s *State) DatabaseContains(hash interfaces.IHash) bool {
 	return false
 }
 
-// JournalMessage writes the message to the message journal for debugging
-func (s *State) JournalMessage(msg interfaces.IMsg) {
-	type journalentry struct {
-		Type    byte
-		Message interfaces.IMsg
-	}
-
-	if s.Journaling && len(s.JournalFile) != 0 {
-		f, err := os.OpenFile(s.JournalFile, os.O_APPEND+os.O_WRONLY, 0666)
-		if err != nil {
-			s.JournalFile = ""
-			return
-		}
-		defer f.Close()
-
-		e := new(journalentry)
-		e.Type = msg.Type()
-		e.Message = msg
-
-		p, err := json.Marshal(e)
-		if err != nil {
-			return
-		}
-		fmt.Fprintln(f, string(p))
-	}
-}
-
-// GetJournalMessages gets all messages from the message journal
-func (s *State) GetJournalMessages() [][]byte {
-	ret := make([][]byte, 0)
-	if !s.Journaling || len(s.JournalFile) == 0 {
-		return nil
-	}
-
-	f, err := os.Open(s.JournalFile)
-	if err != nil {
-		s.JournalFile = ""
-		return nil
-	}
-	defer f.Close()
-
-	r := bufio.NewReader(f)
-	for {
-		p, err := r.ReadBytes('\n')
-		if err != nil {
-			break
-		}
-		ret = append(ret, p)
-	}
-
-	return ret
-}
-
 func (s *State) GetLeaderVM() int {
 	return s.LeaderVMIndex
 }
@@ -1950,12 +1928,19 @@ func (s *State) SetIdentityChainID(chainID interfaces.IHash) {
 	s.IdentityChainID = chainID
 }
 
+// GetDirectoryBlockInSeconds returns the block time of the current block
 func (s *State) GetDirectoryBlockInSeconds() int {
-	return s.DirectoryBlockInSeconds
+	return s.GetBlockPeriod(s.LLeaderHeight).Seconds
 }
 
+// SetDirectoryBlockInSeconds sets the block time of the node. The periods of the block schedule that
+// give their own block time keep it, and the call says so.
 func (s *State) SetDirectoryBlockInSeconds(t int) {
 	s.DirectoryBlockInSeconds = t
+	if p := s.GetBlockPeriod(s.LLeaderHeight); p.Seconds != t {
+		os.Stderr.WriteString(fmt.Sprintf("%s: block time of %d seconds ignored, the block schedule period %v sets it\n",
+			s.FactomNodeName, t, p))
+	}
 }
 
 func (s *State) GetServerPrivateKey() *primitives.PrivateKey {
@@ -2423,7 +2408,7 @@ func (s *State) SetStringQueues() {
 	}
 
 	vmin := s.CurrentMinute
-	if s.CurrentMinute > 9 {
+	if s.CurrentMinute >= s.GetMinutesPerBlock(s.LLeaderHeight) {
 		vmin = 0
 	}
 
@@ -2743,6 +2728,58 @@ func (s *State) updateNetworkControllerConfig() {
 	s.NetworkController.ReloadSpecialPeers(newPeersConfig)
 }
 
+// GetKnownPeers returns the peer database of the p2p network, best reputation first
+func (s *State) GetKnownPeers() (interface{}, error) {
+	if s.NetworkController == nil {
+		return nil, fmt.Errorf("Not connected to the p2p network")
+	}
+	return s.NetworkController.KnownPeers(), nil
+}
+
+func (s *State) SetPeerQuality(address string, quality int32) error {
+	if s.NetworkController == nil {
+		return fmt.Errorf("Not connected to the p2p network")
+	}
+	return s.NetworkController.SetPeerQuality(address, quality)
+}
+
+func (s *State) BanPeer(address string, reason string) error {
+	if s.NetworkController == nil {
+		return fmt.Errorf("Not connected to the p2p network")
+	}
+	return s.NetworkController.BanPeer(address, reason)
+}
+
+func (s *State) UnbanPeer(address string) error {
+	if s.NetworkController == nil {
+		return fmt.Errorf("Not connected to the p2p network")
+	}
+	return s.NetworkController.UnbanPeer(address)
+}
+
+func (s *State) RemovePeer(address string) error {
+	if s.NetworkController == nil {
+		return fmt.Errorf("Not connected to the p2p network")
+	}
+	return s.NetworkController.RemovePeer(address)
+}
+
+// GetLinkFaults returns the fault model of every link of the simulated network
+func (s *State) GetLinkFaults() (interface{}, error) {
+	if s.SimNetwork == nil {
+		return nil, fmt.Errorf("Not running a simulated network")
+	}
+	return s.SimNetwork.LinkFaults(), nil
+}
+
+// SetLinkFault changes the fault model of the links from one node to another, empty names match every node
+func (s *State) SetLinkFault(from, to string, fault interfaces.LinkFault) error {
+	if s.SimNetwork == nil {
+		return fmt.Errorf("Not running a simulated network")
+	}
+	return s.SimNetwork.SetLinkFault(from, to, fault)
+}
+
 // Check and Add a hash to the network replay filter
 func (s *State) AddToReplayFilter(mask int, hash [32]byte, timestamp interfaces.Timestamp, systemtime interfaces.Timestamp) (rval bool) {
 	return s.Replay.IsTSValidAndUpdateState(constants.NETWORK_REPLAY, hash, timestamp, systemtime)
@@ -102,7 +102,7 @@ func (s *State) executeMsg(vm *VM, msg interfaces.IMsg) (ret bool) {
 	msg.ComputeVMIndex(s)
 
 	// never ignore DBState messages
-	if s.IgnoreMissing && msg.Type() != constants.DBSTATE_MSG {
+	if s.IgnoreMissing && msg.Type() != constants.DBSTATE_MSG && msg.Type() != constants.DBSTATE_COMPACT_MSG {
 		now := s.GetTimestamp().GetTimeSeconds()
 		if now-msg.GetTimestamp().GetTimeSeconds() > 60*15 {
 			s.LogMessage("executeMsg", "ignoreMissing", msg)
@@ -198,8 +198,8 @@ func (s *State) Process() (progress bool) {
 	// If we are not running the leader, then look to see if we have waited long enough to
 	// start running the leader.  If we are, start the clock on Ignoring Missing Messages.  This
 	// is so we don't conflict with past version of the network if we have to reboot the network.
-	if s.CurrentMinute > 9 {
-		s.Leader, s.LeaderVMIndex = s.LeaderPL.GetVirtualServers(9, s.IdentityChainID)
+	if last := s.GetMinutesPerBlock(s.LLeaderHeight) - 1; s.CurrentMinute > last {
+		s.Leader, s.LeaderVMIndex = s.LeaderPL.GetVirtualServers(last, s.IdentityChainID)
 	} else {
 		s.Leader, s.LeaderVMIndex = s.LeaderPL.GetVirtualServers(s.CurrentMinute, s.IdentityChainID)
 	}
@@ -287,17 +287,22 @@ ackLoop:
 
 	preEmptyLoopTime := time.Now()
 
-	// Process inbound messages
+	// Process inbound messages, the commits with the highest tips first
+	var inbound []interfaces.IMsg
 emptyLoop:
 	for {
 		select {
 		case msg := <-s.msgQueue:
-			s.LogMessage("msgQueue", "Execute", msg)
-			progress = s.executeMsg(vm, msg) || progress
+			inbound = append(inbound, msg)
 		default:
 			break emptyLoop
 		}
 	}
+	s.SortByECTip(inbound)
+	for _, msg := range inbound {
+		s.LogMessage("msgQueue", "Execute", msg)
+		progress = s.executeMsg(vm, msg) || progress
+	}
 	emptyLoopTime := time.Since(preEmptyLoopTime)
 	TotalEmptyLoopTime.Add(float64(emptyLoopTime.Nanoseconds()))
 
@@ -307,6 +312,7 @@ emptyLoop:
 
 	if s.RunLeader {
 		s.ReviewHolding()
+		s.SortByECTip(s.XReview)
 		for {
 			for _, msg := range s.XReview {
 				if msg == nil {
@@ -557,6 +563,7 @@ func (s *State) AddDBState(isNew bool,
 
 			s.LeaderPL.FactoidBalancesT = map[[32]byte]int64{}
 			s.LeaderPL.ECBalancesT = map[[32]byte]int64{}
+			s.LeaderPL.ECDelegatedT = map[ECDelegationKey]uint64{}
 		}
 
 		s.Leader, s.LeaderVMIndex = s.LeaderPL.GetVirtualServers(s.CurrentMinute, s.IdentityChainID)
@@ -630,6 +637,7 @@ func (s *State) FollowerExecuteEOM(m interfaces.IMsg) {
 // message.
 func (s *State) FollowerExecuteAck(msg interfaces.IMsg) {
 	ack := msg.(*messages.Ack)
+	s.NoteAck(ack.LeaderChainID)
 
 	if ack.DBHeight > s.HighestKnown {
 		s.HighestKnown = ack.DBHeight
@@ -698,6 +706,80 @@ func (s *State) ExecuteEntriesInDBState(dbmsg *messages.DBStateMsg) {
 	}
 }
 
+// FollowerExecuteDBStateCompact rebuilds the full DBState from the transactions and entries we
+// have seen as messages, and then executes it like any other DBState.  If a transaction is
+// missing we ask for the full DBState instead.  Missing entries are left to the entry syncing.
+func (s *State) FollowerExecuteDBStateCompact(msg interfaces.IMsg) {
+	compact, ok := msg.(*messages.DBStateCompactMsg)
+	if !ok {
+		return
+	}
+	dbheight := compact.DirectoryBlock.GetHeader().GetDBHeight()
+
+	// ignore if too old, same as for a full DBState
+	if dbheight > 0 && dbheight <= s.GetHighestSavedBlk() && dbheight < s.EntryDBHeightComplete {
+		return
+	}
+
+	transactions, entries := s.compactDBStateCandidates(dbheight)
+	dbstatemsg, err := compact.Expand(transactions, entries)
+	if err != nil {
+		s.LogMessage("executeMsg", fmt.Sprintf("compact dbstate ht %d, asking for full dbstate: %v", dbheight, err), msg)
+		s.DBStateCompactMissCnt++
+		missing := messages.NewDBStateMissing(s, dbheight, dbheight)
+		missing.SendOut(s, missing)
+		return
+	}
+	s.DBStateCompactCnt++
+
+	if dbstatemsg.Validate(s) != 1 {
+		s.DBStateIgnoreCnt++
+		return
+	}
+	s.FollowerExecuteDBState(dbstatemsg)
+}
+
+// compactDBStateCandidates collects the factoid transactions and entries we know of that could
+// be in the block at the given height: the messages in the process list and in holding.
+func (s *State) compactDBStateCandidates(dbheight uint32) (transactions []interfaces.ITransaction, entries map[[32]byte]interfaces.IEBEntry) {
+	entries = make(map[[32]byte]interfaces.IEBEntry)
+
+	add := func(m interfaces.IMsg) {
+		switch msg := m.(type) {
+		case *messages.FactoidTransaction:
+			if msg.Transaction != nil {
+				transactions = append(transactions, msg.Transaction)
+			}
+		case *messages.RevealEntryMsg:
+			if msg.Entry != nil {
+				entries[msg.Entry.GetHash().Fixed()] = msg.Entry
+			}
+		}
+	}
+
+	if pl := s.ProcessLists.GetSafe(dbheight); pl != nil {
+		for _, vm := range pl.VMs {
+			for _, m := range vm.List {
+				if m != nil {
+					add(m)
+				}
+			}
+		}
+		for _, k := range pl.GetKeysNewEntries() {
+			if e := pl.GetNewEntry(k); e != nil {
+				entries[k] = e
+			}
+		}
+	}
+	for _, m := range s.Holding {
+		add(m)
+	}
+	for _, m := range s.XReview {
+		add(m)
+	}
+	return
+}
+
 func (s *State) FollowerExecuteDBState(msg interfaces.IMsg) {
 	dbstatemsg, _ := msg.(*messages.DBStateMsg)
 
@@ -1128,7 +1210,7 @@ func (s *State) LeaderExecute(m interfaces.IMsg) {
 }
 
 func (s *State) setCurrentMinute(m int) {
-	if m != s.CurrentMinute && m != s.CurrentMinute+1 && !(m == 0 && s.CurrentMinute == 10) {
+	if m != s.CurrentMinute && m != s.CurrentMinute+1 && !(m == 0 && s.CurrentMinute == s.GetMinutesPerBlock(s.LLeaderHeight)) {
 		s.LogPrintf("dbsig-eom", " Jump s.CurrentMinute = %d, from %d %s", m, s.CurrentMinute, atomic.WhereAmIString(1))
 
 	} else {
@@ -1687,6 +1769,10 @@ func (s *State) ProcessEOM(dbheight uint32, msg interfaces.IMsg) bool {
 		vm.LeaderMinute++
 		s.EOMProcessed++
 		//fmt.Println(fmt.Sprintf("EOM PROCESS: %10s vm %2d EOMProcessed++ (%2d)", s.FactomNodeName, e.VMIndex, s.EOMProcessed))
+		if s.Tracing() {
+			s.Trace(TraceEvent{Event: TraceEOM, DBHeight: e.DBHeight, Minute: int(e.Minute), VM: msg.GetVMIndex(), Height: vm.Height,
+				Hash: msg.GetMsgHash().String(), Info: fmt.Sprintf("%d of %d", s.EOMProcessed, len(s.LeaderPL.FedServers))})
+		}
 		vm.Synced = true
 		markNoFault(pl, msg.GetVMIndex())
 		if s.LeaderPL.SysHighest < int(e.SysHeight) {
@@ -1728,13 +1814,14 @@ func (s *State) ProcessEOM(dbheight uint32, msg interfaces.IMsg) bool {
 		}
 
 		s.setCurrentMinute(s.CurrentMinute + 1)
-		s.CurrentMinuteStartTime = time.Now().UnixNano()
+		s.CurrentMinuteStartTime = primitives.Now().UnixNano()
 		// If an election took place, our lists will be unsorted. Fix that
 		pl.SortAuditServers()
 		pl.SortFedServers()
 
+		minutes := s.GetMinutesPerBlock(s.LLeaderHeight)
 		switch {
-		case s.CurrentMinute < 10:
+		case s.CurrentMinute < minutes:
 			if s.CurrentMinute == 1 {
 				dbstate := s.GetDBState(dbheight - 1)
 				// Panic had arose when leaders would reboot and the follower was on a future minute
@@ -1750,7 +1837,7 @@ func (s *State) ProcessEOM(dbheight uint32, msg interfaces.IMsg) bool {
 			s.LeaderPL = s.ProcessLists.Get(s.LLeaderHeight)
 			s.Leader, s.LeaderVMIndex = s.LeaderPL.GetVirtualServers(s.CurrentMinute, s.IdentityChainID)
 
-		case s.CurrentMinute == 10:
+		case s.CurrentMinute == minutes:
 			s.LogPrintf("dbsig-eom", "Start new block")
 			eBlocks := []interfaces.IEntryBlock{}
 			entries := []interfaces.IEBEntry{}
@@ -2043,6 +2130,10 @@ func (s *State) ProcessDBSig(dbheight uint32, msg interfaces.IMsg) bool {
 
 		s.DBSigProcessed++
 		//fmt.Println(fmt.Sprintf("Process DBSig %10s vm %2v DBSigProcessed++ (%2d)", s.FactomNodeName, dbs.VMIndex, s.DBSigProcessed))
+		if s.Tracing() {
+			s.Trace(TraceEvent{Event: TraceDBSig, DBHeight: dbheight, VM: dbs.VMIndex, Hash: msg.GetMsgHash().String(),
+				Info: fmt.Sprintf("%d of %d, signs %x", s.DBSigProcessed, len(s.LeaderPL.FedServers), dbs.DirectoryBlockHeader.GetBodyMR().Bytes()[:4])})
+		}
 		vm.Synced = true
 
 		InMsg := s.EFactory.NewDBSigSigInternal(
@@ -2395,6 +2486,10 @@ func (s *State) NewAck(msg interfaces.IMsg, balanceHash interfaces.IHash) interf
 	}
 
 	ack.Sign(s)
+	if s.Tracing() {
+		s.Trace(TraceEvent{Event: TraceAck, DBHeight: ack.DBHeight, Minute: int(ack.Minute), VM: vmIndex, Height: int(ack.Height),
+			Msg: constants.MessageName(msg.Type()), Hash: ack.MessageHash.String()})
+	}
 
 	return ack
 }
@@ -50,6 +50,7 @@ func (this *State) ProcessRecentFERChainEntries() {
 
 	// Check to see if a price change targets the next block
 	if this.FERChangeHeight == (this.GetDBHeightComplete())+1 {
+		this.activateFERChange(this.FERChangeHeight, this.FERChangePrice)
 		this.FactoshisPerEC = this.FERChangePrice
 		this.FERChangePrice = 0
 		this.FERChangeHeight = 1
@@ -123,6 +124,13 @@ func (this *State) ProcessRecentFERChainEntries() {
 				if this.FERChangeHeight < (this.GetDBHeightComplete() + 2) {
 					this.FERChangeHeight = this.GetDBHeightComplete() + 2
 				}
+				this.recordFERChange(FERChange{
+					EntryHash:    entryHash,
+					EntryHeight:  this.GetDBHeightComplete(),
+					Priority:     this.FERPriority,
+					TargetPrice:  this.FERChangePrice,
+					TargetHeight: this.FERChangeHeight,
+				})
 			} else {
 				this.Println(" Failed FER entry : ", string(entryContent))
 			}
@@ -11,6 +11,7 @@ import (
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/messages"
+	"github.com/FactomProject/factomd/common/primitives"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,7 +22,7 @@ func (state *State) ValidatorLoop() {
 	for {
 		if state.DebugExec() {
 			status := ""
-			now := time.Now()
+			now := primitives.Now()
 			if now.Sub(prev).Minutes() > 1 {
 				state.LogPrintf("executeMsg", "Timestamp DBh/VMh/h %d/%d/%d", state.LLeaderHeight, state.LeaderVMIndex, state.CurrentMinute)
 				pendingEBs := 0
@@ -131,7 +132,7 @@ func (state *State) ValidatorLoop() {
 			if !progress && state.InMsgQueue().Length() == 0 && state.InMsgQueue2().Length() == 0 {
 				// No messages? Sleep for a bit
 				for i := 0; i < 10 && state.InMsgQueue().Length() == 0; i++ {
-					time.Sleep(10 * time.Millisecond)
+					primitives.Sleep(10 * time.Millisecond)
 				}
 
 			}
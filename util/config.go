@@ -26,6 +26,7 @@ type FactomdConfig struct {
 		BoltDBPath                             string
 		DataStorePath                          string
 		DirectoryBlockInSeconds                int
+		BlockSchedule                          string
 		ExportData                             bool
 		ExportDataSubpath                      string
 		FastBoot                               bool
@@ -58,6 +59,8 @@ type FactomdConfig struct {
 		CustomSpecialPeers      string
 		CustomBootstrapIdentity string
 		CustomBootstrapKey      string
+		CustomActivations       string
+		CustomActivationsFile   string
 		FactomdTlsEnabled       bool
 		FactomdTlsPrivateKey    string
 		FactomdTlsPublicCert    string
@@ -117,6 +120,8 @@ LdbPath                               = "database/ldb"
 BoltDBPath                            = "database/bolt"
 DataStorePath                         = "data/export"
 DirectoryBlockInSeconds               = 6
+; --------------- BlockSchedule: <height>:<seconds>:<minutes>,... block time and minutes per block from a height on (not on MAIN)
+BlockSchedule                         = ""
 ExportData                            = false
 ExportDataSubpath                     = "database/export/"
 FastBoot                              = true
@@ -138,6 +143,10 @@ CustomSeedURL        = ""
 CustomSpecialPeers   = ""
 CustomBootstrapIdentity     = 38bab1455b7bd7e5efd15c53c777c79d0c988e9210f1da49a99d95b3a6417be9
 CustomBootstrapKey          = cc1985cdfae4e32b5a454dfda8ce5e1361558482684f3367649c3ad852c8e31a
+; --------------- CustomActivations: <name>:<height>,... activation heights of the CUSTOM network, over CustomActivationsFile
+CustomActivations           = ""
+; --------------- CustomActivationsFile: activation heights of the CUSTOM network in a file signed by its CustomBootstrapKey
+CustomActivationsFile       = ""
 ; --------------- NodeMode: FULL | SERVER ----------------
 NodeMode                                = FULL
 LocalServerPrivKey                      = 4c38c72fc5cdad68f13b74674d3ffb1f3d63a112710868c9b08946553448d26d
@@ -212,6 +221,7 @@ func (s *FactomdConfig) String() string {
 	out.WriteString(fmt.Sprintf("\n    BoltDBPath              %v", s.App.BoltDBPath))
 	out.WriteString(fmt.Sprintf("\n    DataStorePath           %v", s.App.DataStorePath))
 	out.WriteString(fmt.Sprintf("\n    DirectoryBlockInSeconds %v", s.App.DirectoryBlockInSeconds))
+	out.WriteString(fmt.Sprintf("\n    BlockSchedule           %v", s.App.BlockSchedule))
 	out.WriteString(fmt.Sprintf("\n    ExportData              %v", s.App.ExportData))
 	out.WriteString(fmt.Sprintf("\n    ExportDataSubpath       %v", s.App.ExportDataSubpath))
 	out.WriteString(fmt.Sprintf("\n    Network                 %v", s.App.Network))
@@ -230,6 +240,8 @@ func (s *FactomdConfig) String() string {
 	out.WriteString(fmt.Sprintf("\n    CustomSpecialPeers      %v", s.App.CustomSpecialPeers))
 	out.WriteString(fmt.Sprintf("\n    CustomBootstrapIdentity %v", s.App.CustomBootstrapIdentity))
 	out.WriteString(fmt.Sprintf("\n    CustomBootstrapKey      %v", s.App.CustomBootstrapKey))
+	out.WriteString(fmt.Sprintf("\n    CustomActivations       %v", s.App.CustomActivations))
+	out.WriteString(fmt.Sprintf("\n    CustomActivationsFile   %v", s.App.CustomActivationsFile))
 	out.WriteString(fmt.Sprintf("\n    NodeMode                %v", s.App.NodeMode))
 	out.WriteString(fmt.Sprintf("\n    IdentityChainID         %v", s.App.IdentityChainID))
 	out.WriteString(fmt.Sprintf("\n    LocalServerPrivKey      %v", s.App.LocalServerPrivKey))
@@ -110,6 +110,30 @@ func HandleDebugRequest(
 	case "network-info":
 		resp, jsonError = HandleNetworkInfo(state, params)
 		break
+	case "peers":
+		resp, jsonError = HandlePeers(state, params)
+		break
+	case "set-peer-quality":
+		resp, jsonError = HandleSetPeerQuality(state, params)
+		break
+	case "ban-peer":
+		resp, jsonError = HandleBanPeer(state, params)
+		break
+	case "unban-peer":
+		resp, jsonError = HandleUnbanPeer(state, params)
+		break
+	case "remove-peer":
+		resp, jsonError = HandleRemovePeer(state, params)
+		break
+	case "link-faults":
+		resp, jsonError = HandleLinkFaults(state, params)
+		break
+	case "set-link-fault":
+		resp, jsonError = HandleSetLinkFault(state, params)
+		break
+	case "failover":
+		resp, jsonError = HandleFailover(state, params)
+		break
 	case "summary":
 		resp, jsonError = HandleSummary(state, params)
 		break
@@ -119,6 +143,9 @@ func HandleDebugRequest(
 	case "process-list":
 		resp, jsonError = HandleProcessList(state, params)
 		break
+	case "process-lists":
+		resp, jsonError = HandleProcessLists(state, params)
+		break
 	case "reload-configuration":
 		resp, jsonError = HandleReloadConfig(state, params)
 		break
@@ -345,6 +372,167 @@ func HandleNetworkInfo(
 	return r, nil
 }
 
+func HandlePeers(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Peers interface{}
+	}
+	r := new(ret)
+
+	peers, err := state.GetKnownPeers()
+	if err != nil {
+		return nil, NewCustomInternalError(err.Error())
+	}
+	r.Peers = peers
+	return r, nil
+}
+
+func HandleSetPeerQuality(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(SetPeerQualityRequest)
+	err := MapToObject(params, request)
+	if err != nil || request.Address == "" {
+		return nil, NewInvalidParamsError()
+	}
+
+	err = state.SetPeerQuality(request.Address, request.Quality)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return request, nil
+}
+
+func HandleBanPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(BanPeerRequest)
+	err := MapToObject(params, request)
+	if err != nil || request.Address == "" {
+		return nil, NewInvalidParamsError()
+	}
+	if request.Reason == "" {
+		request.Reason = "banned through the debug API"
+	}
+
+	err = state.BanPeer(request.Address, request.Reason)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return request, nil
+}
+
+func HandleUnbanPeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(PeerRequest)
+	err := MapToObject(params, request)
+	if err != nil || request.Address == "" {
+		return nil, NewInvalidParamsError()
+	}
+
+	err = state.UnbanPeer(request.Address)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return request, nil
+}
+
+func HandleRemovePeer(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(PeerRequest)
+	err := MapToObject(params, request)
+	if err != nil || request.Address == "" {
+		return nil, NewInvalidParamsError()
+	}
+
+	err = state.RemovePeer(request.Address)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return request, nil
+}
+
+func HandleLinkFaults(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Links interface{}
+	}
+	r := new(ret)
+
+	links, err := state.GetLinkFaults()
+	if err != nil {
+		return nil, NewCustomInternalError(err.Error())
+	}
+	r.Links = links
+	return r, nil
+}
+
+func HandleSetLinkFault(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(SetLinkFaultRequest)
+	err := MapToObject(params, request)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+
+	err = state.SetLinkFault(request.From, request.To, request.Fault)
+	if err == nil && request.Both {
+		err = state.SetLinkFault(request.To, request.From, request.Fault)
+	}
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return request, nil
+}
+
+func HandleFailover(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	type ret struct {
+		Failover interface{}
+	}
+	r := new(ret)
+	r.Failover = state.GetFailoverStatus()
+	return r, nil
+}
+
 func HandleSummary(
 	state interfaces.IState,
 	params interface{},
@@ -391,6 +579,34 @@ func HandleProcessList(
 	return r, nil
 }
 
+// HandleProcessLists returns the process lists of the node as JSON, all of them or the one of the
+// dbheight param
+func HandleProcessLists(
+	state interfaces.IState,
+	params interface{},
+) (
+	interface{},
+	*primitives.JSONError,
+) {
+	request := new(ProcessListsRequest)
+	request.DBHeight = -1
+	if params != nil {
+		err := MapToObject(params, request)
+		if err != nil {
+			return nil, NewInvalidParamsError()
+		}
+	}
+
+	type ret struct {
+		LeaderHeight uint32      `json:"leaderheight"`
+		ProcessLists interface{} `json:"processlists"`
+	}
+	r := new(ret)
+	r.LeaderHeight = state.GetLLeaderHeight()
+	r.ProcessLists = state.GetProcessListStatus(request.DBHeight)
+	return r, nil
+}
+
 func HandleReloadConfig(
 	state interfaces.IState,
 	params interface{},
@@ -411,3 +627,30 @@ type SetDelayRequest struct {
 type SetDropRateRequest struct {
 	DropRate int `json:"droprate"`
 }
+
+type PeerRequest struct {
+	Address string `json:"address"`
+}
+
+// SetLinkFaultRequest sets the fault model of the links from one node to another (both ways if Both),
+// an empty name matches every node
+type SetLinkFaultRequest struct {
+	From  string               `json:"from"`
+	To    string               `json:"to"`
+	Both  bool                 `json:"both"`
+	Fault interfaces.LinkFault `json:"fault"`
+}
+
+type ProcessListsRequest struct {
+	DBHeight int `json:"dbheight"`
+}
+
+type SetPeerQualityRequest struct {
+	Address string `json:"address"`
+	Quality int32  `json:"quality"`
+}
+
+type BanPeerRequest struct {
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
@@ -179,6 +179,36 @@ var (
 		Name: "factomd_wsapi_v2_api_call_tpsrate_ns",
 		Help: "Time it takes to compelete a tpsrate",
 	})
+
+	HandleV2APICallECRateHistory = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_ecrate_history_ns",
+		Help: "Time it takes to compelete an exchange-rate-history",
+	})
+
+	HandleV2APICallCoinbaseReport = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_coinbase_report_ns",
+		Help: "Time it takes to compelete a coinbase-report",
+	})
+
+	HandleV2APICallAuthoritySet = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_authority_set_ns",
+		Help: "Time it takes to compelete an authority-set",
+	})
+
+	HandleV2APICallAuthorityHistory = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_authority_history_ns",
+		Help: "Time it takes to compelete an authority-history",
+	})
+
+	HandleV2APICallIdentityUpdatePreview = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_identity_update_preview_ns",
+		Help: "Time it takes to compelete an identity-update-preview",
+	})
+
+	HandleV2APICallActivations = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "factomd_wsapi_v2_api_call_activations_ns",
+		Help: "Time it takes to compelete an activations",
+	})
 )
 
 var registered = false
@@ -223,6 +253,12 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(HandleV2APICallABlockByHeight)
 	prometheus.MustRegister(HandleV2APICallAuthorities)
 	prometheus.MustRegister(HandleV2APICallTpsRate)
+	prometheus.MustRegister(HandleV2APICallActivations)
+	prometheus.MustRegister(HandleV2APICallECRateHistory)
+	prometheus.MustRegister(HandleV2APICallCoinbaseReport)
+	prometheus.MustRegister(HandleV2APICallAuthoritySet)
+	prometheus.MustRegister(HandleV2APICallAuthorityHistory)
+	prometheus.MustRegister(HandleV2APICallIdentityUpdatePreview)
 	prometheus.MustRegister(HandleV2APICallAblock)
 	prometheus.MustRegister(HandleV2APICallFblock)
 }
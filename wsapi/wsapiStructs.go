@@ -73,6 +73,7 @@ type CurrentMinuteResponse struct {
 	CurrentMinuteStartTime  int64 `json:"currentminutestarttime"`
 	CurrentTime             int64 `json:"currenttime"`
 	DirectoryBlockInSeconds int64 `json:"directoryblockinseconds"`
+	MinutesPerBlock         int64 `json:"minutesperblock"`
 	StallDetected           bool  `json:"stalldetected"`
 	FaultTimeOut            int64 `json:"faulttimeout"`
 	RoundTimeOut            int64 `json:"roundtimeout"`
@@ -106,6 +107,7 @@ type EntryCreditBlockResponse struct {
 		FullHash   interfaces.IHash          `json:"fullhash"`
 	} `json:"ecblock"`
 	RawData string `json:"rawdata"`
+	Tips    uint64 `json:"tips,omitempty"` // Entry credits the commits of the block paid over the max of their entries
 }
 
 type EntryResponse struct {
@@ -125,6 +127,11 @@ type EntryCreditBalanceResponse struct {
 
 type FactoidBalanceResponse struct {
 	Balance int64 `json:"balance"`
+	// With the RCD of the address: the part of the balance a time lock holds,
+	// and when the lock ends
+	Locked     int64  `json:"locked,omitempty"`
+	LockHeight uint32 `json:"lockheight,omitempty"`
+	LockTime   uint64 `json:"locktime,omitempty"`
 }
 
 type EntryCreditRateResponse struct {
@@ -145,6 +152,20 @@ type TransactionRateResponse struct {
 	InstantTransactionRate float64 `json:"instanttxrate"`
 }
 
+type ActivationsResponse struct {
+	Network     string             `json:"network"`
+	Schedule    string             `json:"schedule"` // Hash of the activation schedule, the same on every node of the network
+	Activations []ActivationStatus `json:"activations"`
+}
+
+type ActivationStatus struct {
+	Name             string `json:"name"`
+	ID               int    `json:"id"`
+	Description      string `json:"description"`
+	ActivationHeight int    `json:"activationheight"` // 2147483647 for never
+	Active           bool   `json:"active"`
+}
+
 /*********************************************************************/
 
 type DBHead struct {
@@ -239,6 +260,41 @@ type AddressRequest struct {
 	Address string `json:"address"`
 }
 
+// FactoidBalanceRequest can have the RCD of the address, in hex, to report the
+// balance a time lock holds.  The address can then be left out.
+type FactoidBalanceRequest struct {
+	Address string `json:"address"`
+	RCD     string `json:"rcd,omitempty"`
+}
+
+// ExchangeRateHistoryRequest pages the FER history, newest first
+type ExchangeRateHistoryRequest struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// CoinbaseReportRequest asks for the coinbase payouts of an authority identity, to the last saved block
+// if To is not set
+type CoinbaseReportRequest struct {
+	ChainID string `json:"chainid"`
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to"`
+}
+
+// AuthoritySetRequest asks for the authority set that signs the directory block at Height, the current
+// set if Height is not set
+type AuthoritySetRequest struct {
+	Height uint32 `json:"height"`
+}
+
+// AuthorityHistoryRequest asks for the authority changes of the admin blocks from From to To, of one
+// identity if ChainID is set
+type AuthorityHistoryRequest struct {
+	ChainID string `json:"chainid,omitempty"`
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to"`
+}
+
 type HeightRequest struct {
 	Height int64 `json:"height"`
 }
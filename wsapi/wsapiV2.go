@@ -9,12 +9,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/FactomProject/factomd/activations"
 	"github.com/FactomProject/factomd/common/constants"
 	"github.com/FactomProject/factomd/common/entryBlock"
 	"github.com/FactomProject/factomd/common/entryCreditBlock"
@@ -76,6 +78,9 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	case "chain-head":
 		resp, jsonError = HandleV2ChainHead(state, params)
 		break
+	case "coinbase-report":
+		resp, jsonError = HandleV2CoinbaseReport(state, params)
+		break
 	case "commit-chain":
 		resp, jsonError = HandleV2CommitChain(state, params)
 		break
@@ -112,6 +117,9 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 	case "entry-credit-rate":
 		resp, jsonError = HandleV2EntryCreditRate(state, params)
 		break
+	case "exchange-rate-history":
+		resp, jsonError = HandleV2ExchangeRateHistory(state, params)
+		break
 	case "factoid-balance":
 		resp, jsonError = HandleV2FactoidBalance(state, params)
 		break
@@ -168,8 +176,16 @@ func HandleV2Request(state interfaces.IState, j *primitives.JSON2Request) (*prim
 		break
 	case "authorities":
 		resp, jsonError = HandleAuthorities(state, params)
+	case "authority-set":
+		resp, jsonError = HandleV2AuthoritySet(state, params)
+	case "authority-history":
+		resp, jsonError = HandleV2AuthorityHistory(state, params)
+	case "identity-update-preview":
+		resp, jsonError = HandleV2IdentityUpdatePreview(state, params)
 	case "tps-rate":
 		resp, jsonError = HandleV2TransactionRate(state, params)
+	case "activations":
+		resp, jsonError = HandleV2Activations(state, params)
 	case "ack":
 		resp, jsonError = HandleV2ACKWithChain(state, params)
 	case "multiple-fct-balances":
@@ -304,6 +320,14 @@ func ECBlockToResp(block interfaces.IEntryCreditBlock) (interface{}, *primitives
 		return nil, NewInternalError()
 	}
 	resp.ECBlock.HeaderHash = tmpHash
+	for _, entry := range block.GetEntries() {
+		switch commit := entry.(type) {
+		case *entryCreditBlock.CommitEntry:
+			resp.Tips += uint64(commit.GetTip())
+		case *entryCreditBlock.CommitChain:
+			resp.Tips += uint64(commit.GetTip())
+		}
+	}
 	return resp, nil
 }
 
@@ -558,7 +582,7 @@ func HandleV2CommitChain(state interfaces.IState, params interface{}) (interface
 		}
 	}
 
-	if !commit.IsValid() {
+	if !commit.IsValidWithTip(messages.MaxECTip(state)) {
 		return nil, NewInvalidCommitChainError()
 	}
 
@@ -606,7 +630,7 @@ func HandleV2CommitEntry(state interfaces.IState, params interface{}) (interface
 		}
 	}
 
-	if !commit.IsValid() {
+	if !commit.IsValidWithTip(messages.MaxECTip(state)) {
 		return nil, NewInvalidCommitEntryError()
 	}
 
@@ -750,7 +774,11 @@ func HandleV2Receipt(state interfaces.IState, params interface{}) (interface{},
 
 	receipt, err := receipts.CreateFullReceipt(dbase, h)
 	if err != nil {
-		return nil, NewReceiptError()
+		// Not an entry, it can be a factoid transaction
+		receipt, err = receipts.CreateTransactionReceipt(dbase, h)
+		if err != nil {
+			return nil, NewReceiptError()
+		}
 	}
 	resp := new(ReceiptResponse)
 	resp.Receipt = receipt
@@ -965,6 +993,7 @@ func HandleV2CurrentMinute(state interfaces.IState, params interface{}) (interfa
 	h.CurrentBlockStartTime = state.GetCurrentBlockStartTime()
 	h.CurrentMinuteStartTime = int64(state.GetCurrentMinuteStartTime())
 	h.DirectoryBlockInSeconds = int64(state.GetDirectoryBlockInSeconds())
+	h.MinutesPerBlock = int64(state.GetMinutesPerBlock(state.GetLLeaderHeight()))
 	h.StallDetected = state.IsStalled()
 	h.FaultTimeOut = int64(globals.Params.FaultTimeout)
 	h.RoundTimeOut = int64(globals.Params.RoundTimeout)
@@ -1020,6 +1049,126 @@ func HandleV2EntryCreditRate(state interfaces.IState, params interface{}) (inter
 	return resp, nil
 }
 
+// HandleV2ExchangeRateHistory returns a page of the FER changes, newest first, with the pending change
+// and the height it takes effect
+func HandleV2ExchangeRateHistory(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallECRateHistory.Observe(float64(time.Since(n).Nanoseconds()))
+
+	request := new(ExchangeRateHistoryRequest)
+	request.Limit = 50
+	if params != nil {
+		if err := MapToObject(params, request); err != nil {
+			return nil, NewInvalidParamsError()
+		}
+	}
+	if request.Offset < 0 || request.Limit < 1 || request.Limit > 1000 {
+		return nil, NewCustomInvalidParamsError("offset must be 0 or more, and limit from 1 to 1000")
+	}
+	return state.GetFERHistory(request.Offset, request.Limit), nil
+}
+
+func HandleV2CoinbaseReport(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallCoinbaseReport.Observe(float64(time.Since(n).Nanoseconds()))
+
+	request := new(CoinbaseReportRequest)
+	request.To = math.MaxUint32
+	err := MapToObject(params, request)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+	chainID, err := primitives.HexToHash(request.ChainID)
+	if err != nil {
+		return nil, NewInvalidHashError()
+	}
+	if request.From > request.To {
+		return nil, NewCustomInvalidParamsError("from must not be past to")
+	}
+
+	r, err := state.GetCoinbaseReport(chainID, request.From, request.To)
+	if err != nil {
+		return nil, NewCustomInternalError(err.Error())
+	}
+	return r, nil
+}
+
+func HandleV2AuthoritySet(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallAuthoritySet.Observe(float64(time.Since(n).Nanoseconds()))
+
+	request := new(AuthoritySetRequest)
+	request.Height = math.MaxUint32
+	if params != nil {
+		err := MapToObject(params, request)
+		if err != nil {
+			return nil, NewInvalidParamsError()
+		}
+	}
+
+	r, err := state.GetAuthoritySet(request.Height)
+	if err != nil {
+		return nil, NewCustomInternalError(err.Error())
+	}
+	return r, nil
+}
+
+func HandleV2AuthorityHistory(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallAuthorityHistory.Observe(float64(time.Since(n).Nanoseconds()))
+
+	request := new(AuthorityHistoryRequest)
+	request.To = math.MaxUint32
+	if params != nil {
+		err := MapToObject(params, request)
+		if err != nil {
+			return nil, NewInvalidParamsError()
+		}
+	}
+	var chainID interfaces.IHash
+	if request.ChainID != "" {
+		h, err := primitives.HexToHash(request.ChainID)
+		if err != nil {
+			return nil, NewInvalidHashError()
+		}
+		chainID = h
+	}
+	if request.From > request.To {
+		return nil, NewCustomInvalidParamsError("from must not be past to")
+	}
+
+	r, err := state.GetAuthorityChanges(chainID, request.From, request.To)
+	if err != nil {
+		return nil, NewCustomInternalError(err.Error())
+	}
+	return r, nil
+}
+
+// HandleV2IdentityUpdatePreview shows what a signed identity entry changes, without submitting it
+func HandleV2IdentityUpdatePreview(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallIdentityUpdatePreview.Observe(float64(time.Since(n).Nanoseconds()))
+
+	e := new(EntryRequest)
+	err := MapToObject(params, e)
+	if err != nil {
+		return nil, NewInvalidParamsError()
+	}
+
+	entry := entryBlock.NewEntry()
+	if p, err := hex.DecodeString(e.Entry); err != nil {
+		return nil, NewInvalidEntryError()
+	} else if _, err := entry.UnmarshalBinaryData(p); err != nil {
+		return nil, NewInvalidEntryError()
+	}
+
+	r, err := state.PreviewIdentityUpdate(entry)
+	if err != nil {
+		return nil, NewCustomInvalidParamsError(err.Error())
+	}
+	return r, nil
+}
+
 func HandleV2FactoidSubmit(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallFctTx.Observe(float64(time.Since(n).Nanoseconds()))
@@ -1057,12 +1206,32 @@ func HandleV2FactoidBalance(state interfaces.IState, params interface{}) (interf
 	n := time.Now()
 	defer HandleV2APICallFABal.Observe(float64(time.Since(n).Nanoseconds()))
 
-	fadr := new(AddressRequest)
+	fadr := new(FactoidBalanceRequest)
 	err := MapToObject(params, fadr)
 	if err != nil {
 		return nil, NewInvalidParamsError()
 	}
 
+	var rcd interfaces.IRCD
+	if fadr.RCD != "" {
+		data, err := hex.DecodeString(fadr.RCD)
+		if err != nil || len(data) == 0 {
+			return nil, NewInvalidParamsError()
+		}
+		var rest []byte
+		rcd, rest, err = factoid.UnmarshalBinaryAuth(data)
+		if err != nil || len(rest) > 0 {
+			return nil, NewInvalidParamsError()
+		}
+		if fadr.Address == "" {
+			address, err := rcd.GetAddress()
+			if err != nil {
+				return nil, NewInvalidParamsError()
+			}
+			fadr.Address = address.String()
+		}
+	}
+
 	var adr []byte
 
 	if primitives.ValidateFUserStr(fadr.Address) {
@@ -1083,9 +1252,41 @@ func HandleV2FactoidBalance(state interfaces.IState, params interface{}) (interf
 
 	resp := new(FactoidBalanceResponse)
 	resp.Balance = state.GetFactoidState().GetFactoidBalance(factoid.NewAddress(adr).Fixed())
+
+	if rcd != nil {
+		address, err := rcd.GetAddress()
+		if err != nil || !address.IsSameAs(factoid.NewAddress(adr)) {
+			return nil, NewCustomInvalidParamsError("The RCD is not the one of the address")
+		}
+		lockedBalance(state, rcd, resp)
+	}
 	return resp, nil
 }
 
+// lockedBalance fills in the part of the balance the time locks of the RCD hold
+// in the block being built, and the height and time all of them end at
+func lockedBalance(state interfaces.IState, rcd interfaces.IRCD, resp *FactoidBalanceResponse) {
+	height := state.GetLLeaderHeight()
+	var blockTime uint64
+	if block := state.GetFactoidState().GetCurrentBlock(); block != nil {
+		if ts := block.GetCoinbaseTimestamp(); ts != nil {
+			blockTime = ts.GetTimeMilliUInt64()
+		}
+	}
+	factoid.WalkRCD(rcd, func(r interfaces.IRCD) error {
+		if lock, ok := r.(*factoid.RCD_3); ok && lock.IsLocked(height, blockTime) {
+			resp.Locked = resp.Balance
+			if lock.LockHeight > resp.LockHeight {
+				resp.LockHeight = lock.LockHeight
+			}
+			if lock.LockTime > resp.LockTime {
+				resp.LockTime = lock.LockTime
+			}
+		}
+		return nil
+	})
+}
+
 func HandleV2Heights(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	n := time.Now()
 	defer HandleV2APICallHeights.Observe(float64(time.Since(n).Nanoseconds()))
@@ -1278,6 +1479,22 @@ func HandleV2TransactionRate(state interfaces.IState, params interface{}) (inter
 	return r, nil
 }
 
+func HandleV2Activations(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
+	n := time.Now()
+	defer HandleV2APICallActivations.Observe(float64(time.Since(n).Nanoseconds()))
+
+	r := new(ActivationsResponse)
+	r.Network = activations.NetworkName()
+	r.Schedule = activations.ScheduleHash(r.Network)
+	for _, a := range activations.Activations() {
+		s := ActivationStatus{Name: a.Name, ID: int(a.Id), Description: a.Description}
+		s.ActivationHeight = activations.GetActivationHeight(a.Id, r.Network)
+		s.Active = state.IsActive(a.Id)
+		r.Activations = append(r.Activations, s)
+	}
+	return r, nil
+}
+
 func HandleV2MultipleECBalances(state interfaces.IState, params interface{}) (interface{}, *primitives.JSONError) {
 	x, ok := params.(map[string]interface{})
 	if ok != true {
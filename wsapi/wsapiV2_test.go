@@ -2,16 +2,24 @@ package wsapi_test
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/FactomProject/factomd/activations"
+	"github.com/FactomProject/factomd/common/constants"
+	"github.com/FactomProject/factomd/common/entryCreditBlock"
+	"github.com/FactomProject/factomd/common/factoid"
+	"github.com/FactomProject/factomd/common/identity"
 	"github.com/FactomProject/factomd/common/interfaces"
 	"github.com/FactomProject/factomd/common/primitives"
 	"github.com/FactomProject/factomd/receipts"
+	"github.com/FactomProject/factomd/state"
 	"github.com/FactomProject/factomd/testHelper"
 	. "github.com/FactomProject/factomd/wsapi"
 )
@@ -225,6 +233,204 @@ func TestV2HandleFactoidBalance(t *testing.T) {
 }
 */
 
+func TestHandleV2FactoidBalanceLocked(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+	height := state.GetLLeaderHeight()
+
+	for _, lockHeight := range []uint32{height + 10, height} {
+		rcd, _ := factoid.NewRCD_3(lockHeight, 0, testHelper.NewFactoidRCDAddress(1))
+		address, _ := rcd.GetAddress()
+		state.PutF(true, address.Fixed(), 1000)
+		data, _ := rcd.MarshalBinary()
+
+		resp, jsonError := HandleV2FactoidBalance(state, &FactoidBalanceRequest{RCD: hex.EncodeToString(data)})
+		if jsonError != nil {
+			t.Fatalf("%v", jsonError)
+		}
+		balance := resp.(*FactoidBalanceResponse)
+		if balance.Balance != 1000 {
+			t.Errorf("Wrong balance %d", balance.Balance)
+		}
+		if lockHeight > height && (balance.Locked != 1000 || balance.LockHeight != lockHeight) {
+			t.Errorf("Expected 1000 locked until %d, got %d until %d", lockHeight, balance.Locked, balance.LockHeight)
+		}
+		if lockHeight == height && balance.Locked != 0 {
+			t.Errorf("Expected nothing locked at the lock height, got %d", balance.Locked)
+		}
+	}
+
+	// The RCD has to be the one of the address
+	rcd, _ := factoid.NewRCD_3(height+10, 0, testHelper.NewFactoidRCDAddress(1))
+	data, _ := rcd.MarshalBinary()
+	req := &FactoidBalanceRequest{Address: testHelper.NewFactoidAddress(1).String(), RCD: hex.EncodeToString(data)}
+	if _, jsonError := HandleV2FactoidBalance(state, req); jsonError == nil {
+		t.Error("Accepted the RCD of another address")
+	}
+}
+
+func TestHandleV2Activations(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+
+	resp, jsonError := HandleV2Activations(state, nil)
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	r := resp.(*ActivationsResponse)
+	if r.Network != activations.NetworkName() || r.Schedule != activations.ScheduleHash(r.Network) {
+		t.Errorf("Wrong network %q or schedule %s", r.Network, r.Schedule)
+	}
+	if len(r.Activations) != activations.ACTIVATION_TYPE_COUNT {
+		t.Fatalf("Expected %d activations, got %d", activations.ACTIVATION_TYPE_COUNT, len(r.Activations))
+	}
+	for _, a := range r.Activations {
+		id := activations.ActivationType(a.ID)
+		if a.Name != id.String() || a.Description == "" {
+			t.Errorf("Wrong activation %+v", a)
+		}
+		if a.ActivationHeight != activations.GetActivationHeight(id, r.Network) || a.Active != state.IsActive(id) {
+			t.Errorf("Wrong status of %s: %+v", a.Name, a)
+		}
+		if a.ActivationHeight == math.MaxInt32 && a.Active {
+			t.Errorf("%s is active but never activates", a.Name)
+		}
+	}
+}
+
+func TestHandleV2ExchangeRateHistory(t *testing.T) {
+	st := testHelper.CreateAndPopulateTestState()
+
+	resp, jsonError := HandleV2ExchangeRateHistory(st, nil)
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	r := resp.(*state.FERHistoryStatus)
+	if r.CurrentRate != st.GetFactoshisPerEC() || r.Total != 0 || r.Pending != nil {
+		t.Errorf("Wrong history %+v", r)
+	}
+
+	for _, params := range []interface{}{
+		map[string]interface{}{"offset": -1},
+		map[string]interface{}{"limit": 0},
+		map[string]interface{}{"limit": 1001},
+	} {
+		if _, jsonError := HandleV2ExchangeRateHistory(st, params); jsonError == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
+
+func TestHandleV2CoinbaseReport(t *testing.T) {
+	st := testHelper.CreateAndPopulateTestState()
+
+	params := map[string]interface{}{"chainid": "888888aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}
+	resp, jsonError := HandleV2CoinbaseReport(st, params)
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	r := resp.(*state.CoinbaseReport)
+	if r.From != 0 || r.To != r.Height || len(r.Periods) != 0 {
+		t.Errorf("Wrong report %+v", r)
+	}
+
+	for _, params := range []interface{}{
+		map[string]interface{}{"chainid": "bad"},
+		map[string]interface{}{"chainid": params["chainid"], "from": 5, "to": 4},
+	} {
+		if _, jsonError := HandleV2CoinbaseReport(st, params); jsonError == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
+
+func TestHandleV2AuthoritySet(t *testing.T) {
+	st := testHelper.CreateAndPopulateTestState()
+
+	resp, jsonError := HandleV2AuthoritySet(st, nil)
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	// The test database has blocks 0 to 9
+	current := resp.(*state.AuthoritySet)
+	if current.Height != 10 || len(current.Federated) == 0 {
+		t.Errorf("Wrong current set %+v", current)
+	}
+
+	resp, jsonError = HandleV2AuthoritySet(st, map[string]interface{}{"height": 0})
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	if r := resp.(*state.AuthoritySet); r.Height != 0 || len(r.Federated) != 1 || !r.Federated[0].AuthorityChainID.IsSameAs(st.GetNetworkBootStrapIdentity()) {
+		t.Errorf("Expected only the bootstrap server before the genesis block, got %+v", r)
+	}
+
+	resp, jsonError = HandleV2AuthorityHistory(st, map[string]interface{}{"to": 0})
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	if changes := resp.([]state.AuthorityChange); len(changes) == 0 || changes[0].Type != "add-federated-server" {
+		t.Errorf("Expected the genesis server, got %+v", changes)
+	}
+
+	for _, params := range []interface{}{
+		map[string]interface{}{"chainid": "bad"},
+		map[string]interface{}{"from": 5, "to": 4},
+	} {
+		if _, jsonError := HandleV2AuthorityHistory(st, params); jsonError == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
+
+func TestHandleV2IdentityUpdatePreview(t *testing.T) {
+	st := testHelper.CreateAndPopulateTestState()
+
+	key := primitives.RandomPrivateKey()
+	ec, _ := primitives.PrivateKeyStringToHumanReadableECPrivateKey(primitives.RandomPrivateKey().PrivateKeyString())
+	kf := &identity.KeyFile{
+		IdentityChainID:   "888888aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		ManagementChainID: "888888bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		IdentityKey:       key.PrivateKeyString(),
+		ECPrivateKey:      ec,
+	}
+	if err := kf.Init(); err != nil {
+		t.Fatal(err)
+	}
+	u := identity.IdentityUpdate{Type: identity.UpdateEfficiency, Value: "12.34"}
+	e, err := kf.BuildIdentityUpdate(u, primitives.NewTimestampNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := e.MarshalBinary()
+	params := map[string]interface{}{"entry": hex.EncodeToString(data)}
+
+	if _, jsonError := HandleV2IdentityUpdatePreview(st, params); jsonError == nil {
+		t.Errorf("Previewed the update of an unknown identity")
+	}
+
+	id := identity.NewIdentity()
+	id.IdentityChainID, _ = primitives.HexToHash(kf.IdentityChainID)
+	id.ManagementChainID, _ = primitives.HexToHash(kf.ManagementChainID)
+	id.Keys[0] = primitives.Shad(append([]byte{0x01}, key.Pub[:]...))
+	st.IdentityControl.SetIdentity(id.IdentityChainID, id)
+
+	resp, jsonError := HandleV2IdentityUpdatePreview(st, params)
+	if jsonError != nil {
+		t.Fatalf("%v", jsonError)
+	}
+	if p := resp.(*identity.IdentityUpdatePreview); p.Type != u.Type || p.EntryHash != e.GetHash().String() {
+		t.Errorf("Wrong preview %+v", p)
+	}
+
+	for _, params := range []interface{}{
+		nil,
+		map[string]interface{}{"entry": "bad"},
+	} {
+		if _, jsonError := HandleV2IdentityUpdatePreview(st, params); jsonError == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
+
 func TestHandleV2CommitChain(t *testing.T) {
 	msg := new(MessageRequest)
 	// Can replace with any Chain message
@@ -246,6 +452,39 @@ func TestHandleV2CommitChain(t *testing.T) {
 	}
 }
 
+func TestHandleV2CommitTip(t *testing.T) {
+	state := testHelper.CreateAndPopulateTestState()
+	height := int(state.GetLLeaderHeight())
+
+	ce := entryCreditBlock.NewCommitEntry()
+	ce.EntryHash = testHelper.NewRepeatingHash(1)
+	ce.Credits = constants.MAX_ENTRY_CREDITS + constants.MAX_EC_TIP
+	testHelper.SignCommit(0, ce)
+	entryData, _ := ce.MarshalBinary()
+
+	cc := entryCreditBlock.NewCommitChain()
+	cc.EntryHash = testHelper.NewRepeatingHash(2)
+	cc.Credits = constants.MAX_CHAIN_CREDITS + constants.MAX_EC_TIP
+	testHelper.SignCommit(0, cc)
+	chainData, _ := cc.MarshalBinary()
+
+	defer activations.SetTestActivationHeight(activations.EC_TIPS, height+1)()
+	if _, jsonError := HandleV2CommitEntry(state, &MessageRequest{Message: hex.EncodeToString(entryData)}); jsonError == nil {
+		t.Error("Accepted a tipped entry commit before the activation")
+	}
+	if _, jsonError := HandleV2CommitChain(state, &MessageRequest{Message: hex.EncodeToString(chainData)}); jsonError == nil {
+		t.Error("Accepted a tipped chain commit before the activation")
+	}
+
+	activations.SetTestActivationHeight(activations.EC_TIPS, height)
+	if _, jsonError := HandleV2CommitEntry(state, &MessageRequest{Message: hex.EncodeToString(entryData)}); jsonError != nil {
+		t.Errorf("Tipped entry commit: %v", jsonError)
+	}
+	if _, jsonError := HandleV2CommitChain(state, &MessageRequest{Message: hex.EncodeToString(chainData)}); jsonError != nil {
+		t.Errorf("Tipped chain commit: %v", jsonError)
+	}
+}
+
 func TestHandleV2GetReceipt(t *testing.T) {
 	state := testHelper.CreateAndPopulateTestState()
 	//Start(state)